@@ -0,0 +1,135 @@
+// crmstub — локальная заглушка внешней CRM для ручной проверки синхронизации.
+//
+// Принимает исходящие вебхуки приложения на POST /webhook (проверяет подпись, сохраняет события
+// в памяти, по флагу -fail отвечает ошибкой первые N раз, чтобы проверить повторы и dead letters),
+// показывает полученные события на GET /events и отправляет в приложение подписанное событие
+// student.upsert через POST /send с данными студента в теле.
+//
+//	go run ./cmd/crmstub -secret <CRM_WEBHOOK_SECRET> -target http://localhost:8080/integrations/crm/webhook
+//
+// В приложении: CRM_WEBHOOK_URL=http://localhost:8090/webhook
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"it_school/crm"
+	"it_school/models"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type stub struct {
+	secret string
+	target string
+
+	mu       sync.Mutex
+	failLeft int
+	events   []models.CrmEvent
+}
+
+func main() {
+	addr := flag.String("addr", ":8090", "адрес заглушки")
+	secret := flag.String("secret", "", "общий секрет подписи (CRM_WEBHOOK_SECRET)")
+	target := flag.String("target", "http://localhost:8080/integrations/crm/webhook", "входящий вебхук приложения")
+	fail := flag.Int("fail", 0, "сколько первых доставок отклонить с 500")
+	flag.Parse()
+
+	s := &stub{secret: *secret, target: *target, failLeft: *fail}
+
+	http.HandleFunc("POST /webhook", s.webhook)
+	http.HandleFunc("GET /events", s.list)
+	http.HandleFunc("POST /send", s.send)
+
+	log.Printf("CRM stub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (s *stub) webhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := crm.Verify(s.secret, r.Header.Get(crm.HeaderTimestamp), r.Header.Get(crm.HeaderSignature), body, time.Now()); err != nil {
+		log.Printf("rejected %s: %v", r.Header.Get(crm.HeaderDelivery), err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failLeft > 0 {
+		s.failLeft--
+		log.Printf("simulated failure for %s", r.Header.Get(crm.HeaderDelivery))
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+		return
+	}
+
+	var event models.CrmEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, event)
+	log.Printf("received %s %s: %s", event.Event, event.Id, event.Data)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *stub) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.events)
+}
+
+// send подписывает данные студента и отправляет их в приложение как событие student.upsert
+func (s *stub) send(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(data) {
+		http.Error(w, "body must be student JSON", http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(models.CrmEvent{
+		Id:         uuid.New(),
+		Event:      "student.upsert",
+		OccurredAt: time.Now(),
+		Data:       data,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, s.target, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(crm.HeaderTimestamp, timestamp)
+	req.Header.Set(crm.HeaderSignature, crm.Sign(s.secret, timestamp, body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
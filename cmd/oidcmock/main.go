@@ -0,0 +1,41 @@
+// oidcmock — локальный OpenID Connect провайдер для ручной проверки входа через OIDC.
+//
+// Любой вход сразу завершается от имени пользователя из флагов, без страницы логина.
+// Флаги -unverified и -hd позволяют проверить отказ по неподтвержденному email и чужому домену.
+//
+//	go run ./cmd/oidcmock -email curator@school.kz -name "Иван Петров"
+//
+// В приложении: OIDC_ISSUER=http://localhost:8091, OIDC_CLIENT_ID=it_school, OIDC_CLIENT_SECRET=любой,
+// OIDC_REDIRECT_URL=http://localhost:8080/auth/oidc/callback
+package main
+
+import (
+	"flag"
+	"it_school/oidcmock"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":8091", "адрес провайдера")
+	issuer := flag.String("issuer", "http://localhost:8091", "issuer (OIDC_ISSUER приложения)")
+	email := flag.String("email", "user@example.com", "email пользователя")
+	name := flag.String("name", "Test User", "имя пользователя")
+	hd := flag.String("hd", "", "домен Google Workspace (claim hd)")
+	unverified := flag.Bool("unverified", false, "выдавать email_verified=false")
+	flag.Parse()
+
+	provider, err := oidcmock.New(*issuer, oidcmock.Identity{
+		Subject:       *email,
+		Email:         *email,
+		EmailVerified: !*unverified,
+		Name:          *name,
+		HostedDomain:  *hd,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("OIDC mock provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, provider))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"it_school/config"
+	"it_school/events"
+	"it_school/logger"
+	"it_school/redact"
+	"it_school/repositories"
+	"it_school/workers"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const usage = `Использование: it_school [команда]
+
+Без команды запускается HTTP-сервер.
+
+Команды:
+  cleanup   удалить истекшие сессии и токены сброса пароля и вывести количество удаленных записей
+`
+
+// commandTimeout ограничивает время выполнения служебных команд
+const commandTimeout = 5 * time.Minute
+
+// runCommand выполняет служебную команду и возвращает код завершения процесса
+func runCommand(args []string) int {
+	switch args[0] {
+	case "cleanup":
+		return runCleanup()
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runCleanup() int {
+	configErr := loadConfig()
+	if configErr == nil {
+		configErr = logger.Configure(config.Config.LogLevel, config.Config.LogFormat)
+		redact.SetEnabled(config.Config.LogRedactPII)
+	}
+
+	logger := logger.GetLogger()
+	defer logger.Sync()
+
+	if configErr != nil {
+		logger.Error("Failed to load config", zap.Error(configErr))
+		return 1
+	}
+
+	conn, err := connectToDb()
+	if err != nil {
+		logger.Error("Database connection failed", zap.Error(err))
+		return 1
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	result, err := workers.RunMaintenance(ctx, repositories.NewSessionsRepository(conn), repositories.NewAuthRepository(conn),
+		events.NewDispatcher(conn), config.Config.OutboxRetention)
+	if err != nil {
+		logger.Error("Cleanup failed", zap.Error(err))
+		return 1
+	}
+
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	return 0
+}
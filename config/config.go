@@ -5,15 +5,78 @@ import "time"
 var Config *MapConfig
 
 type MapConfig struct {
-	DbConnectionString string  		 `mapstructure:"DATABASE_URL"`
-	JwtSecretKey       string  		 `mapstructure:"JWT_SECRET_KEY"`
-	JwtExpiresIn       time.Duration 	 `mapstructure:"JWT_EXPIRE_DURATION"`
-	SMTPEmail   	   string 		 `mapstructure:"SMTP_EMAIL"`
-    	SMTPPassword 	   string 		 `mapstructure:"SMTP_PASSWORD"`
-    	SMTPHost     	   string 		 `mapstructure:"SMTP_HOST"`
-    	SMTPPort     	   string 		 `mapstructure:"SMTP_PORT"`
-    	Initial_Password   string 		 `mapstructure:"INITIAL_PASSWORD"`
-   	Admin_Name   	   string 		 `mapstructure:"ADMIN_NAME"`
-    	Admin_Mail   	   string 		 `mapstructure:"ADMIN_MAIL"`
-    	Admin_Phone   	   string 		 `mapstructure:"ADMIN_PHONE"`
+	DbConnectionString string        `mapstructure:"DATABASE_URL"`
+	JwtSecretKey       string        `mapstructure:"JWT_SECRET_KEY"`
+	JwtExpiresIn       time.Duration `mapstructure:"JWT_EXPIRE_DURATION"`
+	SMTPEmail          string        `mapstructure:"SMTP_EMAIL"`
+	SMTPPassword       string        `mapstructure:"SMTP_PASSWORD"`
+	SMTPHost           string        `mapstructure:"SMTP_HOST"`
+	SMTPPort           string        `mapstructure:"SMTP_PORT"`
+	Initial_Password   string        `mapstructure:"INITIAL_PASSWORD"`
+	Admin_Name         string        `mapstructure:"ADMIN_NAME"`
+	Admin_Mail         string        `mapstructure:"ADMIN_MAIL"`
+	Admin_Phone        string        `mapstructure:"ADMIN_PHONE"`
+
+	// OpenID Connect (Google Workspace и др.)
+	OIDCIssuer        string `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID      string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret  string `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL   string `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDCAllowedDomain string `mapstructure:"OIDC_ALLOWED_DOMAIN"`
+	OIDCAutoProvision bool   `mapstructure:"OIDC_AUTO_PROVISION"`
+	OIDCDefaultRole   string `mapstructure:"OIDC_DEFAULT_ROLE"`
+	// Филиал (по названию), к которому прикрепляется пользователь, созданный при входе через OIDC
+	OIDCDefaultBranch string `mapstructure:"OIDC_DEFAULT_BRANCH"`
+
+	// Каталог для загруженных и сгенерированных файлов (домашние задания, сертификаты)
+	StorageDir          string `mapstructure:"STORAGE_DIR"`
+	HomeworkMaxUploadMB int64  `mapstructure:"HOMEWORK_MAX_UPLOAD_MB"`
+
+	// TTF-шрифт с кириллицей для PDF-табелей и сертификатов
+	ReportFontPath string `mapstructure:"REPORT_FONT_PATH"`
+
+	// Синхронизация с внешней CRM: адрес исходящих вебхуков и общий секрет подписи в обе стороны
+	CrmWebhookURL         string `mapstructure:"CRM_WEBHOOK_URL"`
+	CrmWebhookSecret      string `mapstructure:"CRM_WEBHOOK_SECRET"`
+	CrmWebhookMaxAttempts int    `mapstructure:"CRM_WEBHOOK_MAX_ATTEMPTS"`
+
+	// Исходящие вебхуки доменных событий: число попыток доставки до статуса failed
+	WebhookMaxAttempts int `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+
+	// Логи: уровень (debug, info, warn, error) и формат (json или console)
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	// Маскирование телефонов, email и имен в логах; выключать только для локальной разработки
+	LogRedactPII bool `mapstructure:"LOG_REDACT_PII"`
+
+	// Язык сообщений об ошибках и подписей перечислений, если Accept-Language не задан (ru, kk, en)
+	DefaultLanguage string `mapstructure:"DEFAULT_LANGUAGE"`
+
+	// Трассировка OpenTelemetry: экспортер (none, otlp, stdout), имя сервиса, доля трассируемых
+	// запросов и адрес OTLP/HTTP-коллектора (по умолчанию из OTEL_EXPORTER_OTLP_ENDPOINT)
+	TracingExporter     string  `mapstructure:"TRACING_EXPORTER"`
+	TracingServiceName  string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingSampleRatio  float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	TracingOTLPEndpoint string  `mapstructure:"TRACING_OTLP_ENDPOINT"`
+
+	// Токен для /metrics (Authorization: Bearer); пустой — метрики открыты
+	MetricsToken string `mapstructure:"METRICS_TOKEN"`
+
+	// HTTP-сервер: таймауты соединений и время на завершение запросов при остановке
+	ServerReadTimeout  time.Duration `mapstructure:"SERVER_READ_TIMEOUT"`
+	ServerWriteTimeout time.Duration `mapstructure:"SERVER_WRITE_TIMEOUT"`
+	ServerIdleTimeout  time.Duration `mapstructure:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout    time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// Периодические задания. MAINTENANCE_INTERVAL — удаление истекших сессий и токенов сброса пароля
+	MaintenanceInterval time.Duration `mapstructure:"MAINTENANCE_INTERVAL"`
+	// Обработанные события outbox старше OUTBOX_RETENTION удаляются при обслуживании
+	OutboxRetention       time.Duration `mapstructure:"OUTBOX_RETENTION"`
+	TrialReminderInterval time.Duration `mapstructure:"TRIAL_REMINDER_INTERVAL"`
+	TrialReminderAhead    time.Duration `mapstructure:"TRIAL_REMINDER_AHEAD"`
+	// Корзина: записи старше TRASH_RETENTION удаляются окончательно раз в TRASH_PURGE_INTERVAL; 0 отключает очистку
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
+	// Как часто состояние студентов сверяется с заморозками (active ⇄ frozen); 0 отключает задание
+	LifecycleFreezeInterval time.Duration `mapstructure:"LIFECYCLE_FREEZE_INTERVAL"`
 }
@@ -0,0 +1,212 @@
+package crm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"it_school/events"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	pollInterval   = 5 * time.Second
+	batchSize      = 50
+	baseBackoff    = time.Second
+	maxBackoff     = time.Minute
+	// deliveryLease — на это время выбранные доставки скрыты от других экземпляров.
+	// Покрывает пачку целиком: batchSize попыток по requestTimeout
+	deliveryLease = batchSize * requestTimeout
+)
+
+// Client отправляет изменения студентов и оплат во внешнюю CRM. События CRM (events.CrmPrefix)
+// записываются в outbox вместе с изменением данных; Handle переносит их в crm_deliveries,
+// а Run отправляет в фоне. Повторы планируются по next_attempt_at и не задерживают остальные события;
+// после исчерпания попыток событие переносится в crm_dead_letters. Nil-клиент (интеграция не настроена) ничего не делает
+type Client struct {
+	url         string
+	secret      string
+	maxAttempts int
+	http        *http.Client
+	wake        chan struct{}
+	crmRepo     *repositories.CrmRepository
+}
+
+// NewClient возвращает nil, если адрес вебхука CRM не задан
+func NewClient(url, secret string, maxAttempts int, crmRepo *repositories.CrmRepository) *Client {
+	if url == "" {
+		return nil
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Client{
+		url:         url,
+		secret:      secret,
+		maxAttempts: maxAttempts,
+		http:        &http.Client{Timeout: requestTimeout},
+		wake:        make(chan struct{}, 1),
+		crmRepo:     crmRepo,
+	}
+}
+
+// Handle — обработчик событий из outbox: ставит события CRM в очередь доставки.
+// ID события outbox становится ID события CRM, поэтому повторная обработка не создает дубликат
+func (cl *Client) Handle(ctx context.Context, event events.Event) error {
+	if cl == nil || !strings.HasPrefix(event.Type, events.CrmPrefix) {
+		return nil
+	}
+
+	enqueued, err := cl.crmRepo.EnqueueDelivery(ctx, models.CrmEvent{
+		Id:         event.Id,
+		Event:      strings.TrimPrefix(event.Type, events.CrmPrefix),
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("enqueue crm delivery: %w", err)
+	}
+	if enqueued {
+		cl.Wake()
+	}
+	return nil
+}
+
+// Wake запускает отправку, не дожидаясь очередного опроса
+func (cl *Client) Wake() {
+	select {
+	case cl.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет доставки до отмены контекста
+func (cl *Client) Run(ctx context.Context) {
+	if cl == nil {
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		cl.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cl.wake:
+		}
+	}
+}
+
+func (cl *Client) deliverDue(ctx context.Context) {
+	logger := logger.GetLogger()
+
+	for ctx.Err() == nil {
+		deliveries, err := cl.crmRepo.DueDeliveries(ctx, batchSize, deliveryLease)
+		if err != nil {
+			logger.Error("Failed to fetch CRM deliveries", zap.Error(err))
+			return
+		}
+
+		for _, delivery := range deliveries {
+			cl.deliver(ctx, delivery)
+		}
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (cl *Client) deliver(ctx context.Context, delivery repositories.CrmDelivery) {
+	logger := logger.GetLogger()
+
+	err := cl.Deliver(ctx, delivery.Event)
+	if err == nil {
+		if err := cl.crmRepo.DeleteDelivery(ctx, delivery.Id); err != nil {
+			logger.Error("Failed to remove delivered CRM event", zap.String("event_id", delivery.Event.Id.String()), zap.Error(err))
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	final := attempts >= cl.maxAttempts
+
+	logger.Warn("CRM webhook delivery failed",
+		zap.String("event", delivery.Event.Event),
+		zap.String("event_id", delivery.Event.Id.String()),
+		zap.Int("attempt", attempts),
+		zap.Bool("final", final),
+		zap.Error(err))
+
+	if final {
+		if err := cl.crmRepo.MoveToDeadLetters(ctx, delivery.Id, err.Error()); err != nil {
+			logger.Error("Failed to save CRM dead letter", zap.String("event_id", delivery.Event.Id.String()), zap.Error(err))
+			return
+		}
+		logger.Error("CRM event moved to dead letters",
+			zap.String("event", delivery.Event.Event),
+			zap.String("event_id", delivery.Event.Id.String()),
+			zap.String("last_error", err.Error()))
+		return
+	}
+
+	if err := cl.crmRepo.MarkDeliveryFailed(ctx, delivery.Id, err.Error(), time.Now().Add(backoff(attempts))); err != nil {
+		logger.Error("Failed to record CRM attempt", zap.String("event_id", delivery.Event.Id.String()), zap.Error(err))
+	}
+}
+
+// backoff — экспоненциальная задержка перед попыткой attempts+1
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// Deliver выполняет одну попытку доставки события. Успехом считается любой ответ 2xx
+func (cl *Client) Deliver(ctx context.Context, event models.CrmEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cl.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Event)
+	req.Header.Set(HeaderDelivery, event.Id.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(cl.secret, timestamp, body))
+
+	resp, err := cl.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("crm responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package crm
+
+import (
+	"crypto/hmac"
+	"errors"
+	"it_school/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки вебхуков в обе стороны
+const (
+	HeaderEvent     = "X-Crm-Event"
+	HeaderDelivery  = "X-Crm-Delivery"
+	HeaderTimestamp = "X-Crm-Timestamp"
+	HeaderSignature = "X-Crm-Signature"
+)
+
+// SignatureTolerance — допустимое расхождение времени подписи, защищает от повторной отправки старых запросов
+const SignatureTolerance = 5 * time.Minute
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign подписывает тело запроса так же, как исходящие вебхуки приложения
+func Sign(secret, timestamp string, body []byte) string {
+	return utils.SignWebhook(secret, timestamp, body)
+}
+
+// Verify проверяет подпись и свежесть входящего вебхука
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) error {
+	if secret == "" || !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if diff := now.Sub(time.Unix(unix, 0)); diff > SignatureTolerance || diff < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package crm
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "secret"
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"student.upsert"}`)
+
+	at := func(ts time.Time) string { return strconv.FormatInt(ts.Unix(), 10) }
+	signed := func(ts time.Time) string { return Sign(secret, at(ts), body) }
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		wantErr   bool
+	}{
+		{name: "valid", secret: secret, timestamp: at(now), signature: signed(now), body: body},
+		{name: "clock skew within tolerance", secret: secret, timestamp: at(now.Add(-SignatureTolerance)), signature: signed(now.Add(-SignatureTolerance)), body: body},
+		{name: "future within tolerance", secret: secret, timestamp: at(now.Add(SignatureTolerance)), signature: signed(now.Add(SignatureTolerance)), body: body},
+		{name: "expired", secret: secret, timestamp: at(now.Add(-SignatureTolerance - time.Second)), signature: signed(now.Add(-SignatureTolerance - time.Second)), body: body, wantErr: true},
+		{name: "too far in future", secret: secret, timestamp: at(now.Add(SignatureTolerance + time.Second)), signature: signed(now.Add(SignatureTolerance + time.Second)), body: body, wantErr: true},
+		{name: "tampered body", secret: secret, timestamp: at(now), signature: signed(now), body: []byte(`{"event":"student.deleted"}`), wantErr: true},
+		{name: "timestamp not signed", secret: secret, timestamp: at(now.Add(time.Second)), signature: signed(now), body: body, wantErr: true},
+		{name: "wrong secret", secret: "other", timestamp: at(now), signature: signed(now), body: body, wantErr: true},
+		{name: "empty secret", secret: "", timestamp: at(now), signature: Sign("", at(now), body), body: body, wantErr: true},
+		{name: "missing prefix", secret: secret, timestamp: at(now), signature: signed(now)[len("sha256="):], body: body, wantErr: true},
+		{name: "missing signature", secret: secret, timestamp: at(now), signature: "", body: body, wantErr: true},
+		{name: "malformed timestamp", secret: secret, timestamp: "yesterday", signature: Sign(secret, "yesterday", body), body: body, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, now)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() = %v, want ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() = %v, want nil", err)
+			}
+		})
+	}
+}
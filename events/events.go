@@ -0,0 +1,93 @@
+// Package events — доменные события приложения. Репозитории записывают события в outbox
+// в транзакции доменного изменения, Dispatcher в фоне передает их подписчикам (вебхуки и т.п.).
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Типы доменных событий
+const (
+	StudentCreated            = "student.created"
+	AttendanceLessonConducted = "attendance.lesson.conducted"
+	ProlongationCreated       = "prolongation.created"
+	FreezeCreated             = "freeze.created"
+	UserRoleChanged           = "user.role_changed"
+	StudentLifecycleChanged   = "student.lifecycle_changed"
+)
+
+// Types — все типы событий, на которые можно подписаться
+var Types = []string{
+	StudentCreated,
+	AttendanceLessonConducted,
+	ProlongationCreated,
+	FreezeCreated,
+	UserRoleChanged,
+	StudentLifecycleChanged,
+}
+
+// Служебные события синхронизации с CRM. Не входят в Types — на них нельзя подписаться вебхуком,
+// их обрабатывает crm.Client и отправляет в CRM с типом без префикса CrmPrefix
+const (
+	CrmPrefix         = "crm."
+	CrmStudentCreated = CrmPrefix + "student.created"
+	CrmStudentUpdated = CrmPrefix + "student.updated"
+	CrmStudentDeleted = CrmPrefix + "student.deleted"
+	CrmPaymentCreated = CrmPrefix + "payment.created"
+	CrmPaymentUpdated = CrmPrefix + "payment.updated"
+)
+
+// Event — доменное событие в том виде, в котором оно уходит подписчикам
+type Event struct {
+	Id         uuid.UUID       `json:"id"`
+	Type       string          `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// Handler обрабатывает событие из outbox. Доставка «хотя бы один раз»: при ошибке любого
+// обработчика событие повторяется для всех, поэтому обработчики должны быть идемпотентны
+type Handler func(ctx context.Context, event Event) error
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe регистрирует обработчик всех событий
+func Subscribe(handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+func dispatch(ctx context.Context, event Event) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record записывает событие в outbox в той же транзакции, что и доменное изменение.
+// Если транзакция откатится, событие не будет опубликовано; после фиксации его доставит Dispatcher
+func Record(c context.Context, tx pgx.Tx, eventType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(c,
+		`INSERT INTO outbox (id, event, data) VALUES ($1, $2, $3)`,
+		uuid.New(), eventType, raw,
+	)
+	return err
+}
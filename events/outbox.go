@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"it_school/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	outboxBatchSize    = 50
+	// outboxLease — на это время выбранные события скрыты от других экземпляров.
+	// Если процесс упадет, не отметив событие, после аренды оно будет обработано повторно
+	outboxLease       = time.Minute
+	outboxBaseBackoff = 10 * time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+// Dispatcher читает неотправленные события из outbox и передает их подписчикам.
+// Несколько экземпляров приложения могут работать одновременно: события арендуются
+// через SELECT ... FOR UPDATE SKIP LOCKED
+type Dispatcher struct {
+	db *pgxpool.Pool
+}
+
+func NewDispatcher(conn *pgxpool.Pool) *Dispatcher {
+	return &Dispatcher{db: conn}
+}
+
+// Run обрабатывает outbox до отмены контекста. Начатая пачка событий дорабатывается
+// до конца, поэтому после возврата из Run можно закрывать пул соединений
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := d.processBatch(context.WithoutCancel(ctx))
+			if err != nil {
+				logger.GetLogger().Error("Failed to process outbox", zap.Error(err))
+				break
+			}
+			if processed < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lease арендует пачку готовых к отправке событий, сдвигая available_at на время аренды
+func (d *Dispatcher) lease(ctx context.Context) ([]Event, []int, error) {
+	rows, err := d.db.Query(ctx, `
+		UPDATE outbox o
+		SET available_at = now() + $1::interval
+		FROM (
+			SELECT id FROM outbox
+			WHERE processed_at IS NULL AND available_at <= now()
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE o.id = due.id
+		RETURNING o.id, o.event, o.created_at, o.data, o.attempts`,
+		outboxLease, outboxBatchSize,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var leased []Event
+	var attempts []int
+	for rows.Next() {
+		var event Event
+		var n int
+		if err := rows.Scan(&event.Id, &event.Type, &event.OccurredAt, &event.Data, &n); err != nil {
+			return nil, nil, err
+		}
+		leased = append(leased, event)
+		attempts = append(attempts, n)
+	}
+	return leased, attempts, rows.Err()
+}
+
+func (d *Dispatcher) processBatch(ctx context.Context) (int, error) {
+	logger := logger.GetLogger()
+
+	leased, attempts, err := d.lease(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range leased {
+		if err := dispatch(ctx, event); err != nil {
+			delay := outboxBackoff(attempts[i] + 1)
+			logger.Warn("Outbox event handling failed",
+				zap.String("event", event.Type),
+				zap.String("event_id", event.Id.String()),
+				zap.Int("attempt", attempts[i]+1),
+				zap.Duration("retry_in", delay),
+				zap.Error(err))
+			if err := d.markFailed(ctx, event.Id, delay, err.Error()); err != nil {
+				return i, err
+			}
+			continue
+		}
+
+		if err := d.markProcessed(ctx, event.Id); err != nil {
+			return i, err
+		}
+	}
+	return len(leased), nil
+}
+
+func (d *Dispatcher) markProcessed(ctx context.Context, id uuid.UUID) error {
+	_, err := d.db.Exec(ctx,
+		`UPDATE outbox SET processed_at = now(), attempts = attempts + 1, last_error = NULL WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+func (d *Dispatcher) markFailed(ctx context.Context, id uuid.UUID, delay time.Duration, lastError string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $1, available_at = now() + $2::interval
+		WHERE id = $3`,
+		lastError, delay, id,
+	)
+	return err
+}
+
+// PurgeProcessed удаляет события, обработанные раньше before, и возвращает их количество
+func (d *Dispatcher) PurgeProcessed(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := d.db.Exec(ctx, `DELETE FROM outbox WHERE processed_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// outboxBackoff — экспоненциальная задержка перед попыткой attempts+1
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StudentCreatedData — данные события student.created
+type StudentCreatedData struct {
+	StudentId uuid.UUID   `json:"student_id"`
+	BranchId  uuid.UUID   `json:"branch_id"`
+	FullName  string      `json:"full_name"`
+	CuratorId *uuid.UUID  `json:"curator_id"`
+	CourseIds []uuid.UUID `json:"course_ids"`
+}
+
+// LessonConductedData — данные события attendance.lesson.conducted
+type LessonConductedData struct {
+	AttendanceId  uuid.UUID  `json:"attendance_id"`
+	StudentId     uuid.UUID  `json:"student_id"`
+	CourseId      uuid.UUID  `json:"course_id"`
+	CuratorId     uuid.UUID  `json:"curator_id"`
+	Date          time.Time  `json:"date"`
+	TopicId       *uuid.UUID `json:"topic_id"`
+	GroupLessonId *uuid.UUID `json:"group_lesson_id"`
+}
+
+// ProlongationCreatedData — данные события prolongation.created
+type ProlongationCreatedData struct {
+	AttendanceId uuid.UUID `json:"attendance_id"`
+	StudentId    uuid.UUID `json:"student_id"`
+	CourseId     uuid.UUID `json:"course_id"`
+	PaymentType  string    `json:"payment_type"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Comment      *string   `json:"comment"`
+}
+
+// FreezeCreatedData — данные события freeze.created
+type FreezeCreatedData struct {
+	AttendanceId uuid.UUID `json:"attendance_id"`
+	StudentId    uuid.UUID `json:"student_id"`
+	CourseId     uuid.UUID `json:"course_id"`
+	StartDate    time.Time `json:"start_date"`
+	EndDate      time.Time `json:"end_date"`
+	Comment      *string   `json:"comment"`
+}
+
+// UserRoleChangedData — данные события user.role_changed
+type UserRoleChangedData struct {
+	UserId    uuid.UUID  `json:"user_id"`
+	OldRoleId *uuid.UUID `json:"old_role_id"`
+	NewRoleId uuid.UUID  `json:"new_role_id"`
+}
+
+// StudentLifecycleChangedData — данные события student.lifecycle_changed
+type StudentLifecycleChangedData struct {
+	StudentId uuid.UUID  `json:"student_id"`
+	FromState string     `json:"from_state"`
+	ToState   string     `json:"to_state"`
+	Source    string     `json:"source"`
+	ChangedAt time.Time  `json:"changed_at"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+}
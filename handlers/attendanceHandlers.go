@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"it_school/logger"
+	"it_school/metrics"
 	"it_school/models"
 	"it_school/repositories"
 	"it_school/utils"
@@ -10,57 +12,57 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
-	type AttendanceHandlers struct {
-		attendanceRepo *repositories.AttendanceRepository
-	}
-
-	func NewAttendanceHandlers(attendanceRepo *repositories.AttendanceRepository) *AttendanceHandlers {
-		return &AttendanceHandlers{attendanceRepo: attendanceRepo}
-	}
-
-	type CreateAttendanceRequest struct {
-		StudentId     uuid.UUID                   `json:"student_id" binding:"required"`
-		CourseId      uuid.UUID                   `json:"course_id" binding:"required"`
-		Type          string                      `json:"type" binding:"required,oneof=урок заморозка пролонгация"`
-		Lesson        *AttendanceLessonInput      `json:"lesson,omitempty"`
-		Freeze        *AttendanceFreezeInput      `json:"freeze,omitempty"`
-		Prolongation  *AttendanceProlongationInput `json:"prolongation,omitempty"`
-	}
+type AttendanceHandlers struct {
+	attendanceRepo *repositories.AttendanceRepository
+}
 
-	type AttendanceLessonInput struct {
-		CuratorId     uuid.UUID  `json:"curator_id"`
-		Date          string     `json:"date"`
-		Format        *string    `json:"format"`
-		Feedback      *string    `json:"feedback"`
-		FeedbackDate  *string    `json:"feedback_date"`
-		LessonStatus  string     `json:"lessons_status" binding:"required,oneof=пропущен проведен запланирован отменен"`
-	}
+func NewAttendanceHandlers(attendanceRepo *repositories.AttendanceRepository) *AttendanceHandlers {
+	return &AttendanceHandlers{attendanceRepo: attendanceRepo}
+}
 
-	type AttendanceFreezeInput struct {
-		StartDate string  `json:"start_date"`
-		EndDate   string  `json:"end_date"`
-		Comment   *string `json:"comment"`
-	}
+type CreateAttendanceRequest struct {
+	StudentId    uuid.UUID                    `json:"student_id" binding:"required"`
+	CourseId     uuid.UUID                    `json:"course_id" binding:"required"`
+	Type         models.EnumCode              `json:"type" binding:"required,oneof=lesson freeze prolongation"`
+	Lesson       *AttendanceLessonInput       `json:"lesson,omitempty"`
+	Freeze       *AttendanceFreezeInput       `json:"freeze,omitempty"`
+	Prolongation *AttendanceProlongationInput `json:"prolongation,omitempty"`
+}
 
-	type AttendanceProlongationInput struct {
-		PaymentType string  `json:"payment_type" binding:"required,oneof=оплата предоплата доплата"`
-		Date        string  `json:"date"`
-		Amount      float64 `json:"amount"`
-		Comment     *string `json:"comment"`
-	}
+type AttendanceLessonInput struct {
+	CuratorId    uuid.UUID       `json:"curator_id"`
+	Date         string          `json:"date"`
+	Format       *string         `json:"format"`
+	Feedback     *string         `json:"feedback"`
+	FeedbackDate *string         `json:"feedback_date"`
+	LessonStatus models.EnumCode `json:"lessons_status" binding:"required,oneof=missed conducted scheduled canceled"`
+	TopicId      *uuid.UUID      `json:"topic_id"`
+}
 
+type AttendanceFreezeInput struct {
+	StartDate string  `json:"start_date"`
+	EndDate   string  `json:"end_date"`
+	Comment   *string `json:"comment"`
+}
 
+type AttendanceProlongationInput struct {
+	PaymentType models.EnumCode `json:"payment_type" binding:"required,oneof=payment prepayment additional_payment"`
+	Date        string          `json:"date"`
+	Amount      float64         `json:"amount"`
+	Comment     *string         `json:"comment"`
+}
 
 // CreateAttendance godoc
 // @Summary Создать запись посещаемости
 // @Description Добавляет новую запись: урок, заморозку или пролонгацию
 // @Description Допустимые значения:
-// @Description - type: урок, заморозка, пролонгация
-// @Description - lessons_status: пропущен, проведен, запланирован, отменен
-// @Description - payment_type: оплата, предоплата, доплата
+// @Description - type: lesson, freeze, prolongation
+// @Description - lessons_status: missed, conducted, scheduled, canceled
+// @Description - payment_type: payment, prepayment, additional_payment
 // @Tags Attendance
 // @Accept json
 // @Produce json
@@ -68,18 +70,18 @@ import (
 // @Success 201 {object} map[string]string
 // @Router /attendances [post]
 func (h *AttendanceHandlers) CreateAttendance(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 	var req CreateAttendanceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request"))
+		c.Error(models.NewBindingError("Invalid request", err))
 		return
 	}
 
 	attendance := &models.Attendance{
 		StudentId: req.StudentId,
 		CourseId:  req.CourseId,
-		Type:      req.Type,
+		Type:      string(req.Type),
 		CreatedAt: time.Now(),
 	}
 
@@ -90,36 +92,36 @@ func (h *AttendanceHandlers) CreateAttendance(c *gin.Context) {
 	roleObj, _ := c.Get("userRole")
 	role := roleObj.(*models.Role)
 
-	if !utils.HasAccessToType(role, req.Type) {
-		c.JSON(http.StatusForbidden, models.NewApiError("You are not allowed to create this type of attendance"))
+	if !utils.HasAccessToType(role, string(req.Type)) {
+		c.Error(models.NewForbiddenError("attendance_type_forbidden", "You are not allowed to create this type of attendance"))
 		return
 	}
 
 	switch req.Type {
-	case "урок":
+	case models.AttendanceTypeLesson:
 		if req.Lesson == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Lesson data is required"))
+			c.Error(models.ErrMissingLessonData)
 			return
 		}
 
 		parsedDate, err := utils.ParseRequiredDate(req.Lesson.Date)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid lesson date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_lesson_date", "Invalid lesson date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		feedbackDate, err := utils.ParseDate(req.Lesson.FeedbackDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid feedback date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_feedback_date", "Invalid feedback date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		status := req.Lesson.LessonStatus
 		if status == "" {
 			if parsedDate.After(time.Now()) {
-				status = "запланирован"
+				status = models.LessonScheduled
 			} else {
-				status = "проведен"
+				status = models.LessonConducted
 			}
 		}
 
@@ -128,67 +130,79 @@ func (h *AttendanceHandlers) CreateAttendance(c *gin.Context) {
 			Date:         parsedDate,
 			Format:       req.Lesson.Format,
 			Feedback:     req.Lesson.Feedback,
-			LessonStatus: status,
+			LessonStatus: string(status),
 			CreatedAt:    time.Now(),
 			FeedbackDate: feedbackDate,
+			TopicId:      req.Lesson.TopicId,
 		}
 
-	case "заморозка":
+	case models.AttendanceTypeFreeze:
 		if req.Freeze == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Freeze data is required"))
+			c.Error(models.ErrMissingFreezeData)
 			return
 		}
 
 		startDate, err := utils.ParseRequiredDate(req.Freeze.StartDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid start date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_start_date", "Invalid start date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		endDate, err := utils.ParseRequiredDate(req.Freeze.EndDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid end date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_end_date", "Invalid end date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		if startDate.After(endDate) {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Start date must be before end date"))
+			c.Error(models.NewValidationError("invalid_date_range", "Start date must be before end date"))
 			return
 		}
 
 		freeze = &models.AttendanceFreeze{
-			StartDate:    startDate,
-			EndDate:      endDate,
-			Comment:      req.Freeze.Comment,
+			StartDate: startDate,
+			EndDate:   endDate,
+			Comment:   req.Freeze.Comment,
 		}
 
-	case "пролонгация":
+	case models.AttendanceTypeProlongation:
 		if req.Prolongation == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Prolongation data is required"))
+			c.Error(models.ErrMissingProlongationData)
 			return
 		}
 
 		prolongationDate, err := utils.ParseRequiredDate(req.Prolongation.Date)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid prolongation date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_prolongation_date", "Invalid prolongation date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		prolongation = &models.AttendanceProlongation{
-			PaymentType:  req.Prolongation.PaymentType,
-			Date:         prolongationDate,
-			Amount:       req.Prolongation.Amount,
-			Comment:      req.Prolongation.Comment,
+			PaymentType: string(req.Prolongation.PaymentType),
+			Date:        prolongationDate,
+			Amount:      req.Prolongation.Amount,
+			Comment:     req.Prolongation.Comment,
 		}
 	}
 
 	id, err := h.attendanceRepo.CreateAttendance(c.Request.Context(), attendance, lesson, freeze, prolongation)
+	if errors.Is(err, models.ErrTopicNotInCourse) || errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to create attendance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not create attendance"))
+		c.Error(models.NewInternalError("Could not create attendance"))
 		return
 	}
 
+	switch {
+	case lesson != nil:
+		metrics.LessonsRecorded.WithLabelValues("individual").Inc()
+	case prolongation != nil:
+		metrics.RecordPayment(prolongation.PaymentType, prolongation.Amount)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
@@ -200,122 +214,274 @@ func (h *AttendanceHandlers) CreateAttendance(c *gin.Context) {
 // @Produce json
 // @Param studentId path string true "UUID студента"
 // @Success 200 {array} AttendanceFullResponse
-// @Failure 400 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /attendances/student/{studentId} [get]
 func (h *AttendanceHandlers) GetByStudent(c *gin.Context) {
-    logger := logger.GetLogger()
-    
-    // 1. Парсим и валидируем UUID студента
-    studentIDStr := c.Param("studentId")
-    studentID, err := uuid.Parse(studentIDStr)
-    if err != nil {
-        logger.Warn("Invalid student UUID format", zap.String("studentId", studentIDStr))
-        c.JSON(http.StatusBadRequest, models.NewApiError("Неверный формат UUID студента"))
-        return
-    }
-
-    // 2. Получаем данные из репозитория
-    attendances, err := h.attendanceRepo.FindFullByStudent(c.Request.Context(), studentID)
-    if err != nil {
-        logger.Error("Failed to get attendances from DB", 
-            zap.String("studentId", studentID.String()),
-            zap.Error(err))
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Ошибка при получении данных посещаемости"))
-        return
-    }
-
-    // 3. Если нет данных - возвращаем пустой массив, а не ошибку
-    if len(attendances) == 0 {
-        logger.Info("No attendances found for student", zap.String("studentId", studentID.String()))
-        c.JSON(http.StatusOK, []AttendanceFullResponse{})
-        return
-    }
-
-    // 4. Возвращаем успешный ответ
-    c.JSON(http.StatusOK, attendances)
+	logger := logger.FromContext(c)
+
+	// 1. Парсим и валидируем UUID студента
+	studentIDStr := c.Param("studentId")
+	studentID, err := uuid.Parse(studentIDStr)
+	if err != nil {
+		logger.Warn("Invalid student UUID format", zap.String("studentId", studentIDStr))
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	// 2. Получаем данные из репозитория
+	attendances, err := h.attendanceRepo.FindFullByStudent(c.Request.Context(), studentID)
+	if err != nil {
+		logger.Error("Failed to get attendances from DB",
+			zap.String("studentId", studentID.String()),
+			zap.Error(err))
+		c.Error(models.NewInternalError("Failed to get attendance data"))
+		return
+	}
+
+	// 3. Если нет данных - возвращаем пустой массив, а не ошибку
+	if len(attendances) == 0 {
+		logger.Info("No attendances found for student", zap.String("studentId", studentID.String()))
+		c.JSON(http.StatusOK, []AttendanceFullResponse{})
+		return
+	}
+
+	// 4. Возвращаем успешный ответ
+	c.JSON(http.StatusOK, attendances)
+}
+
+// GetById godoc
+// @Summary Получить запись посещаемости
+// @Description Возвращает запись посещаемости с уроком, заморозкой или пролонгацией. Заголовок ETag нужно передать в If-Match при обновлении
+// @Tags Attendance
+// @Produce json
+// @Param attendanceId path string true "ID записи посещаемости"
+// @Success 200 {object} AttendanceFullResponse
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /attendances/records/{attendanceId} [get]
+func (h *AttendanceHandlers) GetById(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	attendanceID, err := uuid.Parse(c.Param("attendanceId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_attendance_id", "Invalid attendance id"))
+		return
+	}
+
+	attendance, err := h.attendanceRepo.FindFullById(c.Request.Context(), attendanceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get attendance", zap.String("attendance_id", attendanceID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to get attendance data"))
+		return
+	}
+
+	setETag(c, attendance.Attendance.Version)
+	c.JSON(http.StatusOK, attendance)
 }
 
 // Структуры ответа
 type AttendanceFullResponse struct {
-    Attendance  *models.Attendance           `json:"attendance"`
-    Lesson      *models.AttendanceLesson     `json:"lesson,omitempty"`
-    Freeze      *models.AttendanceFreeze     `json:"freeze,omitempty"`
-    Prolongation *models.AttendanceProlongation `json:"prolongation,omitempty"`
+	Attendance   *models.Attendance             `json:"attendance"`
+	Lesson       *models.AttendanceLesson       `json:"lesson,omitempty"`
+	Freeze       *models.AttendanceFreeze       `json:"freeze,omitempty"`
+	Prolongation *models.AttendanceProlongation `json:"prolongation,omitempty"`
 }
 
 // UpdateAttendance godoc
 // @Summary Обновить запись посещаемости
-// @Description Обновляет запись посещаемости (урок, заморозка или пролонгация)
+// @Description Обновляет запись посещаемости (урок, заморозка или пролонгация).
+// @Description Заголовок If-Match обязателен: ETag из GET /attendances/records/{attendanceId}. Если запись уже изменили, возвращается 412
 // @Description Допустимые значения:
-// @Description - type: урок, заморозка, пролонгация
-// @Description - lessons_status: пропущен, проведен, запланирован, отменен
-// @Description - payment_type: оплата, предоплата, доплата
+// @Description - type: lesson, freeze, prolongation
+// @Description - lessons_status: missed, conducted, scheduled, canceled
+// @Description - payment_type: payment, prepayment, additional_payment
 // @Tags Attendance
 // @Accept json
 // @Produce json
 // @Param attendanceId path string true "ID записи посещаемости"
+// @Param If-Match header string true "ETag записи посещаемости"
 // @Param request body CreateAttendanceRequest true "Обновленные данные посещаемости"
 // @Success 200
-// @Failure 400 {object} models.ApiError
-// @Failure 403 {object} models.ApiError
-// @Failure 404 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /attendances/{attendanceId} [put]
 func (h *AttendanceHandlers) UpdateAttendance(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	attendanceIDParam := c.Param("attendanceId")
 	attendanceID, err := uuid.Parse(attendanceIDParam)
 	if err != nil {
 		logger.Error("Invalid attendance ID", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid attendance ID"))
+		c.Error(models.NewValidationError("invalid_attendance_id", "Invalid attendance id"))
 		return
 	}
 
-	exists, _ := h.attendanceRepo.Exists(c.Request.Context(), attendanceID)
+	exists, err := h.attendanceRepo.Exists(c.Request.Context(), attendanceID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 	if !exists {
-		c.JSON(http.StatusNotFound, models.NewApiError("Attendance record not found"))
+		c.Error(models.NewNotFoundError("attendance_record_not_found", "Attendance record not found"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
 		return
 	}
 
 	var req CreateAttendanceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request"))
+		c.Error(models.NewBindingError("Invalid request", err))
+		return
+	}
+
+	h.save(c, attendanceID, req, versions)
+}
+
+// PatchAttendance godoc
+// @Summary Частично обновить запись посещаемости
+// @Description Меняет только переданные поля записи (JSON Merge Patch, RFC 7396; Content-Type application/merge-patch+json или application/json),
+// @Description например {"lesson": {"feedback": "..."}}. Даты — в формате DD.MM.YYYY, проверки те же, что у PUT.
+// @Description If-Match необязателен: без него патч применяется к текущей версии, а если запись изменили одновременно с запросом, возвращается 412
+// @Tags Attendance
+// @Accept json
+// @Produce json
+// @Param attendanceId path string true "ID записи посещаемости"
+// @Param If-Match header string false "ETag записи посещаемости"
+// @Param request body CreateAttendanceRequest true "Изменяемые поля"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /attendances/{attendanceId} [patch]
+func (h *AttendanceHandlers) PatchAttendance(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	attendanceID, err := uuid.Parse(c.Param("attendanceId"))
+	if err != nil {
+		logger.Error("Invalid attendance ID", zap.Error(err))
+		c.Error(models.NewValidationError("invalid_attendance_id", "Invalid attendance id"))
+		return
+	}
+
+	full, err := h.attendanceRepo.FindFullById(c.Request.Context(), attendanceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get attendance for patch", zap.String("attendance_id", attendanceID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Could not update attendance"))
+		return
+	}
+
+	versions, ok := patchVersions(c, full.Attendance.Version)
+	if !ok {
 		return
 	}
 
+	var req CreateAttendanceRequest
+	if err := bindMergePatch(c, attendanceUpdateRequest(full), &req); err != nil {
+		logger.Error("Invalid patch request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request", err))
+		return
+	}
+
+	h.save(c, attendanceID, req, versions)
+}
+
+// attendanceUpdateRequest собирает запрос на полное обновление из текущей записи — основа для PATCH.
+// Даты переводятся обратно в DD.MM.YYYY, чтобы пройти те же проверки, что и у PUT
+func attendanceUpdateRequest(full models.AttendanceFullResponse) CreateAttendanceRequest {
+	req := CreateAttendanceRequest{
+		StudentId: full.Attendance.StudentId,
+		CourseId:  full.Attendance.CourseId,
+		Type:      models.EnumCode(full.Attendance.Type),
+	}
+	if lesson := full.Lesson; lesson != nil {
+		req.Lesson = &AttendanceLessonInput{
+			CuratorId:    lesson.CuratorId,
+			Date:         utils.FormatDate(lesson.Date),
+			Format:       lesson.Format,
+			Feedback:     lesson.Feedback,
+			LessonStatus: models.EnumCode(lesson.LessonStatus),
+			TopicId:      lesson.TopicId,
+		}
+		if lesson.FeedbackDate != nil {
+			feedbackDate := utils.FormatDate(*lesson.FeedbackDate)
+			req.Lesson.FeedbackDate = &feedbackDate
+		}
+	}
+	if freeze := full.Freeze; freeze != nil {
+		req.Freeze = &AttendanceFreezeInput{
+			StartDate: utils.FormatDate(freeze.StartDate),
+			EndDate:   utils.FormatDate(freeze.EndDate),
+			Comment:   freeze.Comment,
+		}
+	}
+	if prolongation := full.Prolongation; prolongation != nil {
+		req.Prolongation = &AttendanceProlongationInput{
+			PaymentType: models.EnumCode(prolongation.PaymentType),
+			Date:        utils.FormatDate(prolongation.Date),
+			Amount:      prolongation.Amount,
+			Comment:     prolongation.Comment,
+		}
+	}
+	return req
+}
+
+// save проверяет права на тип записи и даты, затем сохраняет запись с проверкой версии
+func (h *AttendanceHandlers) save(c *gin.Context, attendanceID uuid.UUID, req CreateAttendanceRequest, versions []int) {
+	logger := logger.FromContext(c)
+
 	attendance := &models.Attendance{
+
 		ID:        attendanceID,
 		StudentId: req.StudentId,
 		CourseId:  req.CourseId,
-		Type:      req.Type,
+		Type:      string(req.Type),
 	}
 
 	var lesson *models.AttendanceLesson
 	var freeze *models.AttendanceFreeze
 	var prolongation *models.AttendanceProlongation
 
-
 	roleObj, _ := c.Get("userRole")
 	role := roleObj.(*models.Role)
 
-	if !utils.HasAccessToType(role, req.Type) {
-		c.JSON(http.StatusForbidden, models.NewApiError("You are not allowed to update this type of attendance"))
+	if !utils.HasAccessToType(role, string(req.Type)) {
+		c.Error(models.NewForbiddenError("attendance_type_forbidden", "You are not allowed to update this type of attendance"))
 		return
 	}
 
 	switch req.Type {
-	case "урок":
+	case models.AttendanceTypeLesson:
 		if req.Lesson == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Lesson data is required"))
+			c.Error(models.ErrMissingLessonData)
 			return
 		}
 
 		parsedDate, err := utils.ParseRequiredDate(req.Lesson.Date)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid lesson date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_lesson_date", "Invalid lesson date format. Use DD.MM.YYYY"))
 			return
 		}
 
@@ -323,7 +489,7 @@ func (h *AttendanceHandlers) UpdateAttendance(c *gin.Context) {
 		if req.Lesson.FeedbackDate != nil && *req.Lesson.FeedbackDate != "" {
 			fd, err := utils.ParseDate(req.Lesson.FeedbackDate)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, models.NewApiError("Invalid feedback date format. Use DD.MM.YYYY"))
+				c.Error(models.NewValidationError("invalid_feedback_date", "Invalid feedback date format. Use DD.MM.YYYY"))
 				return
 			}
 			feedbackDate = fd
@@ -332,9 +498,9 @@ func (h *AttendanceHandlers) UpdateAttendance(c *gin.Context) {
 		status := req.Lesson.LessonStatus
 		if status == "" {
 			if parsedDate.After(time.Now()) {
-				status = "запланирован"
+				status = models.LessonScheduled
 			} else {
-				status = "проведен"
+				status = models.LessonConducted
 			}
 		}
 
@@ -344,30 +510,31 @@ func (h *AttendanceHandlers) UpdateAttendance(c *gin.Context) {
 			Date:         parsedDate,
 			Format:       req.Lesson.Format,
 			Feedback:     req.Lesson.Feedback,
-			LessonStatus: status,
+			LessonStatus: string(status),
 			FeedbackDate: feedbackDate,
+			TopicId:      req.Lesson.TopicId,
 		}
 
-	case "заморозка":
+	case models.AttendanceTypeFreeze:
 		if req.Freeze == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Freeze data is required"))
+			c.Error(models.ErrMissingFreezeData)
 			return
 		}
 
 		startDate, err := utils.ParseRequiredDate(req.Freeze.StartDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid start date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_start_date", "Invalid start date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		endDate, err := utils.ParseRequiredDate(req.Freeze.EndDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid end date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_end_date", "Invalid end date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		if startDate.After(endDate) {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Start date must be before end date"))
+			c.Error(models.NewValidationError("invalid_date_range", "Start date must be before end date"))
 			return
 		}
 
@@ -378,71 +545,80 @@ func (h *AttendanceHandlers) UpdateAttendance(c *gin.Context) {
 			Comment:      req.Freeze.Comment,
 		}
 
-	case "пролонгация":
+	case models.AttendanceTypeProlongation:
 		if req.Prolongation == nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Prolongation data is required"))
+			c.Error(models.ErrMissingProlongationData)
 			return
 		}
 
 		prolongationDate, err := utils.ParseRequiredDate(req.Prolongation.Date)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid prolongation date format. Use DD.MM.YYYY"))
+			c.Error(models.NewValidationError("invalid_prolongation_date", "Invalid prolongation date format. Use DD.MM.YYYY"))
 			return
 		}
 
 		prolongation = &models.AttendanceProlongation{
 			AttendanceID: attendanceID,
-			PaymentType:  req.Prolongation.PaymentType,
+			PaymentType:  string(req.Prolongation.PaymentType),
 			Date:         prolongationDate,
 			Amount:       req.Prolongation.Amount,
 			Comment:      req.Prolongation.Comment,
 		}
 	}
 
-	err = h.attendanceRepo.Update(c.Request.Context(), attendance, lesson, freeze, prolongation)
+	version, err := h.attendanceRepo.Update(c.Request.Context(), attendance, lesson, freeze, prolongation, versions)
+	if errors.Is(err, models.ErrTopicNotInCourse) || errors.Is(err, models.ErrVersionConflict) || errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to update attendance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not update attendance"))
+		c.Error(models.NewInternalError("Could not update attendance"))
 		return
 	}
 
+	setETag(c, version)
 	c.Status(http.StatusOK)
 }
 
 // Delete godoc
 // @Summary Удалить запись посещаемости
-// @Description Удаляет запись посещаемости по ID
+// @Description Переносит запись посещаемости в корзину. Восстановление — POST /settings/trash/attendance/{id}/restore
 // @Tags Attendance
 // @Accept json
 // @Produce json
 // @Param id path string true "ID записи посещаемости"
 // @Success 204
-// @Failure 400 {object} models.ApiError
-// @Failure 404 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /settings/attendance/{id} [delete]
 func (h *AttendanceHandlers) Delete(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	idStr := c.Param("id")
 	attendanceID, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid attendance UUID"))
+		c.Error(models.NewValidationError("invalid_attendance_id", "Invalid attendance id"))
 		return
 	}
 
-	exists, _ := h.attendanceRepo.Exists(c.Request.Context(), attendanceID)
+	exists, err := h.attendanceRepo.Exists(c.Request.Context(), attendanceID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 	if !exists {
-		c.JSON(http.StatusNotFound, models.NewApiError("Attendance record not found"))
+		c.Error(models.NewNotFoundError("attendance_record_not_found", "Attendance record not found"))
 		return
 	}
 
 	err = h.attendanceRepo.Delete(c.Request.Context(), attendanceID)
 	if err != nil {
 		logger.Error("Couldn't delete attendance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Couldn't delete attendance"))
+		c.Error(models.NewInternalError("Couldn't delete attendance"))
 		return
 	}
 
 	c.Status(http.StatusNoContent)
-}
\ No newline at end of file
+}
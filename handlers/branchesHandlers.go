@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type BranchRequest struct {
+	Name    string  `json:"name" binding:"required"`
+	Address *string `json:"address"`
+}
+
+type AssignBranchRequest struct {
+	BranchId uuid.UUID `json:"branch_id" binding:"required"`
+}
+
+type BranchesHandlers struct {
+	branchesRepo *repositories.BranchesRepository
+}
+
+func NewBranchesHandlers(branchesRepo *repositories.BranchesRepository) *BranchesHandlers {
+	return &BranchesHandlers{branchesRepo: branchesRepo}
+}
+
+// resolveBranch определяет филиал для создаваемой записи: текущий филиал запроса,
+// а если запрос не ограничен филиалом (владелец) — филиал, указанный в теле запроса
+func resolveBranch(c *gin.Context, requested *uuid.UUID) (uuid.UUID, bool) {
+	if branchID := models.BranchFromContext(c.Request.Context()); branchID != nil {
+		return *branchID, true
+	}
+	if requested != nil && *requested != uuid.Nil {
+		return *requested, true
+	}
+	return uuid.Nil, false
+}
+
+// Create godoc
+// @Summary Создать филиал
+// @Tags Branches
+// @Accept json
+// @Produce json
+// @Param request body BranchRequest true "Данные филиала"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/branches [post]
+func (h *BranchesHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var request BranchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid branch create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	id, err := h.branchesRepo.Create(c, models.Branch{Name: request.Name, Address: request.Address})
+	if err != nil {
+		logger.Error("Failed to create branch", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create branch"))
+		return
+	}
+
+	logger.Info("Branch created", zap.String("branch_id", id.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// FindAll godoc
+// @Summary Список филиалов
+// @Tags Branches
+// @Produce json
+// @Success 200 {array} models.Branch
+// @Failure 500 {object} models.Problem
+// @Router /settings/branches [get]
+func (h *BranchesHandlers) FindAll(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	branches, err := h.branchesRepo.FindAll(c)
+	if err != nil {
+		logger.Error("Failed to fetch branches", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch branches"))
+		return
+	}
+	c.JSON(http.StatusOK, branches)
+}
+
+// FindMy godoc
+// @Summary Филиалы текущего пользователя
+// @Description Возвращает филиалы, к которым прикреплен пользователь, для выбора X-Branch-ID
+// @Tags Branches
+// @Produce json
+// @Success 200 {array} models.Branch
+// @Failure 500 {object} models.Problem
+// @Router /branches/my [get]
+func (h *BranchesHandlers) FindMy(c *gin.Context) {
+	logger := logger.FromContext(c)
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	branches, err := h.branchesRepo.FindByUser(c, userID)
+	if err != nil {
+		logger.Error("Failed to fetch user branches", zap.String("user_id", userID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch branches"))
+		return
+	}
+	c.JSON(http.StatusOK, branches)
+}
+
+// Update godoc
+// @Summary Обновить филиал
+// @Tags Branches
+// @Accept json
+// @Param branchId path string true "ID филиала"
+// @Param request body BranchRequest true "Данные филиала"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/branches/{branchId} [put]
+func (h *BranchesHandlers) Update(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	branchID, err := uuid.Parse(c.Param("branchId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_branch_id", "Invalid branch id"))
+		return
+	}
+
+	if _, err := h.branchesRepo.FindById(c, branchID); err != nil {
+		c.Error(models.NewNotFoundError("branch_not_found", "Branch not found"))
+		return
+	}
+
+	var request BranchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid branch update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	branch := models.Branch{Id: branchID, Name: request.Name, Address: request.Address}
+	if err := h.branchesRepo.Update(c, branch); err != nil {
+		logger.Error("Failed to update branch", zap.String("branch_id", branchID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update branch"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary Удалить филиал
+// @Description Удалить можно только филиал без студентов и курсов
+// @Tags Branches
+// @Param branchId path string true "ID филиала"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Router /settings/branches/{branchId} [delete]
+func (h *BranchesHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	branchID, err := uuid.Parse(c.Param("branchId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_branch_id", "Invalid branch id"))
+		return
+	}
+
+	if _, err := h.branchesRepo.FindById(c, branchID); err != nil {
+		c.Error(models.NewNotFoundError("branch_not_found", "Branch not found"))
+		return
+	}
+
+	if err := h.branchesRepo.Delete(c, branchID); err != nil {
+		logger.Warn("Failed to delete branch", zap.String("branch_id", branchID.String()), zap.Error(err))
+		c.Error(models.NewConflictError("branch_in_use", "Branch still has students or courses"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// AssignUser godoc
+// @Summary Прикрепить пользователя к филиалу
+// @Tags Branches
+// @Accept json
+// @Param userId path string true "ID пользователя"
+// @Param request body AssignBranchRequest true "Филиал"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/users/{userId}/branches [post]
+func (h *BranchesHandlers) AssignUser(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_user_id", "Invalid user id"))
+		return
+	}
+
+	var request AssignBranchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if err := h.branchesRepo.AssignUser(c, userID, request.BranchId); err != nil {
+		logger.Error("Failed to assign user to branch", zap.String("user_id", userID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to assign user to branch"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User assigned to branch"})
+}
+
+// UnassignUser godoc
+// @Summary Открепить пользователя от филиала
+// @Tags Branches
+// @Param userId path string true "ID пользователя"
+// @Param branchId path string true "ID филиала"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/users/{userId}/branches/{branchId} [delete]
+func (h *BranchesHandlers) UnassignUser(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_user_id", "Invalid user id"))
+		return
+	}
+
+	branchID, err := uuid.Parse(c.Param("branchId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_branch_id", "Invalid branch id"))
+		return
+	}
+
+	if err := h.branchesRepo.UnassignUser(c, userID, branchID); err != nil {
+		logger.Error("Failed to unassign user from branch", zap.String("user_id", userID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to unassign user from branch"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User removed from branch"})
+}
+
+// Report godoc
+// @Summary Сводный отчет по филиалам
+// @Description Доступен владельцу (право access_all_branches). Уроки и оплаты считаются за период from–to, если он указан
+// @Tags Branches
+// @Produce json
+// @Param from query string false "Начало периода DD.MM.YYYY"
+// @Param to query string false "Конец периода DD.MM.YYYY"
+// @Success 200 {array} models.BranchReport
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /reports/branches [get]
+func (h *BranchesHandlers) Report(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	from, err := utils.ParseDate(&fromStr)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_from_date", "Invalid from date format. Use DD.MM.YYYY"))
+		return
+	}
+	to, err := utils.ParseDate(&toStr)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_to_date", "Invalid to date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	reports, err := h.branchesRepo.Report(c, from, to)
+	if err != nil {
+		logger.Error("Failed to build branches report", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to build report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+type CertificateTemplateRequest struct {
+	Title         string  `json:"title" binding:"required"`
+	Body          string  `json:"body" binding:"required"`
+	SignatureName *string `json:"signature_name"`
+}
+
+// CertificateIssuer выпускает сертификаты: рендерит PDF, сохраняет файл и запись с номером.
+// Используется и при завершении зачисления, и при ручной выдаче.
+type CertificateIssuer struct {
+	certificatesRepo *repositories.CertificatesRepository
+	storage          *utils.LocalStorage
+	fontPath         string
+}
+
+func NewCertificateIssuer(certificatesRepo *repositories.CertificatesRepository, storage *utils.LocalStorage, fontPath string) *CertificateIssuer {
+	return &CertificateIssuer{certificatesRepo: certificatesRepo, storage: storage, fontPath: fontPath}
+}
+
+// Issue выдает сертификат по завершенному зачислению. Повторный вызов возвращает уже выданный сертификат
+func (i *CertificateIssuer) Issue(c context.Context, enrollmentID uuid.UUID) (models.Certificate, error) {
+	if existing, err := i.certificatesRepo.FindByEnrollment(c, enrollmentID); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return models.Certificate{}, err
+	}
+
+	certificate, courseID, err := i.certificatesRepo.PrepareForEnrollment(c, enrollmentID)
+	if err != nil {
+		return models.Certificate{}, err
+	}
+
+	template, err := i.certificatesRepo.FindTemplate(c, courseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		template = models.DefaultCertificateTemplate
+	} else if err != nil {
+		return models.Certificate{}, err
+	}
+
+	certificate.IssuedAt = time.Now()
+
+	// Номер случайный; при маловероятном совпадении пробуем еще раз
+	for attempt := 0; ; attempt++ {
+		certificate.Number, err = utils.GenerateCertificateNumber(certificate.IssuedAt.Year())
+		if err != nil {
+			return models.Certificate{}, err
+		}
+
+		var buf bytes.Buffer
+		if err := utils.RenderCertificate(&buf, template, certificate, i.fontPath); err != nil {
+			return models.Certificate{}, err
+		}
+
+		certificate.StoragePath, _, err = i.storage.Save("certificates", certificate.Number+".pdf", &buf)
+		if err != nil {
+			return models.Certificate{}, err
+		}
+
+		certificate.Id, err = i.certificatesRepo.Create(c, certificate)
+		if err == nil {
+			return certificate, nil
+		}
+		i.storage.Remove(certificate.StoragePath)
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" { // unique_violation
+			return models.Certificate{}, err
+		}
+		// Сертификат по этому зачислению уже выдан параллельным запросом
+		if pgErr.ConstraintName == "certificates_enrollment_id_key" {
+			return i.certificatesRepo.FindByEnrollment(c, enrollmentID)
+		}
+		if attempt == 2 {
+			return models.Certificate{}, err
+		}
+	}
+}
+
+type CertificatesHandlers struct {
+	certificatesRepo *repositories.CertificatesRepository
+	issuer           *CertificateIssuer
+	storage          *utils.LocalStorage
+}
+
+func NewCertificatesHandlers(certificatesRepo *repositories.CertificatesRepository, issuer *CertificateIssuer, storage *utils.LocalStorage) *CertificatesHandlers {
+	return &CertificatesHandlers{certificatesRepo: certificatesRepo, issuer: issuer, storage: storage}
+}
+
+// FindTemplate godoc
+// @Summary Шаблон сертификата курса
+// @Description Возвращает шаблон сертификата; если он не задан — шаблон по умолчанию
+// @Tags Certificates
+// @Produce json
+// @Param courseId path string true "ID курса"
+// @Success 200 {object} models.CertificateTemplate
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/certificate-template [get]
+func (h *CertificatesHandlers) FindTemplate(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	template, err := h.certificatesRepo.FindTemplate(c, courseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		template = models.DefaultCertificateTemplate
+		template.CourseId = courseID
+	} else if err != nil {
+		logger.Error("Failed to fetch certificate template", zap.String("course_id", courseID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch certificate template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// SaveTemplate godoc
+// @Summary Сохранить шаблон сертификата
+// @Description body — текст с подстановками {{.StudentName}}, {{.CourseTitle}}, {{.StartDate}}, {{.EndDate}}, {{.CuratorName}}, {{.Number}}
+// @Tags Certificates
+// @Accept json
+// @Param courseId path string true "ID курса"
+// @Param request body CertificateTemplateRequest true "Шаблон"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/certificate-template [put]
+func (h *CertificatesHandlers) SaveTemplate(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	var request CertificateTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid certificate template request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if _, err := utils.ExecuteCertificateBody(request.Body, utils.CertificateData{}); err != nil {
+		c.Error(models.NewValidationError("invalid_template", "Invalid template: "+err.Error(), models.FieldError{Field: "body", Code: "template_syntax", Message: err.Error()}))
+		return
+	}
+
+	err = h.certificatesRepo.SaveTemplate(c, models.CertificateTemplate{
+		CourseId:      courseID,
+		Title:         request.Title,
+		Body:          request.Body,
+		SignatureName: request.SignatureName,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("course_not_found", "Course not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to save certificate template", zap.String("course_id", courseID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save certificate template"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Issue godoc
+// @Summary Выдать сертификат
+// @Description Выдает сертификат по завершенному зачислению (например, завершенному до появления сертификатов).
+// @Description Если сертификат уже выдан, возвращает его
+// @Tags Certificates
+// @Produce json
+// @Param enrollmentId path string true "ID зачисления"
+// @Success 201 {object} models.Certificate
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/enrollments/{enrollmentId}/certificate [post]
+func (h *CertificatesHandlers) Issue(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	enrollmentID, err := uuid.Parse(c.Param("enrollmentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_enrollment_id", "Invalid enrollment id"))
+		return
+	}
+
+	certificate, err := h.issuer.Issue(c, enrollmentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("enrollment_not_found", "Enrollment not found"))
+		return
+	}
+	if errors.Is(err, models.ErrEnrollmentNotCompleted) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to issue certificate", zap.String("enrollment_id", enrollmentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to issue certificate"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, certificate)
+}
+
+// FindByStudent godoc
+// @Summary Сертификаты студента
+// @Tags Certificates
+// @Produce json
+// @Param studentId path string true "UUID студента"
+// @Success 200 {array} models.Certificate
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/students/{studentId}/certificates [get]
+func (h *CertificatesHandlers) FindByStudent(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	certificates, err := h.certificatesRepo.FindByStudent(c, studentID)
+	if err != nil {
+		logger.Error("Failed to fetch certificates", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch certificates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
+}
+
+// Download godoc
+// @Summary Скачать сертификат
+// @Tags Certificates
+// @Produce application/pdf
+// @Param certificateId path string true "ID сертификата"
+// @Success 200 {file} file
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /managers/certificates/{certificateId}/pdf [get]
+func (h *CertificatesHandlers) Download(c *gin.Context) {
+	certificateID, err := uuid.Parse(c.Param("certificateId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_certificate_id", "Invalid certificate id"))
+		return
+	}
+
+	certificate, err := h.certificatesRepo.FindById(c, certificateID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("certificate_not_found", "Certificate not found"))
+		return
+	}
+
+	c.Header("Content-Type", "application/pdf")
+	c.FileAttachment(h.storage.Path(certificate.StoragePath), certificate.Number+".pdf")
+}
+
+// Verify godoc
+// @Summary Проверить сертификат
+// @Description Публичная проверка подлинности сертификата по номеру
+// @Tags Certificates
+// @Produce json
+// @Param number path string true "Номер сертификата"
+// @Success 200 {object} models.CertificateVerification
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /certificates/{number} [get]
+func (h *CertificatesHandlers) Verify(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	certificate, err := h.certificatesRepo.FindByNumber(c, c.Param("number"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("certificate_not_found", "Certificate not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to verify certificate", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to verify certificate"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CertificateVerification{
+		Number:      certificate.Number,
+		StudentName: certificate.StudentName,
+		CourseTitle: certificate.CourseTitle,
+		StartDate:   certificate.StartDate,
+		EndDate:     certificate.EndDate,
+		IssuedAt:    certificate.IssuedAt,
+	})
+}
@@ -1,21 +1,63 @@
 package handlers
 
 import (
+	"errors"
+	"it_school/logger"
 	"it_school/models"
 	"it_school/repositories"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
 )
 
 type CourseRequest struct {
-	Title string `json:"title"`
+	Title         string          `json:"title"`
+	BranchId      *uuid.UUID      `json:"branch_id"`
+	Description   *string         `json:"description"`
+	AgeMin        *int            `json:"age_min" binding:"omitempty,min=0"`
+	AgeMax        *int            `json:"age_max" binding:"omitempty,min=0"`
+	DurationWeeks *int            `json:"duration_weeks" binding:"omitempty,min=1"`
+	LessonsCount  *int            `json:"lessons_count" binding:"omitempty,min=1"`
+	Price         *float64        `json:"price" binding:"omitempty,min=0"`
+	Status        models.EnumCode `json:"status" binding:"omitempty,oneof=active archived"`
 }
 
 type UpdateRequest struct {
-	Title string `json:"title"`
+	Title         string          `json:"title"`
+	Description   *string         `json:"description"`
+	AgeMin        *int            `json:"age_min" binding:"omitempty,min=0"`
+	AgeMax        *int            `json:"age_max" binding:"omitempty,min=0"`
+	DurationWeeks *int            `json:"duration_weeks" binding:"omitempty,min=1"`
+	LessonsCount  *int            `json:"lessons_count" binding:"omitempty,min=1"`
+	Price         *float64        `json:"price" binding:"omitempty,min=0"`
+	Status        models.EnumCode `json:"status" binding:"omitempty,oneof=active archived"`
 }
+
+type CurriculumModuleInput struct {
+	Id          uuid.UUID              `json:"id"`
+	Title       string                 `json:"title" binding:"required"`
+	Description *string                `json:"description"`
+	Topics      []CurriculumTopicInput `json:"topics" binding:"dive"`
+}
+
+type CurriculumTopicInput struct {
+	Id          uuid.UUID `json:"id"`
+	Title       string    `json:"title" binding:"required"`
+	Description *string   `json:"description"`
+	LessonPlan  *string   `json:"lesson_plan"`
+}
+
+type CurriculumRequest struct {
+	Modules []CurriculumModuleInput `json:"modules" binding:"dive"`
+}
+
+func validAgeRange(ageMin, ageMax *int) bool {
+	return ageMin == nil || ageMax == nil || *ageMin <= *ageMax
+}
+
 type CourseHandlers struct {
 	courseRepo *repositories.CourseRepository
 }
@@ -26,30 +68,50 @@ func NewCourseHandlers(courseRepo *repositories.CourseRepository) *CourseHandler
 
 // Create godoc
 // @Summary Создать курс
-// @Description Создает новый курс
+// @Description Создает новый курс. Допустимые значения:
+// @Description - status: active, archived (по умолчанию active)
 // @Tags Courses
 // @Accept json
 // @Produce json
 // @Param request body CourseRequest true "Данные курса"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /settings/courses [post]
 func (h *CourseHandlers) Create(c *gin.Context) {
 	var request CourseRequest
 	err := c.Bind(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("couldn´t create course request"))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if !validAgeRange(request.AgeMin, request.AgeMax) {
+		c.Error(models.NewValidationError("invalid_age_range", "age_min must not exceed age_max"))
+		return
+	}
+
+	branchID, ok := resolveBranch(c, request.BranchId)
+	if !ok {
+		c.Error(models.NewValidationError("branch_required", "Branch is required"))
 		return
 	}
 
 	course := models.Course{
-		Title: request.Title,
+		Title:         request.Title,
+		BranchId:      branchID,
+		Description:   request.Description,
+		AgeMin:        request.AgeMin,
+		AgeMax:        request.AgeMax,
+		DurationWeeks: request.DurationWeeks,
+		LessonsCount:  request.LessonsCount,
+		Price:         request.Price,
+		Status:        string(request.Status),
 	}
 
 	id, err := h.courseRepo.Create(c, course)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -59,48 +121,132 @@ func (h *CourseHandlers) Create(c *gin.Context) {
 
 // Update godoc
 // @Summary Обновить курс
-// @Description Обновляет курс по ID
+// @Description Обновляет курс по ID. Курс, который больше не набирается, переводится в status archived.
+// @Description Заголовок If-Match обязателен: ETag курса. Если курс уже изменили, возвращается 412
 // @Tags Courses
 // @Accept json
 // @Produce json
 // @Param courseId path string true "ID курса"
+// @Param If-Match header string true "ETag курса"
 // @Param request body UpdateRequest true "Обновлённые данные"
 // @Success 200
-// @Failure 400 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Header 200 {string} ETag "Новая версия курса"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /settings/courses/{courseId} [put]
 func (h *CourseHandlers) Update(c *gin.Context) {
 	idStr := c.Param("courseId")
 	courseId, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Seasons Id"))
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
 		return
 	}
 
 	_, err = h.courseRepo.FindById(c, courseId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
 		return
 	}
 
 	var request UpdateRequest
 	err = c.Bind(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("couldn´t create course request"))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	h.save(c, courseId, request, versions)
+}
+
+// Patch godoc
+// @Summary Частично обновить курс
+// @Description Меняет только переданные поля курса (JSON Merge Patch, RFC 7396; Content-Type application/merge-patch+json или application/json).
+// @Description If-Match необязателен: без него патч применяется к текущей версии, а если курс изменили одновременно с запросом, возвращается 412
+// @Tags Courses
+// @Accept json
+// @Produce json
+// @Param courseId path string true "ID курса"
+// @Param If-Match header string false "ETag курса"
+// @Param request body UpdateRequest true "Изменяемые поля"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия курса"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId} [patch]
+func (h *CourseHandlers) Patch(c *gin.Context) {
+	idStr := c.Param("courseId")
+	courseId, err := uuid.Parse(idStr)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	course, err := h.courseRepo.FindById(c, courseId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	versions, ok := patchVersions(c, course.Version)
+	if !ok {
+		return
+	}
+
+	current := UpdateRequest{
+		Title:         course.Title,
+		Description:   course.Description,
+		AgeMin:        course.AgeMin,
+		AgeMax:        course.AgeMax,
+		DurationWeeks: course.DurationWeeks,
+		LessonsCount:  course.LessonsCount,
+		Price:         course.Price,
+		Status:        models.EnumCode(course.Status),
+	}
+	var request UpdateRequest
+	if err := bindMergePatch(c, current, &request); err != nil {
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	h.save(c, courseId, request, versions)
+}
+
+// save проверяет возрастной диапазон и сохраняет курс с проверкой версии (nil — без проверки)
+func (h *CourseHandlers) save(c *gin.Context, courseId uuid.UUID, request UpdateRequest, versions []int) {
+	if !validAgeRange(request.AgeMin, request.AgeMax) {
+		c.Error(models.NewValidationError("invalid_age_range", "age_min must not exceed age_max"))
 		return
 	}
 
 	course := models.Course{
-		Id:    courseId,
-		Title: request.Title,
+		Id:            courseId,
+		Title:         request.Title,
+		Description:   request.Description,
+		AgeMin:        request.AgeMin,
+		AgeMax:        request.AgeMax,
+		DurationWeeks: request.DurationWeeks,
+		LessonsCount:  request.LessonsCount,
+		Price:         request.Price,
+		Status:        string(request.Status),
 	}
 
-	err = h.courseRepo.Update(c, course)
+	version, err := h.courseRepo.Update(c, course, versions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+		c.Error(err)
 		return
 	}
 
+	setETag(c, version)
 	c.Status(http.StatusOK)
 }
 
@@ -111,21 +257,23 @@ func (h *CourseHandlers) Update(c *gin.Context) {
 // @Produce json
 // @Param courseId path string true "ID курса"
 // @Success 200 {object} models.Course
-// @Failure 400 {object} models.ApiError
+// @Header 200 {string} ETag "Версия курса"
+// @Failure 400 {object} models.Problem
 // @Router /settings/courses/{courseId} [get]
 func (h *CourseHandlers) FindById(c *gin.Context) {
 	idStr := c.Param("courseId")
 	courseId, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid coursse id"))
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
 		return
 	}
 
 	course, err := h.courseRepo.FindById(c, courseId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
+	setETag(c, course.Version)
 	c.JSON(http.StatusOK, course)
 }
 
@@ -134,13 +282,14 @@ func (h *CourseHandlers) FindById(c *gin.Context) {
 // @Description Возвращает список всех курсов
 // @Tags Courses
 // @Produce json
+// @Param status query string false "Статус курса" Enums(active, archived)
 // @Success 200 {array} models.Course
-// @Failure 400 {object} models.ApiError
+// @Failure 400 {object} models.Problem
 // @Router /settings/courses [get]
 func (h *CourseHandlers) FindAll(c *gin.Context) {
-	courses, err := h.courseRepo.FindAll(c)
+	courses, err := h.courseRepo.FindAll(c, models.NormalizeEnum(c.Query("status")))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, courses)
@@ -148,32 +297,164 @@ func (h *CourseHandlers) FindAll(c *gin.Context) {
 
 // Delete godoc
 // @Summary Удалить курс
-// @Description Удаляет курс по ID
+// @Description Переносит курс в корзину. Курс, на который зачислены студенты, удалить нельзя.
+// @Description Восстановление — POST /settings/trash/courses/{id}/restore
 // @Tags Courses
 // @Produce json
 // @Param courseId path string true "ID курса"
 // @Success 200
-// @Failure 400 {object} models.ApiError
+// @Failure 400 {object} models.Problem
+// @Failure 409 {object} models.Problem
 // @Router /settings/courses/{courseId} [delete]
 func (h *CourseHandlers) Delete(c *gin.Context) {
 	idStr := c.Param("courseId")
 	courseId, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Seasons Id"))
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
 		return
 	}
 
 	_, err = h.courseRepo.FindById(c, courseId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
 	err = h.courseRepo.Delete(c, courseId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// FindCurriculum godoc
+// @Summary Программа курса
+// @Description Возвращает модули курса с темами и планами уроков в порядке прохождения
+// @Tags Courses
+// @Produce json
+// @Param courseId path string true "ID курса"
+// @Success 200 {array} models.CourseModule
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/curriculum [get]
+func (h *CourseHandlers) FindCurriculum(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseId, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	modules, err := h.courseRepo.FindCurriculum(c, courseId)
+	if err != nil {
+		logger.Error("Failed to fetch curriculum", zap.String("course_id", courseId.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch curriculum"))
+		return
+	}
+
+	c.JSON(http.StatusOK, modules)
+}
+
+// ReplaceCurriculum godoc
+// @Summary Сохранить программу курса
+// @Description Заменяет программу курса целиком. Порядок модулей и тем задается порядком в списке.
+// @Description Модули и темы с id обновляются, без id — создаются, не переданные — удаляются
+// @Tags Courses
+// @Accept json
+// @Param courseId path string true "ID курса"
+// @Param request body CurriculumRequest true "Модули и темы"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/curriculum [put]
+func (h *CourseHandlers) ReplaceCurriculum(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseId, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	var request CurriculumRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid curriculum request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	modules := make([]models.CourseModule, 0, len(request.Modules))
+	for _, input := range request.Modules {
+		module := models.CourseModule{
+			Id:          input.Id,
+			Title:       input.Title,
+			Description: input.Description,
+			Topics:      make([]models.CourseTopic, 0, len(input.Topics)),
+		}
+		for _, topic := range input.Topics {
+			module.Topics = append(module.Topics, models.CourseTopic{
+				Id:          topic.Id,
+				Title:       topic.Title,
+				Description: topic.Description,
+				LessonPlan:  topic.LessonPlan,
+			})
+		}
+		modules = append(modules, module)
+	}
+
+	err = h.courseRepo.ReplaceCurriculum(c, courseId, modules)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("course_module_or_topic_not_found", "Course, module or topic not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to save curriculum", zap.String("course_id", courseId.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save curriculum"))
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
+
+// Progress godoc
+// @Summary Прогресс студента по программе курса
+// @Description Для каждой темы курса возвращает дату первого проведенного урока студента по ней
+// @Tags Courses
+// @Produce json
+// @Param studentId path string true "UUID студента"
+// @Param courseId path string true "ID курса"
+// @Success 200 {object} models.CourseProgress
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/students/{studentId}/courses/{courseId}/progress [get]
+func (h *CourseHandlers) Progress(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentId, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	courseId, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	progress, err := h.courseRepo.Progress(c, studentId, courseId)
+	if err != nil {
+		logger.Error("Failed to fetch course progress",
+			zap.String("student_id", studentId.String()),
+			zap.String("course_id", courseId.String()),
+			zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch course progress"))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"it_school/crm"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Событие входящего вебхука: создать или обновить студента по external_id
+const crmEventStudentUpsert = "student.upsert"
+
+const crmMaxWebhookBytes = 1 << 20
+
+// CrmStudentUpsert — данные студента во входящем вебхуке CRM
+type CrmStudentUpsert struct {
+	ExternalId        string           `json:"external_id" binding:"required"`
+	BranchId          *uuid.UUID       `json:"branch_id"`
+	FullName          string           `json:"full_name" binding:"required"`
+	PhoneNumber       string           `json:"phone_number" binding:"required"`
+	ParentName        string           `json:"parent_name" binding:"required"`
+	ParentPhoneNumber string           `json:"parent_phone_number" binding:"required"`
+	PlatformLink      string           `json:"platform_link"`
+	CrmLink           string           `json:"crm_link"`
+	IsActive          *models.EnumCode `json:"is_active" binding:"omitempty,oneof=active inactive"`
+}
+
+type CrmHandlers struct {
+	studentsRepo *repositories.StudentsRepository
+	crmRepo      *repositories.CrmRepository
+	crmClient    *crm.Client
+	secret       string
+}
+
+func NewCrmHandlers(studentsRepo *repositories.StudentsRepository, crmRepo *repositories.CrmRepository, crmClient *crm.Client, secret string) *CrmHandlers {
+	return &CrmHandlers{studentsRepo: studentsRepo, crmRepo: crmRepo, crmClient: crmClient, secret: secret}
+}
+
+// Webhook godoc
+// @Summary Входящий вебхук CRM
+// @Description Создает или обновляет студента по external_id (событие student.upsert).
+// @Description Запрос подписывается так же, как исходящие вебхуки: заголовки X-Crm-Timestamp (unix-время)
+// @Description и X-Crm-Signature = sha256=HMAC-SHA256(CRM_WEBHOOK_SECRET, "<timestamp>.<body>").
+// @Description branch_id обязателен только при создании студента. Студента из корзины вебхук не меняет (409)
+// @Tags CRM
+// @Accept json
+// @Produce json
+// @Param request body models.CrmEvent true "Событие с данными CrmStudentUpsert в data"
+// @Success 200 {object} object{id=string,created=bool}
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 409 {object} models.Problem "Студент в корзине"
+// @Failure 500 {object} models.Problem
+// @Router /integrations/crm/webhook [post]
+func (h *CrmHandlers) Webhook(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, crmMaxWebhookBytes))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_request_body", "Failed to read request body"))
+		return
+	}
+
+	err = crm.Verify(h.secret, c.GetHeader(crm.HeaderTimestamp), c.GetHeader(crm.HeaderSignature), body, time.Now())
+	if err != nil {
+		logger.Warn("Rejected CRM webhook", zap.String("client_ip", c.ClientIP()), zap.Error(err))
+		c.Error(models.NewUnauthorizedError("invalid_signature", "Invalid signature"))
+		return
+	}
+
+	var event models.CrmEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.Error(models.NewValidationError("invalid_request", "Invalid request data"))
+		return
+	}
+	if event.Event != crmEventStudentUpsert {
+		c.Error(models.NewValidationError("unsupported_event", "Unsupported event"))
+		return
+	}
+
+	var data CrmStudentUpsert
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		c.Error(models.NewValidationError("invalid_request", "Invalid request data"))
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&data); err != nil {
+		logger.Warn("Invalid CRM student data", zap.Error(err))
+		c.Error(models.NewValidationError("invalid_request", "Invalid request data"))
+		return
+	}
+
+	phone, err := formatPhoneNumber(data.PhoneNumber, "KZ")
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_phone_number", "Invalid student's phone number"))
+		return
+	}
+	parentPhone, err := formatPhoneNumber(data.ParentPhoneNumber, "KZ")
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_parent_phone_number", "Invalid parent's phone number"))
+		return
+	}
+
+	now := time.Now()
+	student := models.Student{
+		FullName:          data.FullName,
+		PhoneNumber:       &phone,
+		ParentName:        data.ParentName,
+		ParentPhoneNumber: &parentPhone,
+		PlatformLink:      data.PlatformLink,
+		CrmLink:           data.CrmLink,
+		CreatedAt:         &now,
+		IsActive:          (*string)(data.IsActive),
+		CrmExternalId:     &data.ExternalId,
+	}
+	if data.BranchId != nil {
+		student.BranchId = *data.BranchId
+	}
+
+	// Изменения, пришедшие из CRM, обратно в CRM не отправляются
+	id, created, err := h.studentsRepo.UpsertByExternalId(c, student)
+	switch {
+	case errors.Is(err, models.ErrCrmBranchRequired), errors.Is(err, models.ErrCrmStudentInTrash):
+		c.Error(err)
+		return
+	case isForeignKeyViolation(err):
+		c.Error(models.NewValidationError("branch_not_found", "Branch not found"))
+		return
+	case err != nil:
+		logger.Error("Failed to upsert student from CRM", zap.String("external_id", data.ExternalId), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save student"))
+		return
+	}
+
+	logger.Info("Student synced from CRM",
+		zap.String("student_id", id.String()),
+		zap.String("external_id", data.ExternalId),
+		zap.Bool("created", created))
+	c.JSON(http.StatusOK, gin.H{"id": id, "created": created})
+}
+
+// DeadLetters godoc
+// @Summary Недоставленные события CRM
+// @Description События, которые не удалось доставить в CRM после всех повторов
+// @Tags CRM
+// @Produce json
+// @Success 200 {array} models.CrmDeadLetter
+// @Failure 500 {object} models.Problem
+// @Router /settings/integrations/crm/dead-letters [get]
+func (h *CrmHandlers) DeadLetters(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	letters, err := h.crmRepo.FindDeadLetters(c)
+	if err != nil {
+		logger.Error("Failed to fetch CRM dead letters", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch dead letters"))
+		return
+	}
+
+	c.JSON(http.StatusOK, letters)
+}
+
+// RetryDeadLetter godoc
+// @Summary Повторить доставку события в CRM
+// @Description Одна синхронная попытка доставки. При успехе событие удаляется из списка недоставленных
+// @Tags CRM
+// @Param deadLetterId path string true "ID недоставленного события"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 502 {object} models.Problem
+// @Failure 503 {object} models.Problem
+// @Router /settings/integrations/crm/dead-letters/{deadLetterId}/retry [post]
+func (h *CrmHandlers) RetryDeadLetter(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	if h.crmClient == nil {
+		c.Error(models.NewUnavailableError("crm_disabled", "CRM integration is disabled"))
+		return
+	}
+
+	letterID, err := uuid.Parse(c.Param("deadLetterId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_dead_letter_id", "Invalid dead letter id"))
+		return
+	}
+
+	letter, err := h.crmRepo.FindDeadLetter(c, letterID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("dead_letter_not_found", "Dead letter not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch CRM dead letter", zap.String("dead_letter_id", letterID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch dead letter"))
+		return
+	}
+
+	var event models.CrmEvent
+	if err := json.Unmarshal(letter.Payload, &event); err != nil {
+		logger.Error("Corrupted CRM dead letter", zap.String("dead_letter_id", letterID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Corrupted dead letter"))
+		return
+	}
+
+	if err := h.crmClient.Deliver(c, event); err != nil {
+		if recordErr := h.crmRepo.RecordDeadLetterAttempt(c, letterID, err.Error()); recordErr != nil {
+			logger.Error("Failed to record CRM retry", zap.String("dead_letter_id", letterID.String()), zap.Error(recordErr))
+		}
+		c.Error(models.NewBadGatewayError("crm_delivery_failed", "CRM delivery failed: "+err.Error()))
+		return
+	}
+
+	if err := h.crmRepo.DeleteDeadLetter(c, letterID); err != nil {
+		logger.Error("Failed to delete CRM dead letter", zap.String("dead_letter_id", letterID.String()), zap.Error(err))
+	}
+
+	c.Status(http.StatusOK)
+}
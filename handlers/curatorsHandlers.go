@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"it_school/logger"
 	"it_school/models"
 	"it_school/repositories"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -21,33 +23,39 @@ func NewCuratorsHandler(repo *repositories.CuratorsRepository) *CuratorsHandler
 
 // AddStudent godoc
 // @Summary Add student to curator
-// @Description Assigns a student to a curator
+// @Description Assigns a student of the current branch to a curator of the student's branch
 // @Tags Curators
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request body handlers.CuratorsHandler.AddStudent.request true "Student assignment data"
 // @Success 200 {object} object{message=string} "Student added successfully"
-// @Failure 400 {object} models.ApiError "Invalid request data"
-// @Failure 403 {object} models.ApiError "Forbidden"
-// @Failure 404 {object} models.ApiError "Curator or student not found"
-// @Failure 500 {object} models.ApiError "Internal server error"
-// @Router /settings/curators/add-student [post]
+// @Failure 400 {object} models.Problem "Invalid request data"
+// @Failure 403 {object} models.Problem "Forbidden"
+// @Failure 404 {object} models.Problem "Curator or student not found"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/add-student [post]
 func (h *CuratorsHandler) AddStudent(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 	var req struct {
 		CuratorID uuid.UUID `json:"curator_id"`
 		StudentID uuid.UUID `json:"student_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request for adding student", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
+		c.Error(models.NewBindingError("Invalid request data", err))
 		return
 	}
 
-	if err := h.repo.AddStudent(c, req.CuratorID, req.StudentID); err != nil {
+	assignedBy := c.MustGet("userID").(uuid.UUID)
+	err := h.repo.AddStudent(c, req.CuratorID, req.StudentID, &assignedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
 		logger.Error("Failed to add student", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to add student"))
+		c.Error(models.NewInternalError("Failed to add student"))
 		return
 	}
 
@@ -63,26 +71,31 @@ func (h *CuratorsHandler) AddStudent(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param request body handlers.CuratorsHandler.RemoveStudent.request true "Student unassignment data"
 // @Success 200 {object} object{message=string} "Student removed successfully"
-// @Failure 400 {object} models.ApiError "Invalid request data"
-// @Failure 403 {object} models.ApiError "Forbidden"
-// @Failure 404 {object} models.ApiError "Assignment not found"
-// @Failure 500 {object} models.ApiError "Internal server error"
-// @Router /settings/curators/remove-student [post]
+// @Failure 400 {object} models.Problem "Invalid request data"
+// @Failure 403 {object} models.Problem "Forbidden"
+// @Failure 404 {object} models.Problem "Assignment not found"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/remove-student [post]
 func (h *CuratorsHandler) RemoveStudent(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 	var req struct {
 		CuratorID uuid.UUID `json:"curator_id"`
 		StudentID uuid.UUID `json:"student_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request for removing student", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
+		c.Error(models.NewBindingError("Invalid request data", err))
 		return
 	}
 
-	if err := h.repo.RemoveStudent(c, req.CuratorID, req.StudentID); err != nil {
+	err := h.repo.RemoveStudent(c, req.CuratorID, req.StudentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("assignment_not_found", "Assignment not found"))
+		return
+	}
+	if err != nil {
 		logger.Error("Failed to remove student", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to remove student"))
+		c.Error(models.NewInternalError("Failed to remove student"))
 		return
 	}
 
@@ -91,33 +104,38 @@ func (h *CuratorsHandler) RemoveStudent(c *gin.Context) {
 
 // AddCourse godoc
 // @Summary Add course to curator
-// @Description Assigns a course to a curator
+// @Description Assigns a course of the current branch to a curator of the course's branch
 // @Tags Curators
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request body handlers.CuratorsHandler.AddCourse.request true "Course assignment data"
 // @Success 200 {object} object{message=string} "Course added successfully"
-// @Failure 400 {object} models.ApiError "Invalid request data"
-// @Failure 403 {object} models.ApiError "Forbidden"
-// @Failure 404 {object} models.ApiError "Curator or course not found"
-// @Failure 500 {object} models.ApiError "Internal server error"
-// @Router /settings/curators/add-course [post]
+// @Failure 400 {object} models.Problem "Invalid request data"
+// @Failure 403 {object} models.Problem "Forbidden"
+// @Failure 404 {object} models.Problem "Curator or course not found"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/add-course [post]
 func (h *CuratorsHandler) AddCourse(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 	var req struct {
 		CuratorID uuid.UUID `json:"curator_id"`
 		CourseID  uuid.UUID `json:"course_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request for adding course", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
+		c.Error(models.NewBindingError("Invalid request data", err))
 		return
 	}
 
-	if err := h.repo.AddCourse(c, req.CuratorID, req.CourseID); err != nil {
+	err := h.repo.AddCourse(c, req.CuratorID, req.CourseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
 		logger.Error("Failed to add course", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to add course"))
+		c.Error(models.NewInternalError("Failed to add course"))
 		return
 	}
 
@@ -133,28 +151,109 @@ func (h *CuratorsHandler) AddCourse(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param request body handlers.CuratorsHandler.RemoveCourse.request true "Course unassignment data"
 // @Success 200 {object} object{message=string} "Course removed successfully"
-// @Failure 400 {object} models.ApiError "Invalid request data"
-// @Failure 403 {object} models.ApiError "Forbidden"
-// @Failure 404 {object} models.ApiError "Assignment not found"
-// @Failure 500 {object} models.ApiError "Internal server error"
-// @Router /settings/curators/remove-course [post]
+// @Failure 400 {object} models.Problem "Invalid request data"
+// @Failure 403 {object} models.Problem "Forbidden"
+// @Failure 404 {object} models.Problem "Assignment not found"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/remove-course [post]
 func (h *CuratorsHandler) RemoveCourse(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 	var req struct {
 		CuratorID uuid.UUID `json:"curator_id"`
 		CourseID  uuid.UUID `json:"course_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request for removing course", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
+		c.Error(models.NewBindingError("Invalid request data", err))
 		return
 	}
 
-	if err := h.repo.RemoveCourse(c, req.CuratorID, req.CourseID); err != nil {
+	err := h.repo.RemoveCourse(c, req.CuratorID, req.CourseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("assignment_not_found", "Assignment not found"))
+		return
+	}
+	if err != nil {
 		logger.Error("Failed to remove course", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to remove course"))
+		c.Error(models.NewInternalError("Failed to remove course"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Course removed from curator"})
 }
+
+// ReassignStudent godoc
+// @Summary Reassign student to another curator
+// @Description Closes the current assignment and opens a new one in a single transaction. The previous assignment stays in the history
+// @Tags Curators
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body handlers.CuratorsHandler.ReassignStudent.request true "Reassignment data"
+// @Success 200 {object} object{message=string} "Student reassigned successfully"
+// @Failure 400 {object} models.Problem "Invalid request data"
+// @Failure 403 {object} models.Problem "Forbidden"
+// @Failure 404 {object} models.Problem "Student is not assigned to from_curator_id, or to_curator_id is not a curator of the student's branch"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/reassign-student [post]
+func (h *CuratorsHandler) ReassignStudent(c *gin.Context) {
+	logger := logger.FromContext(c)
+	var req struct {
+		StudentID     uuid.UUID `json:"student_id" binding:"required"`
+		FromCuratorID uuid.UUID `json:"from_curator_id" binding:"required"`
+		ToCuratorID   uuid.UUID `json:"to_curator_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid request for reassigning student", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	assignedBy := c.MustGet("userID").(uuid.UUID)
+	err := h.repo.ReassignStudent(c, req.StudentID, req.FromCuratorID, req.ToCuratorID, &assignedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to reassign student", zap.String("student_id", req.StudentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to reassign student"))
+		return
+	}
+
+	logger.Info("Student reassigned",
+		zap.String("student_id", req.StudentID.String()),
+		zap.String("from_curator_id", req.FromCuratorID.String()),
+		zap.String("to_curator_id", req.ToCuratorID.String()))
+	c.JSON(http.StatusOK, gin.H{"message": "Student reassigned"})
+}
+
+// History godoc
+// @Summary Curator history of a student
+// @Description Returns all curator assignments of the student, newest first. The current one has no unassigned_at
+// @Tags Curators
+// @Produce json
+// @Security ApiKeyAuth
+// @Param studentId path string true "Student UUID" format(uuid)
+// @Success 200 {array} models.CuratorAssignment
+// @Failure 400 {object} models.Problem "Invalid student id"
+// @Failure 500 {object} models.Problem "Internal server error"
+// @Router /curators/students/{studentId}/curator-history [get]
+func (h *CuratorsHandler) History(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	history, err := h.repo.History(c, studentID)
+	if err != nil {
+		logger.Error("Failed to fetch curator history", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch curator history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CreateEnrollmentRequest struct {
+	CourseId  uuid.UUID       `json:"course_id" binding:"required"`
+	CuratorId *uuid.UUID      `json:"curator_id"`
+	StartDate string          `json:"start_date" binding:"required"`
+	EndDate   *string         `json:"end_date"`
+	Status    models.EnumCode `json:"status" binding:"omitempty,oneof=active paused completed canceled"`
+}
+
+type UpdateEnrollmentRequest struct {
+	CuratorId *uuid.UUID      `json:"curator_id"`
+	StartDate string          `json:"start_date" binding:"required"`
+	EndDate   *string         `json:"end_date"`
+	Status    models.EnumCode `json:"status" binding:"required,oneof=active paused completed canceled"`
+}
+
+type EnrollmentsHandlers struct {
+	enrollmentsRepo   *repositories.EnrollmentsRepository
+	certificateIssuer *CertificateIssuer
+}
+
+func NewEnrollmentsHandlers(enrollmentsRepo *repositories.EnrollmentsRepository, certificateIssuer *CertificateIssuer) *EnrollmentsHandlers {
+	return &EnrollmentsHandlers{enrollmentsRepo: enrollmentsRepo, certificateIssuer: certificateIssuer}
+}
+
+// Create godoc
+// @Summary Зачислить студента на курс
+// @Description Студент может одновременно учиться на нескольких курсах. Допустимые значения:
+// @Description - status: active, paused, completed, canceled (по умолчанию active)
+// @Description - start_date, end_date: дата в формате DD.MM.YYYY
+// @Tags Enrollments
+// @Accept json
+// @Produce json
+// @Param studentId path string true "UUID студента" format(uuid)
+// @Param request body CreateEnrollmentRequest true "Данные зачисления"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/students/{studentId}/enrollments [post]
+func (h *EnrollmentsHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	var request CreateEnrollmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid enrollment create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	startDate, err := utils.ParseRequiredDate(request.StartDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_start_date", "Invalid start date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	endDate, err := utils.ParseDate(request.EndDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_end_date", "Invalid end date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	if endDate != nil && endDate.Before(startDate) {
+		c.Error(models.NewValidationError("invalid_date_range", "Start date must be before end date"))
+		return
+	}
+
+	enrollment := models.Enrollment{
+		StudentId: studentID,
+		CourseId:  request.CourseId,
+		CuratorId: request.CuratorId,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Status:    string(request.Status),
+	}
+
+	id, err := h.enrollmentsRepo.Create(c, enrollment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("student_not_found", "Student not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create enrollment", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create enrollment"))
+		return
+	}
+
+	logger.Info("Student enrolled",
+		zap.String("student_id", studentID.String()),
+		zap.String("course_id", request.CourseId.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// FindByStudent godoc
+// @Summary Зачисления студента
+// @Description Возвращает все курсы студента со статусами, кураторами и сроками
+// @Tags Enrollments
+// @Produce json
+// @Param studentId path string true "UUID студента" format(uuid)
+// @Success 200 {array} models.Enrollment
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/students/{studentId}/enrollments [get]
+func (h *EnrollmentsHandlers) FindByStudent(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	enrollments, err := h.enrollmentsRepo.FindByStudent(c, studentID)
+	if err != nil {
+		logger.Error("Failed to fetch enrollments", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch enrollments"))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// Update godoc
+// @Summary Обновить зачисление
+// @Description Меняет куратора, сроки или статус зачисления (active, paused, completed, canceled).
+// @Description При переводе в статус completed выдается сертификат, его номер возвращается в ответе.
+// @Description Заголовок If-Match обязателен: version зачисления из списка зачислений студента в кавычках ("3").
+// @Description Если зачисление уже изменили, возвращается 412
+// @Tags Enrollments
+// @Accept json
+// @Produce json
+// @Param enrollmentId path string true "ID зачисления" format(uuid)
+// @Param If-Match header string true "Версия зачисления"
+// @Param request body UpdateEnrollmentRequest true "Данные зачисления"
+// @Success 200 {object} object{certificate_number=string}
+// @Header 200 {string} ETag "Новая версия зачисления"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/enrollments/{enrollmentId} [put]
+func (h *EnrollmentsHandlers) Update(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	enrollmentID, err := uuid.Parse(c.Param("enrollmentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_enrollment_id", "Invalid enrollment id"))
+		return
+	}
+
+	enrollment, err := h.enrollmentsRepo.FindById(c, enrollmentID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("enrollment_not_found", "Enrollment not found"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateEnrollmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid enrollment update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	startDate, err := utils.ParseRequiredDate(request.StartDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_start_date", "Invalid start date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	endDate, err := utils.ParseDate(request.EndDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_end_date", "Invalid end date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	if endDate != nil && endDate.Before(startDate) {
+		c.Error(models.NewValidationError("invalid_date_range", "Start date must be before end date"))
+		return
+	}
+
+	completed := enrollment.Status != models.EnrollmentCompleted && request.Status == models.EnrollmentCompleted
+
+	enrollment.CuratorId = request.CuratorId
+	enrollment.StartDate = startDate
+	enrollment.EndDate = endDate
+	enrollment.Status = string(request.Status)
+
+	version, err := h.enrollmentsRepo.Update(c, enrollment, versions)
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, models.ErrVersionConflict) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update enrollment", zap.String("enrollment_id", enrollmentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update enrollment"))
+		return
+	}
+	setETag(c, version)
+
+	if !completed {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	// Ошибка выдачи сертификата не отменяет завершение: его можно выдать повторно вручную
+	certificate, err := h.certificateIssuer.Issue(c, enrollmentID)
+	if err != nil {
+		logger.Warn("Failed to issue certificate", zap.String("enrollment_id", enrollmentID.String()), zap.Error(err))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"certificate_number": certificate.Number})
+}
+
+// Delete godoc
+// @Summary Удалить зачисление
+// @Description Удаляет ошибочно созданное зачисление. Для окончания обучения используйте статус completed
+// @Tags Enrollments
+// @Param enrollmentId path string true "ID зачисления" format(uuid)
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/enrollments/{enrollmentId} [delete]
+func (h *EnrollmentsHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	enrollmentID, err := uuid.Parse(c.Param("enrollmentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_enrollment_id", "Invalid enrollment id"))
+		return
+	}
+
+	if _, err := h.enrollmentsRepo.FindById(c, enrollmentID); err != nil {
+		c.Error(models.NewNotFoundError("enrollment_not_found", "Enrollment not found"))
+		return
+	}
+
+	if err := h.enrollmentsRepo.Delete(c, enrollmentID); err != nil {
+		logger.Error("Failed to delete enrollment", zap.String("enrollment_id", enrollmentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to delete enrollment"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"it_school/i18n"
+	"it_school/middlewares"
+	"it_school/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnumValue — значение перечисления: код для запросов и подпись на языке пользователя
+type EnumValue struct {
+	Code  string `json:"code" example:"freeze"`
+	Label string `json:"label" example:"заморозка"`
+}
+
+type EnumsHandlers struct{}
+
+func NewEnumsHandlers() *EnumsHandlers {
+	return &EnumsHandlers{}
+}
+
+// FindAll godoc
+// @Summary Значения перечислений
+// @Description Коды всех перечислений API (статусы, типы посещаемости и оплаты) с подписями на языке из Accept-Language (ru, kk, en).
+// @Description В запросах передаются коды; прежние русские значения тоже принимаются
+// @Tags Enums
+// @Produce json
+// @Param Accept-Language header string false "Язык подписей" Enums(ru, kk, en)
+// @Success 200 {object} map[string][]EnumValue
+// @Router /enums [get]
+func (h *EnumsHandlers) FindAll(c *gin.Context) {
+	lang := middlewares.Language(c)
+
+	enums := make(map[string][]EnumValue, len(models.Enums))
+	for name, codes := range models.Enums {
+		values := make([]EnumValue, 0, len(codes))
+		for _, code := range codes {
+			values = append(values, EnumValue{Code: code, Label: i18n.EnumLabel(lang, name, code)})
+		}
+		enums[name] = values
+	}
+	c.JSON(http.StatusOK, enums)
+}
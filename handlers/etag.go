@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"it_school/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag — ETag записи по ее версии (сильный, в кавычках)
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag отдает версию записи в заголовке ETag
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatchVersions разбирает обязательный заголовок If-Match и возвращает допустимые версии.
+// nil означает If-Match: * (подходит любая версия). Без заголовка отвечает 428,
+// ETag не нашего формата не совпадет ни с одной версией, и репозиторий ответит 412
+func ifMatchVersions(c *gin.Context) ([]int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.Error(models.ErrIfMatchRequired)
+		return nil, false
+	}
+	if header == "*" {
+		return nil, true
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// Слабые ETag по RFC 9110 не участвуют в сравнении для If-Match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, true
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testContext — контекст gin с запросом, в котором заданы заголовки headers
+func testContext(method string, headers map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/", nil)
+	for name, value := range headers {
+		c.Request.Header.Set(name, value)
+	}
+	return c, w
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		wantVersions []int
+		wantOK       bool
+	}{
+		{name: "missing", ifMatch: "", wantOK: false},
+		{name: "blank", ifMatch: "   ", wantOK: false},
+		{name: "any version", ifMatch: "*", wantVersions: nil, wantOK: true},
+		{name: "single", ifMatch: `"3"`, wantVersions: []int{3}, wantOK: true},
+		{name: "unquoted", ifMatch: `3`, wantVersions: []int{3}, wantOK: true},
+		{name: "list", ifMatch: `"3", "4" ,"7"`, wantVersions: []int{3, 4, 7}, wantOK: true},
+		{name: "weak ignored", ifMatch: `W/"3", "4"`, wantVersions: []int{4}, wantOK: true},
+		{name: "only weak", ifMatch: `W/"3"`, wantVersions: []int{}, wantOK: true},
+		{name: "foreign etag", ifMatch: `"abc123"`, wantVersions: []int{}, wantOK: true},
+		{name: "foreign and ours", ifMatch: `"abc123", "5"`, wantVersions: []int{5}, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testContext(http.MethodPut, map[string]string{"If-Match": tt.ifMatch})
+
+			versions, ok := ifMatchVersions(c)
+
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if len(c.Errors) != 1 || !errors.Is(c.Errors.Last().Err, models.ErrIfMatchRequired) {
+					t.Errorf("errors = %v, want ErrIfMatchRequired", c.Errors)
+				}
+				return
+			}
+			if len(c.Errors) != 0 {
+				t.Errorf("unexpected errors %v", c.Errors)
+			}
+			// nil (любая версия) и пустой список (ни одна версия) означают разное
+			if (versions == nil) != (tt.wantVersions == nil) || !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("versions = %#v, want %#v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	c, w := testContext(http.MethodGet, nil)
+	setETag(c, 12)
+	if got := w.Header().Get("ETag"); got != `"12"` {
+		t.Errorf("ETag = %s, want \"12\"", got)
+	}
+
+	// ETag, выданный сервером, принимается обратно в If-Match
+	c, _ = testContext(http.MethodPut, map[string]string{"If-Match": etag(12)})
+	if versions, ok := ifMatchVersions(c); !ok || !slices.Equal(versions, []int{12}) {
+		t.Errorf("round trip = %v, %v", versions, ok)
+	}
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type RubricCriterionInput struct {
+	Id          uuid.UUID `json:"id"`
+	Title       string    `json:"title" binding:"required"`
+	Description *string   `json:"description"`
+	MinScore    *int      `json:"min_score"`
+	MaxScore    *int      `json:"max_score"`
+}
+
+type RubricRequest struct {
+	Criteria []RubricCriterionInput `json:"criteria" binding:"dive"`
+}
+
+type ScoreInput struct {
+	CriterionId uuid.UUID `json:"criterion_id" binding:"required"`
+	Score       int       `json:"score"`
+	Comment     *string   `json:"comment"`
+}
+
+type ScoresRequest struct {
+	Scores []ScoreInput `json:"scores" binding:"required,min=1,dive"`
+}
+
+type GradesHandlers struct {
+	gradesRepo *repositories.GradesRepository
+	fontPath   string
+}
+
+func NewGradesHandlers(gradesRepo *repositories.GradesRepository, fontPath string) *GradesHandlers {
+	return &GradesHandlers{gradesRepo: gradesRepo, fontPath: fontPath}
+}
+
+// FindRubric godoc
+// @Summary Рубрика оценивания курса
+// @Description Возвращает критерии оценивания курса со шкалами баллов
+// @Tags Grades
+// @Produce json
+// @Param courseId path string true "ID курса"
+// @Success 200 {array} models.RubricCriterion
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/rubric [get]
+func (h *GradesHandlers) FindRubric(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	criteria, err := h.gradesRepo.FindRubric(c, courseID)
+	if err != nil {
+		logger.Error("Failed to fetch rubric", zap.String("course_id", courseID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch rubric"))
+		return
+	}
+
+	c.JSON(http.StatusOK, criteria)
+}
+
+// ReplaceRubric godoc
+// @Summary Сохранить рубрику курса
+// @Description Заменяет критерии оценивания курса целиком. Шкала по умолчанию 1–5.
+// @Description Критерии с id обновляются, без id — создаются, не переданные удаляются вместе с баллами
+// @Tags Grades
+// @Accept json
+// @Param courseId path string true "ID курса"
+// @Param request body RubricRequest true "Критерии"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/courses/{courseId}/rubric [put]
+func (h *GradesHandlers) ReplaceRubric(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	var request RubricRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid rubric request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	criteria := make([]models.RubricCriterion, 0, len(request.Criteria))
+	for _, input := range request.Criteria {
+		criterion := models.RubricCriterion{
+			Id:          input.Id,
+			Title:       input.Title,
+			Description: input.Description,
+			MinScore:    1,
+			MaxScore:    5,
+		}
+		if input.MinScore != nil {
+			criterion.MinScore = *input.MinScore
+		}
+		if input.MaxScore != nil {
+			criterion.MaxScore = *input.MaxScore
+		}
+		if criterion.MinScore >= criterion.MaxScore {
+			c.Error(models.NewValidationError("invalid_score_range", "min_score must be less than max_score"))
+			return
+		}
+		criteria = append(criteria, criterion)
+	}
+
+	err = h.gradesRepo.ReplaceRubric(c, courseID, criteria)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("course_or_criterion_not_found", "Course or criterion not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to save rubric", zap.String("course_id", courseID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save rubric"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func toScores(inputs []ScoreInput) []models.StudentScore {
+	scores := make([]models.StudentScore, 0, len(inputs))
+	for _, input := range inputs {
+		scores = append(scores, models.StudentScore{
+			CriterionId: input.CriterionId,
+			Score:       input.Score,
+			Comment:     input.Comment,
+		})
+	}
+	return scores
+}
+
+// ScoreLesson godoc
+// @Summary Оценить урок
+// @Description Выставляет баллы студенту за урок по критериям рубрики курса. Повторная оценка перезаписывает прежнюю
+// @Tags Grades
+// @Accept json
+// @Param attendanceId path string true "ID записи посещаемости (урока)"
+// @Param request body ScoresRequest true "Баллы"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/lessons/{attendanceId}/scores [put]
+func (h *GradesHandlers) ScoreLesson(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	attendanceID, err := uuid.Parse(c.Param("attendanceId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_attendance_id", "Invalid attendance id"))
+		return
+	}
+
+	var request ScoresRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid scores request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	err = h.gradesRepo.ScoreLesson(c, attendanceID, toScores(request.Scores), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("lesson_not_found", "Lesson not found"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidScore) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to score lesson", zap.String("attendance_id", attendanceID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save scores"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// ScoreModule godoc
+// @Summary Оценить модуль
+// @Description Выставляет студенту итоговые баллы за модуль программы по критериям рубрики курса
+// @Tags Grades
+// @Accept json
+// @Param studentId path string true "UUID студента"
+// @Param moduleId path string true "ID модуля программы"
+// @Param request body ScoresRequest true "Баллы"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/students/{studentId}/modules/{moduleId}/scores [put]
+func (h *GradesHandlers) ScoreModule(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	moduleID, err := uuid.Parse(c.Param("moduleId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_module_id", "Invalid module id"))
+		return
+	}
+
+	var request ScoresRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid scores request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	err = h.gradesRepo.ScoreModule(c, studentID, moduleID, toScores(request.Scores), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("student_or_module_not_found", "Student or module not found"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidScore) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to score module",
+			zap.String("student_id", studentID.String()),
+			zap.String("module_id", moduleID.String()),
+			zap.Error(err))
+		c.Error(models.NewInternalError("Failed to save scores"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// parsePeriod читает необязательные from/to в формате DD.MM.YYYY
+func parsePeriod(c *gin.Context) (*time.Time, *time.Time, error) {
+	from, to := c.Query("from"), c.Query("to")
+	fromDate, err := utils.ParseDate(&from)
+	if err != nil {
+		return nil, nil, err
+	}
+	toDate, err := utils.ParseDate(&to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fromDate, toDate, nil
+}
+
+// Scores godoc
+// @Summary Динамика баллов студента
+// @Description Данные для графиков: баллы по каждому критерию курса по датам и средний балл за период
+// @Tags Grades
+// @Produce json
+// @Param studentId path string true "UUID студента"
+// @Param courseId path string true "ID курса"
+// @Param from query string false "Начало периода (DD.MM.YYYY)"
+// @Param to query string false "Конец периода (DD.MM.YYYY)"
+// @Success 200 {array} models.CriterionProgress
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/students/{studentId}/courses/{courseId}/scores [get]
+func (h *GradesHandlers) Scores(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_date", "Invalid date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	progress, err := h.gradesRepo.Progress(c, studentID, courseID, from, to)
+	if err != nil {
+		logger.Error("Failed to fetch scores",
+			zap.String("student_id", studentID.String()),
+			zap.String("course_id", courseID.String()),
+			zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch scores"))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// ReportCard godoc
+// @Summary Табель студента
+// @Description Формирует табель за период (PDF для отправки родителям). По умолчанию период — последние 3 месяца.
+// @Description format=json возвращает данные табеля без PDF
+// @Tags Grades
+// @Produce application/pdf
+// @Produce json
+// @Param studentId path string true "UUID студента"
+// @Param courseId path string true "ID курса"
+// @Param from query string false "Начало периода (DD.MM.YYYY)"
+// @Param to query string false "Конец периода (DD.MM.YYYY)"
+// @Param format query string false "pdf (по умолчанию) или json"
+// @Success 200 {object} models.ReportCard
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/students/{studentId}/courses/{courseId}/report-card [get]
+func (h *GradesHandlers) ReportCard(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+		return
+	}
+
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_date", "Invalid date format. Use DD.MM.YYYY"))
+		return
+	}
+	if to == nil {
+		today := time.Now().Truncate(24 * time.Hour)
+		to = &today
+	}
+	if from == nil {
+		start := to.AddDate(0, -3, 0)
+		from = &start
+	}
+	if from.After(*to) {
+		c.Error(models.NewValidationError("invalid_date_range", "Start date must be before end date"))
+		return
+	}
+
+	card, err := h.gradesRepo.ReportCard(c, studentID, courseID, *from, *to)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("student_or_course_not_found", "Student or course not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to build report card",
+			zap.String("student_id", studentID.String()),
+			zap.String("course_id", courseID.String()),
+			zap.Error(err))
+		c.Error(models.NewInternalError("Failed to build report card"))
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, card)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := utils.RenderReportCard(&buf, card, h.fontPath); err != nil {
+		logger.Error("Failed to render report card", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to render report card"))
+		return
+	}
+
+	fileName := fmt.Sprintf("report-card-%s.pdf", to.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
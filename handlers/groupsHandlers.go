@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"it_school/logger"
+	"it_school/metrics"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type GroupRequest struct {
+	CourseId  uuid.UUID                  `json:"course_id" binding:"required"`
+	CuratorId *uuid.UUID                 `json:"curator_id"`
+	Title     string                     `json:"title" binding:"required"`
+	Schedule  []models.GroupScheduleSlot `json:"schedule"`
+	BranchId  *uuid.UUID                 `json:"branch_id"`
+}
+
+type GroupMemberRequest struct {
+	StudentId uuid.UUID `json:"student_id" binding:"required"`
+}
+
+type GroupLessonRequest struct {
+	Date          string                 `json:"date" binding:"required"`
+	Format        *string                `json:"format"`
+	CuratorId     *uuid.UUID             `json:"curator_id"`
+	TopicId       *uuid.UUID             `json:"topic_id"`
+	DefaultStatus models.EnumCode        `json:"default_status" binding:"omitempty,oneof=missed conducted scheduled canceled"`
+	Students      []GroupLessonMarkInput `json:"students" binding:"dive"`
+}
+
+type GroupLessonMarkInput struct {
+	StudentId    uuid.UUID       `json:"student_id" binding:"required"`
+	LessonStatus models.EnumCode `json:"lessons_status" binding:"omitempty,oneof=missed conducted"`
+	Feedback     *string         `json:"feedback"`
+}
+
+type GroupsHandlers struct {
+	groupsRepo *repositories.GroupsRepository
+}
+
+func NewGroupsHandlers(groupsRepo *repositories.GroupsRepository) *GroupsHandlers {
+	return &GroupsHandlers{groupsRepo: groupsRepo}
+}
+
+// groupAccessible проверяет, что пользователь может работать с группой: куратор — только со своими
+// группами, пользователь с доступом к настройкам — со всеми группами филиала. Чужая группа для куратора не найдена
+func groupAccessible(c *gin.Context, group models.Group) bool {
+	role := c.MustGet("userRole").(*models.Role)
+	if role.Permissions["access_settings"] {
+		return true
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if group.CuratorId != nil && *group.CuratorId == userID {
+		return true
+	}
+	c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+	return false
+}
+
+// validateSchedule проверяет день недели, время начала и длительность каждого занятия
+func validateSchedule(schedule []models.GroupScheduleSlot) bool {
+	for _, slot := range schedule {
+		if slot.Weekday < 1 || slot.Weekday > 7 || slot.DurationMinutes <= 0 {
+			return false
+		}
+		if _, err := time.Parse("15:04", slot.StartTime); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Create godoc
+// @Summary Создать группу
+// @Description Создает учебную группу курса. schedule — список занятий: weekday (1–7), start_time (HH:MM), duration_minutes.
+// @Description Курс и куратор должны относиться к филиалу группы
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param request body GroupRequest true "Данные группы"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem "Курс или куратор не найден"
+// @Failure 500 {object} models.Problem
+// @Router /settings/groups [post]
+func (h *GroupsHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var request GroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid group create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if !validateSchedule(request.Schedule) {
+		c.Error(models.NewValidationError("invalid_schedule", "Invalid schedule"))
+		return
+	}
+
+	branchID, ok := resolveBranch(c, request.BranchId)
+	if !ok {
+		c.Error(models.NewValidationError("branch_required", "Branch is required"))
+		return
+	}
+
+	group := models.Group{
+		BranchId:  branchID,
+		CourseId:  request.CourseId,
+		CuratorId: request.CuratorId,
+		Title:     request.Title,
+		Schedule:  request.Schedule,
+	}
+	if group.Schedule == nil {
+		group.Schedule = []models.GroupScheduleSlot{}
+	}
+
+	id, err := h.groupsRepo.Create(c, group)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create group", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create group"))
+		return
+	}
+
+	logger.Info("Group created", zap.String("group_id", id.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// Update godoc
+// @Summary Обновить группу
+// @Description Курс и куратор должны относиться к филиалу группы.
+// @Description Заголовок If-Match обязателен: ETag из GET /curators/groups/{groupId}. Если группу уже изменили, возвращается 412
+// @Tags Groups
+// @Accept json
+// @Param groupId path string true "ID группы"
+// @Param If-Match header string true "ETag группы"
+// @Param request body GroupRequest true "Данные группы"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия группы"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/groups/{groupId} [put]
+func (h *GroupsHandlers) Update(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	if _, err := h.groupsRepo.FindById(c, groupID); err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var request GroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid group update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if !validateSchedule(request.Schedule) {
+		c.Error(models.NewValidationError("invalid_schedule", "Invalid schedule"))
+		return
+	}
+
+	group := models.Group{
+		Id:        groupID,
+		CourseId:  request.CourseId,
+		CuratorId: request.CuratorId,
+		Title:     request.Title,
+		Schedule:  request.Schedule,
+	}
+	if group.Schedule == nil {
+		group.Schedule = []models.GroupScheduleSlot{}
+	}
+
+	version, err := h.groupsRepo.Update(c, group, versions)
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, models.ErrVersionConflict) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update group", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update group"))
+		return
+	}
+
+	setETag(c, version)
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary Удалить группу
+// @Description Удаляет группу, ее состав и групповые уроки вместе с записями посещаемости по ним (и отметками, заданиями и оценками этих уроков)
+// @Tags Groups
+// @Param groupId path string true "ID группы"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/groups/{groupId} [delete]
+func (h *GroupsHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	if _, err := h.groupsRepo.FindById(c, groupID); err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+
+	err = h.groupsRepo.Delete(c, groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete group", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to delete group"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// AddMember godoc
+// @Summary Добавить студента в группу
+// @Description Студент должен учиться на курсе группы в том же филиале
+// @Tags Groups
+// @Accept json
+// @Param groupId path string true "ID группы"
+// @Param request body GroupMemberRequest true "Студент"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/groups/{groupId}/members [post]
+func (h *GroupsHandlers) AddMember(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	if _, err := h.groupsRepo.FindById(c, groupID); err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+
+	var request GroupMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	err = h.groupsRepo.AddMember(c, groupID, request.StudentId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewValidationError("student_not_enrolled", "Student is not enrolled in the group's course"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to add group member", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to add student to group"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Student added to group"})
+}
+
+// RemoveMember godoc
+// @Summary Убрать студента из группы
+// @Tags Groups
+// @Param groupId path string true "ID группы"
+// @Param studentId path string true "ID студента"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/groups/{groupId}/members/{studentId} [delete]
+func (h *GroupsHandlers) RemoveMember(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	if _, err := h.groupsRepo.FindById(c, groupID); err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+
+	if err := h.groupsRepo.RemoveMember(c, groupID, studentID); err != nil {
+		logger.Error("Failed to remove group member", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to remove student from group"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Student removed from group"})
+}
+
+// FindAll godoc
+// @Summary Список групп
+// @Description Куратор видит только свои группы; администратор — все группы филиала (можно отфильтровать по curator_id)
+// @Tags Groups
+// @Produce json
+// @Param curator_id query string false "Фильтр по ID куратора" format(uuid)
+// @Success 200 {array} models.Group
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/groups [get]
+func (h *GroupsHandlers) FindAll(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var curatorID *uuid.UUID
+	role := c.MustGet("userRole").(*models.Role)
+	if !role.Permissions["access_settings"] {
+		userID := c.MustGet("userID").(uuid.UUID)
+		curatorID = &userID
+	} else if param := c.Query("curator_id"); param != "" {
+		id, err := uuid.Parse(param)
+		if err != nil {
+			c.Error(models.NewValidationError("invalid_curator_id", "Invalid curator id"))
+			return
+		}
+		curatorID = &id
+	}
+
+	groups, err := h.groupsRepo.FindAll(c, curatorID)
+	if err != nil {
+		logger.Error("Failed to fetch groups", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch groups"))
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// FindById godoc
+// @Summary Получить группу
+// @Description Куратор видит только свои группы. Заголовок ETag нужно передать в If-Match при обновлении
+// @Tags Groups
+// @Produce json
+// @Param groupId path string true "ID группы"
+// @Success 200 {object} models.Group
+// @Header 200 {string} ETag "Версия группы"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /curators/groups/{groupId} [get]
+func (h *GroupsHandlers) FindById(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	group, err := h.groupsRepo.FindById(c, groupID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+	if !groupAccessible(c, group) {
+		return
+	}
+
+	setETag(c, group.Version)
+	c.JSON(http.StatusOK, group)
+}
+
+// CreateLesson godoc
+// @Summary Провести групповой урок
+// @Description Создает урок группы и запись посещаемости lesson для каждого участника.
+// @Description Статус отдельного студента задается в students (missed, conducted), остальные получают default_status.
+// @Description Если default_status не указан — scheduled для будущей даты, иначе conducted.
+// @Description Куратор проводит уроки только в своих группах и только от своего имени. Администратор может указать curator_id —
+// @Description куратора филиала группы; без него урок ведет куратор группы
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "ID группы"
+// @Param request body GroupLessonRequest true "Данные урока"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/groups/{groupId}/lessons [post]
+func (h *GroupsHandlers) CreateLesson(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	role := c.MustGet("userRole").(*models.Role)
+	if !utils.HasAccessToType(role, models.AttendanceTypeLesson) {
+		c.Error(models.NewForbiddenError("lesson_create_forbidden", "You are not allowed to create lessons"))
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	group, err := h.groupsRepo.FindById(c, groupID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+	if !groupAccessible(c, group) {
+		return
+	}
+
+	var request GroupLessonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid group lesson request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request", err))
+		return
+	}
+
+	if len(group.StudentIds) == 0 {
+		c.Error(models.NewValidationError("group_has_no_students", "Group has no students"))
+		return
+	}
+
+	date, err := utils.ParseRequiredDate(request.Date)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lesson_date", "Invalid lesson date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	members := make(map[uuid.UUID]bool, len(group.StudentIds))
+	for _, id := range group.StudentIds {
+		members[id] = true
+	}
+
+	marks := make([]repositories.GroupLessonMark, 0, len(request.Students))
+	for i, input := range request.Students {
+		if !members[input.StudentId] {
+			c.Error(models.NewValidationError("student_not_in_group", "Student "+input.StudentId.String()+" is not a member of the group", models.FieldError{
+				Field:   fmt.Sprintf("students[%d].student_id", i),
+				Code:    "student_not_in_group",
+				Message: "Student " + input.StudentId.String() + " is not a member of the group",
+			}))
+			return
+		}
+		marks = append(marks, repositories.GroupLessonMark{
+			StudentId:    input.StudentId,
+			LessonStatus: string(input.LessonStatus),
+			Feedback:     input.Feedback,
+		})
+	}
+
+	defaultStatus := request.DefaultStatus
+	if defaultStatus == "" {
+		if date.After(time.Now()) {
+			defaultStatus = models.LessonScheduled
+		} else {
+			defaultStatus = models.LessonConducted
+		}
+	}
+
+	// Куратор ведет урок сам. Администратор — от имени указанного куратора, иначе куратора группы,
+	// а если у группы его нет — от своего имени, если сам куратор
+	curatorID := c.MustGet("userID").(uuid.UUID)
+	curatorRequired := false
+	switch {
+	case !role.Permissions["access_settings"]:
+		if request.CuratorId != nil && *request.CuratorId != curatorID {
+			c.Error(models.NewForbiddenError("lesson_curator_forbidden", "You can only record your own lessons"))
+			return
+		}
+	case request.CuratorId != nil:
+		curatorID = *request.CuratorId
+	case group.CuratorId != nil:
+		curatorID = *group.CuratorId
+	default:
+		curatorRequired = true
+	}
+
+	lesson := models.GroupLesson{
+		GroupId:   groupID,
+		CuratorId: curatorID,
+		Date:      date,
+		Format:    request.Format,
+		TopicId:   request.TopicId,
+	}
+
+	id, err := h.groupsRepo.CreateLesson(c, group, lesson, string(defaultStatus), marks)
+	if errors.Is(err, pgx.ErrNoRows) && curatorRequired {
+		c.Error(models.NewValidationError("curator_required", "Group has no curator. Specify curator_id"))
+		return
+	}
+	if errors.Is(err, models.ErrTopicNotInCourse) || errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create group lesson", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Could not create group lesson"))
+		return
+	}
+
+	metrics.LessonsRecorded.WithLabelValues("group").Add(float64(len(group.StudentIds)))
+	logger.Info("Group lesson created",
+		zap.String("group_id", groupID.String()),
+		zap.String("group_lesson_id", id.String()),
+		zap.Int("students", len(group.StudentIds)))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// FindLessons godoc
+// @Summary Журнал группы
+// @Description Возвращает групповые уроки с индивидуальными статусами студентов. Куратор видит журнал только своих групп
+// @Tags Groups
+// @Produce json
+// @Param groupId path string true "ID группы"
+// @Success 200 {array} models.GroupLesson
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/groups/{groupId}/lessons [get]
+func (h *GroupsHandlers) FindLessons(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_group_id", "Invalid group id"))
+		return
+	}
+
+	group, err := h.groupsRepo.FindById(c, groupID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("group_not_found", "Group not found"))
+		return
+	}
+	if !groupAccessible(c, group) {
+		return
+	}
+
+	lessons, err := h.groupsRepo.FindLessons(c, groupID)
+	if err != nil {
+		logger.Error("Failed to fetch group lessons", zap.String("group_id", groupID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch group lessons"))
+		return
+	}
+
+	c.JSON(http.StatusOK, lessons)
+}
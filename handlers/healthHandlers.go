@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"it_school/logger"
+	"it_school/migrations"
+	"it_school/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// readinessTimeout ограничивает проверки готовности, чтобы /readyz отвечал быстрее таймаута балансировщика
+const readinessTimeout = 2 * time.Second
+
+type HealthResponse struct {
+	Status string `json:"status" example:"ok"`
+}
+
+type ReadinessResponse struct {
+	Status            string   `json:"status" example:"ok"`
+	Database          string   `json:"database" example:"ok"`
+	PendingMigrations []string `json:"pending_migrations,omitempty"`
+}
+
+type HealthHandlers struct {
+	healthRepo *repositories.HealthRepository
+}
+
+func NewHealthHandlers(healthRepo *repositories.HealthRepository) *HealthHandlers {
+	return &HealthHandlers{healthRepo: healthRepo}
+}
+
+// Liveness godoc
+// @Summary Проверка живости
+// @Description Процесс запущен и обрабатывает запросы. БД не проверяется
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (h *HealthHandlers) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Readiness godoc
+// @Summary Проверка готовности
+// @Description Доступна БД и применены все миграции, встроенные в бинарник
+// @Tags Health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (h *HealthHandlers) Readiness(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	response := ReadinessResponse{Status: "ok", Database: "ok"}
+
+	if err := h.healthRepo.Ping(ctx); err != nil {
+		logger.Warn("Readiness check: database is unavailable", zap.Error(err))
+		response.Status = "unavailable"
+		response.Database = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	applied, err := h.healthRepo.AppliedMigrations(ctx)
+	if err != nil {
+		logger.Warn("Readiness check: failed to read schema_migrations", zap.Error(err))
+		response.Status = "unavailable"
+		response.PendingMigrations = migrations.Versions()
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	for _, version := range migrations.Versions() {
+		if !applied[version] {
+			response.PendingMigrations = append(response.PendingMigrations, version)
+		}
+	}
+	if len(response.PendingMigrations) > 0 {
+		response.Status = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CreateHomeworkRequest struct {
+	AttendanceId  *uuid.UUID `json:"attendance_id"`
+	GroupLessonId *uuid.UUID `json:"group_lesson_id"`
+	Description   string     `json:"description" binding:"required"`
+	DueDate       string     `json:"due_date" binding:"required"`
+}
+
+type UpdateSubmissionRequest struct {
+	Status  models.EnumCode `json:"status" binding:"required,oneof=not_submitted submitted checked"`
+	Grade   *int            `json:"grade" binding:"omitempty,min=0,max=100"`
+	Comment *string         `json:"comment"`
+}
+
+type HomeworkHandlers struct {
+	homeworkRepo   *repositories.HomeworkRepository
+	storage        *utils.LocalStorage
+	maxUploadBytes int64
+}
+
+func NewHomeworkHandlers(homeworkRepo *repositories.HomeworkRepository, storage *utils.LocalStorage, maxUploadBytes int64) *HomeworkHandlers {
+	return &HomeworkHandlers{homeworkRepo: homeworkRepo, storage: storage, maxUploadBytes: maxUploadBytes}
+}
+
+// Create godoc
+// @Summary Выдать домашнее задание
+// @Description Задание привязывается к индивидуальному уроку (attendance_id) или групповому (group_lesson_id) — ровно к одному.
+// @Description Каждый студент урока получает сдачу со статусом not_submitted. due_date — дата в формате DD.MM.YYYY
+// @Tags Homework
+// @Accept json
+// @Produce json
+// @Param request body CreateHomeworkRequest true "Данные задания"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/homework [post]
+func (h *HomeworkHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var request CreateHomeworkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid homework create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	if (request.AttendanceId == nil) == (request.GroupLessonId == nil) {
+		c.Error(models.NewValidationError("homework_target_required", "Exactly one of attendance_id or group_lesson_id is required"))
+		return
+	}
+
+	dueDate, err := utils.ParseRequiredDate(request.DueDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_due_date", "Invalid due date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	homework := models.Homework{
+		AttendanceId:  request.AttendanceId,
+		GroupLessonId: request.GroupLessonId,
+		Description:   request.Description,
+		DueDate:       dueDate,
+		CreatedBy:     &userID,
+	}
+
+	id, err := h.homeworkRepo.Create(c, homework)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("lesson_not_found", "Lesson not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create homework", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create homework"))
+		return
+	}
+
+	logger.Info("Homework created", zap.String("homework_id", id.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// FindById godoc
+// @Summary Домашнее задание
+// @Description Возвращает задание со сдачами студентов и вложениями
+// @Tags Homework
+// @Produce json
+// @Param homeworkId path string true "ID задания"
+// @Success 200 {object} models.Homework
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /curators/homework/{homeworkId} [get]
+func (h *HomeworkHandlers) FindById(c *gin.Context) {
+	homeworkID, err := uuid.Parse(c.Param("homeworkId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_homework_id", "Invalid homework id"))
+		return
+	}
+
+	homework, err := h.homeworkRepo.FindById(c, homeworkID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("homework_not_found", "Homework not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, homework)
+}
+
+// FindByStudent godoc
+// @Summary Домашние задания студента
+// @Description Возвращает задания студента, новые первыми, с его сдачей и вложениями
+// @Tags Homework
+// @Produce json
+// @Param studentId path string true "UUID студента"
+// @Success 200 {array} models.Homework
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/students/{studentId}/homework [get]
+func (h *HomeworkHandlers) FindByStudent(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentID, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	homeworks, err := h.homeworkRepo.FindByStudent(c, studentID)
+	if err != nil {
+		logger.Error("Failed to fetch homework", zap.String("student_id", studentID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch homework"))
+		return
+	}
+
+	c.JSON(http.StatusOK, homeworks)
+}
+
+// Delete godoc
+// @Summary Удалить домашнее задание
+// @Description Удаляет задание вместе со сдачами и файлами
+// @Tags Homework
+// @Param homeworkId path string true "ID задания"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/homework/{homeworkId} [delete]
+func (h *HomeworkHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	homeworkID, err := uuid.Parse(c.Param("homeworkId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_homework_id", "Invalid homework id"))
+		return
+	}
+
+	if _, err := h.homeworkRepo.FindById(c, homeworkID); err != nil {
+		c.Error(models.NewNotFoundError("homework_not_found", "Homework not found"))
+		return
+	}
+
+	paths, err := h.homeworkRepo.Delete(c, homeworkID)
+	if err != nil {
+		logger.Error("Failed to delete homework", zap.String("homework_id", homeworkID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to delete homework"))
+		return
+	}
+
+	for _, path := range paths {
+		if err := h.storage.Remove(path); err != nil {
+			logger.Warn("Failed to remove homework attachment", zap.String("path", path), zap.Error(err))
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UpdateSubmission godoc
+// @Summary Обновить сдачу задания
+// @Description Меняет статус сдачи (not_submitted, submitted, checked), оценку (0–100) и комментарий куратора
+// @Tags Homework
+// @Accept json
+// @Param submissionId path string true "ID сдачи"
+// @Param request body UpdateSubmissionRequest true "Статус и оценка"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/homework/submissions/{submissionId} [put]
+func (h *HomeworkHandlers) UpdateSubmission(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	submissionID, err := uuid.Parse(c.Param("submissionId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_submission_id", "Invalid submission id"))
+		return
+	}
+
+	submission, err := h.homeworkRepo.FindSubmission(c, submissionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var request UpdateSubmissionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid submission update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	submission.Status = string(request.Status)
+	submission.Grade = request.Grade
+	submission.Comment = request.Comment
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	err = h.homeworkRepo.UpdateSubmission(c, submission, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update submission", zap.String("submission_id", submissionID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update submission"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UploadAttachment godoc
+// @Summary Загрузить файл к сдаче
+// @Description Сохраняет файл с работой студента. Несданное задание отмечается как submitted
+// @Tags Homework
+// @Accept multipart/form-data
+// @Produce json
+// @Param submissionId path string true "ID сдачи"
+// @Param file formData file true "Файл"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 413 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/homework/submissions/{submissionId}/attachments [post]
+func (h *HomeworkHandlers) UploadAttachment(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	submissionID, err := uuid.Parse(c.Param("submissionId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_submission_id", "Invalid submission id"))
+		return
+	}
+
+	if _, err := h.homeworkRepo.FindSubmission(c, submissionID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadBytes)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Error(models.NewTooLargeError("file_too_large", "File is too large"))
+			return
+		}
+		c.Error(models.NewValidationError("file_required", "File is required"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("Failed to open uploaded file", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to upload file"))
+		return
+	}
+	defer file.Close()
+
+	// Тип определяется по содержимому файла, а не по заголовку, который прислал клиент
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		logger.Error("Failed to read uploaded file", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to upload file"))
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	path, size, err := h.storage.Save("homework/"+submissionID.String(), fileHeader.Filename, io.MultiReader(bytes.NewReader(head), file))
+	if err != nil {
+		logger.Error("Failed to store homework attachment", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to upload file"))
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := h.homeworkRepo.AddAttachment(c, models.HomeworkAttachment{
+		SubmissionId: submissionID,
+		FileName:     fileHeader.Filename,
+		ContentType:  contentType,
+		Size:         size,
+		StoragePath:  path,
+		UploadedBy:   &userID,
+	})
+	if err != nil {
+		h.storage.Remove(path)
+		logger.Error("Failed to save homework attachment", zap.String("submission_id", submissionID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to upload file"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// DownloadAttachment godoc
+// @Summary Скачать файл сдачи
+// @Tags Homework
+// @Produce octet-stream
+// @Param attachmentId path string true "ID вложения"
+// @Success 200 {file} file
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /curators/homework/attachments/{attachmentId} [get]
+func (h *HomeworkHandlers) DownloadAttachment(c *gin.Context) {
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_attachment_id", "Invalid attachment id"))
+		return
+	}
+
+	attachment, err := h.homeworkRepo.FindAttachment(c, attachmentID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Файл всегда отдается как двоичный, чтобы браузер не открыл загруженный HTML или скрипт как страницу
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.FileAttachment(h.storage.Path(attachment.StoragePath), attachment.FileName)
+}
+
+// Overdue godoc
+// @Summary Просроченные домашние задания
+// @Description Несданные задания с истекшим сроком. Куратор видит задания со своих уроков,
+// @Description менеджер и администратор — все задания филиала (можно отфильтровать по curator_id)
+// @Tags Homework
+// @Produce json
+// @Param curator_id query string false "Фильтр по ID куратора" format(uuid)
+// @Success 200 {array} models.OverdueHomework
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/homework/overdue [get]
+func (h *HomeworkHandlers) Overdue(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var curatorID *uuid.UUID
+	role := c.MustGet("userRole").(*models.Role)
+	if !role.Permissions["access_settings"] && !role.Permissions["access_manager"] {
+		userID := c.MustGet("userID").(uuid.UUID)
+		curatorID = &userID
+	} else if param := c.Query("curator_id"); param != "" {
+		id, err := uuid.Parse(param)
+		if err != nil {
+			c.Error(models.NewValidationError("invalid_curator_id", "Invalid curator id"))
+			return
+		}
+		curatorID = &id
+	}
+
+	overdue, err := h.homeworkRepo.Overdue(c, curatorID)
+	if err != nil {
+		logger.Error("Failed to fetch overdue homework", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch overdue homework"))
+		return
+	}
+
+	c.JSON(http.StatusOK, overdue)
+}
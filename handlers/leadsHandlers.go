@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/logger"
+	"it_school/metrics"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CreateLeadRequest struct {
+	BranchId          *uuid.UUID `json:"branch_id"`
+	FullName          string     `json:"full_name" binding:"required"`
+	PhoneNumber       *string    `json:"phone_number"`
+	ParentName        string     `json:"parent_name" binding:"required"`
+	ParentPhoneNumber string     `json:"parent_phone_number" binding:"required"`
+	Source            *string    `json:"source"`
+	CourseId          *uuid.UUID `json:"course_id"`
+	Comment           *string    `json:"comment"`
+}
+
+type UpdateLeadRequest struct {
+	FullName          string          `json:"full_name" binding:"required"`
+	PhoneNumber       *string         `json:"phone_number"`
+	ParentName        string          `json:"parent_name" binding:"required"`
+	ParentPhoneNumber string          `json:"parent_phone_number" binding:"required"`
+	Source            *string         `json:"source"`
+	CourseId          *uuid.UUID      `json:"course_id"`
+	Status            models.EnumCode `json:"status" binding:"required,oneof=new contacted trial_scheduled trial_conducted lost"`
+	Comment           *string         `json:"comment"`
+}
+
+type ScheduleTrialRequest struct {
+	CuratorId uuid.UUID `json:"curator_id" binding:"required"`
+	Date      string    `json:"date" binding:"required"`
+}
+
+type UpdateTrialRequest struct {
+	Date     string          `json:"date" binding:"required"`
+	Status   models.EnumCode `json:"status" binding:"required,oneof=scheduled conducted missed canceled"`
+	Feedback *string         `json:"feedback"`
+}
+
+type ConvertLeadRequest struct {
+	CourseId     *uuid.UUID      `json:"course_id"`
+	CuratorId    *uuid.UUID      `json:"curator_id"`
+	PhoneNumber  *string         `json:"phone_number"`
+	PlatformLink string          `json:"platform_link"`
+	PaymentType  models.EnumCode `json:"payment_type" binding:"required,oneof=payment prepayment additional_payment"`
+	PaymentDate  string          `json:"payment_date" binding:"required"`
+	Amount       float64         `json:"amount" binding:"required,gt=0"`
+	Comment      *string         `json:"comment"`
+}
+
+type LeadsHandlers struct {
+	leadsRepo *repositories.LeadsRepository
+}
+
+func NewLeadsHandlers(leadsRepo *repositories.LeadsRepository) *LeadsHandlers {
+	return &LeadsHandlers{leadsRepo: leadsRepo}
+}
+
+// formatLeadPhones приводит телефоны лида к формату студентов
+func formatLeadPhones(phone *string, parentPhone string) (*string, string, error) {
+	formattedParent, err := formatPhoneNumber(parentPhone, "KZ")
+	if err != nil {
+		return nil, "", err
+	}
+	if phone == nil || *phone == "" {
+		return nil, formattedParent, nil
+	}
+	formatted, err := formatPhoneNumber(*phone, "KZ")
+	if err != nil {
+		return nil, "", err
+	}
+	return &formatted, formattedParent, nil
+}
+
+// Create godoc
+// @Summary Создать лида
+// @Description Потенциальный студент до первой оплаты. Создается в статусе new.
+// @Description - branch_id: нужен, только если запрос не привязан к филиалу (X-Branch-ID)
+// @Description - source: откуда пришел лид (сайт, instagram, рекомендация...)
+// @Tags Leads
+// @Accept json
+// @Produce json
+// @Param request body CreateLeadRequest true "Данные лида"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads [post]
+func (h *LeadsHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var request CreateLeadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid lead create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	phone, parentPhone, err := formatLeadPhones(request.PhoneNumber, request.ParentPhoneNumber)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_phone_number", "Invalid phone number"))
+		return
+	}
+
+	branchID, ok := resolveBranch(c, request.BranchId)
+	if !ok {
+		c.Error(models.NewValidationError("branch_required", "Branch is required"))
+		return
+	}
+
+	id, err := h.leadsRepo.Create(c, models.Lead{
+		BranchId:          branchID,
+		FullName:          request.FullName,
+		PhoneNumber:       phone,
+		ParentName:        request.ParentName,
+		ParentPhoneNumber: parentPhone,
+		Source:            request.Source,
+		CourseId:          request.CourseId,
+		Comment:           request.Comment,
+	})
+	if isForeignKeyViolation(err) {
+		c.Error(models.NewValidationError("course_or_branch_not_found", "Course or branch not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create lead", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create lead"))
+		return
+	}
+
+	logger.Info("Lead created", zap.String("lead_id", id.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// FindAll godoc
+// @Summary Список лидов
+// @Tags Leads
+// @Produce json
+// @Param status query string false "Статус" Enums(new, contacted, trial_scheduled, trial_conducted, converted, lost)
+// @Param source query string false "Источник"
+// @Param course_id query string false "Интересующий курс"
+// @Param search query string false "Поиск по имени лида или родителя"
+// @Success 200 {array} models.Lead
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads [get]
+func (h *LeadsHandlers) FindAll(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	filters := models.LeadFilters{
+		Status: models.NormalizeEnum(c.Query("status")),
+		Source: c.Query("source"),
+		Search: c.Query("search"),
+	}
+	if param := c.Query("course_id"); param != "" {
+		courseID, err := uuid.Parse(param)
+		if err != nil {
+			c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+			return
+		}
+		filters.CourseId = &courseID
+	}
+
+	leads, err := h.leadsRepo.FindAll(c, filters)
+	if err != nil {
+		logger.Error("Failed to fetch leads", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch leads"))
+		return
+	}
+
+	c.JSON(http.StatusOK, leads)
+}
+
+// FindById godoc
+// @Summary Карточка лида
+// @Description Возвращает лида вместе с пробными уроками. Заголовок ETag нужно передать в If-Match при обновлении
+// @Tags Leads
+// @Produce json
+// @Param leadId path string true "ID лида"
+// @Success 200 {object} models.Lead
+// @Header 200 {string} ETag "Версия лида"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads/{leadId} [get]
+func (h *LeadsHandlers) FindById(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lead_id", "Invalid lead id"))
+		return
+	}
+
+	lead, err := h.leadsRepo.FindById(c, leadID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch lead", zap.String("lead_id", leadID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch lead"))
+		return
+	}
+
+	setETag(c, lead.Version)
+	c.JSON(http.StatusOK, lead)
+}
+
+// Update godoc
+// @Summary Обновить лида
+// @Description Меняет данные и статус лида. Статус converted выставляется только конвертацией,
+// @Description конвертированный лид не редактируется.
+// @Description Заголовок If-Match обязателен: ETag из GET /managers/leads/{leadId}. Если лида уже изменили, возвращается 412
+// @Tags Leads
+// @Accept json
+// @Param leadId path string true "ID лида"
+// @Param If-Match header string true "ETag лида"
+// @Param request body UpdateLeadRequest true "Данные лида"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия лида"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads/{leadId} [put]
+func (h *LeadsHandlers) Update(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lead_id", "Invalid lead id"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateLeadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid lead update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	phone, parentPhone, err := formatLeadPhones(request.PhoneNumber, request.ParentPhoneNumber)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_phone_number", "Invalid phone number"))
+		return
+	}
+
+	version, err := h.leadsRepo.Update(c, models.Lead{
+		Id:                leadID,
+		FullName:          request.FullName,
+		PhoneNumber:       phone,
+		ParentName:        request.ParentName,
+		ParentPhoneNumber: parentPhone,
+		Source:            request.Source,
+		CourseId:          request.CourseId,
+		Status:            string(request.Status),
+		Comment:           request.Comment,
+	}, versions)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	case errors.Is(err, models.ErrLeadConverted), errors.Is(err, models.ErrVersionConflict):
+		c.Error(err)
+		return
+	case isForeignKeyViolation(err):
+		c.Error(models.NewValidationError("course_not_found", "Course not found"))
+		return
+	case err != nil:
+		logger.Error("Failed to update lead", zap.String("lead_id", leadID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update lead"))
+		return
+	}
+
+	setETag(c, version)
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary Удалить лида
+// @Description Удаляет ошибочно созданного лида вместе с пробными уроками. Для отказа используйте статус lost
+// @Tags Leads
+// @Param leadId path string true "ID лида"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/leads/{leadId} [delete]
+func (h *LeadsHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lead_id", "Invalid lead id"))
+		return
+	}
+
+	err = h.leadsRepo.Delete(c, leadID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete lead", zap.String("lead_id", leadID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to delete lead"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// ScheduleTrial godoc
+// @Summary Назначить пробный урок
+// @Description Назначает пробный урок с куратором и переводит лида в статус trial_scheduled.
+// @Description - date: дата и время в формате DD.MM.YYYY HH:MM
+// @Tags Leads
+// @Accept json
+// @Produce json
+// @Param leadId path string true "ID лида"
+// @Param request body ScheduleTrialRequest true "Куратор и время урока"
+// @Success 201 {object} object{id=string}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads/{leadId}/trials [post]
+func (h *LeadsHandlers) ScheduleTrial(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lead_id", "Invalid lead id"))
+		return
+	}
+
+	var request ScheduleTrialRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid trial schedule request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	date, err := utils.ParseRequiredDateTime(request.Date)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_trial_date", "Invalid trial date format. Use DD.MM.YYYY HH:MM"))
+		return
+	}
+
+	id, err := h.leadsRepo.ScheduleTrial(c, models.TrialLesson{
+		LeadId:    leadID,
+		CuratorId: &request.CuratorId,
+		Date:      date,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	case errors.Is(err, models.ErrLeadConverted):
+		c.Error(err)
+		return
+	case isForeignKeyViolation(err):
+		c.Error(models.NewValidationError("curator_not_found", "Curator not found"))
+		return
+	case err != nil:
+		logger.Error("Failed to schedule trial lesson", zap.String("lead_id", leadID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to schedule trial lesson"))
+		return
+	}
+
+	logger.Info("Trial lesson scheduled",
+		zap.String("lead_id", leadID.String()),
+		zap.String("curator_id", request.CuratorId.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// trialCuratorScope ограничивает куратора его собственными пробными уроками
+func trialCuratorScope(c *gin.Context) *uuid.UUID {
+	role := c.MustGet("userRole").(*models.Role)
+	if role.Permissions["access_settings"] || role.Permissions["access_manager"] {
+		return nil
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	return &userID
+}
+
+// FindTrials godoc
+// @Summary Пробные уроки
+// @Description Куратор видит только свои пробные уроки, менеджер — все уроки филиала
+// @Tags Leads
+// @Produce json
+// @Param from query string false "Начало периода (DD.MM.YYYY)"
+// @Param to query string false "Конец периода включительно (DD.MM.YYYY)"
+// @Success 200 {array} models.TrialLesson
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/trials [get]
+func (h *LeadsHandlers) FindTrials(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_period", "Invalid period. Use DD.MM.YYYY"))
+		return
+	}
+	if to != nil {
+		next := to.AddDate(0, 0, 1)
+		to = &next
+	}
+
+	trials, err := h.leadsRepo.FindTrials(c, trialCuratorScope(c), from, to)
+	if err != nil {
+		logger.Error("Failed to fetch trial lessons", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch trial lessons"))
+		return
+	}
+
+	c.JSON(http.StatusOK, trials)
+}
+
+// UpdateTrial godoc
+// @Summary Итог пробного урока
+// @Description Переносит урок или фиксирует его итог. Статус conducted переводит лида в trial_conducted.
+// @Description - date: дата и время в формате DD.MM.YYYY HH:MM
+// @Description - status: scheduled, conducted, missed, canceled
+// @Description Заголовок If-Match обязателен: version урока из карточки лида или списка пробных уроков в кавычках ("2").
+// @Description Если урок уже изменили, возвращается 412
+// @Tags Leads
+// @Accept json
+// @Param trialId path string true "ID пробного урока"
+// @Param If-Match header string true "Версия пробного урока"
+// @Param request body UpdateTrialRequest true "Данные урока"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия пробного урока"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /curators/trials/{trialId} [put]
+func (h *LeadsHandlers) UpdateTrial(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	trialID, err := uuid.Parse(c.Param("trialId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_trial_id", "Invalid trial id"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateTrialRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid trial update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	date, err := utils.ParseRequiredDateTime(request.Date)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_trial_date", "Invalid trial date format. Use DD.MM.YYYY HH:MM"))
+		return
+	}
+
+	version, err := h.leadsRepo.UpdateTrial(c, models.TrialLesson{
+		Id:       trialID,
+		Date:     date,
+		Status:   string(request.Status),
+		Feedback: request.Feedback,
+	}, trialCuratorScope(c), versions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("trial_lesson_not_found", "Trial lesson not found"))
+		return
+	}
+	if errors.Is(err, models.ErrVersionConflict) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update trial lesson", zap.String("trial_id", trialID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update trial lesson"))
+		return
+	}
+
+	setETag(c, version)
+	c.Status(http.StatusOK)
+}
+
+// Convert godoc
+// @Summary Конвертировать лида в студента
+// @Description Создает студента по данным лида, зачисление на курс и первую пролонгацию (оплату).
+// @Description - course_id: по умолчанию интересующий курс лида
+// @Description - phone_number: по умолчанию телефон лида; обязателен, если у лида его нет
+// @Description - payment_type: payment, prepayment, additional_payment
+// @Description - payment_date: дата в формате DD.MM.YYYY, она же дата начала обучения
+// @Tags Leads
+// @Accept json
+// @Produce json
+// @Param leadId path string true "ID лида"
+// @Param request body ConvertLeadRequest true "Данные первой оплаты"
+// @Success 201 {object} models.LeadConversionResult
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /managers/leads/{leadId}/convert [post]
+func (h *LeadsHandlers) Convert(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_lead_id", "Invalid lead id"))
+		return
+	}
+
+	var request ConvertLeadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid lead convert request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	paymentDate, err := utils.ParseRequiredDate(request.PaymentDate)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_payment_date", "Invalid payment date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	lead, err := h.leadsRepo.FindById(c, leadID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	}
+
+	phone := request.PhoneNumber
+	if phone == nil || *phone == "" {
+		phone = lead.PhoneNumber
+	}
+	if phone == nil {
+		c.Error(models.NewValidationError("student_phone_required", "Student's phone number is required"))
+		return
+	}
+	formattedPhone, err := formatPhoneNumber(*phone, "KZ")
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_phone_number", "Invalid student's phone number"))
+		return
+	}
+
+	result, err := h.leadsRepo.Convert(c, leadID, models.LeadConversion{
+		CourseId:     request.CourseId,
+		CuratorId:    request.CuratorId,
+		PhoneNumber:  formattedPhone,
+		PlatformLink: request.PlatformLink,
+		PaymentType:  string(request.PaymentType),
+		PaymentDate:  paymentDate,
+		Amount:       request.Amount,
+		Comment:      request.Comment,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.Error(models.NewNotFoundError("lead_not_found", "Lead not found"))
+		return
+	case errors.Is(err, models.ErrLeadConverted):
+		c.Error(err)
+		return
+	case errors.Is(err, models.ErrLeadCourseRequired):
+		c.Error(err)
+		return
+	case isForeignKeyViolation(err):
+		c.Error(models.NewValidationError("course_or_curator_not_found", "Course or curator not found"))
+		return
+	case err != nil:
+		logger.Error("Failed to convert lead", zap.String("lead_id", leadID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to convert lead"))
+		return
+	}
+
+	metrics.RecordPayment(string(request.PaymentType), request.Amount)
+
+	logger.Info("Lead converted to student",
+		zap.String("lead_id", leadID.String()),
+		zap.String("student_id", result.StudentId.String()))
+	c.JSON(http.StatusCreated, result)
+}
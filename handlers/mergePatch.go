@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"it_school/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindMergePatch накладывает тело запроса (JSON Merge Patch, RFC 7396) на current —
+// запрос на полное обновление, собранный из текущей записи, — и пишет результат в request.
+// Поля, которых нет в теле, остаются прежними, null сбрасывает поле. Результат проходит
+// те же binding-проверки, что и PUT; ошибку стоит обернуть в models.NewBindingError
+func bindMergePatch(c *gin.Context, current any, request any) error {
+	patch, err := c.GetRawData()
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	merged, err := utils.MergePatch(original, patch)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(merged, request); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(request)
+}
+
+// patchVersions — допустимые версии для PATCH. If-Match необязателен: без него
+// патч применяется к той версии, из которой собран, и параллельное изменение даст 412
+func patchVersions(c *gin.Context, current int) ([]int, bool) {
+	if c.GetHeader("If-Match") == "" {
+		return []int{current}, true
+	}
+	return ifMatchVersions(c)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPatchVersions(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		wantVersions []int
+		wantOK       bool
+	}{
+		// Без If-Match патч применяется только к версии, из которой собран
+		{name: "no if-match", ifMatch: "", wantVersions: []int{7}, wantOK: true},
+		{name: "explicit version", ifMatch: `"5"`, wantVersions: []int{5}, wantOK: true},
+		{name: "any version", ifMatch: "*", wantVersions: nil, wantOK: true},
+		{name: "foreign etag", ifMatch: `"abc"`, wantVersions: []int{}, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.ifMatch != "" {
+				headers["If-Match"] = tt.ifMatch
+			}
+			c, _ := testContext(http.MethodPatch, headers)
+
+			versions, ok := patchVersions(c, 7)
+
+			if ok != tt.wantOK || len(c.Errors) != 0 {
+				t.Fatalf("ok = %v, errors = %v", ok, c.Errors)
+			}
+			if (versions == nil) != (tt.wantVersions == nil) || !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("versions = %#v, want %#v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+type testPatchRequest struct {
+	FullName string  `json:"full_name" binding:"required"`
+	Comment  *string `json:"comment"`
+	Amount   float64 `json:"amount" binding:"gte=0"`
+}
+
+func TestBindMergePatch(t *testing.T) {
+	comment := "старый комментарий"
+	current := testPatchRequest{FullName: "Иван", Comment: &comment, Amount: 100}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    testPatchRequest
+		wantErr bool
+	}{
+		{name: "empty patch keeps record", body: `{}`, want: current},
+		{name: "change one field", body: `{"amount":250}`, want: testPatchRequest{FullName: "Иван", Comment: &comment, Amount: 250}},
+		{name: "null clears optional field", body: `{"comment":null}`, want: testPatchRequest{FullName: "Иван", Amount: 100}},
+		{name: "null on required field fails validation", body: `{"full_name":null}`, wantErr: true},
+		{name: "invalid value fails validation", body: `{"amount":-1}`, wantErr: true},
+		{name: "wrong type", body: `{"amount":"many"}`, wantErr: true},
+		{name: "malformed body", body: `{"amount":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testContext(http.MethodPatch, nil)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+
+			var got testPatchRequest
+			err := bindMergePatch(c, current, &got)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("bindMergePatch() = nil, want error; got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindMergePatch() error = %v", err)
+			}
+			if got.FullName != tt.want.FullName || got.Amount != tt.want.Amount ||
+				(got.Comment == nil) != (tt.want.Comment == nil) ||
+				got.Comment != nil && *got.Comment != *tt.want.Comment {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Без If-Match ifMatchVersions отвечает 428, а patchVersions — нет
+func TestPatchVersionsDoesNotRequireIfMatch(t *testing.T) {
+	c, _ := testContext(http.MethodPatch, nil)
+	if _, ok := patchVersions(c, 1); !ok {
+		t.Fatal("patchVersions rejected a request without If-Match")
+	}
+
+	c, _ = testContext(http.MethodPut, nil)
+	if _, ok := ifMatchVersions(c); ok || !errors.Is(c.Errors.Last().Err, models.ErrIfMatchRequired) {
+		t.Fatal("ifMatchVersions accepted a request without If-Match")
+	}
+}
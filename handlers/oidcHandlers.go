@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"it_school/config"
+	"it_school/logger"
+	"it_school/metrics"
+	"it_school/models"
+	"it_school/redact"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcNonceCookie = "oidc_nonce"
+	oidcCookieTTL   = 600 // 10 минут на прохождение входа у провайдера
+)
+
+// errOIDCMisconfigured — роль OIDC_DEFAULT_ROLE или филиал OIDC_DEFAULT_BRANCH не найдены.
+// Это ошибка настройки, а не пользователя, поэтому она не сводится к user_not_registered
+var errOIDCMisconfigured = errors.New("oidc auto-provisioning is misconfigured")
+
+// oidcClaims — поля ID токена, которые нужны для сопоставления с пользователем
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	HostedDomain  string `json:"hd"`
+	Nonce         string `json:"nonce"`
+}
+
+type OIDCHandler struct {
+	auth         *AuthHandler
+	usersRepo    *repositories.UsersRepository
+	rolesRepo    *repositories.RoleRepository
+	branchesRepo *repositories.BranchesRepository
+
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+}
+
+// NewOIDCHandler загружает discovery-документ провайдера (OIDC_ISSUER) и готовит OAuth2 клиент.
+// Провайдером может быть Google Workspace или любой совместимый, в том числе локальный mock (cmd/oidcmock).
+func NewOIDCHandler(c context.Context, auth *AuthHandler, usersRepo *repositories.UsersRepository, rolesRepo *repositories.RoleRepository, branchesRepo *repositories.BranchesRepository) (*OIDCHandler, error) {
+	provider, err := oidc.NewProvider(c, config.Config.OIDCIssuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCHandler{
+		auth:         auth,
+		usersRepo:    usersRepo,
+		rolesRepo:    rolesRepo,
+		branchesRepo: branchesRepo,
+		oauth2Config: oauth2.Config{
+			ClientID:     config.Config.OIDCClientID,
+			ClientSecret: config.Config.OIDCClientSecret,
+			RedirectURL:  config.Config.OIDCRedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.Config.OIDCClientID}),
+	}, nil
+}
+
+// Login godoc
+// @Summary Вход через OpenID Connect
+// @Description Перенаправляет пользователя на страницу входа провайдера (например, Google Workspace)
+// @Tags Auth
+// @Success 302
+// @Failure 500 {object} models.Problem
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	state, err := utils.GenerateResetToken()
+	if err != nil {
+		logger.Error("Failed to generate OIDC state", zap.Error(err))
+		c.Error(models.NewInternalError("failed to start oidc login"))
+		return
+	}
+
+	nonce, err := utils.GenerateResetToken()
+	if err != nil {
+		logger.Error("Failed to generate OIDC nonce", zap.Error(err))
+		c.Error(models.NewInternalError("failed to start oidc login"))
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, state, oidcCookieTTL, "/auth/oidc", "", false, true)
+	c.SetCookie(oidcNonceCookie, nonce, oidcCookieTTL, "/auth/oidc", "", false, true)
+
+	c.Redirect(http.StatusFound, h.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce)))
+}
+
+// Callback godoc
+// @Summary Завершение входа через OpenID Connect
+// @Description Обменивает код авторизации на ID токен, сопоставляет подтвержденный email с пользователем и выдает JWT и сессию, как при обычном входе
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Код авторизации"
+// @Param state query string true "State из запроса на вход"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	state, err := c.Cookie(oidcStateCookie)
+	if err != nil || state == "" || c.Query("state") != state {
+		logger.Warn("OIDC state mismatch")
+		c.Error(models.NewValidationError("invalid_oidc_state", "invalid oidc state"))
+		return
+	}
+	nonce, _ := c.Cookie(oidcNonceCookie)
+
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", false, true)
+	c.SetCookie(oidcNonceCookie, "", -1, "/auth/oidc", "", false, true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		logger.Warn("OIDC provider returned error", zap.String("error", errParam))
+		metrics.LoginFailures.WithLabelValues("oidc", "rejected").Inc()
+		c.Error(models.NewUnauthorizedError("oidc_login_rejected", "oidc login was rejected"))
+		return
+	}
+
+	oauth2Token, err := h.oauth2Config.Exchange(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		logger.Warn("Failed to exchange OIDC code", zap.Error(err))
+		metrics.LoginFailures.WithLabelValues("oidc", "invalid_token").Inc()
+		c.Error(models.NewUnauthorizedError("oidc_code_exchange_failed", "failed to exchange authorization code"))
+		return
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		logger.Warn("OIDC token response without id_token")
+		metrics.LoginFailures.WithLabelValues("oidc", "invalid_token").Inc()
+		c.Error(models.NewUnauthorizedError("missing_id_token", "missing id token"))
+		return
+	}
+
+	idToken, err := h.verifier.Verify(c.Request.Context(), rawIDToken)
+	if err != nil {
+		logger.Warn("Invalid OIDC id token", zap.Error(err))
+		metrics.LoginFailures.WithLabelValues("oidc", "invalid_token").Inc()
+		c.Error(models.NewUnauthorizedError("invalid_id_token", "invalid id token"))
+		return
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		logger.Warn("Failed to parse OIDC claims", zap.Error(err))
+		metrics.LoginFailures.WithLabelValues("oidc", "invalid_token").Inc()
+		c.Error(models.NewUnauthorizedError("invalid_id_token_claims", "invalid id token claims"))
+		return
+	}
+
+	if claims.Nonce != nonce {
+		logger.Warn("OIDC nonce mismatch")
+		metrics.LoginFailures.WithLabelValues("oidc", "invalid_token").Inc()
+		c.Error(models.NewUnauthorizedError("invalid_id_token_nonce", "invalid id token nonce"))
+		return
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		logger.Warn("OIDC email is missing or not verified", zap.String("subject", idToken.Subject))
+		metrics.LoginFailures.WithLabelValues("oidc", "email_not_verified").Inc()
+		c.Error(models.NewForbiddenError("email_not_verified", "email is not verified"))
+		return
+	}
+
+	if !h.isAllowedDomain(claims) {
+		logger.Warn("OIDC login from foreign domain", zap.String("email", redact.Email(claims.Email)))
+		metrics.LoginFailures.WithLabelValues("oidc", "domain_not_allowed").Inc()
+		c.Error(models.NewForbiddenError("email_domain_not_allowed", "email domain is not allowed"))
+		return
+	}
+
+	user, err := h.usersRepo.FindByEmail(c.Request.Context(), claims.Email)
+	if errors.Is(err, pgx.ErrNoRows) && config.Config.OIDCAutoProvision {
+		user, err = h.provisionUser(c, claims)
+		// Email занят пользователем из корзины: заново его не создаем, пока администратор не восстановит его
+		if isUniqueViolation(err) {
+			err = pgx.ErrNoRows
+		}
+	}
+	if errors.Is(err, errOIDCMisconfigured) {
+		logger.Error("OIDC auto-provisioning failed", zap.String("email", redact.Email(claims.Email)), zap.Error(err))
+		c.Error(models.NewInternalError("oidc auto-provisioning is misconfigured"))
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Info("OIDC login for unknown user", zap.String("email", redact.Email(claims.Email)))
+		metrics.LoginFailures.WithLabelValues("oidc", "unknown_user").Inc()
+		c.Error(models.NewForbiddenError("user_not_registered", "user is not registered"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to resolve OIDC user", zap.String("email", redact.Email(claims.Email)), zap.Error(err))
+		c.Error(models.NewInternalError("failed to resolve user"))
+		return
+	}
+
+	h.auth.issueSession(c, user)
+}
+
+// isAllowedDomain проверяет, что email принадлежит домену школы, если он задан в OIDC_ALLOWED_DOMAIN
+func (h *OIDCHandler) isAllowedDomain(claims oidcClaims) bool {
+	domain := strings.ToLower(config.Config.OIDCAllowedDomain)
+	if domain == "" {
+		return true
+	}
+	if claims.HostedDomain != "" {
+		return strings.ToLower(claims.HostedDomain) == domain
+	}
+	return strings.HasSuffix(strings.ToLower(claims.Email), "@"+domain)
+}
+
+// provisionUser создает пользователя с ролью по умолчанию (OIDC_DEFAULT_ROLE) в филиале по умолчанию
+// (OIDC_DEFAULT_BRANCH); куратору сразу создается запись в curators.
+// Пароль заполняется случайным значением — войти можно только через провайдера или после сброса пароля.
+func (h *OIDCHandler) provisionUser(c context.Context, claims oidcClaims) (models.User, error) {
+	logger := logger.FromContext(c)
+
+	roleName := config.Config.OIDCDefaultRole
+	if roleName == "" {
+		roleName = "curator"
+	}
+
+	role, err := h.rolesRepo.GetRoleByName(c, roleName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, fmt.Errorf("%w: role %q not found", errOIDCMisconfigured, roleName)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	branch, err := h.branchesRepo.FindByName(c, config.Config.OIDCDefaultBranch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, fmt.Errorf("%w: branch %q not found", errOIDCMisconfigured, config.Config.OIDCDefaultBranch)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	randomPassword, err := utils.GenerateResetToken()
+	if err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Full_name:    claims.Name,
+		Email:        claims.Email,
+		PasswordHash: hashedPassword,
+		RoleID:       role.Id,
+	}
+
+	user.Id, err = h.usersRepo.Provision(c, user, []uuid.UUID{branch.Id}, role.Name == "curator")
+	if err != nil {
+		return models.User{}, err
+	}
+
+	logger.Info("User provisioned via OIDC",
+		zap.String("user_id", user.Id.String()),
+		zap.String("role", role.Name),
+		zap.String("branch_id", branch.Id.String()))
+	return user, nil
+}
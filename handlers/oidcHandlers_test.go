@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"it_school/config"
+	"it_school/models"
+	"it_school/oidcmock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcLogin — ответ провайдера, с которым браузер возвращается на /auth/oidc/callback
+type oidcLogin struct {
+	query   url.Values
+	cookies map[string]string
+}
+
+func newTestOIDCHandler(t *testing.T, identity oidcmock.Identity, allowedDomain string) *OIDCHandler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	provider, err := oidcmock.New("", identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(provider)
+	t.Cleanup(server.Close)
+	provider.Issuer = server.URL
+
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+	config.Config = &config.MapConfig{
+		OIDCIssuer:        server.URL,
+		OIDCClientID:      "it_school",
+		OIDCClientSecret:  "secret",
+		OIDCRedirectURL:   "http://app.test/auth/oidc/callback",
+		OIDCAllowedDomain: allowedDomain,
+	}
+
+	// До сопоставления с пользователем обработчик не обращается к БД
+	h, err := NewOIDCHandler(context.Background(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// login проходит вход у провайдера: Login ставит cookies и перенаправляет на /authorize,
+// провайдер перенаправляет обратно с кодом и state
+func login(t *testing.T, h *OIDCHandler) oidcLogin {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil)
+	h.Login(c)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", w.Code, http.StatusFound)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := make(map[string]string)
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+	return oidcLogin{query: callback.Query(), cookies: cookies}
+}
+
+func callback(h *OIDCHandler, l oidcLogin) *gin.Context {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+l.query.Encode(), nil)
+	for name, value := range l.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	h.Callback(c)
+	return c
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	verified := oidcmock.Identity{Subject: "1", Email: "curator@school.kz", EmailVerified: true, Name: "Curator"}
+
+	tests := []struct {
+		name          string
+		identity      oidcmock.Identity
+		allowedDomain string
+		mutate        func(l *oidcLogin)
+		wantCode      string
+	}{
+		{
+			name:     "state mismatch",
+			identity: verified,
+			mutate:   func(l *oidcLogin) { l.query.Set("state", "forged") },
+			wantCode: "invalid_oidc_state",
+		},
+		{
+			name:     "missing state cookie",
+			identity: verified,
+			mutate:   func(l *oidcLogin) { delete(l.cookies, oidcStateCookie) },
+			wantCode: "invalid_oidc_state",
+		},
+		{
+			name:     "provider error",
+			identity: verified,
+			mutate:   func(l *oidcLogin) { l.query.Set("error", "access_denied") },
+			wantCode: "oidc_login_rejected",
+		},
+		{
+			name:     "unknown code",
+			identity: verified,
+			mutate:   func(l *oidcLogin) { l.query.Set("code", "forged") },
+			wantCode: "oidc_code_exchange_failed",
+		},
+		{
+			name:     "nonce mismatch",
+			identity: verified,
+			mutate:   func(l *oidcLogin) { l.cookies[oidcNonceCookie] = "forged" },
+			wantCode: "invalid_id_token_nonce",
+		},
+		{
+			name:     "email not verified",
+			identity: oidcmock.Identity{Subject: "2", Email: "curator@school.kz", Name: "Curator"},
+			wantCode: "email_not_verified",
+		},
+		{
+			name:     "email missing",
+			identity: oidcmock.Identity{Subject: "3", EmailVerified: true, Name: "Curator"},
+			wantCode: "email_not_verified",
+		},
+		{
+			name:          "foreign email domain",
+			identity:      oidcmock.Identity{Subject: "4", Email: "curator@gmail.com", EmailVerified: true},
+			allowedDomain: "school.kz",
+			wantCode:      "email_domain_not_allowed",
+		},
+		{
+			name:          "foreign hosted domain",
+			identity:      oidcmock.Identity{Subject: "5", Email: "curator@school.kz", EmailVerified: true, HostedDomain: "other.kz"},
+			allowedDomain: "school.kz",
+			wantCode:      "email_domain_not_allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestOIDCHandler(t, tt.identity, tt.allowedDomain)
+			l := login(t, h)
+			if tt.mutate != nil {
+				tt.mutate(&l)
+			}
+
+			c := callback(h, l)
+
+			if len(c.Errors) == 0 {
+				t.Fatalf("callback succeeded, want %s", tt.wantCode)
+			}
+			var appErr *models.AppError
+			if !errors.As(c.Errors.Last().Err, &appErr) {
+				t.Fatalf("error = %v, want *models.AppError", c.Errors.Last().Err)
+			}
+			if appErr.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", appErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOIDCIsAllowedDomain(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowedDomain string
+		claims        oidcClaims
+		want          bool
+	}{
+		{name: "no restriction", claims: oidcClaims{Email: "user@gmail.com"}, want: true},
+		{name: "email domain", allowedDomain: "school.kz", claims: oidcClaims{Email: "user@school.kz"}, want: true},
+		{name: "case insensitive", allowedDomain: "School.kz", claims: oidcClaims{Email: "User@SCHOOL.KZ"}, want: true},
+		{name: "subdomain is foreign", allowedDomain: "school.kz", claims: oidcClaims{Email: "user@mail.school.kz"}, want: false},
+		{name: "foreign email", allowedDomain: "school.kz", claims: oidcClaims{Email: "user@gmail.com"}, want: false},
+		{name: "hosted domain wins", allowedDomain: "school.kz", claims: oidcClaims{Email: "user@school.kz", HostedDomain: "other.kz"}, want: false},
+		{name: "hosted domain matches", allowedDomain: "school.kz", claims: oidcClaims{Email: "user@alias.kz", HostedDomain: "school.kz"}, want: true},
+	}
+
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+
+	h := &OIDCHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config = &config.MapConfig{OIDCAllowedDomain: tt.allowedDomain}
+			if got := h.isAllowedDomain(tt.claims); got != tt.want {
+				t.Errorf("isAllowedDomain(%+v) = %v, want %v", tt.claims, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgViolations(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}
+	foreignKey := &pgconn.PgError{Code: "23503", ConstraintName: "students_branch_id_fkey"}
+
+	tests := []struct {
+		name           string
+		err            error
+		wantUnique     bool
+		wantForeignKey bool
+	}{
+		{name: "nil", err: nil},
+		{name: "unique violation", err: unique, wantUnique: true},
+		{name: "wrapped unique violation", err: fmt.Errorf("create user: %w", unique), wantUnique: true},
+		{name: "foreign key violation", err: foreignKey, wantForeignKey: true},
+		{name: "wrapped foreign key violation", err: fmt.Errorf("create student: %w", foreignKey), wantForeignKey: true},
+		{name: "other constraint", err: &pgconn.PgError{Code: "23514"}},
+		{name: "no rows", err: pgx.ErrNoRows},
+		{name: "plain error with code text", err: errors.New("ERROR: duplicate key (SQLSTATE 23505)")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUniqueViolation(tt.err); got != tt.wantUnique {
+				t.Errorf("isUniqueViolation() = %v, want %v", got, tt.wantUnique)
+			}
+			if got := isForeignKeyViolation(tt.err); got != tt.wantForeignKey {
+				t.Errorf("isForeignKeyViolation() = %v, want %v", got, tt.wantForeignKey)
+			}
+		})
+	}
+}
@@ -1,44 +1,58 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"it_school/logger"
 	"it_school/models"
+	"it_school/redact"
 	"it_school/repositories"
+	"it_school/utils"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/nyaruka/phonenumbers"
 	"go.uber.org/zap"
 )
 
 type createStudentRequest struct {
-	CourseId          uuid.UUID `json:"course_id"`
-	FullName          string    `json:"full_name"`
-	PhoneNumber       *string   `json:"phone_number"`
-	ParentName        string    `json:"parent_name"`
-	ParentPhoneNumber *string   `json:"parent_phone_number"`
-	CuratorId         uuid.UUID  `json:"curator_id"`
-	PlatformLink string   `json:"platform_link"`
-	CrmLink      string   `json:"crm_link"`
-	CreatedAt    *string  `json:"created_at"`
-    IsActive     *string  `json:"is_active" enums:"активен,неактивен" example:"активен"`
+	CourseId          *uuid.UUID       `json:"course_id"`
+	BranchId          *uuid.UUID       `json:"branch_id"`
+	FullName          string           `json:"full_name"`
+	PhoneNumber       *string          `json:"phone_number" binding:"required"`
+	ParentName        string           `json:"parent_name"`
+	ParentPhoneNumber *string          `json:"parent_phone_number" binding:"required"`
+	CuratorId         uuid.UUID        `json:"curator_id"`
+	PlatformLink      string           `json:"platform_link"`
+	CrmLink           string           `json:"crm_link"`
+	CreatedAt         *string          `json:"created_at" binding:"required"`
+	IsActive          *models.EnumCode `json:"is_active" binding:"omitempty,oneof=active inactive" enums:"active,inactive" example:"active"`
+	// LifecycleState — начальное состояние; если не задано, берется из is_active
+	LifecycleState *models.EnumCode `json:"lifecycle_state" binding:"omitempty,oneof=lead trial active" enums:"lead,trial,active" example:"active"`
 }
 
 type updateStudentRequest struct {
-	CourseId          uuid.UUID `json:"course_id"`
-	FullName          string    `json:"full_name"`
-	PhoneNumber       *string   `json:"phone_number"`
-	ParentName        string    `json:"parent_name"`
-	ParentPhoneNumber *string   `json:"parent_phone_number"`
-	CuratorId         uuid.UUID  `json:"curator_id"`
-	PlatformLink string   `json:"platform_link"`
-	CrmLink      string   `json:"crm_link"`
-	CreatedAt    *string  `json:"created_at"`
-	IsActive     *string  `json:"is_active" enums:"активен,неактивен" example:"активен"`
+	FullName          string  `json:"full_name" binding:"required"`
+	PhoneNumber       *string `json:"phone_number" binding:"required"`
+	ParentName        string  `json:"parent_name" binding:"required"`
+	ParentPhoneNumber *string `json:"parent_phone_number" binding:"required"`
+	PlatformLink      string  `json:"platform_link"`
+	CrmLink           string  `json:"crm_link"`
+	CreatedAt         *string `json:"created_at" binding:"required"`
 }
+
+type changeLifecycleRequest struct {
+	State   models.EnumCode `json:"state" binding:"required,oneof=lead trial active frozen graduated churned archived" enums:"lead,trial,active,frozen,graduated,churned,archived" example:"graduated"`
+	Comment *string         `json:"comment"`
+}
+
 type StudentsHandlers struct {
 	StudentsRepo *repositories.StudentsRepository
 }
@@ -77,225 +91,397 @@ func formatPhoneNumber(input string, defaultRegion string) (string, error) {
 // Create godoc
 // @Summary Создать нового студента
 // @Description Создает запись о студенте. Допустимые значения:
-// @Description - is_active: активен, неактивен
-// @Description - created_at: дата в формате DD.MM.YYYY
-// @Description - phone_number: международный формат (+7XXX...)
+// @Description - lifecycle_state: lead, trial, active (по умолчанию active)
+// @Description - is_active: active, inactive — устаревший способ задать состояние: inactive создает студента в состоянии churned
+// @Description - created_at: дата в формате DD.MM.YYYY, обязательна
+// @Description - phone_number, parent_phone_number: международный формат (+7XXX...), обязательны
+// @Description - branch_id: нужен, только если запрос не привязан к филиалу (X-Branch-ID)
+// @Description - course_id: необязательный, создает первичное зачисление на курс
 // @Tags Students
 // @Accept json
 // @Produce json
 // @Param request body createStudentRequest true "Данные студента"
 // @Success 201 {object} object{id=string} "ID созданного студента"
-// @Failure 400 {object} models.ApiError "Неверный формат данных"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 400 {object} models.Problem "Неверный формат данных"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/students [post]
 func (h *StudentsHandlers) Create(c *gin.Context) {
-    logger := logger.GetLogger()
-    var request createStudentRequest
-    
-    if err := c.ShouldBindJSON(&request); err != nil {
-        logger.Warn("Invalid student create request format", zap.Error(err))
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
-        return
-    }
-
-    logger.Info("Creating student", 
-        zap.String("full_name", request.FullName),
-    )
-
-    formattedPhone, err := formatPhoneNumber(*request.PhoneNumber, "KZ")
-    if err != nil {
-        logger.Warn("Invalid student phone format", 
-            zap.String("phone", *request.PhoneNumber),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid student's phone number"))
-        return
-    }
-
-    formattedParentsPhone, err := formatPhoneNumber(*request.ParentPhoneNumber, "KZ")
-    if err != nil {
-        logger.Warn("Invalid parent phone format", 
-            zap.String("phone", *request.ParentPhoneNumber),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid parent's phone number"))
-        return
-    }
-
-    CreatedAt, err := time.Parse("02.01.2006", *request.CreatedAt)
-    if err != nil {
-        logger.Warn("Invalid date format", 
-            zap.String("date", *request.CreatedAt),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid created date format. Use DD.MM.YYYY"))
-        return
-    }
-
-    student := models.Student{
-        CourseId:          request.CourseId,
-        FullName:          request.FullName,
-        PhoneNumber:       &formattedPhone,
-        ParentName:        request.ParentName,
-        ParentPhoneNumber: &formattedParentsPhone,
-        PlatformLink:      request.PlatformLink,
-        CrmLink:           request.CrmLink,
-        CreatedAt:         &CreatedAt,
-        IsActive:          request.IsActive,
-    }
-
-    id, err := h.StudentsRepo.Create(c, student)
-    if err != nil {
-        logger.Error("Failed to create student", 
-            zap.Error(err),
-            zap.Any("student_data", student),
-        )
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to create student"))
-        return
-    }
-
-    logger.Info("Student created successfully", zap.String("student_id", id.String()))
-    c.JSON(http.StatusCreated, gin.H{"id": id})
+	logger := logger.FromContext(c)
+	var request createStudentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid student create request format", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	logger.Info("Creating student",
+		zap.String("full_name", redact.Name(request.FullName)),
+	)
+
+	formattedPhone, err := formatPhoneNumber(*request.PhoneNumber, "KZ")
+	if err != nil {
+		logger.Warn("Invalid student phone format",
+			zap.String("phone", redact.Phone(*request.PhoneNumber)),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_phone_number", "Invalid student's phone number"))
+		return
+	}
+
+	formattedParentsPhone, err := formatPhoneNumber(*request.ParentPhoneNumber, "KZ")
+	if err != nil {
+		logger.Warn("Invalid parent phone format",
+			zap.String("phone", redact.Phone(*request.ParentPhoneNumber)),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_parent_phone_number", "Invalid parent's phone number"))
+		return
+	}
+
+	CreatedAt, err := utils.ParseRequiredDate(*request.CreatedAt)
+	if err != nil {
+		logger.Warn("Invalid date format",
+			zap.String("date", *request.CreatedAt),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_created_date", "Invalid created date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	branchID, ok := resolveBranch(c, request.BranchId)
+	if !ok {
+		c.Error(models.NewValidationError("branch_required", "Branch is required"))
+		return
+	}
+
+	student := models.Student{
+		BranchId:          branchID,
+		FullName:          request.FullName,
+		PhoneNumber:       &formattedPhone,
+		ParentName:        request.ParentName,
+		ParentPhoneNumber: &formattedParentsPhone,
+		PlatformLink:      request.PlatformLink,
+		CrmLink:           request.CrmLink,
+		CreatedAt:         &CreatedAt,
+		IsActive:          (*string)(request.IsActive),
+	}
+	if request.LifecycleState != nil {
+		student.LifecycleState = string(*request.LifecycleState)
+	}
+	if request.CuratorId != uuid.Nil {
+		student.CuratorId = &request.CuratorId
+	}
+
+	// course_id — первичное зачисление; остальные курсы добавляются через /enrollments
+	if request.CourseId != nil {
+		enrollment := models.Enrollment{
+			CourseId:  *request.CourseId,
+			StartDate: CreatedAt,
+		}
+		if request.CuratorId != uuid.Nil {
+			enrollment.CuratorId = &request.CuratorId
+		}
+		student.Enrollments = []models.Enrollment{enrollment}
+	}
+
+	id, err := h.StudentsRepo.Create(c, student)
+	if err != nil {
+		logger.Error("Failed to create student",
+			zap.Error(err),
+			zap.Any("student_data", student),
+		)
+		c.Error(models.NewInternalError("Failed to create student"))
+		return
+	}
+
+	logger.Info("Student created successfully", zap.String("student_id", id.String()))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
 // FindById godoc
 // @Summary Получить данные студента
-// @Description Возвращает полную информацию о студенте по его ID
+// @Description Возвращает полную информацию о студенте по его ID. Заголовок ETag нужно передать в If-Match при обновлении
 // @Tags Managers
 // @Produce json
 // @Param studentId path string true "UUID студента" format(uuid)
 // @Success 200 {object} models.Student "Данные студента"
-// @Failure 400 {object} models.ApiError "Неверный формат UUID"
-// @Failure 404 {object} models.ApiError "Студент не найден"
+// @Header 200 {string} ETag "Версия карточки студента"
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 404 {object} models.Problem "Студент не найден"
 // @Router /managers/students/{studentId} [get]
 func (h *StudentsHandlers) FindById(c *gin.Context) {
-    logger := logger.GetLogger()
-    idStr := c.Param("studentId")
-    
-    studentId, err := uuid.Parse(idStr)
-    if err != nil {
-        logger.Warn("Invalid student ID format", 
-            zap.String("student_id", idStr),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid student id"))
-        return
-    }
-
-    logger.Debug("Looking for student", zap.String("student_id", studentId.String()))
-    
-    student, err := h.StudentsRepo.FindById(c, studentId)
-    if err != nil {
-        logger.Error("Student not found", 
-            zap.String("student_id", studentId.String()),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusNotFound, models.NewApiError("Student not found"))
-        return
-    }
-
-    logger.Debug("Student found", zap.String("student_id", studentId.String()))
-    c.JSON(http.StatusOK, student)
+	logger := logger.FromContext(c)
+	idStr := c.Param("studentId")
+
+	studentId, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("Invalid student ID format",
+			zap.String("student_id", idStr),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	logger.Debug("Looking for student", zap.String("student_id", studentId.String()))
+
+	student, err := h.StudentsRepo.FindById(c, studentId)
+	if err != nil {
+		logger.Error("Student not found",
+			zap.String("student_id", studentId.String()),
+			zap.Error(err),
+		)
+		c.Error(models.NewNotFoundError("student_not_found", "Student not found"))
+		return
+	}
+
+	logger.Debug("Student found", zap.String("student_id", studentId.String()))
+	setETag(c, student.Version)
+	c.JSON(http.StatusOK, student)
 }
 
 // Update godoc
 // @Summary Обновить данные студента
-// @Description Обновляет информацию о существующем студенте. Допустимые значения:
-// @Description - is_active: активен, неактивен
+// @Description Заменяет карточку существующего студента целиком. Заголовок If-Match обязателен: ETag из GET /managers/students/{studentId}.
+// @Description Если карточку уже изменили, возвращается 412 — загрузите ее заново. Для изменения отдельных полей есть PATCH.
+// @Description is_active, lifecycle_state, course_id и curator_id здесь не меняются, запрос с ними отклоняется (400 field_not_updatable):
+// @Description состояние переключает POST /settings/students/{studentId}/lifecycle, курс и куратора — зачисления. Допустимые значения:
 // @Description - created_at: дата в формате DD.MM.YYYY
 // @Description - phone_number: международный формат (+7XXX...)
 // @Tags Managers
 // @Accept json
 // @Produce json
 // @Param studentId path string true "UUID студента" format(uuid)
-// @Param is_active body string true "Статус студента" Enums(активен, неактивен)
+// @Param If-Match header string true "ETag карточки студента"
 // @Param request body updateStudentRequest true "Обновленные данные"
 // @Success 200 "Данные успешно обновлены"
-// @Failure 400 {object} models.ApiError "Неверный формат данных"
-// @Failure 404 {object} models.ApiError "Студент не найден"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
-// @Router /manager/students/{studentId} [put]
+// @Header 200 {string} ETag "Новая версия карточки студента"
+// @Failure 400 {object} models.Problem "Неверный формат данных"
+// @Failure 404 {object} models.Problem "Студент не найден"
+// @Failure 412 {object} models.Problem "Карточку изменили после получения ETag"
+// @Failure 428 {object} models.Problem "Не передан If-Match"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /settings/students/{studentId} [put]
 func (h *StudentsHandlers) Update(c *gin.Context) {
-    logger := logger.GetLogger()
-    idStr := c.Param("studentId")
-    
-    studentId, err := uuid.Parse(idStr)
-    if err != nil {
-        logger.Warn("Invalid student ID format", 
-            zap.String("input_id", idStr),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid student Id"))
-        return
-    }
-
-    logger.Info("Updating student", zap.String("student_id", studentId.String()))
-
-    var request updateStudentRequest
-    if err := c.ShouldBindJSON(&request); err != nil {
-        logger.Warn("Invalid update request format", 
-            zap.Error(err),
-            zap.String("student_id", studentId.String()),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request payload"))
-        return
-    }
-
-    formattedPhone, err := formatPhoneNumber(*request.PhoneNumber, "KZ")
-    if err != nil {
-        logger.Warn("Invalid student phone format in update", 
-            zap.String("phone", *request.PhoneNumber),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid student's phone number"))
-        return
-    }
-
-    formattedParentsPhone, err := formatPhoneNumber(*request.ParentPhoneNumber, "KZ")
-    if err != nil {
-        logger.Warn("Invalid parent phone format in update", 
-            zap.String("phone", *request.ParentPhoneNumber),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid parent's phone number"))
-        return
-    }
-
-    CreatedAt, err := time.Parse("02.01.2006", *request.CreatedAt)
-    if err != nil {
-        logger.Warn("Invalid date format in update", 
-            zap.String("date", *request.CreatedAt),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid created date format. Use DD.MM.YYYY"))
-        return
-    }
-
-    student := models.Student{
-        Id:                studentId,
-        CourseId:          request.CourseId,
-        FullName:          request.FullName,
-        PhoneNumber:       &formattedPhone,
-        ParentName:        request.ParentName,
-        ParentPhoneNumber: &formattedParentsPhone,
-        PlatformLink:      request.PlatformLink,
-        CrmLink:           request.CrmLink,
-        CreatedAt:         &CreatedAt,
-        IsActive:          request.IsActive,
-    }
-
-    if err := h.StudentsRepo.Update(c, student); err != nil {
-        logger.Error("Failed to update student", 
-            zap.String("student_id", studentId.String()),
-            zap.Error(err),
-            zap.Any("update_data", student),
-        )
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to update student"))
-        return
-    }
-
-    logger.Info("Student updated successfully", zap.String("student_id", studentId.String()))
-    c.Status(http.StatusOK)
+	logger := logger.FromContext(c)
+	idStr := c.Param("studentId")
+
+	studentId, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("Invalid student ID format",
+			zap.String("input_id", idStr),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	if !rejectNotUpdatableFields(c) {
+		return
+	}
+
+	logger.Info("Updating student", zap.String("student_id", studentId.String()))
+
+	var request updateStudentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid update request format",
+			zap.Error(err),
+			zap.String("student_id", studentId.String()),
+		)
+		c.Error(models.NewBindingError("Invalid request payload", err))
+		return
+	}
+
+	h.save(c, studentId, request, versions)
+}
+
+// Patch godoc
+// @Summary Частично обновить данные студента
+// @Description Меняет только переданные поля карточки (JSON Merge Patch, RFC 7396; Content-Type application/merge-patch+json или application/json).
+// @Description Проверки и неизменяемые поля те же, что у PUT. If-Match необязателен: без него патч применяется к текущей версии,
+// @Description а если карточку изменили одновременно с запросом, возвращается 412
+// @Tags Managers
+// @Accept json
+// @Produce json
+// @Param studentId path string true "UUID студента" format(uuid)
+// @Param If-Match header string false "ETag карточки студента"
+// @Param request body updateStudentRequest true "Изменяемые поля"
+// @Success 200 "Данные успешно обновлены"
+// @Header 200 {string} ETag "Новая версия карточки студента"
+// @Failure 400 {object} models.Problem "Неверный формат данных"
+// @Failure 404 {object} models.Problem "Студент не найден"
+// @Failure 412 {object} models.Problem "Карточку изменили после получения ETag"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /settings/students/{studentId} [patch]
+func (h *StudentsHandlers) Patch(c *gin.Context) {
+	logger := logger.FromContext(c)
+	idStr := c.Param("studentId")
+
+	studentId, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("Invalid student ID format",
+			zap.String("input_id", idStr),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	student, err := h.StudentsRepo.FindById(c, studentId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load student for patch", zap.String("student_id", studentId.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update student"))
+		return
+	}
+
+	versions, ok := patchVersions(c, student.Version)
+	if !ok {
+		return
+	}
+
+	if !rejectNotUpdatableFields(c) {
+		return
+	}
+
+	logger.Info("Patching student", zap.String("student_id", studentId.String()))
+
+	var request updateStudentRequest
+	if err := bindMergePatch(c, studentUpdateRequest(student), &request); err != nil {
+		logger.Warn("Invalid patch request format",
+			zap.Error(err),
+			zap.String("student_id", studentId.String()),
+		)
+		c.Error(models.NewBindingError("Invalid request payload", err))
+		return
+	}
+
+	h.save(c, studentId, request, versions)
+}
+
+// studentFieldsNotUpdatable — поля карточки, которые PUT и PATCH не меняют. Раньше PUT их принимал,
+// поэтому запрос с ними отклоняется, а не теряет значения молча
+var studentFieldsNotUpdatable = []string{"is_active", "lifecycle_state", "course_id", "curator_id"}
+
+// rejectNotUpdatableFields отвечает 400, если в теле есть поле из studentFieldsNotUpdatable.
+// Тело запроса остается доступным для дальнейшего разбора
+func rejectNotUpdatableFields(c *gin.Context) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.Error(models.NewBindingError("Invalid request payload", err))
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Синтаксические ошибки сообщит разбор запроса
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return true
+	}
+
+	var errs []models.FieldError
+	for _, name := range studentFieldsNotUpdatable {
+		if _, ok := fields[name]; ok {
+			errs = append(errs, models.FieldError{Field: name, Code: "not_updatable", Message: name + " cannot be changed by this request"})
+		}
+	}
+	if len(errs) > 0 {
+		c.Error(models.NewValidationError("field_not_updatable", "Some fields cannot be changed by this request", errs...))
+		return false
+	}
+	return true
+}
+
+// studentUpdateRequest собирает запрос на полное обновление из текущей карточки — основа для PATCH
+func studentUpdateRequest(student models.Student) updateStudentRequest {
+	request := updateStudentRequest{
+		FullName:          student.FullName,
+		PhoneNumber:       student.PhoneNumber,
+		ParentName:        student.ParentName,
+		ParentPhoneNumber: student.ParentPhoneNumber,
+		PlatformLink:      student.PlatformLink,
+		CrmLink:           student.CrmLink,
+	}
+	if student.CreatedAt != nil {
+		createdAt := utils.FormatDate(*student.CreatedAt)
+		request.CreatedAt = &createdAt
+	}
+	return request
+}
+
+// save проверяет телефоны и дату, сохраняет карточку с проверкой версии и отправляет ее в CRM
+func (h *StudentsHandlers) save(c *gin.Context, studentId uuid.UUID, request updateStudentRequest, versions []int) {
+	logger := logger.FromContext(c)
+
+	formattedPhone, err := formatPhoneNumber(*request.PhoneNumber, "KZ")
+	if err != nil {
+		logger.Warn("Invalid student phone format in update",
+			zap.String("phone", redact.Phone(*request.PhoneNumber)),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_phone_number", "Invalid student's phone number"))
+		return
+	}
+
+	formattedParentsPhone, err := formatPhoneNumber(*request.ParentPhoneNumber, "KZ")
+	if err != nil {
+		logger.Warn("Invalid parent phone format in update",
+			zap.String("phone", redact.Phone(*request.ParentPhoneNumber)),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_parent_phone_number", "Invalid parent's phone number"))
+		return
+	}
+
+	CreatedAt, err := utils.ParseRequiredDate(*request.CreatedAt)
+	if err != nil {
+		logger.Warn("Invalid date format in update",
+			zap.String("date", *request.CreatedAt),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_created_date", "Invalid created date format. Use DD.MM.YYYY"))
+		return
+	}
+
+	student := models.Student{
+		Id:                studentId,
+		FullName:          request.FullName,
+		PhoneNumber:       &formattedPhone,
+		ParentName:        request.ParentName,
+		ParentPhoneNumber: &formattedParentsPhone,
+		PlatformLink:      request.PlatformLink,
+		CrmLink:           request.CrmLink,
+		CreatedAt:         &CreatedAt,
+	}
+
+	version, err := h.StudentsRepo.Update(c, student, versions)
+	if errors.Is(err, models.ErrVersionConflict) || errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("Student update rejected", zap.String("student_id", studentId.String()), zap.Error(err))
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update student",
+			zap.String("student_id", studentId.String()),
+			zap.Error(err),
+			zap.Any("update_data", student),
+		)
+		c.Error(models.NewInternalError("Failed to update student"))
+		return
+	}
+
+	logger.Info("Student updated successfully", zap.String("student_id", studentId.String()))
+	setETag(c, version)
+	c.Status(http.StatusOK)
 }
 
 // FindAll godoc
@@ -304,87 +490,243 @@ func (h *StudentsHandlers) Update(c *gin.Context) {
 // @Tags Students
 // @Produce json
 // @Param search query string false "Поиск по ФИО"
-// @Param course query string false "Фильтр по ID курса" format(uuid)
-// @Param is_active body string false "Фильтр по активности" Enums(активен, неактивен)
+// @Param course query string false "Фильтр по ID курса (по зачислениям)" format(uuid)
+// @Param enrollment_status query string false "Фильтр по статусу зачисления" Enums(active, paused, completed, canceled)
+// @Param is_active query string false "Фильтр по активности" Enums(active, inactive)
+// @Param lifecycle_state query string false "Фильтр по состоянию; несколько состояний — через запятую" Enums(lead, trial, active, frozen, graduated, churned, archived)
 // @Param curator_id query string false "Фильтр по ID куратора" format(uuid)
 // @Success 200 {array} models.Student "Список студентов"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 400 {object} models.Problem "Неверный ID курса или куратора, неизвестное состояние или статус"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /managers/students [get]
 func (h *StudentsHandlers) FindAll(c *gin.Context) {
-    logger := logger.GetLogger()
-
-    filters := models.StudentFilters{
-        Search:    c.Query("search"),
-        Course:    c.Query("course"),
-        IsActive:  c.Query("is_active"),
-        CuratorId: c.Query("curator_id"),
-    }
-
-    logger.Debug("Fetching students with filters", 
-        zap.Any("filters", filters),
-    )
-
-    students, err := h.StudentsRepo.FindAll(c, filters)
-    if err != nil {
-        logger.Error("Failed to fetch students", 
-            zap.Error(err),
-            zap.Any("filters", filters),
-        )
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to fetch students"))
-        return
-    }
-
-    logger.Debug("Students fetched successfully", 
-        zap.Int("count", len(students)),
-    )
-    c.JSON(http.StatusOK, students)
-}
+	logger := logger.FromContext(c)
+
+	filters := models.StudentFilters{
+		Search:           c.Query("search"),
+		Course:           c.Query("course"),
+		IsActive:         models.NormalizeEnum(c.Query("is_active")),
+		CuratorId:        c.Query("curator_id"),
+		EnrollmentStatus: models.NormalizeEnum(c.Query("enrollment_status")),
+	}
 
+	if filters.Course != "" {
+		if _, err := uuid.Parse(filters.Course); err != nil {
+			c.Error(models.NewValidationError("invalid_course_id", "Invalid course id"))
+			return
+		}
+	}
+	if filters.CuratorId != "" {
+		if _, err := uuid.Parse(filters.CuratorId); err != nil {
+			c.Error(models.NewValidationError("invalid_curator_id", "Invalid curator id"))
+			return
+		}
+	}
+	if filters.IsActive != "" && !slices.Contains(models.Enums[models.EnumIsActive], filters.IsActive) {
+		c.Error(models.NewValidationError("invalid_is_active", "Unknown is_active value"))
+		return
+	}
+	if filters.EnrollmentStatus != "" && !slices.Contains(models.Enums[models.EnumEnrollmentStatus], filters.EnrollmentStatus) {
+		c.Error(models.NewValidationError("invalid_enrollment_status", "Unknown enrollment status"))
+		return
+	}
+
+	if states := c.Query("lifecycle_state"); states != "" {
+		for _, state := range strings.Split(states, ",") {
+			state = strings.TrimSpace(state)
+			if !models.IsLifecycleState(state) {
+				c.Error(models.NewValidationError("invalid_lifecycle_state", "Unknown student lifecycle state"))
+				return
+			}
+			filters.LifecycleStates = append(filters.LifecycleStates, state)
+		}
+	}
+
+	logger.Debug("Fetching students with filters",
+		zap.Any("filters", filters),
+	)
+
+	students, err := h.StudentsRepo.FindAll(c, filters)
+	if err != nil {
+		logger.Error("Failed to fetch students",
+			zap.Error(err),
+			zap.Any("filters", filters),
+		)
+		c.Error(models.NewInternalError("Failed to fetch students"))
+		return
+	}
+
+	logger.Debug("Students fetched successfully",
+		zap.Int("count", len(students)),
+	)
+	c.JSON(http.StatusOK, students)
+}
 
 // Delete godoc
 // @Summary Удалить студента
-// @Description Удаляет запись о студенте из системы
+// @Description Переносит студента в корзину вместе с историей посещаемости и оплат. Восстановление — POST /settings/trash/students/{id}/restore
 // @Tags Students
 // @Param studentId path string true "UUID студента" format(uuid)
 // @Success 204 "Студент успешно удален"
-// @Failure 400 {object} models.ApiError "Неверный формат UUID"
-// @Failure 404 {object} models.ApiError "Студент не найден"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 404 {object} models.Problem "Студент не найден"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/students/{studentId} [delete]
 func (h *StudentsHandlers) Delete(c *gin.Context) {
-    logger := logger.GetLogger()
-    idStr := c.Param("studentId")
-    
-    studentId, err := uuid.Parse(idStr)
-    if err != nil {
-        logger.Warn("Invalid student ID format", 
-            zap.String("input_id", idStr),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusBadRequest, models.NewApiError("Invalid student id"))
-        return
-    }
-
-    logger.Info("Deleting student", zap.String("student_id", studentId.String()))
-
-    if _, err := h.StudentsRepo.FindById(c, studentId); err != nil {
-        logger.Warn("Student not found for deletion", 
-            zap.String("student_id", studentId.String()),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusNotFound, models.NewApiError("Student not found"))
-        return
-    }
-
-    if err := h.StudentsRepo.Delete(c, studentId); err != nil {
-        logger.Error("Failed to delete student", 
-            zap.String("student_id", studentId.String()),
-            zap.Error(err),
-        )
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to delete student"))
-        return
-    }
-
-    logger.Info("Student deleted successfully", zap.String("student_id", studentId.String()))
-    c.Status(http.StatusOK)
-}
\ No newline at end of file
+	logger := logger.FromContext(c)
+	idStr := c.Param("studentId")
+
+	studentId, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("Invalid student ID format",
+			zap.String("input_id", idStr),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	logger.Info("Deleting student", zap.String("student_id", studentId.String()))
+
+	_, err = h.StudentsRepo.FindById(c, studentId)
+	if err != nil {
+		logger.Warn("Student not found for deletion",
+			zap.String("student_id", studentId.String()),
+			zap.Error(err),
+		)
+		c.Error(models.NewNotFoundError("student_not_found", "Student not found"))
+		return
+	}
+
+	if err := h.StudentsRepo.Delete(c, studentId); err != nil {
+		logger.Error("Failed to delete student",
+			zap.String("student_id", studentId.String()),
+			zap.Error(err),
+		)
+		c.Error(models.NewInternalError("Failed to delete student"))
+		return
+	}
+
+	logger.Info("Student deleted successfully", zap.String("student_id", studentId.String()))
+	c.Status(http.StatusOK)
+}
+
+// ChangeLifecycle godoc
+// @Summary Сменить состояние студента
+// @Description Переводит студента в другое состояние жизненного цикла. Разрешенные переходы:
+// @Description - lead → trial, active, churned, archived
+// @Description - trial → active, churned, archived
+// @Description - active → frozen, graduated, churned, archived
+// @Description - frozen → active, churned, archived
+// @Description - graduated → active, archived
+// @Description - churned → trial, active, archived
+// @Description - archived → lead, active
+// @Description На время заморозки (attendance_freezes) студент в состоянии active переводится в frozen автоматически
+// @Description и возвращается в active, когда заморозка закончится. If-Match необязателен; с ним устаревшая карточка дает 412
+// @Tags Students
+// @Accept json
+// @Produce json
+// @Param studentId path string true "UUID студента" format(uuid)
+// @Param If-Match header string false "ETag карточки студента"
+// @Param request body changeLifecycleRequest true "Новое состояние"
+// @Success 200 {object} models.StudentLifecycleTransition "Запись истории о переходе"
+// @Header 200 {string} ETag "Новая версия карточки студента"
+// @Failure 400 {object} models.Problem "Неверный формат данных"
+// @Failure 404 {object} models.Problem "Студент не найден"
+// @Failure 409 {object} models.Problem "Переход из текущего состояния запрещен"
+// @Failure 412 {object} models.Problem "Карточку изменили после получения ETag"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /settings/students/{studentId}/lifecycle [post]
+func (h *StudentsHandlers) ChangeLifecycle(c *gin.Context) {
+	logger := logger.FromContext(c)
+	idStr := c.Param("studentId")
+
+	studentId, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("Invalid student ID format",
+			zap.String("input_id", idStr),
+			zap.Error(err),
+		)
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	var versions []int
+	if c.GetHeader("If-Match") != "" {
+		var ok bool
+		if versions, ok = ifMatchVersions(c); !ok {
+			return
+		}
+	}
+
+	var request changeLifecycleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid lifecycle request format",
+			zap.Error(err),
+			zap.String("student_id", studentId.String()),
+		)
+		c.Error(models.NewBindingError("Invalid request payload", err))
+		return
+	}
+
+	transition, version, err := h.StudentsRepo.ChangeLifecycle(c, studentId, string(request.State), request.Comment, versions)
+	if errors.Is(err, models.ErrLifecycleTransition) || errors.Is(err, models.ErrVersionConflict) || errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("Student lifecycle change rejected",
+			zap.String("student_id", studentId.String()),
+			zap.String("state", string(request.State)),
+			zap.Error(err),
+		)
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to change student lifecycle",
+			zap.String("student_id", studentId.String()),
+			zap.String("state", string(request.State)),
+			zap.Error(err),
+		)
+		c.Error(models.NewInternalError("Failed to change student state"))
+		return
+	}
+
+	logger.Info("Student lifecycle changed",
+		zap.String("student_id", studentId.String()),
+		zap.Stringp("from", transition.FromState),
+		zap.String("to", transition.ToState),
+	)
+	setETag(c, version)
+	c.JSON(http.StatusOK, transition)
+}
+
+// LifecycleHistory godoc
+// @Summary История состояний студента
+// @Description Все переходы жизненного цикла студента, последние первыми. source: manual — сотрудник,
+// @Description created — создание карточки, crm — вебхук CRM, attendance_freeze — автоматическая заморозка и разморозка
+// @Tags Managers
+// @Produce json
+// @Param studentId path string true "UUID студента" format(uuid)
+// @Success 200 {array} models.StudentLifecycleTransition
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /managers/students/{studentId}/lifecycle [get]
+func (h *StudentsHandlers) LifecycleHistory(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	studentId, err := uuid.Parse(c.Param("studentId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_student_id", "Invalid student id"))
+		return
+	}
+
+	history, err := h.StudentsRepo.LifecycleHistory(c, studentId)
+	if err != nil {
+		logger.Error("Failed to fetch student lifecycle history",
+			zap.String("student_id", studentId.String()),
+			zap.Error(err),
+		)
+		c.Error(models.NewInternalError("Failed to fetch student lifecycle history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
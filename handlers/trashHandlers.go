@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type TrashHandlers struct {
+	trashRepo *repositories.TrashRepository
+	retention time.Duration
+}
+
+// NewTrashHandlers создает обработчики корзины; retention — срок хранения удаленных записей (0 — бессрочно)
+func NewTrashHandlers(trashRepo *repositories.TrashRepository, retention time.Duration) *TrashHandlers {
+	return &TrashHandlers{trashRepo: trashRepo, retention: retention}
+}
+
+// trashType читает тип записей корзины; пустая строка допустима, только если allowAll
+func trashType(c *gin.Context, itemType string, allowAll bool) (string, bool) {
+	if (itemType == "" && allowAll) || models.IsTrashType(itemType) {
+		return itemType, true
+	}
+	c.Error(models.NewValidationError("invalid_trash_type", "Unknown trash item type"))
+	return "", false
+}
+
+// FindAll godoc
+// @Summary Корзина
+// @Description Удаленные студенты, пользователи, курсы и записи посещаемости текущего филиала, недавно удаленные первыми.
+// @Description purge_at — когда запись будет удалена окончательно (TRASH_RETENTION)
+// @Tags Trash
+// @Produce json
+// @Param type query string false "Тип записей" Enums(students, users, courses, attendance)
+// @Success 200 {array} models.TrashItem
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/trash [get]
+func (h *TrashHandlers) FindAll(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	itemType, ok := trashType(c, c.Query("type"), true)
+	if !ok {
+		return
+	}
+
+	items, err := h.trashRepo.FindAll(c, itemType)
+	if err != nil {
+		logger.Error("Failed to fetch trash", zap.String("type", itemType), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch trash"))
+		return
+	}
+
+	if h.retention > 0 {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.Add(h.retention)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// Restore godoc
+// @Summary Восстановить из корзины
+// @Description Возвращает удаленную запись. Восстановленный студент снова отправляется в CRM
+// @Tags Trash
+// @Param type path string true "Тип записи" Enums(students, users, courses, attendance)
+// @Param id path string true "ID записи" format(uuid)
+// @Success 204
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem "Записи нет в корзине"
+// @Failure 500 {object} models.Problem
+// @Router /settings/trash/{type}/{id}/restore [post]
+func (h *TrashHandlers) Restore(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	itemType, ok := trashType(c, c.Param("type"), false)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_trash_item_id", "Invalid trash item id"))
+		return
+	}
+
+	err = h.trashRepo.Restore(c, itemType, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to restore from trash", zap.String("type", itemType), zap.String("id", id.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to restore item"))
+		return
+	}
+
+	logger.Info("Restored from trash", zap.String("type", itemType), zap.String("id", id.String()))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/models"
+	"net/http"
+	"testing"
+)
+
+func TestTrashType(t *testing.T) {
+	tests := []struct {
+		name     string
+		itemType string
+		allowAll bool
+		wantOk   bool
+	}{
+		{name: "students", itemType: models.TrashStudents, wantOk: true},
+		{name: "users", itemType: models.TrashUsers, wantOk: true},
+		{name: "courses", itemType: models.TrashCourses, wantOk: true},
+		{name: "attendance", itemType: models.TrashAttendance, wantOk: true},
+		{name: "all types in list", itemType: "", allowAll: true, wantOk: true},
+		{name: "all types on restore", itemType: "", wantOk: false},
+		{name: "unknown type", itemType: "groups", allowAll: true, wantOk: false},
+		{name: "singular", itemType: "student", wantOk: false},
+		{name: "case sensitive", itemType: "Students", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testContext(http.MethodGet, nil)
+
+			got, ok := trashType(c, tt.itemType, tt.allowAll)
+			if ok != tt.wantOk {
+				t.Fatalf("trashType(%q, %v) ok = %v, want %v", tt.itemType, tt.allowAll, ok, tt.wantOk)
+			}
+			if ok {
+				if got != tt.itemType {
+					t.Errorf("trashType(%q) = %q, want %q", tt.itemType, got, tt.itemType)
+				}
+				if len(c.Errors) != 0 {
+					t.Errorf("errors = %v, want none", c.Errors)
+				}
+				return
+			}
+
+			var appErr *models.AppError
+			if len(c.Errors) == 0 || !errors.As(c.Errors.Last().Err, &appErr) {
+				t.Fatalf("errors = %v, want *models.AppError", c.Errors)
+			}
+			if appErr.Code != "invalid_trash_type" || appErr.Kind.Status() != http.StatusBadRequest {
+				t.Errorf("error = %s (%d), want invalid_trash_type (400)", appErr.Code, appErr.Kind.Status())
+			}
+		})
+	}
+}
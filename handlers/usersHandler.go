@@ -1,57 +1,66 @@
 package handlers
 
 import (
+	"errors"
 	"it_school/logger"
 	"it_school/models"
+	"it_school/redact"
 	"it_school/repositories"
 	"it_school/utils"
 	"net/http"
 
-
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 type UserHandler struct {
-	usersRepo *repositories.UsersRepository
+	usersRepo   *repositories.UsersRepository
 	curatorRepo *repositories.CuratorsRepository
-	roleRepo *repositories.RoleRepository
+	roleRepo    *repositories.RoleRepository
 }
 
 type CreateRequest struct {
-	FullName   string    `json:"full_name"`
-	Email      string    `json:"email"`
-	Telephone  string    `json:"telephone"`
-	Password   string    `json:"password"`
-	RoleName   string 	 `json:"role_name"`
+	FullName  string      `json:"full_name"`
+	Email     string      `json:"email"`
+	Telephone string      `json:"telephone"`
+	Password  string      `json:"password"`
+	RoleName  string      `json:"role_name"`
+	BranchIds []uuid.UUID `json:"branch_ids"`
+}
+
+// UpdateUserRequest — профиль пользователя для PUT и основа для PATCH
+type UpdateUserRequest struct {
+	FullName  string `json:"full_name" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Telephone string `json:"telephone"`
 }
 
 type CuratorResponse struct {
-    ID         uuid.UUID   `json:"id"`
-    FullName   string      `json:"full_name"`
-    Email      string      `json:"email"`
-    Telephone  string      `json:"telephone"`
-    RoleID     uuid.UUID   `json:"role_id"`
-    StudentIDs []uuid.UUID `json:"student_ids"`
-    CourseIDs  []uuid.UUID `json:"course_ids"`
+	ID         uuid.UUID   `json:"id"`
+	FullName   string      `json:"full_name"`
+	Email      string      `json:"email"`
+	Telephone  string      `json:"telephone"`
+	RoleID     uuid.UUID   `json:"role_id"`
+	StudentIDs []uuid.UUID `json:"student_ids"`
+	CourseIDs  []uuid.UUID `json:"course_ids"`
 }
 
 type UserResponse struct {
-    ID                  uuid.UUID  `json:"id"`
-    FullName            string     `json:"full_name"`
-    Email               string     `json:"email"`
-    Telephone           string     `json:"telephone"`
-    RoleId              uuid.UUID  `json:"roleId"`
-    RoleName            string  	`json:"role_name"`
+	ID        uuid.UUID `json:"id"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	Telephone string    `json:"telephone"`
+	RoleId    uuid.UUID `json:"roleId"`
+	RoleName  string    `json:"role_name"`
 }
 
-
 func NewUserHandlers(usersRepo *repositories.UsersRepository, curatorRepo *repositories.CuratorsRepository, roleRepo *repositories.RoleRepository) *UserHandler {
 	return &UserHandler{
-		usersRepo: usersRepo,
+		usersRepo:   usersRepo,
 		curatorRepo: curatorRepo,
-		roleRepo: roleRepo,
+		roleRepo:    roleRepo,
 	}
 }
 
@@ -62,11 +71,11 @@ func NewUserHandlers(usersRepo *repositories.UsersRepository, curatorRepo *repos
 // @Produce json
 // @Param role query string false "Фильтр по ID роли" format(uuid)
 // @Success 200 {array} models.User "Список пользователей"
-// @Failure 400 {object} models.ApiError "Неверный формат UUID"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/users [get]
 func (h *UserHandler) FindAll(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	roleParam := c.Query("role")
 	var roleID *uuid.UUID
@@ -75,7 +84,7 @@ func (h *UserHandler) FindAll(c *gin.Context) {
 		id, err := uuid.Parse(roleParam) //  правильно парсим UUID
 		if err != nil {
 			logger.Warn("Invalid role query param", zap.String("role", roleParam))
-			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid role parameter"))
+			c.Error(models.NewValidationError("invalid_role_parameter", "Invalid role parameter"))
 			return
 		}
 		roleID = &id
@@ -84,7 +93,7 @@ func (h *UserHandler) FindAll(c *gin.Context) {
 	users, err := h.usersRepo.FindAll(c.Request.Context(), roleID)
 	if err != nil {
 		logger.Error("Failed to get users from repository", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("could not get users"))
+		c.Error(models.NewInternalError("could not get users"))
 		return
 	}
 
@@ -98,36 +107,36 @@ func (h *UserHandler) FindAll(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param id path string true "ID роли"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} models.ApiError            
-// @Failure 404 {object} models.ApiError             
-// @Failure 500 {object} models.ApiError            
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /role/{id} [get]
 func (h *UserHandler) GetRole(c *gin.Context) {
-    logger := logger.GetLogger()
-
-    idParam := c.Param("id")
-    if idParam == "" {
-        logger.Error("Missing role id query parameter")
-        c.JSON(http.StatusBadRequest, models.NewApiError("role parameter is required"))
-        return
-    }
-
-    id, err := uuid.Parse(idParam)
-    if err != nil {
-        logger.Error("Invalid UUID format for role", zap.String("roleParam", idParam), zap.Error(err))
-        c.JSON(http.StatusBadRequest, models.NewApiError("invalid role id format"))
-        return
-    }
-
-    role, err := h.roleRepo.GetRoleByID(c, id)
-    if err != nil {
-        logger.Error("Failed to get role name from repository", zap.Error(err))
-        c.JSON(http.StatusInternalServerError, models.NewApiError("could not get role name"))
-        return
-    }
-
-    logger.Info("Successfully retrieved role name", zap.Any("role", role))
-    c.JSON(http.StatusOK, gin.H{"role": role})
+	logger := logger.FromContext(c)
+
+	idParam := c.Param("id")
+	if idParam == "" {
+		logger.Error("Missing role id query parameter")
+		c.Error(models.NewValidationError("role_required", "role parameter is required"))
+		return
+	}
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		logger.Error("Invalid UUID format for role", zap.String("roleParam", idParam), zap.Error(err))
+		c.Error(models.NewValidationError("invalid_role_id", "invalid role id format"))
+		return
+	}
+
+	role, err := h.roleRepo.GetRoleByID(c, id)
+	if err != nil {
+		logger.Error("Failed to get role name from repository", zap.Error(err))
+		c.Error(models.NewInternalError("could not get role name"))
+		return
+	}
+
+	logger.Info("Successfully retrieved role name", zap.Any("role", role))
+	c.JSON(http.StatusOK, gin.H{"role": role})
 }
 
 // FindManagers godoc
@@ -136,16 +145,16 @@ func (h *UserHandler) GetRole(c *gin.Context) {
 // @Tags Users
 // @Produce json
 // @Success 200 {array} models.User "Список менеджеров"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/users/managers [get]
 func (h *UserHandler) FindManagers(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	manager, _ := h.roleRepo.GetRoleByName(c, "manager")
 	users, err := h.usersRepo.FindAll(c.Request.Context(), &manager.Id)
 	if err != nil {
 		logger.Error("Failed to get managers from repository", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("could not get managers"))
+		c.Error(models.NewInternalError("could not get managers"))
 		return
 	}
 
@@ -159,16 +168,16 @@ func (h *UserHandler) FindManagers(c *gin.Context) {
 // @Tags Users
 // @Produce json
 // @Success 200 {array} handlers.CuratorResponse "Список кураторов с деталями"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/users/curators [get]
 func (h *UserHandler) FindCurators(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	curatorRole, _ := h.roleRepo.GetRoleByName(c, "curator")
 	users, err := h.usersRepo.FindAll(c.Request.Context(), &curatorRole.Id)
 	if err != nil {
 		logger.Error("Failed to get curators from repository", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("could not get curators"))
+		c.Error(models.NewInternalError("could not get curators"))
 		return
 	}
 
@@ -177,7 +186,7 @@ func (h *UserHandler) FindCurators(c *gin.Context) {
 		curatorData, err := h.curatorRepo.GetCuratorByUserID(c.Request.Context(), user.Id)
 		if err != nil {
 			logger.Error("Failed to get curator data", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, models.NewApiError("could not get curator data"))
+			c.Error(models.NewInternalError("could not get curator data"))
 			return
 		}
 
@@ -204,252 +213,400 @@ func (h *UserHandler) FindCurators(c *gin.Context) {
 // @Produce json
 // @Param userId path string true "ID пользователя" format(uuid)
 // @Success 200 {object} models.User "Данные пользователя"
-// @Failure 400 {object} models.ApiError "Неверный формат UUID"
-// @Failure 404 {object} models.ApiError "Пользователь не найден"
+// @Header 200 {string} ETag "Версия профиля пользователя"
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 404 {object} models.Problem "Пользователь не найден"
 // @Router /settings/users/{userId} [get]
 func (h *UserHandler) FindById(c *gin.Context) {
-    logger := logger.GetLogger()
-
-    id, err := uuid.Parse(c.Param("userId"))
-    if err != nil {
-        logger.Error("Invalid user id", zap.String("userId", c.Param("userId")), zap.Error(err))
-        c.JSON(http.StatusBadRequest, models.NewApiError("invalid user id"))
-        return
-    }
-
-    user, err := h.usersRepo.FindById(c.Request.Context(), id)
-    if err != nil {
-        logger.Error("Failed fetching user", zap.Error(err))
-        c.JSON(http.StatusNotFound, models.NewApiError("user not found"))
-        return
-    }
+	logger := logger.FromContext(c)
+
+	id, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		logger.Error("Invalid user id", zap.String("userId", c.Param("userId")), zap.Error(err))
+		c.Error(models.NewValidationError("invalid_user_id", "invalid user id"))
+		return
+	}
+
+	user, err := h.usersRepo.FindById(c.Request.Context(), id)
+	if err != nil {
+		logger.Error("Failed fetching user", zap.Error(err))
+		c.Error(models.NewNotFoundError("user_not_found", "user not found"))
+		return
+	}
 
 	role, err := h.roleRepo.GetRoleByID(c.Request.Context(), user.RoleID)
-    if err != nil {
-        logger.Error("Failed to get user role", zap.String("user_id", user.Id.String()), zap.Error(err))
-        c.JSON(http.StatusInternalServerError, models.NewApiError("Couldn't find role"))
-        return
-    }
-
-    resp := UserResponse{
-        ID:        user.Id,
-        FullName:  user.Full_name,
-        Email:     user.Email,
-        Telephone: user.Telephone,
-        RoleId:    role.Id,
+	if err != nil {
+		logger.Error("Failed to get user role", zap.String("user_id", user.Id.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Couldn't find role"))
+		return
+	}
+
+	resp := UserResponse{
+		ID:        user.Id,
+		FullName:  user.Full_name,
+		Email:     user.Email,
+		Telephone: user.Telephone,
+		RoleId:    role.Id,
 		RoleName:  role.Name,
-    }
+	}
 
-    c.JSON(http.StatusOK, resp)
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, resp)
 }
 
-
 // Create godoc
 // @Summary Создать пользователя
 // @Description Создает нового пользователя с указанной ролью. Для роли 'curator' автоматически создает связанную запись.
+// @Description Пользователь прикрепляется к филиалам из branch_ids, а если они не указаны — к текущему филиалу.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param request body handlers.CreateRequest true "Данные для создания пользователя" example={"full_name": "Иванов Иван", "email": "user@example.com", "telephone": "+77071234567", "password": "securePassword123", "role_name": "curator"}
 // @Success 201 {object} object{message=string} "Пользователь создан"
-// @Failure 400 {object} models.ApiError "Неверные данные"
-// @Failure 409 {object} models.ApiError "Email уже существует"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Failure 400 {object} models.Problem "Неверные данные или филиал из branch_ids не найден"
+// @Failure 409 {object} models.Problem "Email уже существует или принадлежит пользователю в корзине (восстановление — POST /settings/trash/users/{id}/restore)"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/users [post]
 func (h *UserHandler) Create(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	var req CreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request data", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request data"))
+		c.Error(models.NewBindingError("Invalid request data", err))
 		return
 	}
 
-	_, err := h.usersRepo.FindByEmail(c, req.Email)
-	if err == nil {
-		logger.Warn("Email already exists", zap.String("email", req.Email))
-		c.JSON(http.StatusConflict, models.NewApiError("Email already exists"))
+	telephone, err := formatUserTelephone(req.Telephone)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_phone_number", "Invalid phone number"))
+		return
+	}
+
+	if !h.emailAvailable(c, req.Email, uuid.Nil) {
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		logger.Error("Failed to hash password", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to hash password"))
+		c.Error(models.NewInternalError("Failed to hash password"))
 		return
 	}
 
 	role, err := h.roleRepo.GetRoleByName(c, req.RoleName)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiError("Unknown role"))
+		c.Error(models.NewValidationError("unknown_role", "Unknown role"))
 		return
 	}
 
 	newUser := models.User{
-		Full_name:     req.FullName,
-		Email:         req.Email,
-		Telephone:     req.Telephone,
-		PasswordHash:  hashedPassword,
-		RoleID:        role.Id,
+		Full_name:    req.FullName,
+		Email:        req.Email,
+		Telephone:    telephone,
+		PasswordHash: hashedPassword,
+		RoleID:       role.Id,
+	}
+
+	// Пользователь прикрепляется к указанным филиалам, по умолчанию — к текущему
+	branchIDs := req.BranchIds
+	if len(branchIDs) == 0 {
+		if branchID := models.BranchFromContext(c.Request.Context()); branchID != nil {
+			branchIDs = []uuid.UUID{*branchID}
+		}
 	}
 
-	userID, err := h.usersRepo.Create(c, newUser)
+	// Для куратора сразу создается доп. запись
+	_, err = h.usersRepo.Provision(c, newUser, branchIDs, role.Name == "curator")
+	if isUniqueViolation(err) {
+		logger.Warn("Email already exists", zap.String("email", redact.Email(req.Email)))
+		c.Error(models.ErrEmailAlreadyExists)
+		return
+	}
+	if isForeignKeyViolation(err) {
+		c.Error(models.NewValidationError("branch_not_found", "Branch not found"))
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to create user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to create user"))
+		c.Error(models.NewInternalError("Failed to create user"))
 		return
 	}
 
-	if role.Name == "curator" {
-		// Это куратор — создаем доп. запись
-		curator := models.Curator{
-			UserID:     userID,
-			StudentIDs: []uuid.UUID{},
-			CourseIDs:  []uuid.UUID{},
-		}
-	
-		err := h.curatorRepo.Create(c, curator)
-		if err != nil {
-			logger.Error("Failed to create curator data", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to create curator data"))
-			return
-		}
-	}
-
-	logger.Info("User created successfully", zap.String("email", newUser.Email))
+	logger.Info("User created successfully", zap.String("email", redact.Email(newUser.Email)))
 	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
 }
 
 // Update godoc
 // @Summary Обновить пользователя
-// @Description Обновляет информацию о существующем пользователе
+// @Description Заменяет профиль существующего пользователя: ФИО, email и телефон. Email и телефон проверяются так же, как при создании.
+// @Description Заголовок If-Match обязателен: ETag профиля. Если профиль уже изменили, возвращается 412
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param userId path string true "ID пользователя" format(uuid)
-// @Param request body models.User true "Обновленные данные пользователя"
+// @Param If-Match header string true "ETag профиля пользователя"
+// @Param request body UpdateUserRequest true "Обновленные данные пользователя"
 // @Success 200 "Данные обновлены"
-// @Failure 400 {object} models.ApiError "Неверные данные"
-// @Failure 404 {object} models.ApiError "Пользователь не найден"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
+// @Header 200 {string} ETag "Новая версия профиля пользователя"
+// @Failure 400 {object} models.Problem "Неверные данные"
+// @Failure 404 {object} models.Problem "Пользователь не найден"
+// @Failure 409 {object} models.Problem "Email занят другим пользователем или пользователем в корзине"
+// @Failure 412 {object} models.Problem "Версия в If-Match устарела"
+// @Failure 428 {object} models.Problem "Не передан If-Match"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
 // @Router /settings/users/{userId} [put]
 func (h *UserHandler) Update(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
 	idStr := c.Param("userId")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		logger.Error("Invalid user id", zap.String("id", idStr))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewValidationError("invalid_user_id", "Invalid user id"))
 		return
 	}
 
 	_, err = h.usersRepo.FindById(c, id)
 	if err != nil {
 		logger.Error("Failed to find user for update", zap.String("userID", id.String()), zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
 		return
 	}
 
-	var req models.User
+	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request body"))
+		c.Error(models.NewBindingError("Invalid request body", err))
 		return
 	}
 
-	err = h.usersRepo.Update(c, id, req)
+	h.save(c, id, req, versions)
+}
+
+// Patch godoc
+// @Summary Частично обновить пользователя
+// @Description Меняет только переданные поля профиля (JSON Merge Patch, RFC 7396; Content-Type application/merge-patch+json или application/json).
+// @Description If-Match необязателен: без него патч применяется к текущей версии, а если профиль изменили одновременно с запросом, возвращается 412
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param userId path string true "ID пользователя" format(uuid)
+// @Param If-Match header string false "ETag профиля пользователя"
+// @Param request body UpdateUserRequest true "Изменяемые поля"
+// @Success 200 "Данные обновлены"
+// @Header 200 {string} ETag "Новая версия профиля пользователя"
+// @Failure 400 {object} models.Problem "Неверные данные"
+// @Failure 404 {object} models.Problem "Пользователь не найден"
+// @Failure 409 {object} models.Problem "Email занят другим пользователем или пользователем в корзине"
+// @Failure 412 {object} models.Problem "Профиль изменили после получения ETag"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /settings/users/{userId} [patch]
+func (h *UserHandler) Patch(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	idStr := c.Param("userId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Error("Invalid user id", zap.String("id", idStr))
+		c.Error(models.NewValidationError("invalid_user_id", "Invalid user id"))
+		return
+	}
+
+	user, err := h.usersRepo.FindById(c, id)
+	if err != nil {
+		logger.Error("Failed to find user for patch", zap.String("userID", id.String()), zap.Error(err))
+		c.Error(err)
+		return
+	}
+
+	versions, ok := patchVersions(c, user.Version)
+	if !ok {
+		return
+	}
+
+	current := UpdateUserRequest{FullName: user.Full_name, Email: user.Email, Telephone: user.Telephone}
+	var req UpdateUserRequest
+	if err := bindMergePatch(c, current, &req); err != nil {
+		logger.Error("Invalid patch body", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request body", err))
+		return
+	}
+
+	h.save(c, id, req, versions)
+}
+
+// save проверяет телефон и email так же, как при создании, и сохраняет профиль пользователя
+// с проверкой версии (nil — без проверки)
+func (h *UserHandler) save(c *gin.Context, id uuid.UUID, req UpdateUserRequest, versions []int) {
+	logger := logger.FromContext(c)
+
+	telephone, err := formatUserTelephone(req.Telephone)
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_phone_number", "Invalid phone number"))
+		return
+	}
+
+	if !h.emailAvailable(c, req.Email, id) {
+		return
+	}
+
+	user := models.User{Full_name: req.FullName, Email: req.Email, Telephone: telephone}
+	version, err := h.usersRepo.Update(c, id, user, versions)
+	if isUniqueViolation(err) {
+		logger.Warn("Email already exists", zap.String("email", redact.Email(req.Email)))
+		c.Error(models.ErrEmailAlreadyExists)
+		return
+	}
+	if errors.Is(err, models.ErrVersionConflict) || errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("User update rejected", zap.String("userID", id.String()), zap.Error(err))
+		c.Error(err)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to update manager", zap.String("userID", id.String()), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError("Failed to update manager"))
+		c.Error(models.NewInternalError("Failed to update manager"))
 		return
 	}
 
 	logger.Info("User updated successfully", zap.String("userID", id.String()))
+	setETag(c, version)
 	c.Status(http.StatusOK)
 }
 
+// formatUserTelephone приводит телефон пользователя к формату студентов; пустой телефон допустим
+func formatUserTelephone(telephone string) (string, error) {
+	if telephone == "" {
+		return "", nil
+	}
+	return formatPhoneNumber(telephone, "KZ")
+}
+
+// emailAvailable проверяет, что email не занят другим пользователем (self — изменяемый пользователь,
+// uuid.Nil при создании). Email пользователя из корзины тоже занят: его нужно восстановить
+func (h *UserHandler) emailAvailable(c *gin.Context, email string, self uuid.UUID) bool {
+	logger := logger.FromContext(c)
+
+	user, err := h.usersRepo.FindByEmail(c, email)
+	switch {
+	case err == nil && user.Id != self:
+		logger.Warn("Email already exists", zap.String("email", redact.Email(email)))
+		c.Error(models.ErrEmailAlreadyExists)
+		return false
+	case err != nil && !errors.Is(err, pgx.ErrNoRows):
+		logger.Error("Failed to check email", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to check email"))
+		return false
+	}
+
+	trashedID, err := h.usersRepo.FindTrashedIdByEmail(c, email)
+	switch {
+	case err == nil:
+		logger.Warn("Email belongs to a user in the trash", zap.String("userID", trashedID.String()))
+		c.Error(models.ErrEmailInTrash)
+		return false
+	case !errors.Is(err, pgx.ErrNoRows):
+		logger.Error("Failed to check email", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to check email"))
+		return false
+	}
+	return true
+}
+
 // @Summary Обновить роль пользователя
 // @Security ApiKeyAuth
 // @Tags Users
 // @Param userId path string true "ID пользователя"
 // @Param roleId query string true "ID новой роли"
 // @Success 200 {object} map[string]string "role updated successfully"
-// @Failure 400 {object} models.ApiError
-// @Failure 500 {object} models.ApiError
+// @Failure 400 {object} models.Problem "Неверный ID или роль не найдена"
+// @Failure 404 {object} models.Problem "Пользователь не найден"
+// @Failure 500 {object} models.Problem
 // @Router /users/{userId}/role [put]
 func (h *UserHandler) UpdateUserRole(c *gin.Context) {
-    logger := logger.GetLogger()
-
-    userID, err := uuid.Parse(c.Param("userId"))
-    if err != nil {
-        logger.Error("Invalid user id", zap.String("userId", c.Param("userId")), zap.Error(err))
-        c.JSON(http.StatusBadRequest, models.NewApiError("invalid user id"))
-        return
-    }
-
-    roleIDParam := c.Query("roleId")
-    if roleIDParam == "" {
-        logger.Error("Missing roleId parameter")
-        c.JSON(http.StatusBadRequest, models.NewApiError("roleId is required"))
-        return
-    }
-
-    roleID, err := uuid.Parse(roleIDParam)
-    if err != nil {
-        logger.Error("Invalid role id", zap.String("roleId", roleIDParam), zap.Error(err))
-        c.JSON(http.StatusBadRequest, models.NewApiError("invalid role id format"))
-        return
-    }
-
-    if err := h.usersRepo.UpdateUserRole(c.Request.Context(), userID, roleID); err != nil {
-        logger.Error("Failed to update user role", zap.String("userId", userID.String()), zap.Error(err))
-        c.JSON(http.StatusInternalServerError, models.NewApiError("could not update user role"))
-        return
-    }
-
-    logger.Info("User role updated", zap.String("userId", userID.String()), zap.String("roleId", roleID.String()))
-    c.JSON(http.StatusOK, gin.H{"message": "role updated successfully"})
-}
+	logger := logger.FromContext(c)
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		logger.Error("Invalid user id", zap.String("userId", c.Param("userId")), zap.Error(err))
+		c.Error(models.NewValidationError("invalid_user_id", "invalid user id"))
+		return
+	}
+
+	roleIDParam := c.Query("roleId")
+	if roleIDParam == "" {
+		logger.Error("Missing roleId parameter")
+		c.Error(models.NewValidationError("role_id_required", "roleId is required"))
+		return
+	}
+
+	roleID, err := uuid.Parse(roleIDParam)
+	if err != nil {
+		logger.Error("Invalid role id", zap.String("roleId", roleIDParam), zap.Error(err))
+		c.Error(models.NewValidationError("invalid_role_id", "invalid role id format"))
+		return
+	}
 
+	err = h.usersRepo.UpdateUserRole(c.Request.Context(), userID, roleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if isForeignKeyViolation(err) {
+		c.Error(models.NewValidationError("role_not_found", "Role not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update user role", zap.String("userId", userID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("could not update user role"))
+		return
+	}
+
+	logger.Info("User role updated", zap.String("userId", userID.String()), zap.String("roleId", roleID.String()))
+	c.JSON(http.StatusOK, gin.H{"message": "role updated successfully"})
+}
 
 // Delete godoc
 // @Summary Удалить пользователя
-// @Description Удаляет пользователя из системы
+// @Description Переносит пользователя в корзину и завершает его сессии. Восстановление — POST /settings/trash/users/{id}/restore
 // @Tags Users
-// @Param id path string true "ID пользователя" format(uuid)
+// @Param userId path string true "ID пользователя" format(uuid)
 // @Success 204 "Пользователь удален"
-// @Failure 400 {object} models.ApiError "Неверный формат UUID"
-// @Failure 404 {object} models.ApiError "Пользователь не найден"
-// @Failure 500 {object} models.ApiError "Ошибка сервера"
-// @Router /settings/users/{id} [delete]
+// @Failure 400 {object} models.Problem "Неверный формат UUID"
+// @Failure 404 {object} models.Problem "Пользователь не найден"
+// @Failure 500 {object} models.Problem "Ошибка сервера"
+// @Router /settings/users/{userId} [delete]
 func (h *UserHandler) Delete(c *gin.Context) {
-	logger := logger.GetLogger()
+	logger := logger.FromContext(c)
 
-	idStr := c.Param("id")
+	idStr := c.Param("userId")
 	id, err := uuid.Parse(idStr)
 
 	if err != nil {
 		logger.Error("Invalid user id", zap.String("id", idStr))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user id"))
+		c.Error(models.NewValidationError("invalid_user_id", "Invalid user id"))
 		return
 	}
 
 	_, err = h.usersRepo.FindById(c, id)
 	if err != nil {
 		logger.Error("Failed to find user", zap.String("userID", id.String()), zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
 	err = h.usersRepo.Delete(c, id)
 	if err != nil {
 		logger.Error("Failed to delete user", zap.String("userID", id.String()), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		c.Error(err)
 		return
 	}
 
 	logger.Info("User deleted successfully", zap.String("userID", id.String()))
 	c.Status(http.StatusOK)
-}
\ No newline at end of file
+}
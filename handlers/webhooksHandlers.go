@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+	"it_school/events"
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+	"it_school/utils"
+	"it_school/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CreateWebhookRequest struct {
+	Url         string   `json:"url" binding:"required,url"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=student.created attendance.lesson.conducted prolongation.created freeze.created user.role_changed student.lifecycle_changed"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16"`
+	IsActive    *bool    `json:"is_active"`
+	Description *string  `json:"description"`
+}
+
+type UpdateWebhookRequest struct {
+	Url         string   `json:"url" binding:"required,url"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=student.created attendance.lesson.conducted prolongation.created freeze.created user.role_changed student.lifecycle_changed"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16"`
+	IsActive    bool     `json:"is_active"`
+	Description *string  `json:"description"`
+}
+
+type WebhooksHandlers struct {
+	webhooksRepo *repositories.WebhooksRepository
+	dispatcher   *webhooks.Dispatcher
+}
+
+func NewWebhooksHandlers(webhooksRepo *repositories.WebhooksRepository, dispatcher *webhooks.Dispatcher) *WebhooksHandlers {
+	return &WebhooksHandlers{webhooksRepo: webhooksRepo, dispatcher: dispatcher}
+}
+
+// EventTypes godoc
+// @Summary Типы доменных событий
+// @Description События, на которые можно подписать вебхук
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} string
+// @Router /settings/webhooks/events [get]
+func (h *WebhooksHandlers) EventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, events.Types)
+}
+
+// Create godoc
+// @Summary Создать подписку на события
+// @Description Запросы подписчику подписываются: X-Webhook-Timestamp (unix-время) и
+// @Description X-Webhook-Signature = sha256=HMAC-SHA256(secret, "<timestamp>.<body>").
+// @Description Если secret не передан, он генерируется. Секрет возвращается только в ответе на создание
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Подписка"
+// @Success 201 {object} object{id=string,secret=string}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks [post]
+func (h *WebhooksHandlers) Create(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	var request CreateWebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid webhook create request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		var err error
+		if secret, err = utils.GenerateWebhookSecret(); err != nil {
+			logger.Error("Failed to generate webhook secret", zap.Error(err))
+			c.Error(models.NewInternalError("Failed to create webhook"))
+			return
+		}
+	}
+
+	isActive := true
+	if request.IsActive != nil {
+		isActive = *request.IsActive
+	}
+
+	id, err := h.webhooksRepo.CreateSubscription(c, models.WebhookSubscription{
+		Url:         request.Url,
+		Secret:      secret,
+		Events:      request.Events,
+		IsActive:    isActive,
+		Description: request.Description,
+	})
+	if err != nil {
+		logger.Error("Failed to create webhook", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to create webhook"))
+		return
+	}
+
+	logger.Info("Webhook subscription created", zap.String("webhook_id", id.String()), zap.Strings("events", request.Events))
+	c.JSON(http.StatusCreated, gin.H{"id": id, "secret": secret})
+}
+
+// FindAll godoc
+// @Summary Подписки на события
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookSubscription
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks [get]
+func (h *WebhooksHandlers) FindAll(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	subscriptions, err := h.webhooksRepo.FindSubscriptions(c)
+	if err != nil {
+		logger.Error("Failed to fetch webhooks", zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// FindById godoc
+// @Summary Подписка на события
+// @Description Заголовок ETag нужно передать в If-Match при обновлении
+// @Tags Webhooks
+// @Produce json
+// @Param webhookId path string true "ID подписки"
+// @Success 200 {object} models.WebhookSubscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /settings/webhooks/{webhookId} [get]
+func (h *WebhooksHandlers) FindById(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_webhook_id", "Invalid webhook id"))
+		return
+	}
+
+	subscription, err := h.webhooksRepo.FindSubscription(c, webhookID)
+	if err != nil {
+		c.Error(models.NewNotFoundError("webhook_not_found", "Webhook not found"))
+		return
+	}
+
+	setETag(c, subscription.Version)
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Update godoc
+// @Summary Обновить подписку
+// @Description Пустой secret оставляет прежний секрет.
+// @Description Заголовок If-Match обязателен: ETag из GET /settings/webhooks/{webhookId}. Если подписку уже изменили, возвращается 412
+// @Tags Webhooks
+// @Accept json
+// @Param webhookId path string true "ID подписки"
+// @Param If-Match header string true "ETag подписки"
+// @Param request body UpdateWebhookRequest true "Подписка"
+// @Success 200
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks/{webhookId} [put]
+func (h *WebhooksHandlers) Update(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	webhookID, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_webhook_id", "Invalid webhook id"))
+		return
+	}
+
+	versions, ok := ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Warn("Invalid webhook update request", zap.Error(err))
+		c.Error(models.NewBindingError("Invalid request data", err))
+		return
+	}
+
+	version, err := h.webhooksRepo.UpdateSubscription(c, models.WebhookSubscription{
+		Id:          webhookID,
+		Url:         request.Url,
+		Secret:      request.Secret,
+		Events:      request.Events,
+		IsActive:    request.IsActive,
+		Description: request.Description,
+	}, versions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("webhook_not_found", "Webhook not found"))
+		return
+	}
+	if errors.Is(err, models.ErrVersionConflict) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update webhook", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to update webhook"))
+		return
+	}
+
+	setETag(c, version)
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary Удалить подписку
+// @Description Удаляет подписку вместе с историей доставок
+// @Tags Webhooks
+// @Param webhookId path string true "ID подписки"
+// @Success 200
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks/{webhookId} [delete]
+func (h *WebhooksHandlers) Delete(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	webhookID, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_webhook_id", "Invalid webhook id"))
+		return
+	}
+
+	err = h.webhooksRepo.DeleteSubscription(c, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("webhook_not_found", "Webhook not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete webhook", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to delete webhook"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Deliveries godoc
+// @Summary Доставки подписки
+// @Description Последние доставки с кодом ответа, числом попыток и последней ошибкой
+// @Tags Webhooks
+// @Produce json
+// @Param webhookId path string true "ID подписки"
+// @Param status query string false "Статус: pending, delivered, failed"
+// @Param limit query int false "Количество записей (по умолчанию 100, не более 500)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks/{webhookId}/deliveries [get]
+func (h *WebhooksHandlers) Deliveries(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	webhookID, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_webhook_id", "Invalid webhook id"))
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && status != "pending" && status != "delivered" && status != "failed" {
+		c.Error(models.NewValidationError("invalid_status", "Invalid status"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.Error(models.NewValidationError("invalid_limit", "Invalid limit"))
+		return
+	}
+
+	deliveries, err := h.webhooksRepo.FindDeliveries(c, webhookID, status, limit)
+	if err != nil {
+		logger.Error("Failed to fetch webhook deliveries", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to fetch deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver godoc
+// @Summary Повторить доставку
+// @Description Ставит доставку в очередь заново со сброшенным счетчиком попыток
+// @Tags Webhooks
+// @Param deliveryId path string true "ID доставки"
+// @Success 202
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /settings/webhooks/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhooksHandlers) Redeliver(c *gin.Context) {
+	logger := logger.FromContext(c)
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		c.Error(models.NewValidationError("invalid_delivery_id", "Invalid delivery id"))
+		return
+	}
+
+	err = h.webhooksRepo.Redeliver(c, deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Error(models.NewNotFoundError("delivery_not_found", "Delivery not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to redeliver webhook", zap.String("delivery_id", deliveryID.String()), zap.Error(err))
+		c.Error(models.NewInternalError("Failed to redeliver webhook"))
+		return
+	}
+
+	h.dispatcher.Wake()
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,66 @@
+package i18n
+
+// enumLabels — подписи значений перечислений: имя перечисления → код → подпись
+var enumLabels = map[string]map[string]Text{
+	"attendance_type": {
+		"lesson":       {Ru: "урок", Kk: "сабақ", En: "lesson"},
+		"freeze":       {Ru: "заморозка", Kk: "тоқтата тұру", En: "freeze"},
+		"prolongation": {Ru: "пролонгация", Kk: "ұзарту", En: "prolongation"},
+	},
+	"is_active": {
+		"active":   {Ru: "активен", Kk: "белсенді", En: "active"},
+		"inactive": {Ru: "неактивен", Kk: "белсенді емес", En: "inactive"},
+	},
+	"lessons_status": {
+		"scheduled": {Ru: "запланирован", Kk: "жоспарланған", En: "scheduled"},
+		"conducted": {Ru: "проведен", Kk: "өткізілді", En: "conducted"},
+		"missed":    {Ru: "пропущен", Kk: "өткізіп алды", En: "missed"},
+		"canceled":  {Ru: "отменен", Kk: "бас тартылды", En: "canceled"},
+	},
+	"payment_type": {
+		"payment":            {Ru: "оплата", Kk: "төлем", En: "payment"},
+		"prepayment":         {Ru: "предоплата", Kk: "алдын ала төлем", En: "prepayment"},
+		"additional_payment": {Ru: "доплата", Kk: "қосымша төлем", En: "additional payment"},
+	},
+	"enrollment_status": {
+		"active":    {Ru: "активен", Kk: "белсенді", En: "active"},
+		"paused":    {Ru: "приостановлен", Kk: "тоқтатылған", En: "paused"},
+		"completed": {Ru: "завершен", Kk: "аяқталған", En: "completed"},
+		"canceled":  {Ru: "отменен", Kk: "бас тартылды", En: "canceled"},
+	},
+	"course_status": {
+		"active":   {Ru: "активен", Kk: "белсенді", En: "active"},
+		"archived": {Ru: "архив", Kk: "мұрағат", En: "archived"},
+	},
+	"homework_status": {
+		"not_submitted": {Ru: "не сдано", Kk: "тапсырылмаған", En: "not submitted"},
+		"submitted":     {Ru: "сдано", Kk: "тапсырылды", En: "submitted"},
+		"checked":       {Ru: "проверено", Kk: "тексерілді", En: "checked"},
+	},
+	"lead_status": {
+		"new":             {Ru: "новый", Kk: "жаңа", En: "new"},
+		"contacted":       {Ru: "связались", Kk: "байланыс орнатылды", En: "contacted"},
+		"trial_scheduled": {Ru: "пробный назначен", Kk: "сынақ сабағы тағайындалды", En: "trial scheduled"},
+		"trial_conducted": {Ru: "пробный проведен", Kk: "сынақ сабағы өткізілді", En: "trial conducted"},
+		"converted":       {Ru: "конвертирован", Kk: "студентке ауыстырылды", En: "converted"},
+		"lost":            {Ru: "потерян", Kk: "жоғалтылды", En: "lost"},
+	},
+	"student_lifecycle_state": {
+		"lead":      {Ru: "лид", Kk: "лид", En: "lead"},
+		"trial":     {Ru: "пробный период", Kk: "сынақ кезеңі", En: "trial"},
+		"active":    {Ru: "учится", Kk: "оқып жүр", En: "active"},
+		"frozen":    {Ru: "заморожен", Kk: "тоқтатылған", En: "frozen"},
+		"graduated": {Ru: "выпускник", Kk: "түлек", En: "graduated"},
+		"churned":   {Ru: "ушел", Kk: "кетіп қалды", En: "churned"},
+		"archived":  {Ru: "в архиве", Kk: "мұрағатта", En: "archived"},
+	},
+}
+
+// EnumLabel возвращает подпись значения code перечисления enum на языке lang; для неизвестного значения — сам код
+func EnumLabel(lang, enum, code string) string {
+	text, ok := enumLabels[enum][code]
+	if !ok {
+		return code
+	}
+	return text.In(lang)
+}
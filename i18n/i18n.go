@@ -0,0 +1,78 @@
+// Package i18n — тексты API на русском, казахском и английском: сообщения об ошибках
+// по стабильным кодам и подписи значений перечислений. Язык выбирается по Accept-Language
+package i18n
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Поддерживаемые языки (коды BCP 47)
+const (
+	Russian = "ru"
+	Kazakh  = "kk"
+	English = "en"
+)
+
+// Languages — поддерживаемые языки в порядке предпочтения при равных весах Accept-Language
+var Languages = []string{Russian, Kazakh, English}
+
+var matcher = language.NewMatcher([]language.Tag{language.Russian, language.Kazakh, language.English})
+
+// Text — строка на всех поддерживаемых языках
+type Text struct {
+	Ru string
+	Kk string
+	En string
+}
+
+// In возвращает строку на языке lang; если перевода нет — на русском
+func (t Text) In(lang string) string {
+	switch lang {
+	case Kazakh:
+		if t.Kk != "" {
+			return t.Kk
+		}
+	case English:
+		if t.En != "" {
+			return t.En
+		}
+	}
+	return t.Ru
+}
+
+// IsSupported сообщает, поддерживается ли язык
+func IsSupported(lang string) bool {
+	for _, supported := range Languages {
+		if lang == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language. Если заголовка нет или ни один
+// из языков не поддерживается, возвращается fallback. Устаревший код kz понимается как kk
+func Negotiate(acceptLanguage, fallback string) string {
+	acceptLanguage = strings.TrimSpace(acceptLanguage)
+	if acceptLanguage == "" {
+		return fallback
+	}
+	ranges := strings.Split(acceptLanguage, ",")
+	for i, r := range ranges {
+		r = strings.TrimSpace(r)
+		if strings.HasPrefix(strings.ToLower(r), "kz") {
+			ranges[i] = "kk" + r[2:]
+		}
+	}
+	tags, _, err := language.ParseAcceptLanguage(strings.Join(ranges, ","))
+	if err != nil || len(tags) == 0 {
+		return fallback
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return fallback
+	}
+	return Languages[index]
+}
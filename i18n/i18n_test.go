@@ -0,0 +1,140 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "no header", acceptLanguage: "", want: Kazakh},
+		{name: "blank header", acceptLanguage: "  ", want: Kazakh},
+		{name: "russian", acceptLanguage: "ru", want: Russian},
+		{name: "regional variant", acceptLanguage: "en-US,en;q=0.9", want: English},
+		{name: "browser default", acceptLanguage: "ru-RU,ru;q=0.9,en-US;q=0.8", want: Russian},
+		{name: "weights", acceptLanguage: "ru;q=0.1, en;q=0.9", want: English},
+		{name: "skips unsupported", acceptLanguage: "de, en;q=0.5", want: English},
+		{name: "kazakh", acceptLanguage: "kk-KZ", want: Kazakh},
+		{name: "legacy kz", acceptLanguage: "kz", want: Kazakh},
+		{name: "legacy kz with region", acceptLanguage: "kz-KZ", want: Kazakh},
+		{name: "legacy kz upper case", acceptLanguage: "KZ", want: Kazakh},
+		{name: "legacy kz after other", acceptLanguage: "de, kz;q=0.8", want: Kazakh},
+		{name: "unsupported", acceptLanguage: "fr", want: Kazakh},
+		{name: "wildcard", acceptLanguage: "*", want: Kazakh},
+		{name: "explicitly rejected", acceptLanguage: "en;q=0", want: Kazakh},
+		{name: "malformed", acceptLanguage: "garbage;;;", want: Kazakh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// fallback отличается от первого языка, чтобы было видно, что он выбран именно как fallback
+			if got := Negotiate(tt.acceptLanguage, Kazakh); got != tt.want {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextIn(t *testing.T) {
+	full := Text{Ru: "ру", Kk: "қаз", En: "en"}
+	ruOnly := Text{Ru: "ру"}
+
+	tests := []struct {
+		name string
+		text Text
+		lang string
+		want string
+	}{
+		{name: "russian", text: full, lang: Russian, want: "ру"},
+		{name: "kazakh", text: full, lang: Kazakh, want: "қаз"},
+		{name: "english", text: full, lang: English, want: "en"},
+		{name: "unknown language", text: full, lang: "fr", want: "ру"},
+		{name: "missing kazakh", text: ruOnly, lang: Kazakh, want: "ру"},
+		{name: "missing english", text: ruOnly, lang: English, want: "ру"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.text.In(tt.lang); got != tt.want {
+				t.Errorf("In(%s) = %s, want %s", tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	tests := []struct {
+		lang string
+		want bool
+	}{
+		{lang: Russian, want: true},
+		{lang: Kazakh, want: true},
+		{lang: English, want: true},
+		{lang: "kz", want: false},
+		{lang: "en-US", want: false},
+		{lang: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSupported(tt.lang); got != tt.want {
+			t.Errorf("IsSupported(%q) = %v, want %v", tt.lang, got, tt.want)
+		}
+	}
+}
+
+// Каждый код ошибки и каждая подпись перечисления переведены на все языки
+func TestTranslationsComplete(t *testing.T) {
+	for code, text := range messages {
+		if text.Ru == "" || text.Kk == "" || text.En == "" {
+			t.Errorf("message %s: missing translation %+v", code, text)
+		}
+	}
+	for tag, text := range fieldMessages {
+		if text.Ru == "" || text.Kk == "" || text.En == "" {
+			t.Errorf("field message %q: missing translation %+v", tag, text)
+		}
+	}
+	for enum, labels := range enumLabels {
+		for code, text := range labels {
+			if text.Ru == "" || text.Kk == "" || text.En == "" {
+				t.Errorf("enum %s.%s: missing translation %+v", enum, code, text)
+			}
+		}
+	}
+}
+
+func TestFieldMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		lang  string
+		tag   string
+		param string
+		want  string
+	}{
+		{name: "required", lang: English, tag: "required", want: "phone_number is required"},
+		{name: "alias", lang: English, tag: "gte", param: "1", want: "phone_number must be at least 1"},
+		{name: "uuid4 alias", lang: Russian, tag: "uuid4", want: "phone_number: некорректный UUID"},
+		{name: "unknown tag", lang: English, tag: "e164", want: "phone_number is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FieldMessage(tt.lang, "phone_number", tt.tag, tt.param); got != tt.want {
+				t.Errorf("FieldMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumLabel(t *testing.T) {
+	if got := EnumLabel(Kazakh, "attendance_type", "lesson"); got != "сабақ" {
+		t.Errorf("EnumLabel(kk, attendance_type, lesson) = %s", got)
+	}
+	if got := EnumLabel(English, "attendance_type", "unknown"); got != "unknown" {
+		t.Errorf("unknown code = %s, want the code itself", got)
+	}
+	if got := EnumLabel(English, "unknown_enum", "lesson"); got != "lesson" {
+		t.Errorf("unknown enum = %s, want the code itself", got)
+	}
+}
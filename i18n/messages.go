@@ -0,0 +1,208 @@
+package i18n
+
+import "fmt"
+
+// messages — тексты ошибок API по кодам из models.AppError. Для кода без перевода
+// клиент получает исходный текст ошибки
+var messages = map[string]Text{
+	// Общие
+	"internal_error":        {Ru: "Внутренняя ошибка сервера", Kk: "Сервердің ішкі қатесі", En: "Internal server error"},
+	"invalid_request":       {Ru: "Некорректные данные запроса", Kk: "Сұраныс деректері қате", En: "Invalid request data"},
+	"invalid_request_body":  {Ru: "Не удалось прочитать тело запроса", Kk: "Сұраныс денесін оқу мүмкін болмады", En: "Failed to read request body"},
+	"not_found":             {Ru: "Ресурс не найден", Kk: "Ресурс табылмады", En: "Resource not found"},
+	"route_not_found":       {Ru: "Маршрут не найден", Kk: "Маршрут табылмады", En: "Route not found"},
+	"already_exists":        {Ru: "Такая запись уже существует", Kk: "Мұндай жазба бар", En: "Resource already exists"},
+	"reference_violation":   {Ru: "Связанная запись не существует или используется", Kk: "Байланысты жазба жоқ немесе қолданыста", En: "Referenced resource does not exist or is in use"},
+	"constraint_violation":  {Ru: "Данные нарушают ограничение", Kk: "Деректер шектеуді бұзады", En: "Request violates a data constraint"},
+	"permission_denied":     {Ru: "Недостаточно прав", Kk: "Құқық жеткіліксіз", En: "Permission denied"},
+	"invalid_date":          {Ru: "Неверный формат даты. Используйте ДД.ММ.ГГГГ", Kk: "Күн пішімі қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid date format. Use DD.MM.YYYY"},
+	"invalid_date_range":    {Ru: "Дата начала должна быть раньше даты окончания", Kk: "Басталу күні аяқталу күнінен ерте болуы керек", En: "Start date must be before end date"},
+	"invalid_period":        {Ru: "Неверный период. Используйте ДД.ММ.ГГГГ", Kk: "Кезең қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid period. Use DD.MM.YYYY"},
+	"invalid_limit":         {Ru: "Неверный limit", Kk: "limit мәні қате", En: "Invalid limit"},
+	"invalid_status":        {Ru: "Неверный статус", Kk: "Мәртебе қате", En: "Invalid status"},
+	"invalid_phone_number":  {Ru: "Неверный номер телефона", Kk: "Телефон нөмірі қате", En: "Invalid phone number"},
+	"invalid_schedule":      {Ru: "Неверное расписание", Kk: "Кесте қате", En: "Invalid schedule"},
+	"file_required":         {Ru: "Файл обязателен", Kk: "Файл міндетті", En: "File is required"},
+	"file_too_large":        {Ru: "Файл слишком большой", Kk: "Файл тым үлкен", En: "File is too large"},
+	"invalid_template":      {Ru: "Некорректный шаблон", Kk: "Үлгі қате", En: "Invalid template"},
+	"unsupported_event":     {Ru: "Событие не поддерживается", Kk: "Оқиғаға қолдау көрсетілмейді", En: "Unsupported event"},
+	"invalid_signature":     {Ru: "Неверная подпись", Kk: "Қолтаңба қате", En: "Invalid signature"},
+	"crm_disabled":          {Ru: "Интеграция с CRM отключена", Kk: "CRM интеграциясы өшірулі", En: "CRM integration is disabled"},
+	"crm_delivery_failed":   {Ru: "Не удалось доставить событие в CRM", Kk: "Оқиғаны CRM-ге жеткізу мүмкін болмады", En: "CRM delivery failed"},
+	"crm_branch_required":   {Ru: "Для создания студента из CRM нужен филиал", Kk: "CRM-нен студент құру үшін филиал қажет", En: "Branch is required to create student from CRM"},
+	"crm_student_in_trash":  {Ru: "Студент в корзине. Восстановите его, чтобы синхронизировать из CRM", Kk: "Студент себетте. CRM-нен синхрондау үшін оны қалпына келтіріңіз", En: "Student is in the trash. Restore it before syncing from CRM"},
+	"dead_letter_not_found": {Ru: "Недоставленное событие не найдено", Kk: "Жеткізілмеген оқиға табылмады", En: "Dead letter not found"},
+
+	// Аутентификация и доступ
+	"invalid_credentials":            {Ru: "Неверный email или пароль", Kk: "Email немесе құпиясөз қате", En: "Invalid credentials"},
+	"invalid_token":                  {Ru: "Недействительный токен", Kk: "Токен жарамсыз", En: "Invalid token"},
+	"invalid_token_claims":           {Ru: "Недействительные данные токена", Kk: "Токен деректері жарамсыз", En: "Invalid token claims"},
+	"invalid_token_subject":          {Ru: "Недействительный субъект токена", Kk: "Токен субъектісі жарамсыз", En: "Invalid token subject"},
+	"no_session_token":               {Ru: "Нет токена сессии", Kk: "Сессия токені жоқ", En: "No session token"},
+	"invalid_session_token":          {Ru: "Недействительный токен сессии", Kk: "Сессия токені жарамсыз", En: "Invalid session token"},
+	"expired_session_token":          {Ru: "Срок действия сессии истек", Kk: "Сессияның мерзімі өтті", En: "Expired session token"},
+	"invalid_or_expired_reset_token": {Ru: "Ссылка для сброса пароля недействительна или устарела", Kk: "Құпиясөзді қалпына келтіру сілтемесі жарамсыз немесе мерзімі өткен", En: "Invalid or expired reset token"},
+	"user_not_found":                 {Ru: "Пользователь не найден", Kk: "Пайдаланушы табылмады", En: "User not found"},
+	"user_not_registered":            {Ru: "Пользователь не зарегистрирован", Kk: "Пайдаланушы тіркелмеген", En: "User is not registered"},
+	"email_already_exists":           {Ru: "Пользователь с таким email уже существует", Kk: "Мұндай email-мен пайдаланушы бар", En: "Email already exists"},
+	"email_in_trash":                 {Ru: "Пользователь с таким email в корзине. Восстановите его", Kk: "Мұндай email-мен пайдаланушы себетте. Оны қалпына келтіріңіз", En: "User with this email is in the trash. Restore it instead"},
+	"email_domain_not_allowed":       {Ru: "Домен email не разрешен", Kk: "Email доменіне рұқсат жоқ", En: "Email domain is not allowed"},
+	"email_not_verified":             {Ru: "Email не подтвержден", Kk: "Email расталмаған", En: "Email is not verified"},
+	"invalid_oidc_state":             {Ru: "Неверный параметр state", Kk: "state параметрі қате", En: "Invalid OIDC state"},
+	"oidc_login_rejected":            {Ru: "Вход через провайдера отклонен", Kk: "Провайдер арқылы кіру қабылданбады", En: "OIDC login was rejected"},
+	"oidc_code_exchange_failed":      {Ru: "Не удалось обменять код авторизации", Kk: "Авторизация кодын алмастыру мүмкін болмады", En: "Failed to exchange authorization code"},
+	"missing_id_token":               {Ru: "Провайдер не вернул ID-токен", Kk: "Провайдер ID-токенді қайтармады", En: "Missing ID token"},
+	"invalid_id_token":               {Ru: "Недействительный ID-токен", Kk: "ID-токен жарамсыз", En: "Invalid ID token"},
+	"invalid_id_token_claims":        {Ru: "Недействительные данные ID-токена", Kk: "ID-токен деректері жарамсыз", En: "Invalid ID token claims"},
+	"invalid_id_token_nonce":         {Ru: "Неверный nonce ID-токена", Kk: "ID-токеннің nonce мәні қате", En: "Invalid ID token nonce"},
+	"branch_header_required":         {Ru: "Заголовок X-Branch-ID обязателен", Kk: "X-Branch-ID тақырыбы міндетті", En: "X-Branch-ID header is required"},
+	"invalid_branch_header":          {Ru: "Неверный заголовок X-Branch-ID", Kk: "X-Branch-ID тақырыбы қате", En: "Invalid X-Branch-ID header"},
+	"branch_access_denied":           {Ru: "Нет доступа к этому филиалу", Kk: "Бұл филиалға қолжетімділік жоқ", En: "No access to this branch"},
+	"lesson_create_forbidden":        {Ru: "Нет прав на создание уроков", Kk: "Сабақ құруға құқық жоқ", En: "You are not allowed to create lessons"},
+	"lesson_curator_forbidden":       {Ru: "Куратор может проводить уроки только от своего имени", Kk: "Куратор сабақты тек өз атынан өткізе алады", En: "You can only record your own lessons"},
+	"attendance_type_forbidden":      {Ru: "Нет прав на записи посещаемости этого типа", Kk: "Бұл түрдегі қатысу жазбаларына құқық жоқ", En: "You are not allowed to manage this type of attendance"},
+
+	// Идентификаторы
+	"invalid_attachment_id":  {Ru: "Неверный идентификатор файла", Kk: "Файл идентификаторы қате", En: "Invalid attachment id"},
+	"invalid_attendance_id":  {Ru: "Неверный идентификатор записи посещаемости", Kk: "Қатысу жазбасының идентификаторы қате", En: "Invalid attendance id"},
+	"invalid_branch_id":      {Ru: "Неверный идентификатор филиала", Kk: "Филиал идентификаторы қате", En: "Invalid branch id"},
+	"invalid_certificate_id": {Ru: "Неверный идентификатор сертификата", Kk: "Сертификат идентификаторы қате", En: "Invalid certificate id"},
+	"invalid_course_id":      {Ru: "Неверный идентификатор курса", Kk: "Курс идентификаторы қате", En: "Invalid course id"},
+	"invalid_curator_id":     {Ru: "Неверный идентификатор куратора", Kk: "Куратор идентификаторы қате", En: "Invalid curator id"},
+	"invalid_dead_letter_id": {Ru: "Неверный идентификатор недоставленного события", Kk: "Жеткізілмеген оқиға идентификаторы қате", En: "Invalid dead letter id"},
+	"invalid_delivery_id":    {Ru: "Неверный идентификатор доставки", Kk: "Жеткізу идентификаторы қате", En: "Invalid delivery id"},
+	"invalid_enrollment_id":  {Ru: "Неверный идентификатор зачисления", Kk: "Курсқа жазылу идентификаторы қате", En: "Invalid enrollment id"},
+	"invalid_group_id":       {Ru: "Неверный идентификатор группы", Kk: "Топ идентификаторы қате", En: "Invalid group id"},
+	"invalid_homework_id":    {Ru: "Неверный идентификатор задания", Kk: "Тапсырма идентификаторы қате", En: "Invalid homework id"},
+	"invalid_lead_id":        {Ru: "Неверный идентификатор лида", Kk: "Лид идентификаторы қате", En: "Invalid lead id"},
+	"invalid_module_id":      {Ru: "Неверный идентификатор модуля", Kk: "Модуль идентификаторы қате", En: "Invalid module id"},
+	"invalid_role_id":        {Ru: "Неверный идентификатор роли", Kk: "Рөл идентификаторы қате", En: "Invalid role id"},
+	"invalid_student_id":     {Ru: "Неверный идентификатор студента", Kk: "Студент идентификаторы қате", En: "Invalid student id"},
+	"invalid_submission_id":  {Ru: "Неверный идентификатор сдачи", Kk: "Тапсырылған жұмыс идентификаторы қате", En: "Invalid submission id"},
+	"invalid_trash_item_id":  {Ru: "Неверный идентификатор записи в корзине", Kk: "Себеттегі жазбаның идентификаторы қате", En: "Invalid trash item id"},
+	"invalid_trash_type":     {Ru: "Неизвестный тип записей корзины: students, users, courses или attendance", Kk: "Себет жазбаларының түрі белгісіз: students, users, courses немесе attendance", En: "Unknown trash item type: use students, users, courses or attendance"},
+	"invalid_trial_id":       {Ru: "Неверный идентификатор пробного урока", Kk: "Сынақ сабағының идентификаторы қате", En: "Invalid trial id"},
+	"invalid_user_id":        {Ru: "Неверный идентификатор пользователя", Kk: "Пайдаланушы идентификаторы қате", En: "Invalid user id"},
+	"invalid_webhook_id":     {Ru: "Неверный идентификатор вебхука", Kk: "Вебхук идентификаторы қате", En: "Invalid webhook id"},
+
+	// Даты
+	"invalid_created_date":      {Ru: "Неверная дата создания. Используйте ДД.ММ.ГГГГ", Kk: "Құрылған күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid created date format. Use DD.MM.YYYY"},
+	"invalid_due_date":          {Ru: "Неверный срок сдачи. Используйте ДД.ММ.ГГГГ", Kk: "Тапсыру мерзімі қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid due date format. Use DD.MM.YYYY"},
+	"invalid_end_date":          {Ru: "Неверная дата окончания. Используйте ДД.ММ.ГГГГ", Kk: "Аяқталу күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid end date format. Use DD.MM.YYYY"},
+	"invalid_feedback_date":     {Ru: "Неверная дата отзыва. Используйте ДД.ММ.ГГГГ", Kk: "Пікір күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid feedback date format. Use DD.MM.YYYY"},
+	"invalid_from_date":         {Ru: "Неверная дата from. Используйте ДД.ММ.ГГГГ", Kk: "from күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid from date format. Use DD.MM.YYYY"},
+	"invalid_to_date":           {Ru: "Неверная дата to. Используйте ДД.ММ.ГГГГ", Kk: "to күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid to date format. Use DD.MM.YYYY"},
+	"invalid_lesson_date":       {Ru: "Неверная дата урока. Используйте ДД.ММ.ГГГГ", Kk: "Сабақ күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid lesson date format. Use DD.MM.YYYY"},
+	"invalid_payment_date":      {Ru: "Неверная дата оплаты. Используйте ДД.ММ.ГГГГ", Kk: "Төлем күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid payment date format. Use DD.MM.YYYY"},
+	"invalid_prolongation_date": {Ru: "Неверная дата пролонгации. Используйте ДД.ММ.ГГГГ", Kk: "Ұзарту күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid prolongation date format. Use DD.MM.YYYY"},
+	"invalid_start_date":        {Ru: "Неверная дата начала. Используйте ДД.ММ.ГГГГ", Kk: "Басталу күні қате. КК.АА.ЖЖЖЖ пішімін қолданыңыз", En: "Invalid start date format. Use DD.MM.YYYY"},
+	"invalid_trial_date":        {Ru: "Неверная дата пробного урока. Используйте ДД.ММ.ГГГГ ЧЧ:ММ", Kk: "Сынақ сабағының күні қате. КК.АА.ЖЖЖЖ СС:ММ пішімін қолданыңыз", En: "Invalid trial date format. Use DD.MM.YYYY HH:MM"},
+
+	// Не найдено
+	"assignment_not_found":             {Ru: "Назначение куратора не найдено", Kk: "Куратор тағайындауы табылмады", En: "Assignment not found"},
+	"attachment_not_found":             {Ru: "Файл не найден", Kk: "Файл табылмады", En: "Attachment not found"},
+	"attendance_record_not_found":      {Ru: "Запись посещаемости не найдена", Kk: "Қатысу жазбасы табылмады", En: "Attendance record not found"},
+	"branch_not_found":                 {Ru: "Филиал не найден", Kk: "Филиал табылмады", En: "Branch not found"},
+	"certificate_not_found":            {Ru: "Сертификат не найден", Kk: "Сертификат табылмады", En: "Certificate not found"},
+	"course_not_found":                 {Ru: "Курс не найден", Kk: "Курс табылмады", En: "Course not found"},
+	"course_module_or_topic_not_found": {Ru: "Курс, модуль или тема не найдены", Kk: "Курс, модуль немесе тақырып табылмады", En: "Course, module or topic not found"},
+	"course_or_branch_not_found":       {Ru: "Курс или филиал не найден", Kk: "Курс немесе филиал табылмады", En: "Course or branch not found"},
+	"course_or_criterion_not_found":    {Ru: "Курс или критерий не найден", Kk: "Курс немесе критерий табылмады", En: "Course or criterion not found"},
+	"course_or_curator_not_found":      {Ru: "Курс или куратор не найден", Kk: "Курс немесе куратор табылмады", En: "Course or curator not found"},
+	"criterion_not_found":              {Ru: "Критерий не найден", Kk: "Критерий табылмады", En: "Criterion not found"},
+	"curator_course_not_found":         {Ru: "Курс не закреплен за куратором", Kk: "Курс кураторға бекітілмеген", En: "Course is not assigned to this curator"},
+	"curator_not_found":                {Ru: "Куратор не найден", Kk: "Куратор табылмады", En: "Curator not found"},
+	"curator_or_course_not_found":      {Ru: "Куратор или курс не найден", Kk: "Куратор немесе курс табылмады", En: "Curator or course not found"},
+	"curator_or_student_not_found":     {Ru: "Куратор или студент не найден", Kk: "Куратор немесе студент табылмады", En: "Curator or student not found"},
+	"delivery_not_found":               {Ru: "Доставка не найдена", Kk: "Жеткізу табылмады", En: "Delivery not found"},
+	"enrollment_not_found":             {Ru: "Зачисление не найдено", Kk: "Курсқа жазылу табылмады", En: "Enrollment not found"},
+	"group_not_found":                  {Ru: "Группа не найдена", Kk: "Топ табылмады", En: "Group not found"},
+	"group_or_student_not_found":       {Ru: "Группа или студент не найдены", Kk: "Топ немесе студент табылмады", En: "Group or student not found"},
+	"homework_not_found":               {Ru: "Задание не найдено", Kk: "Тапсырма табылмады", En: "Homework not found"},
+	"lead_not_found":                   {Ru: "Лид не найден", Kk: "Лид табылмады", En: "Lead not found"},
+	"lesson_not_found":                 {Ru: "Урок не найден", Kk: "Сабақ табылмады", En: "Lesson not found"},
+	"module_not_found":                 {Ru: "Модуль не найден", Kk: "Модуль табылмады", En: "Module not found"},
+	"role_not_found":                   {Ru: "Роль не найдена", Kk: "Рөл табылмады", En: "Role not found"},
+	"student_not_assigned_to_curator":  {Ru: "Студент не закреплен за этим куратором", Kk: "Студент бұл кураторға бекітілмеген", En: "Student is not assigned to this curator"},
+	"student_not_found":                {Ru: "Студент не найден", Kk: "Студент табылмады", En: "Student not found"},
+	"student_or_course_not_found":      {Ru: "Студент или курс не найден", Kk: "Студент немесе курс табылмады", En: "Student or course not found"},
+	"student_or_module_not_found":      {Ru: "Студент или модуль не найден", Kk: "Студент немесе модуль табылмады", En: "Student or module not found"},
+	"submission_not_found":             {Ru: "Сдача задания не найдена", Kk: "Тапсырылған жұмыс табылмады", En: "Submission not found"},
+	"topic_not_found":                  {Ru: "Тема не найдена", Kk: "Тақырып табылмады", En: "Topic not found"},
+	"trash_item_not_found":             {Ru: "Запись в корзине не найдена", Kk: "Себеттен жазба табылмады", En: "Item not found in trash"},
+	"trial_lesson_not_found":           {Ru: "Пробный урок не найден", Kk: "Сынақ сабағы табылмады", En: "Trial lesson not found"},
+	"webhook_not_found":                {Ru: "Вебхук не найден", Kk: "Вебхук табылмады", En: "Webhook not found"},
+
+	// Бизнес-правила
+	"branch_required":              {Ru: "Филиал обязателен", Kk: "Филиал міндетті", En: "Branch is required"},
+	"branch_in_use":                {Ru: "В филиале есть студенты или курсы", Kk: "Филиалда студенттер немесе курстар бар", En: "Branch still has students or courses"},
+	"course_in_use":                {Ru: "На курс зачислены студенты", Kk: "Курсқа студенттер жазылған", En: "Course has enrolled students"},
+	"invalid_lifecycle_transition": {Ru: "Такой переход между состояниями студента запрещен", Kk: "Студент күйлері арасындағы мұндай ауысуға тыйым салынған", En: "Student lifecycle transition is not allowed"},
+	"invalid_lifecycle_state":      {Ru: "Неизвестное состояние студента", Kk: "Студент күйі белгісіз", En: "Unknown student lifecycle state"},
+	"invalid_is_active":            {Ru: "Неизвестное значение is_active: active или inactive", Kk: "is_active мәні белгісіз: active немесе inactive", En: "Unknown is_active value: use active or inactive"},
+	"invalid_enrollment_status":    {Ru: "Неизвестный статус зачисления", Kk: "Курсқа жазылу мәртебесі белгісіз", En: "Unknown enrollment status"},
+	"invalid_age_range":            {Ru: "age_min не может быть больше age_max", Kk: "age_min мәні age_max мәнінен аспауы керек", En: "age_min must not exceed age_max"},
+	"invalid_score":                {Ru: "Критерий не относится к курсу или балл вне диапазона", Kk: "Критерий курсқа жатпайды немесе балл ауқымнан тыс", En: "Criterion does not belong to the course or score is out of range"},
+	"invalid_score_range":          {Ru: "min_score должен быть меньше max_score", Kk: "min_score мәні max_score мәнінен кіші болуы керек", En: "min_score must be less than max_score"},
+	"topic_not_in_course":          {Ru: "Тема не относится к курсу", Kk: "Тақырып курсқа жатпайды", En: "Topic does not belong to the course"},
+	"lesson_data_required":         {Ru: "Для типа lesson нужны данные урока", Kk: "lesson түрі үшін сабақ деректері қажет", En: "Lesson data is required for type 'lesson'"},
+	"freeze_data_required":         {Ru: "Для типа freeze нужны данные заморозки", Kk: "freeze түрі үшін тоқтата тұру деректері қажет", En: "Freeze data is required for type 'freeze'"},
+	"prolongation_data_required":   {Ru: "Для типа prolongation нужны данные пролонгации", Kk: "prolongation түрі үшін ұзарту деректері қажет", En: "Prolongation data is required for type 'prolongation'"},
+	"enrollment_not_completed":     {Ru: "Зачисление не завершено", Kk: "Курсқа жазылу аяқталмаған", En: "Enrollment is not completed"},
+	"field_not_updatable":          {Ru: "Эти поля не меняются при обновлении карточки: состояние — через /lifecycle, курс и куратор — через зачисления", Kk: "Бұл өрістер карточканы жаңартқанда өзгермейді: күйі — /lifecycle арқылы, курс пен куратор — оқуға қабылдау арқылы", En: "Some fields cannot be changed by this request: use /lifecycle for the state and enrollments for the course and curator"},
+	"curator_required":             {Ru: "У группы нет куратора. Укажите curator_id", Kk: "Топтың кураторы жоқ. curator_id көрсетіңіз", En: "Group has no curator. Specify curator_id"},
+	"group_has_no_students":        {Ru: "В группе нет студентов", Kk: "Топта студенттер жоқ", En: "Group has no students"},
+	"student_not_enrolled":         {Ru: "Студент не зачислен на курс группы", Kk: "Студент топтың курсына жазылмаған", En: "Student is not enrolled in the group's course"},
+	"student_not_in_group":         {Ru: "Студент не состоит в группе", Kk: "Студент топ құрамында жоқ", En: "Student is not a member of the group"},
+	"homework_target_required":     {Ru: "Укажите ровно одно из полей attendance_id или group_lesson_id", Kk: "attendance_id немесе group_lesson_id өрістерінің дәл біреуін көрсетіңіз", En: "Exactly one of attendance_id or group_lesson_id is required"},
+	"if_match_required":            {Ru: "Заголовок If-Match обязателен. Получите ETag запросом GET", Kk: "If-Match тақырыбы міндетті. ETag мәнін GET сұранысымен алыңыз", En: "If-Match header is required. Get the ETag with a GET request"},
+	"version_conflict":             {Ru: "Запись уже изменил другой пользователь. Загрузите ее заново", Kk: "Жазбаны басқа пайдаланушы өзгертті. Оны қайта жүктеңіз", En: "Resource was modified by another request. Reload it and try again"},
+	"lead_converted":               {Ru: "Лид уже переведен в студенты", Kk: "Лид студентке ауыстырылған", En: "Lead is already converted"},
+	"lead_course_required":         {Ru: "Для перевода лида нужен курс", Kk: "Лидті ауыстыру үшін курс қажет", En: "Course is required to convert lead"},
+	"student_phone_required":       {Ru: "Телефон студента обязателен", Kk: "Студенттің телефоны міндетті", En: "Student's phone number is required"},
+	"invalid_student_phone_number": {Ru: "Неверный номер телефона студента", Kk: "Студенттің телефон нөмірі қате", En: "Invalid student's phone number"},
+	"invalid_parent_phone_number":  {Ru: "Неверный номер телефона родителя", Kk: "Ата-ананың телефон нөмірі қате", En: "Invalid parent's phone number"},
+	"role_required":                {Ru: "Параметр role обязателен", Kk: "role параметрі міндетті", En: "Role parameter is required"},
+	"role_id_required":             {Ru: "Параметр roleId обязателен", Kk: "roleId параметрі міндетті", En: "roleId is required"},
+	"invalid_role_parameter":       {Ru: "Неверный параметр role", Kk: "role параметрі қате", En: "Invalid role parameter"},
+	"unknown_role":                 {Ru: "Неизвестная роль", Kk: "Белгісіз рөл", En: "Unknown role"},
+}
+
+// Message возвращает текст ошибки с кодом code на языке lang
+func Message(lang, code string) (string, bool) {
+	text, ok := messages[code]
+	if !ok {
+		return "", false
+	}
+	return text.In(lang), true
+}
+
+// fieldMessages — шаблоны ошибок валидации полей по тегу валидатора; %[1]s — поле, %[2]s — параметр тега
+var fieldMessages = map[string]Text{
+	"required":     {Ru: "%[1]s: обязательное поле", Kk: "%[1]s: міндетті өріс", En: "%[1]s is required"},
+	"email":        {Ru: "%[1]s: некорректный email", Kk: "%[1]s: email қате", En: "%[1]s must be a valid email"},
+	"uuid":         {Ru: "%[1]s: некорректный UUID", Kk: "%[1]s: UUID қате", En: "%[1]s must be a valid UUID"},
+	"oneof":        {Ru: "%[1]s: допустимые значения: %[2]s", Kk: "%[1]s: рұқсат етілген мәндер: %[2]s", En: "%[1]s must be one of: %[2]s"},
+	"min":          {Ru: "%[1]s: не меньше %[2]s", Kk: "%[1]s: кемінде %[2]s", En: "%[1]s must be at least %[2]s"},
+	"max":          {Ru: "%[1]s: не больше %[2]s", Kk: "%[1]s: ең көбі %[2]s", En: "%[1]s must be at most %[2]s"},
+	"gt":           {Ru: "%[1]s: должно быть больше %[2]s", Kk: "%[1]s: мәні %[2]s мәнінен үлкен болуы керек", En: "%[1]s must be greater than %[2]s"},
+	"lt":           {Ru: "%[1]s: должно быть меньше %[2]s", Kk: "%[1]s: мәні %[2]s мәнінен кіші болуы керек", En: "%[1]s must be less than %[2]s"},
+	"len":          {Ru: "%[1]s: длина должна быть %[2]s", Kk: "%[1]s: ұзындығы %[2]s болуы керек", En: "%[1]s must have length %[2]s"},
+	"invalid_type": {Ru: "%[1]s: неверный тип значения", Kk: "%[1]s: мән түрі қате", En: "%[1]s must be %[2]s"},
+	"":             {Ru: "%[1]s: некорректное значение", Kk: "%[1]s: мән қате", En: "%[1]s is invalid"},
+}
+
+// fieldTagAliases сводит родственные теги валидатора к одному шаблону
+var fieldTagAliases = map[string]string{"uuid4": "uuid", "gte": "min", "lte": "max"}
+
+// FieldMessage возвращает текст ошибки поля field, не прошедшего проверку tag с параметром param
+func FieldMessage(lang, field, tag, param string) string {
+	if alias, ok := fieldTagAliases[tag]; ok {
+		tag = alias
+	}
+	text, ok := fieldMessages[tag]
+	if !ok {
+		text = fieldMessages[""]
+	}
+	return fmt.Sprintf(text.In(lang), field, param)
+}
@@ -1,22 +1,77 @@
-	package logger
+package logger
 
-	import (
-		"sync"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
-		"go.uber.org/zap"
-	)
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
-	var (
-		instance *zap.Logger
-		once     sync.Once
-	)
+var (
+	instance atomic.Pointer[zap.Logger]
+	once     sync.Once
+)
 
-	func GetLogger() *zap.Logger {
-		once.Do(func() {
+// GetLogger возвращает глобальный логгер. До вызова Configure это production-логгер zap (JSON, info)
+func GetLogger() *zap.Logger {
+	once.Do(func() {
+		if instance.Load() == nil {
 			logger, _ := zap.NewProduction()
-			defer logger.Sync()
-			instance = logger
-		})
+			instance.CompareAndSwap(nil, logger)
+		}
+	})
+	return instance.Load()
+}
 
-		return instance
+// Configure пересоздает глобальный логгер с уровнем (debug, info, warn, error)
+// и форматом: json — для сбора логов, console — для локальной разработки
+func Configure(level, format string) error {
+	var cfg zap.Config
+	switch strings.ToLower(format) {
+	case "", "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return fmt.Errorf("unknown log format %q", format)
 	}
+
+	if level != "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		cfg.Level = zap.NewAtomicLevelAt(parsed)
+	}
+
+	logger, err := cfg.Build()
+	if err != nil {
+		return err
+	}
+	if previous := instance.Swap(logger); previous != nil {
+		previous.Sync()
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// WithContext кладет логгер запроса в контекст
+func WithContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса с request_id, user_id и ролью, если он есть в контексте,
+// иначе глобальный логгер. Принимает и *gin.Context (через ContextWithFallback)
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+			return logger
+		}
+	}
+	return GetLogger()
+}
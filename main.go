@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"it_school/config"
+	"it_school/crm"
 	"it_school/docs"
+	"it_school/events"
 	"it_school/handlers"
+	"it_school/i18n"
 	"it_school/logger"
+	"it_school/metrics"
 	"it_school/middlewares"
+	"it_school/redact"
 	"it_school/repositories"
+	"it_school/tracing"
 	"it_school/utils"
+	"it_school/webhooks"
+	"it_school/workers"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/viper"
 
@@ -20,14 +34,52 @@ import (
 	ginzap "github.com/gin-contrib/zap"
 	swaggerfiles "github.com/swaggo/files"
 	swagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func main() {
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
+	// Служебные команды (например, `it_school cleanup`) выполняются без запуска сервера
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// Конфигурация читается до создания логгера: от нее зависят уровень и формат логов
+	configErr := loadConfig()
+	var logConfigErr error
+	if configErr == nil {
+		logConfigErr = logger.Configure(config.Config.LogLevel, config.Config.LogFormat)
+		redact.SetEnabled(config.Config.LogRedactPII)
+	}
 
 	logger := logger.GetLogger()
+	if configErr != nil {
+		logger.Fatal("Failed to load config", zap.Error(configErr))
+	}
+	if logConfigErr != nil {
+		logger.Warn("Invalid log configuration, using defaults", zap.Error(logConfigErr))
+	}
+	if !i18n.IsSupported(config.Config.DefaultLanguage) {
+		logger.Warn("Unsupported default language, using ru", zap.String("language", config.Config.DefaultLanguage))
+		config.Config.DefaultLanguage = i18n.Russian
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     config.Config.TracingExporter,
+		ServiceName:  config.Config.TracingServiceName,
+		SampleRatio:  config.Config.TracingSampleRatio,
+		OTLPEndpoint: config.Config.TracingOTLPEndpoint,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	// Репозитории получают *gin.Context и читают филиал из контекста запроса
+	r.ContextWithFallback = true
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -36,30 +88,41 @@ func main() {
 	}()
 
 	r.Use(
-		ginzap.Ginzap(logger, time.RFC3339, true),
-		ginzap.RecoveryWithZap(logger, true),
+		otelgin.Middleware(config.Config.TracingServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+			return !probePaths[req.URL.Path]
+		})),
+		middlewares.RequestIDMiddleware(),
+		middlewares.LanguageMiddleware(config.Config.DefaultLanguage),
+		ginzap.GinzapWithConfig(logger, &ginzap.Config{
+			TimeFormat: time.RFC3339,
+			UTC:        true,
+			// Пробы оркестратора и сбор метрик не засоряют журнал запросов
+			SkipPaths: []string{"/healthz", "/readyz", "/metrics"},
+			Context:   accessLogFields,
+		}),
+		ginzap.CustomRecoveryWithZap(logger, true, middlewares.RecoveryHandler),
+		middlewares.MetricsMiddleware(),
+		middlewares.ErrorMiddleware(),
 	)
+	r.NoRoute(middlewares.NoRouteHandler)
 
 	corsConfig := cors.Config{
 		AllowAllOrigins: true,
 		AllowHeaders:    []string{"*"},
 		AllowMethods:    []string{"*"},
+		ExposeHeaders:   []string{middlewares.RequestIDHeader, "Content-Language", "ETag"},
 	}
 
 	r.Use(cors.New(corsConfig))
 
-	logger.Info("Loading configuration...")
-	err := loadConfig()
-	if err != nil {
-		logger.Fatal("Failed to load config", zap.Error(err))
-	}
-
 	logger.Info("Connecting to database...")
 	conn, err := connectToDb()
 	if err != nil {
 		logger.Fatal("Database connection failed", zap.Error(err))
 	}
 
+	metrics.RegisterPool(conn)
+
 	r.Use(func(c *gin.Context) {
 		c.Set("db", conn)
 		c.Next()
@@ -72,6 +135,12 @@ func main() {
 		})
 	})
 
+	// Liveness/readiness для оркестратора и метрики Prometheus
+	HealthHandlers := handlers.NewHealthHandlers(repositories.NewHealthRepository(conn))
+	r.GET("/healthz", HealthHandlers.Liveness)
+	r.GET("/readyz", HealthHandlers.Readiness)
+	r.GET("/metrics", middlewares.MetricsTokenMiddleware(config.Config.MetricsToken), gin.WrapH(metrics.Handler()))
+
 	AuthRepository := repositories.NewAuthRepository(conn)
 	UsersRepository := repositories.NewRUsersRepository(conn)
 	SessionsRepository := repositories.NewSessionsRepository(conn)
@@ -80,15 +149,77 @@ func main() {
 	CourseRepository := repositories.NewCourseRepository(conn)
 	StudentsRepository := repositories.NewStudentsRepository(conn)
 	AttendanceRepository := repositories.NewAttendanceRepository(conn)
+	BranchesRepository := repositories.NewBranchesRepository(conn)
+	GroupsRepository := repositories.NewGroupsRepository(conn)
+	EnrollmentsRepository := repositories.NewEnrollmentsRepository(conn)
+	HomeworkRepository := repositories.NewHomeworkRepository(conn)
+	GradesRepository := repositories.NewGradesRepository(conn)
+	CertificatesRepository := repositories.NewCertificatesRepository(conn)
+	LeadsRepository := repositories.NewLeadsRepository(conn)
+	CrmRepository := repositories.NewCrmRepository(conn)
+	WebhooksRepository := repositories.NewWebhooksRepository(conn)
+	TrashRepository := repositories.NewTrashRepository(conn)
 
 	if err := utils.SeedAdminAndRoles(RolesRepository, UsersRepository); err != nil {
 		logger.Fatal("Couldn't create admin", zap.Error(err))
 	}
 
+	if err := utils.SeedDefaultBranch(BranchesRepository); err != nil {
+		logger.Fatal("Couldn't create default branch", zap.Error(err))
+	}
+
+	// Фоновые воркеры запускаются после старта HTTP-сервера и останавливаются до закрытия пула
+	backgroundWorkers := workers.NewRegistry()
+
+	// Исходящая синхронизация с CRM; без CRM_WEBHOOK_URL клиент отключен
+	crmClient := crm.NewClient(config.Config.CrmWebhookURL, config.Config.CrmWebhookSecret, config.Config.CrmWebhookMaxAttempts, CrmRepository)
+	events.Subscribe(crmClient.Handle)
+	backgroundWorkers.Go("crm-sync", crmClient.Run)
+
+	// Доставка доменных событий подписчикам /settings/webhooks
+	webhookDispatcher := webhooks.NewDispatcher(WebhooksRepository, config.Config.WebhookMaxAttempts)
+	events.Subscribe(webhookDispatcher.Handle)
+	backgroundWorkers.Go("webhook-dispatcher", webhookDispatcher.Run)
+
+	// События записываются в outbox вместе с изменением данных и передаются подписчикам в фоне
+	outboxDispatcher := events.NewDispatcher(conn)
+	backgroundWorkers.Go("outbox-dispatcher", outboxDispatcher.Run)
+
+	backgroundWorkers.Every("maintenance", config.Config.MaintenanceInterval, workers.Maintenance(SessionsRepository, AuthRepository, outboxDispatcher, config.Config.OutboxRetention))
+	if config.Config.SMTPHost != "" {
+		backgroundWorkers.Every("trial-reminders", config.Config.TrialReminderInterval,
+			workers.TrialReminders(LeadsRepository, config.Config.TrialReminderAhead))
+	}
+	if config.Config.TrashRetention > 0 {
+		backgroundWorkers.Every("trash-purge", config.Config.TrashPurgeInterval,
+			workers.PurgeTrash(TrashRepository, config.Config.TrashRetention))
+	}
+	if config.Config.LifecycleFreezeInterval > 0 {
+		backgroundWorkers.Every("lifecycle-freezes", config.Config.LifecycleFreezeInterval,
+			workers.LifecycleFreezes(StudentsRepository))
+	}
+
 	StudentsHandlers := handlers.NewStudentsHandlers(StudentsRepository)
 	AttendanceHandlers := handlers.NewAttendanceHandlers(AttendanceRepository)
 	CuratorsHandlers := handlers.NewCuratorsHandler(CuratorsRepository)
 	CourseHandlers := handlers.NewCourseHandlers(CourseRepository)
+	BranchesHandlers := handlers.NewBranchesHandlers(BranchesRepository)
+	EnumsHandlers := handlers.NewEnumsHandlers()
+	GroupsHandlers := handlers.NewGroupsHandlers(GroupsRepository)
+
+	fileStorage, err := utils.NewLocalStorage(config.Config.StorageDir)
+	if err != nil {
+		logger.Fatal("Failed to initialize file storage", zap.String("dir", config.Config.StorageDir), zap.Error(err))
+	}
+	certificateIssuer := handlers.NewCertificateIssuer(CertificatesRepository, fileStorage, config.Config.ReportFontPath)
+	EnrollmentsHandlers := handlers.NewEnrollmentsHandlers(EnrollmentsRepository, certificateIssuer)
+	CertificatesHandlers := handlers.NewCertificatesHandlers(CertificatesRepository, certificateIssuer, fileStorage)
+	HomeworkHandlers := handlers.NewHomeworkHandlers(HomeworkRepository, fileStorage, config.Config.HomeworkMaxUploadMB<<20)
+	GradesHandlers := handlers.NewGradesHandlers(GradesRepository, config.Config.ReportFontPath)
+	LeadsHandlers := handlers.NewLeadsHandlers(LeadsRepository)
+	WebhooksHandlers := handlers.NewWebhooksHandlers(WebhooksRepository, webhookDispatcher)
+	TrashHandlers := handlers.NewTrashHandlers(TrashRepository, config.Config.TrashRetention)
+	CrmHandlers := handlers.NewCrmHandlers(StudentsRepository, CrmRepository, crmClient, config.Config.CrmWebhookSecret)
 
 	authHandler := handlers.NewAuthHandler(UsersRepository, SessionsRepository, RolesRepository)
 	UserHandler := handlers.NewUserHandlers(UsersRepository, CuratorsRepository, RolesRepository)
@@ -96,6 +227,14 @@ func main() {
 
 	r.GET("/role/:id", UserHandler.GetRole)
 
+	// Публичная проверка подлинности сертификата по номеру
+	r.GET("/certificates/:number", CertificatesHandlers.Verify)
+
+	// Входящие вебхуки CRM, аутентификация по подписи
+	if config.Config.CrmWebhookSecret != "" {
+		r.POST("/integrations/crm/webhook", CrmHandlers.Webhook)
+	}
+
 	// Маршруты для аутентификации
 	authGroup := r.Group("/auth")
 	{
@@ -107,9 +246,34 @@ func main() {
 		authGroup.POST("/new-password", resetPasswordHandler.SetNewPassword)
 	}
 
+	// Вход через OpenID Connect (Google Workspace), если настроен провайдер
+	if config.Config.OIDCIssuer != "" {
+		oidcHandler, err := handlers.NewOIDCHandler(context.Background(), authHandler, UsersRepository, RolesRepository, BranchesRepository)
+		if err != nil {
+			logger.Error("Failed to initialize OIDC provider", zap.String("issuer", config.Config.OIDCIssuer), zap.Error(err))
+		} else {
+			authGroup.GET("/oidc/login", oidcHandler.Login)
+			authGroup.GET("/oidc/callback", oidcHandler.Callback)
+		}
+	}
+
 	// Приватные маршруты (требуют аутентификацию)
 	privateRoutes := r.Group("/")
-	privateRoutes.Use(middlewares.AuthMiddleware(SessionsRepository, UsersRepository, RolesRepository))
+	privateRoutes.Use(
+		middlewares.AuthMiddleware(SessionsRepository, UsersRepository, RolesRepository),
+		middlewares.BranchMiddleware(BranchesRepository),
+	)
+
+	// Филиалы текущего пользователя (для выбора X-Branch-ID)
+	privateRoutes.GET("/branches/my", BranchesHandlers.FindMy)
+
+	// Коды перечислений с подписями на языке пользователя
+	privateRoutes.GET("/enums", EnumsHandlers.FindAll)
+
+	// Сводные отчеты по всем филиалам. Доступ имеет только владелец
+	reportsRoutes := privateRoutes.Group("/reports")
+	reportsRoutes.Use(middlewares.PermissionMiddleware("access_all_branches"))
+	reportsRoutes.GET("/branches", BranchesHandlers.Report)
 
 	// Роуты настроек. Доступ имеет только Админ
 	settingsRoutes := privateRoutes.Group("/settings")
@@ -118,22 +282,46 @@ func main() {
 	// Роуты для работы со студентами внутри настроек
 	settingsRoutes.POST("/students", StudentsHandlers.Create)
 	settingsRoutes.PUT("/students/:studentId", StudentsHandlers.Update)
+	settingsRoutes.PATCH("/students/:studentId", StudentsHandlers.Patch)
 	settingsRoutes.DELETE("/students/:studentId", StudentsHandlers.Delete)
+	settingsRoutes.POST("/students/:studentId/lifecycle", StudentsHandlers.ChangeLifecycle)
+
+	// Зачисления студентов на курсы
+	settingsRoutes.POST("/students/:studentId/enrollments", EnrollmentsHandlers.Create)
+	settingsRoutes.PUT("/enrollments/:enrollmentId", EnrollmentsHandlers.Update)
+	settingsRoutes.DELETE("/enrollments/:enrollmentId", EnrollmentsHandlers.Delete)
+	settingsRoutes.POST("/enrollments/:enrollmentId/certificate", CertificatesHandlers.Issue)
 
 	// Роуты для работы с курсами внутри настроек
 	settingsRoutes.POST("/courses", CourseHandlers.Create)
 	settingsRoutes.GET("/courses/:courseId", CourseHandlers.FindById)
 	settingsRoutes.GET("/courses", CourseHandlers.FindAll)
 	settingsRoutes.PUT("/courses/:courseId", CourseHandlers.Update)
+	settingsRoutes.PATCH("/courses/:courseId", CourseHandlers.Patch)
 	settingsRoutes.DELETE("/courses/:courseId", CourseHandlers.Delete)
+	settingsRoutes.GET("/courses/:courseId/curriculum", CourseHandlers.FindCurriculum)
+	settingsRoutes.PUT("/courses/:courseId/curriculum", CourseHandlers.ReplaceCurriculum)
+	settingsRoutes.GET("/courses/:courseId/rubric", GradesHandlers.FindRubric)
+	settingsRoutes.PUT("/courses/:courseId/rubric", GradesHandlers.ReplaceRubric)
+	settingsRoutes.GET("/courses/:courseId/certificate-template", CertificatesHandlers.FindTemplate)
+	settingsRoutes.PUT("/courses/:courseId/certificate-template", CertificatesHandlers.SaveTemplate)
 
 	// Роуты для работы с пользователями внутри настроек
 	settingsRoutes.POST("/users", UserHandler.Create)
 	settingsRoutes.GET("/users/:userId", UserHandler.FindById)
 	settingsRoutes.GET("/users", UserHandler.FindAll)
 	settingsRoutes.PUT("/users/:userId", UserHandler.Update)
+	settingsRoutes.PATCH("/users/:userId", UserHandler.Patch)
 	settingsRoutes.PUT("/users/:userId/role", UserHandler.UpdateUserRole)
 	settingsRoutes.DELETE("/users/:userId", UserHandler.Delete)
+	settingsRoutes.POST("/users/:userId/branches", BranchesHandlers.AssignUser)
+	settingsRoutes.DELETE("/users/:userId/branches/:branchId", BranchesHandlers.UnassignUser)
+
+	// Роуты для работы с филиалами внутри настроек
+	settingsRoutes.POST("/branches", BranchesHandlers.Create)
+	settingsRoutes.GET("/branches", BranchesHandlers.FindAll)
+	settingsRoutes.PUT("/branches/:branchId", BranchesHandlers.Update)
+	settingsRoutes.DELETE("/branches/:branchId", BranchesHandlers.Delete)
 
 	// Получение списков Менеджеров и Кураторов
 	settingsRoutes.GET("/users/managers", UserHandler.FindManagers)
@@ -141,11 +329,40 @@ func main() {
 
 	settingsRoutes.DELETE("/attendance/:id", AttendanceHandlers.Delete)
 
+	// Роуты для работы с группами внутри настроек
+	settingsRoutes.POST("/groups", GroupsHandlers.Create)
+	settingsRoutes.PUT("/groups/:groupId", GroupsHandlers.Update)
+	settingsRoutes.DELETE("/groups/:groupId", GroupsHandlers.Delete)
+	settingsRoutes.POST("/groups/:groupId/members", GroupsHandlers.AddMember)
+	settingsRoutes.DELETE("/groups/:groupId/members/:studentId", GroupsHandlers.RemoveMember)
+
+	settingsRoutes.DELETE("/leads/:leadId", LeadsHandlers.Delete)
+
+	// Недоставленные в CRM события
+	settingsRoutes.GET("/integrations/crm/dead-letters", CrmHandlers.DeadLetters)
+	settingsRoutes.POST("/integrations/crm/dead-letters/:deadLetterId/retry", CrmHandlers.RetryDeadLetter)
+
+	// Подписки на доменные события
+	settingsRoutes.GET("/webhooks/events", WebhooksHandlers.EventTypes)
+	settingsRoutes.POST("/webhooks", WebhooksHandlers.Create)
+	settingsRoutes.GET("/webhooks", WebhooksHandlers.FindAll)
+	settingsRoutes.GET("/webhooks/:webhookId", WebhooksHandlers.FindById)
+	settingsRoutes.PUT("/webhooks/:webhookId", WebhooksHandlers.Update)
+	settingsRoutes.DELETE("/webhooks/:webhookId", WebhooksHandlers.Delete)
+	settingsRoutes.GET("/webhooks/:webhookId/deliveries", WebhooksHandlers.Deliveries)
+	settingsRoutes.POST("/webhooks/deliveries/:deliveryId/redeliver", WebhooksHandlers.Redeliver)
+
+	// Корзина: удаленные студенты, пользователи, курсы и записи посещаемости
+	settingsRoutes.GET("/trash", TrashHandlers.FindAll)
+	settingsRoutes.POST("/trash/:type/:id/restore", TrashHandlers.Restore)
+
 	attendanceGroup := privateRoutes.Group("/attendances")
 	{
 		attendanceGroup.POST("", AttendanceHandlers.CreateAttendance)
 		attendanceGroup.GET("/:studentId", AttendanceHandlers.GetByStudent)
+		attendanceGroup.GET("/records/:attendanceId", AttendanceHandlers.GetById)
 		attendanceGroup.PUT("/:attendanceId", AttendanceHandlers.UpdateAttendance)
+		attendanceGroup.PATCH("/:attendanceId", AttendanceHandlers.PatchAttendance)
 	}
 
 	// Фунеции Куратора для работы со студентами и курсами
@@ -153,14 +370,44 @@ func main() {
 	curatorsRoutes.Use(middlewares.PermissionMiddleware("access_curator"))
 	{
 		curatorsRoutes.GET("/courses", CourseHandlers.FindAll)
+		curatorsRoutes.GET("/courses/:courseId/curriculum", CourseHandlers.FindCurriculum)
+		curatorsRoutes.GET("/students/:studentId/courses/:courseId/progress", CourseHandlers.Progress)
 		curatorsRoutes.GET("/users", UserHandler.FindAll)
 		curatorsRoutes.GET("/students", StudentsHandlers.FindAll)
 		curatorsRoutes.GET("/students/:studentId", StudentsHandlers.FindById)
+		curatorsRoutes.GET("/students/:studentId/enrollments", EnrollmentsHandlers.FindByStudent)
 
 		curatorsRoutes.POST("/add-student", CuratorsHandlers.AddStudent)
 		curatorsRoutes.POST("/remove-student", CuratorsHandlers.RemoveStudent)
 		curatorsRoutes.POST("/add-course", CuratorsHandlers.AddCourse)
 		curatorsRoutes.POST("/remove-course", CuratorsHandlers.RemoveCourse)
+		curatorsRoutes.POST("/reassign-student", CuratorsHandlers.ReassignStudent)
+		curatorsRoutes.GET("/students/:studentId/curator-history", CuratorsHandlers.History)
+
+		curatorsRoutes.GET("/groups", GroupsHandlers.FindAll)
+		curatorsRoutes.GET("/groups/:groupId", GroupsHandlers.FindById)
+		curatorsRoutes.GET("/groups/:groupId/lessons", GroupsHandlers.FindLessons)
+		curatorsRoutes.POST("/groups/:groupId/lessons", GroupsHandlers.CreateLesson)
+
+		// Домашние задания
+		curatorsRoutes.POST("/homework", HomeworkHandlers.Create)
+		curatorsRoutes.GET("/homework/overdue", HomeworkHandlers.Overdue)
+		curatorsRoutes.GET("/homework/:homeworkId", HomeworkHandlers.FindById)
+		curatorsRoutes.DELETE("/homework/:homeworkId", HomeworkHandlers.Delete)
+		curatorsRoutes.PUT("/homework/submissions/:submissionId", HomeworkHandlers.UpdateSubmission)
+		curatorsRoutes.POST("/homework/submissions/:submissionId/attachments", HomeworkHandlers.UploadAttachment)
+		curatorsRoutes.GET("/homework/attachments/:attachmentId", HomeworkHandlers.DownloadAttachment)
+		curatorsRoutes.GET("/students/:studentId/homework", HomeworkHandlers.FindByStudent)
+
+		// Оценивание по рубрике курса
+		curatorsRoutes.GET("/courses/:courseId/rubric", GradesHandlers.FindRubric)
+		curatorsRoutes.PUT("/lessons/:attendanceId/scores", GradesHandlers.ScoreLesson)
+		curatorsRoutes.PUT("/students/:studentId/modules/:moduleId/scores", GradesHandlers.ScoreModule)
+		curatorsRoutes.GET("/students/:studentId/courses/:courseId/scores", GradesHandlers.Scores)
+
+		// Пробные уроки лидов
+		curatorsRoutes.GET("/trials", LeadsHandlers.FindTrials)
+		curatorsRoutes.PUT("/trials/:trialId", LeadsHandlers.UpdateTrial)
 	}
 
 	// Функции Менеджера для просмотра студентов
@@ -168,9 +415,31 @@ func main() {
 	managerRoutes.Use(middlewares.PermissionMiddleware("access_manager"))
 	{
 		managerRoutes.GET("/courses", CourseHandlers.FindAll)
+		managerRoutes.GET("/courses/:courseId/curriculum", CourseHandlers.FindCurriculum)
+		managerRoutes.GET("/students/:studentId/courses/:courseId/progress", CourseHandlers.Progress)
 		managerRoutes.GET("/users", UserHandler.FindAll)
 		managerRoutes.GET("/students", StudentsHandlers.FindAll)
 		managerRoutes.GET("/students/:studentId", StudentsHandlers.FindById)
+		managerRoutes.GET("/students/:studentId/enrollments", EnrollmentsHandlers.FindByStudent)
+		managerRoutes.GET("/students/:studentId/lifecycle", StudentsHandlers.LifecycleHistory)
+		managerRoutes.GET("/students/:studentId/homework", HomeworkHandlers.FindByStudent)
+		managerRoutes.GET("/homework/overdue", HomeworkHandlers.Overdue)
+		managerRoutes.GET("/homework/:homeworkId", HomeworkHandlers.FindById)
+		managerRoutes.GET("/homework/attachments/:attachmentId", HomeworkHandlers.DownloadAttachment)
+		managerRoutes.GET("/students/:studentId/courses/:courseId/scores", GradesHandlers.Scores)
+		managerRoutes.GET("/students/:studentId/courses/:courseId/report-card", GradesHandlers.ReportCard)
+		managerRoutes.GET("/students/:studentId/certificates", CertificatesHandlers.FindByStudent)
+		managerRoutes.GET("/certificates/:certificateId/pdf", CertificatesHandlers.Download)
+
+		// Лиды и пробные уроки до создания студента
+		managerRoutes.POST("/leads", LeadsHandlers.Create)
+		managerRoutes.GET("/leads", LeadsHandlers.FindAll)
+		managerRoutes.GET("/leads/:leadId", LeadsHandlers.FindById)
+		managerRoutes.PUT("/leads/:leadId", LeadsHandlers.Update)
+		managerRoutes.POST("/leads/:leadId/trials", LeadsHandlers.ScheduleTrial)
+		managerRoutes.POST("/leads/:leadId/convert", LeadsHandlers.Convert)
+		managerRoutes.GET("/trials", LeadsHandlers.FindTrials)
+		managerRoutes.PUT("/trials/:trialId", LeadsHandlers.UpdateTrial)
 	}
 
 	docs.SwaggerInfo.BasePath = "/"
@@ -186,13 +455,92 @@ func main() {
 		port = "8081"
 	}
 
-	logger.Info("Starting on port:", zap.String("port", port))
+	server := &http.Server{
+		Addr:              "0.0.0.0:" + port,
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       config.Config.ServerReadTimeout,
+		WriteTimeout:      config.Config.ServerWriteTimeout,
+		IdleTimeout:       config.Config.ServerIdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Starting on port:", zap.String("port", port))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	backgroundWorkers.Start()
+
+	failed := false
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received, draining requests...")
+	case err := <-serverErr:
+		logger.Error("Server failed", zap.Error(err))
+		failed = true
+	}
+	stop()
+
+	shutdown(server, backgroundWorkers, conn, shutdownTracing)
+	if failed {
+		os.Exit(1)
+	}
+}
 
-	if err := r.Run("0.0.0.0:" + port); err != nil {
-		logger.Fatal("Server failed to start", zap.Error(err))
+// accessLogFields добавляет в журнал запросов идентификатор запроса, пользователя и код ошибки
+func accessLogFields(c *gin.Context) []zapcore.Field {
+	fields := []zapcore.Field{zap.String("request_id", c.GetString("requestID"))}
+	if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+		fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+	}
+	if userID, ok := c.Get("userID"); ok {
+		fields = append(fields, zap.Any("user_id", userID))
 	}
+	if code := c.GetString(middlewares.ErrorCodeKey); code != "" {
+		fields = append(fields, zap.String("error_code", code))
+	}
+	return fields
 }
 
+// probePaths — служебные маршруты, которые не трассируются и не пишутся в журнал запросов
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// readHeaderTimeout защищает от медленной отправки заголовков (Slowloris)
+const readHeaderTimeout = 10 * time.Second
+
+// shutdown останавливает приложение по порядку: сначала HTTP-сервер дожидается текущих запросов,
+// затем останавливаются фоновые воркеры (они дописывают события в БД), закрывается пул
+// и в конце отправляются накопленные span
+func shutdown(server *http.Server, backgroundWorkers *workers.Registry, conn *pgxpool.Pool, shutdownTracing func(context.Context) error) {
+	logger := logger.GetLogger()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server shutdown timed out, closing remaining connections", zap.Error(err))
+		server.Close()
+	}
+
+	if err := backgroundWorkers.Stop(ctx); err != nil {
+		logger.Error("Background workers did not stop in time", zap.Error(err))
+	}
+
+	conn.Close()
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", zap.Error(err))
+	}
+
+	logger.Info("Application stopped")
+	logger.Sync()
+}
 
 func loadConfig() error {
 	// Указываем путь к .env файлу
@@ -201,6 +549,43 @@ func loadConfig() error {
 	// Загружаем переменные из .env, если он есть (необязательно)
 	_ = viper.ReadInConfig() // не падаем, если файла нет
 
+	// Значения по умолчанию (заодно регистрируют ключи для Unmarshal из окружения)
+	viper.SetDefault("OIDC_ISSUER", "")
+	viper.SetDefault("OIDC_CLIENT_ID", "")
+	viper.SetDefault("OIDC_CLIENT_SECRET", "")
+	viper.SetDefault("OIDC_REDIRECT_URL", "")
+	viper.SetDefault("OIDC_ALLOWED_DOMAIN", "")
+	viper.SetDefault("OIDC_AUTO_PROVISION", false)
+	viper.SetDefault("OIDC_DEFAULT_ROLE", "curator")
+	viper.SetDefault("OIDC_DEFAULT_BRANCH", utils.DefaultBranchName)
+	viper.SetDefault("STORAGE_DIR", "uploads")
+	viper.SetDefault("HOMEWORK_MAX_UPLOAD_MB", 20)
+	viper.SetDefault("REPORT_FONT_PATH", "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf")
+	viper.SetDefault("CRM_WEBHOOK_URL", "")
+	viper.SetDefault("CRM_WEBHOOK_SECRET", "")
+	viper.SetDefault("CRM_WEBHOOK_MAX_ATTEMPTS", 5)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("METRICS_TOKEN", "")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LOG_REDACT_PII", true)
+	viper.SetDefault("DEFAULT_LANGUAGE", "ru")
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_SERVICE_NAME", "it_school")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "")
+	viper.SetDefault("SERVER_READ_TIMEOUT", 60*time.Second)
+	viper.SetDefault("SERVER_WRITE_TIMEOUT", 60*time.Second)
+	viper.SetDefault("SERVER_IDLE_TIMEOUT", 120*time.Second)
+	viper.SetDefault("SHUTDOWN_TIMEOUT", 30*time.Second)
+	viper.SetDefault("MAINTENANCE_INTERVAL", time.Hour)
+	viper.SetDefault("OUTBOX_RETENTION", 7*24*time.Hour)
+	viper.SetDefault("TRIAL_REMINDER_INTERVAL", 15*time.Minute)
+	viper.SetDefault("TRIAL_REMINDER_AHEAD", 24*time.Hour)
+	viper.SetDefault("TRASH_RETENTION", 30*24*time.Hour)
+	viper.SetDefault("TRASH_PURGE_INTERVAL", 24*time.Hour)
+	viper.SetDefault("LIFECYCLE_FREEZE_INTERVAL", time.Hour)
+
 	// Читаем переменные окружения (например, из Railway)
 	viper.AutomaticEnv()
 
@@ -218,9 +603,8 @@ func loadConfig() error {
 
 // func loadConfig() error {
 //     viper.SetConfigFile(".env")
-//     _ = viper.ReadInConfig()   
-//     viper.AutomaticEnv()      
-
+//     _ = viper.ReadInConfig()
+//     viper.AutomaticEnv()
 
 //     var cfg config.MapConfig
 //     if err := viper.Unmarshal(&cfg); err != nil {
@@ -239,14 +623,21 @@ func loadConfig() error {
 //     cfg.SMTPEmail       = viper.GetString("SMTP_EMAIL")
 //     cfg.SMTPHost             = viper.GetString("SMTP_HOST")
 //     cfg.SMTPPort             = viper.GetString("SMTP_PORT")
-  
+
 //     config.Config = &cfg
 //     return nil
 // }
 
-
 func connectToDb() (*pgxpool.Pool, error) {
-	conn, err := pgxpool.New(context.Background(), config.Config.DbConnectionString)
+	poolConfig, err := pgxpool.ParseConfig(config.Config.DbConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	// Ошибки запросов пишутся в лог с request_id запроса, который их вызвал;
+	// каждый запрос к БД — отдельный span внутри span HTTP-запроса
+	poolConfig.ConnConfig.Tracer = multitracer.New(repositories.QueryTracer{}, tracing.PgxTracer{})
+
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,89 @@
+// Package metrics — метрики приложения в формате Prometheus, отдаются на /metrics
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "it_school"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration — длительность запросов по шаблону маршрута и статусу ответа
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// LoginFailures — неудачные попытки входа; method — password или oidc
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed login attempts by method and reason.",
+	}, []string{"method", "reason"})
+
+	// LessonsRecorded — записанные уроки (по одному на студента); source — individual или group
+	LessonsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lessons_recorded_total",
+		Help:      "Lesson attendance records created.",
+	}, []string{"source"})
+
+	// PaymentsRecorded — созданные пролонгации (оплаты) по типу оплаты
+	PaymentsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_recorded_total",
+		Help:      "Payments (prolongations) recorded by payment type.",
+	}, []string{"payment_type"})
+
+	// PaymentsAmount — сумма созданных пролонгаций по типу оплаты
+	PaymentsAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_amount_total",
+		Help:      "Total amount of recorded payments by payment type.",
+	}, []string{"payment_type"})
+
+	// MaintenanceDeleted — записи, удаленные обслуживанием БД; kind — sessions, reset_tokens, outbox или trash_<тип записи>
+	MaintenanceDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "maintenance_deleted_total",
+		Help:      "Rows purged by the maintenance job.",
+	}, []string{"kind"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		LoginFailures,
+		LessonsRecorded,
+		PaymentsRecorded,
+		PaymentsAmount,
+		MaintenanceDeleted,
+	)
+}
+
+// RegisterPool добавляет метрики пула соединений с БД
+func RegisterPool(pool *pgxpool.Pool) {
+	registry.MustRegister(newPoolCollector(pool))
+}
+
+// RecordPayment учитывает созданную пролонгацию
+func RecordPayment(paymentType string, amount float64) {
+	PaymentsRecorded.WithLabelValues(paymentType).Inc()
+	PaymentsAmount.WithLabelValues(paymentType).Add(amount)
+}
+
+// Handler отдает метрики в текстовом формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"it_school/logger"
+	"it_school/models"
+	"it_school/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BranchHeader — заголовок, в котором клиент передает текущий филиал
+const BranchHeader = "X-Branch-ID"
+
+// BranchMiddleware — определяет филиал, в рамках которого выполняется запрос.
+// Должен идти после AuthMiddleware. Филиал берется из заголовка X-Branch-ID; если заголовка нет,
+// а пользователь прикреплен ровно к одному филиалу, используется он. Пользователь с правом
+// access_all_branches может работать в любом филиале, а без заголовка видит данные всех филиалов.
+func BranchMiddleware(branchesRepo *repositories.BranchesRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.FromContext(c)
+
+		userID := c.MustGet("userID").(uuid.UUID)
+		role := c.MustGet("userRole").(*models.Role)
+		allBranches := role.Permissions["access_all_branches"]
+
+		var branchID uuid.UUID
+		header := c.GetHeader(BranchHeader)
+
+		if header == "" {
+			if allBranches {
+				c.Next()
+				return
+			}
+
+			branches, err := branchesRepo.FindByUser(c.Request.Context(), userID)
+			if err != nil {
+				logger.Error("Failed to get user branches", zap.String("user_id", userID.String()), zap.Error(err))
+				c.Error(models.NewInternalError("couldn't get user branches"))
+				c.Abort()
+				return
+			}
+			if len(branches) != 1 {
+				logger.Warn("Branch is not selected", zap.String("user_id", userID.String()), zap.Int("branches", len(branches)))
+				c.Error(models.NewValidationError("branch_header_required", "X-Branch-ID header is required"))
+				c.Abort()
+				return
+			}
+			branchID = branches[0].Id
+		} else {
+			id, err := uuid.Parse(header)
+			if err != nil {
+				c.Error(models.NewValidationError("invalid_branch_header", "invalid X-Branch-ID header"))
+				c.Abort()
+				return
+			}
+			branchID = id
+
+			if allBranches {
+				if _, err := branchesRepo.FindById(c.Request.Context(), branchID); err != nil {
+					c.Error(models.NewNotFoundError("branch_not_found", "branch not found"))
+					c.Abort()
+					return
+				}
+			} else {
+				assigned, err := branchesRepo.IsUserAssigned(c.Request.Context(), userID, branchID)
+				if err != nil {
+					logger.Error("Failed to check branch assignment", zap.Error(err))
+					c.Error(models.NewInternalError("couldn't check branch access"))
+					c.Abort()
+					return
+				}
+				if !assigned {
+					logger.Warn("Access to foreign branch denied",
+						zap.String("user_id", userID.String()),
+						zap.String("branch_id", branchID.String()))
+					c.Error(models.NewForbiddenError("branch_access_denied", "no access to this branch"))
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		// Репозитории читают филиал из контекста запроса
+		c.Set("branchID", branchID)
+		c.Request = c.Request.WithContext(models.WithBranch(c.Request.Context(), branchID))
+
+		c.Next()
+	}
+}
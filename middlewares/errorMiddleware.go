@@ -0,0 +1,196 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"it_school/i18n"
+	"it_school/logger"
+	"it_school/models"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ProblemContentType — тип ответа об ошибке по RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// ErrorCodeKey — ключ контекста gin с кодом ошибки ответа (для журнала запросов)
+const ErrorCodeKey = "errorCode"
+
+// ErrorMiddleware — единая точка превращения ошибок в ответы. Обработчик вызывает c.Error(err)
+// и выходит, а middleware выбирает статус по типу ошибки и пишет application/problem+json.
+// Тексты detail и errors[].message переводятся на язык запроса (см. LanguageMiddleware), code — нет.
+// Ошибки после ответа удаляются из c.Errors, чтобы журнал запросов писал обычную строку
+func ErrorMiddleware() gin.HandlerFunc {
+	useJSONFieldNames()
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last().Err
+		c.Errors = c.Errors[:0]
+
+		if c.Writer.Written() {
+			logger.FromContext(c).Warn("Error after response was written", zap.Error(err))
+			return
+		}
+		writeProblem(c, toAppError(err, Language(c)))
+	}
+}
+
+// RecoveryHandler отвечает на панику обработчика ошибкой 500 в формате problem+json
+func RecoveryHandler(c *gin.Context, _ any) {
+	writeProblem(c, models.NewInternalError("Internal server error"))
+}
+
+// NoRouteHandler отвечает на неизвестный маршрут ошибкой 404 в формате problem+json
+func NoRouteHandler(c *gin.Context) {
+	c.Error(models.NewNotFoundError("route_not_found", "Route not found"))
+}
+
+func writeProblem(c *gin.Context, appErr *models.AppError) {
+	status := appErr.Kind.Status()
+	lang := Language(c)
+
+	if status >= http.StatusInternalServerError {
+		if appErr.Err != nil {
+			logger.FromContext(c).Error("Request failed", zap.String("code", appErr.Code), zap.Error(appErr.Err))
+		}
+		span := trace.SpanFromContext(c.Request.Context())
+		span.RecordError(appErr)
+		span.SetStatus(codes.Error, appErr.Code)
+	}
+
+	detail := appErr.Message
+	if localized, ok := i18n.Message(lang, appErr.Code); ok {
+		detail = localized
+	}
+
+	c.Set(ErrorCodeKey, appErr.Code)
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		RequestId: c.GetString("requestID"),
+		Errors:    localizeFields(lang, appErr.Fields),
+	})
+}
+
+// toAppError приводит любую ошибку к AppError. Нетипизированные ошибки БД и биндинга
+// распознаются здесь, все остальное — внутренняя ошибка без подробностей для клиента
+func toAppError(err error, lang string) *models.AppError {
+	if appErr, ok := models.AsAppError(err); ok {
+		if appErr.Kind == models.KindValidation && len(appErr.Fields) == 0 && appErr.Err != nil {
+			if fields := bindingFields(appErr.Err, lang); len(fields) > 0 {
+				appErr = appErr.Wrap(appErr.Err)
+				appErr.Fields = fields
+			}
+		}
+		return appErr
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.NewNotFoundError(models.CodeNotFound, "Resource not found").Wrap(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return models.NewConflictError("already_exists", "Resource already exists").Wrap(err)
+		case "23503": // foreign_key_violation
+			return models.NewConflictError("reference_violation", "Referenced resource does not exist or is in use").Wrap(err)
+		case "23502", "23514": // not_null_violation, check_violation
+			return models.NewValidationError("constraint_violation", "Request violates a data constraint").Wrap(err)
+		}
+	}
+
+	if fields := bindingFields(err, lang); len(fields) > 0 {
+		return models.NewValidationError(models.CodeInvalidRequest, "Invalid request data", fields...).Wrap(err)
+	}
+
+	return models.NewInternalError("Internal server error").Wrap(err)
+}
+
+// localizeFields переводит сообщения полей, код которых есть в каталоге i18n.
+// Остальные сообщения (ошибки валидатора, текст ошибки шаблона) остаются как есть
+func localizeFields(lang string, fields []models.FieldError) []models.FieldError {
+	if len(fields) == 0 {
+		return nil
+	}
+	localized := make([]models.FieldError, len(fields))
+	for i, field := range fields {
+		if message, ok := i18n.Message(lang, field.Code); ok {
+			field.Message = message
+		}
+		localized[i] = field
+	}
+	return localized
+}
+
+// bindingFields раскладывает ошибку ShouldBind по полям запроса, сообщения — на языке lang
+func bindingFields(err error, lang string) []models.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]models.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			name := fieldErr.Namespace()
+			if _, rest, found := strings.Cut(name, "."); found {
+				name = rest
+			}
+			fields = append(fields, models.FieldError{
+				Field:   name,
+				Code:    fieldErr.Tag(),
+				Message: i18n.FieldMessage(lang, name, fieldErr.Tag(), fieldErr.Param()),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []models.FieldError{{
+			Field:   typeErr.Field,
+			Code:    "invalid_type",
+			Message: i18n.FieldMessage(lang, typeErr.Field, "invalid_type", typeErr.Type.String()),
+		}}
+	}
+	return nil
+}
+
+// useJSONFieldNames переключает валидатор gin на имена полей из тегов json/form,
+// чтобы в ответе были поля API, а не имена полей Go-структур
+func useJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
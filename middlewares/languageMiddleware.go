@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"it_school/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LanguageKey — ключ контекста gin с языком ответа (ru, kk или en)
+const LanguageKey = "lang"
+
+// LanguageMiddleware выбирает язык ответа по Accept-Language (по умолчанию defaultLang)
+// и сообщает его клиенту в Content-Language. Язык влияет только на тексты ошибок
+// и подписи перечислений — коды в API от языка не зависят
+func LanguageMiddleware(defaultLang string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"), defaultLang)
+		c.Set(LanguageKey, lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
+
+// Language возвращает язык ответа, выбранный LanguageMiddleware
+func Language(c *gin.Context) string {
+	if lang := c.GetString(LanguageKey); lang != "" {
+		return lang
+	}
+	return i18n.Russian
+}
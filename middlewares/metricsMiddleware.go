@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"it_school/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware — учитывает длительность запросов. В метку route попадает шаблон маршрута
+// (/students/:studentId), а не фактический путь, чтобы не плодить временные ряды
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(started).Seconds())
+	}
+}
+
+// MetricsTokenMiddleware — закрывает /metrics токеном (Authorization: Bearer <token>), если он задан
+func MetricsTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"it_school/logger"
 	"it_school/models"
 	"it_school/repositories"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -17,7 +16,7 @@ import (
 // AuthMiddleware — middleware для аутентификации пользователя. Поддерживает как JWT, так и сессионную аутентификацию.
 func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *repositories.UsersRepository, rolesRepo *repositories.RoleRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger := logger.GetLogger()
+		logger := logger.FromContext(c)
 
 		// Извлекаем заголовок Authorization из запроса
 		authHeader := c.GetHeader("Authorization")
@@ -38,7 +37,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			// Если токен невалиден, возвращаем ошибку
 			if err != nil || !token.Valid {
 				logger.Warn("Invalid token", zap.Error(err)) // Логируем предупреждение
-				c.JSON(http.StatusUnauthorized, models.NewApiError("invalid token"))
+				c.Error(models.NewUnauthorizedError("invalid_token", "invalid token"))
 				c.Abort() // Прерываем выполнение дальнейших middleware
 				return
 			}
@@ -47,7 +46,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			claims, ok := token.Claims.(jwt.MapClaims)
 			if !ok {
 				logger.Warn("Invalid token claims")
-				c.JSON(http.StatusUnauthorized, models.NewApiError("invalid token claims"))
+				c.Error(models.NewUnauthorizedError("invalid_token_claims", "invalid token claims"))
 				c.Abort()
 				return
 			}
@@ -56,7 +55,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			subject, ok := claims["sub"].(string)
 			if !ok {
 				logger.Warn("Invalid subject in token")
-				c.JSON(http.StatusUnauthorized, models.NewApiError("invalid token subject"))
+				c.Error(models.NewUnauthorizedError("invalid_token_subject", "invalid token subject"))
 				c.Abort()
 				return
 			}
@@ -65,7 +64,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			userID, err = uuid.Parse(subject)
 			if err != nil {
 				logger.Warn("Invalid user ID format in token")
-				c.JSON(http.StatusUnauthorized, models.NewApiError("invalid user ID format"))
+				c.Error(models.NewUnauthorizedError("invalid_token_subject", "invalid user ID format"))
 				c.Abort()
 				return
 			}
@@ -75,7 +74,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			sessionToken, err := c.Cookie("session_token")
 			if err != nil {
 				logger.Warn("No session token found", zap.Error(err))
-				c.JSON(http.StatusUnauthorized, models.NewApiError("no session token"))
+				c.Error(models.NewUnauthorizedError("no_session_token", "no session token"))
 				c.Abort()
 				return
 			}
@@ -84,7 +83,7 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 			session, _, err := sessionsRepo.GetSession(c.Request.Context(), sessionToken)
 			if err != nil {
 				logger.Warn("Invalid session token", zap.Error(err))
-				c.JSON(http.StatusUnauthorized, models.NewApiError("invalid session token"))
+				c.Error(models.NewUnauthorizedError("invalid_session_token", "invalid session token"))
 				c.Abort()
 				return
 			}
@@ -95,70 +94,71 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 		user, err := usersRepo.FindById(c.Request.Context(), userID)
 		if err != nil {
 			logger.Warn("User not found", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, models.NewApiError("user not found"))
+			c.Error(models.NewUnauthorizedError("user_not_found", "user not found"))
 			c.Abort()
 			return
 		}
 
 		// Получаем роль пользователя из базы данных
 		role, err := rolesRepo.GetRoleByID(c.Request.Context(), user.RoleID)
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, models.NewApiError("couldn't find role"))
-            c.Abort()
-            return
-        }
+		if err != nil {
+			c.Error(models.NewInternalError("couldn't find role"))
+			c.Abort()
+			return
+		}
 
 		// Сохраняем информацию о пользователе в контексте для использования в последующих middleware
 		c.Set("userID", userID)
 		c.Set("userRole", role)
 		c.Set("isSessionAuth", isSessionAuth)
+		// Репозитории записывают пользователя в updated_by
+		c.Request = c.Request.WithContext(models.WithUser(c.Request.Context(), userID))
+
+		// Дальнейшие записи лога этого запроса несут пользователя и роль
+		logger = logger.With(zap.String("user_id", userID.String()), zap.String("role", role.Name))
+		setRequestLogger(c, logger)
 
+		logger.Debug("User authenticated", zap.Bool("isSessionAuth", isSessionAuth))
 
-		logger.Info("User authenticated", 
-			zap.Any("userID", userID),
-			zap.String("role", role.Name),
-			zap.Bool("isSessionAuth", isSessionAuth))
-		
 		c.Next()
 	}
 }
 
 // PermissionMiddleware — middleware для проверки наличия разрешений у пользователя на выполнение действия.
 func PermissionMiddleware(permission string) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        logger := logger.GetLogger()
-        
-        // Извлекаем роль из контекста
-        roleObj, exists := c.Get("userRole")
-        if !exists {
-            logger.Warn("Role missing - access denied")
-            c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
-            c.Abort()
-            return
-        }
-
-        // Проверяем, что роль имеет правильный тип
-        role, ok := roleObj.(*models.Role)
-        if !ok {
-            logger.Error("Invalid role type in context")
-            c.JSON(http.StatusInternalServerError, models.NewApiError("role parsing error"))
-            c.Abort()
-            return
-        }
-
-        // Проверяем, есть ли у роли нужные разрешения
-        if !role.Permissions[permission] {
-            logger.Warn("Permission denied",
-                zap.String("role", role.Name),
-                zap.String("need", permission))
-            
-            c.JSON(http.StatusForbidden, models.NewApiError("forbidden"))
-            c.Abort()
-            return
-        }
-
-
-        logger.Debug("Access granted")
-        c.Next() 
-    }
-}
\ No newline at end of file
+	return func(c *gin.Context) {
+		logger := logger.FromContext(c)
+
+		// Извлекаем роль из контекста
+		roleObj, exists := c.Get("userRole")
+		if !exists {
+			logger.Warn("Role missing - access denied")
+			c.Error(models.NewForbiddenError("permission_denied", "access denied"))
+			c.Abort()
+			return
+		}
+
+		// Проверяем, что роль имеет правильный тип
+		role, ok := roleObj.(*models.Role)
+		if !ok {
+			logger.Error("Invalid role type in context")
+			c.Error(models.NewInternalError("role parsing error"))
+			c.Abort()
+			return
+		}
+
+		// Проверяем, есть ли у роли нужные разрешения
+		if !role.Permissions[permission] {
+			logger.Warn("Permission denied",
+				zap.String("role", role.Name),
+				zap.String("need", permission))
+
+			c.Error(models.NewForbiddenError("permission_denied", "forbidden"))
+			c.Abort()
+			return
+		}
+
+		logger.Debug("Access granted")
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"it_school/logger"
+	"it_school/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса. Входящее значение сохраняется,
+// чтобы запрос можно было проследить от балансировщика или фронтенда
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware — присваивает запросу идентификатор, возвращает его в ответе
+// и кладет в контекст логгер с полями request_id и trace_id (см. logger.FromContext).
+// Должен идти после otelgin, чтобы span запроса уже был в контексте
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		fields := []zap.Field{zap.String("request_id", requestID)}
+		// Если запрос трассируется (otelgin), логи связываются со span по trace_id
+		if span := trace.SpanFromContext(c.Request.Context()); span.SpanContext().IsValid() {
+			span.SetAttributes(tracing.RequestIDAttribute(requestID))
+			fields = append(fields,
+				zap.String("trace_id", span.SpanContext().TraceID().String()),
+				zap.String("span_id", span.SpanContext().SpanID().String()))
+		}
+		setRequestLogger(c, logger.GetLogger().With(fields...))
+
+		c.Next()
+	}
+}
+
+// setRequestLogger заменяет логгер запроса; репозитории получают его через контекст запроса
+func setRequestLogger(c *gin.Context, requestLogger *zap.Logger) {
+	c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), requestLogger))
+}
+
+// validRequestID пропускает только короткие идентификаторы из печатных ASCII-символов,
+// чтобы значение из заголовка нельзя было использовать для подделки строк в логах
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+-- Филиалы школы. Все существующие данные переносятся в основной филиал.
+CREATE TABLE branches (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    address text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE user_branches (
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, branch_id)
+);
+
+INSERT INTO branches (name) VALUES ('Основной филиал');
+
+ALTER TABLE courses ADD COLUMN branch_id uuid NULL REFERENCES branches(id) ON DELETE RESTRICT;
+ALTER TABLE students ADD COLUMN branch_id uuid NULL REFERENCES branches(id) ON DELETE RESTRICT;
+
+UPDATE courses SET branch_id = (SELECT id FROM branches WHERE name = 'Основной филиал');
+UPDATE students SET branch_id = (SELECT id FROM branches WHERE name = 'Основной филиал');
+
+INSERT INTO user_branches (user_id, branch_id)
+SELECT u.id, b.id FROM users u CROSS JOIN branches b WHERE b.name = 'Основной филиал';
+
+ALTER TABLE courses ALTER COLUMN branch_id SET NOT NULL;
+ALTER TABLE students ALTER COLUMN branch_id SET NOT NULL;
+
+CREATE INDEX students_branch_id_idx ON students(branch_id);
+CREATE INDEX courses_branch_id_idx ON courses(branch_id);
+
+-- Администратор (владелец) видит все филиалы
+UPDATE roles SET permissions = permissions || '{"access_all_branches": true}'::jsonb WHERE name = 'admin';
@@ -0,0 +1,29 @@
+-- Учебные группы и групповые уроки
+CREATE TABLE groups (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    title text NOT NULL,
+    schedule jsonb DEFAULT '[]'::jsonb NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE group_members (
+    group_id uuid NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    joined_at timestamptz DEFAULT now() NOT NULL,
+    PRIMARY KEY (group_id, student_id)
+);
+
+CREATE TABLE group_lessons (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    group_id uuid NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
+    date date NOT NULL,
+    format text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+ALTER TABLE attendance_lessons ADD COLUMN group_lesson_id uuid NULL REFERENCES group_lessons(id) ON DELETE CASCADE;
+CREATE INDEX attendance_lessons_group_lesson_id_idx ON attendance_lessons(group_lesson_id);
@@ -0,0 +1,28 @@
+-- Зачисления студентов на несколько курсов вместо students.course_id
+CREATE TYPE public."enrollment_status" AS ENUM ('активен', 'приостановлен', 'завершен', 'отменен');
+
+CREATE TABLE enrollments (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE RESTRICT,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    start_date date NOT NULL,
+    end_date date NULL,
+    status public."enrollment_status" DEFAULT 'активен'::enrollment_status NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX enrollments_student_id_idx ON enrollments(student_id);
+CREATE INDEX enrollments_course_id_idx ON enrollments(course_id);
+CREATE UNIQUE INDEX enrollments_active_uniq ON enrollments(student_id, course_id) WHERE status = 'активен';
+
+-- Переносим текущий курс каждого студента в зачисление
+INSERT INTO enrollments (student_id, course_id, curator_id, start_date, status)
+SELECT s.id, s.course_id, s.curator_id, COALESCE(s.created_at::date, CURRENT_DATE),
+       CASE WHEN s.is_active = 'неактивен' THEN 'приостановлен'::enrollment_status ELSE 'активен'::enrollment_status END
+FROM students s
+WHERE s.course_id IS NOT NULL;
+
+-- Удаление курса больше не удаляет студентов
+ALTER TABLE students DROP COLUMN course_id;
+ALTER TABLE students DROP COLUMN courses;
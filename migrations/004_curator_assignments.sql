@@ -0,0 +1,45 @@
+-- Связи куратор–студент и куратор–курс в отдельных таблицах вместо массивов
+CREATE TABLE curator_students (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    assigned_at timestamptz DEFAULT now() NOT NULL,
+    unassigned_at timestamptz NULL,
+    assigned_by uuid NULL REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX curator_students_active_uniq ON curator_students(student_id) WHERE unassigned_at IS NULL;
+CREATE INDEX curator_students_curator_id_idx ON curator_students(curator_id);
+
+CREATE TABLE curator_courses (
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    assigned_at timestamptz DEFAULT now() NOT NULL,
+    PRIMARY KEY (curator_id, course_id)
+);
+
+-- Текущий куратор студента: students.curator_id приоритетнее массива curators.student_ids,
+-- ссылки на удаленных студентов и курсы отбрасываются
+INSERT INTO curator_students (curator_id, student_id)
+SELECT DISTINCT ON (student_id) curator_id, student_id
+FROM (
+    SELECT s.curator_id, s.id AS student_id, 0 AS priority
+    FROM students s
+    WHERE s.curator_id IS NOT NULL
+    UNION ALL
+    SELECT c.user_id, sid, 1
+    FROM curators c
+    CROSS JOIN LATERAL unnest(c.student_ids) AS sid
+    JOIN students s ON s.id = sid
+) assignments
+ORDER BY student_id, priority;
+
+INSERT INTO curator_courses (curator_id, course_id)
+SELECT DISTINCT c.user_id, cid
+FROM curators c
+CROSS JOIN LATERAL unnest(c.course_ids) AS cid
+JOIN courses co ON co.id = cid;
+
+ALTER TABLE students DROP COLUMN curator_id;
+ALTER TABLE curators DROP COLUMN student_ids;
+ALTER TABLE curators DROP COLUMN course_ids;
@@ -0,0 +1,39 @@
+-- Каталог курсов: описание, параметры, статус и программа из модулей и тем
+CREATE TYPE public."course_status" AS ENUM ('активен', 'архив');
+
+ALTER TABLE courses
+    ADD COLUMN description text NULL,
+    ADD COLUMN age_min int NULL,
+    ADD COLUMN age_max int NULL,
+    ADD COLUMN duration_weeks int NULL,
+    ADD COLUMN lessons_count int NULL,
+    ADD COLUMN price numeric NULL,
+    ADD COLUMN status public."course_status" DEFAULT 'активен'::course_status NOT NULL,
+    ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL,
+    ADD CHECK (age_min IS NULL OR age_max IS NULL OR age_min <= age_max);
+
+CREATE TABLE course_modules (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL
+);
+
+CREATE INDEX course_modules_course_id_idx ON course_modules(course_id, position);
+
+CREATE TABLE course_topics (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    module_id uuid NOT NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL,
+    lesson_plan text NULL
+);
+
+CREATE INDEX course_topics_module_id_idx ON course_topics(module_id, position);
+
+-- Пройденная на уроке тема
+ALTER TABLE group_lessons ADD COLUMN topic_id uuid NULL REFERENCES course_topics(id) ON DELETE SET NULL;
+ALTER TABLE attendance_lessons ADD COLUMN topic_id uuid NULL REFERENCES course_topics(id) ON DELETE SET NULL;
+CREATE INDEX attendance_lessons_topic_id_idx ON attendance_lessons(topic_id);
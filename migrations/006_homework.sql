@@ -0,0 +1,45 @@
+-- Домашние задания, сдачи и вложения
+CREATE TYPE public."homework_status" AS ENUM ('не сдано', 'сдано', 'проверено');
+
+-- Домашнее задание выдается на индивидуальном (attendance_id) или групповом (group_lesson_id) уроке.
+-- Каждый студент урока получает свою сдачу со статусом и оценкой.
+CREATE TABLE homework (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    attendance_id uuid NULL REFERENCES attendance_lessons(attendance_id) ON DELETE CASCADE,
+    group_lesson_id uuid NULL REFERENCES group_lessons(id) ON DELETE CASCADE,
+    description text NOT NULL,
+    due_date date NOT NULL,
+    created_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    CHECK ((attendance_id IS NULL) <> (group_lesson_id IS NULL))
+);
+
+CREATE INDEX homework_attendance_id_idx ON homework(attendance_id);
+CREATE INDEX homework_group_lesson_id_idx ON homework(group_lesson_id);
+
+CREATE TABLE homework_submissions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    homework_id uuid NOT NULL REFERENCES homework(id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    status public."homework_status" DEFAULT 'не сдано'::homework_status NOT NULL,
+    submitted_at timestamptz NULL,
+    checked_at timestamptz NULL,
+    checked_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    grade int NULL,
+    comment text NULL,
+    UNIQUE (homework_id, student_id)
+);
+
+CREATE INDEX homework_submissions_student_id_idx ON homework_submissions(student_id);
+
+-- Файлы хранятся на диске (HOMEWORK_STORAGE_DIR), в базе — только метаданные и относительный путь
+CREATE TABLE homework_attachments (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    submission_id uuid NOT NULL REFERENCES homework_submissions(id) ON DELETE CASCADE,
+    file_name text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    storage_path text NOT NULL,
+    uploaded_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    uploaded_at timestamptz DEFAULT now() NOT NULL
+);
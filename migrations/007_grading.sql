@@ -0,0 +1,32 @@
+-- Рубрики оценивания и оценки студентов
+-- Критерии оценивания курса (логика, синтаксис, проектная работа...) со шкалой баллов
+CREATE TABLE rubric_criteria (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL,
+    min_score int DEFAULT 1 NOT NULL,
+    max_score int DEFAULT 5 NOT NULL,
+    CHECK (min_score < max_score)
+);
+
+CREATE INDEX rubric_criteria_course_id_idx ON rubric_criteria(course_id, position);
+
+-- Оценка студента по критерию: за урок (attendance_id) или за модуль программы (module_id)
+CREATE TABLE student_scores (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    criterion_id uuid NOT NULL REFERENCES rubric_criteria(id) ON DELETE CASCADE,
+    attendance_id uuid NULL REFERENCES attendance_lessons(attendance_id) ON DELETE CASCADE,
+    module_id uuid NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    score int NOT NULL,
+    comment text NULL,
+    scored_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    scored_at timestamptz DEFAULT now() NOT NULL,
+    CHECK ((attendance_id IS NULL) <> (module_id IS NULL))
+);
+
+CREATE UNIQUE INDEX student_scores_lesson_uniq ON student_scores(criterion_id, attendance_id) WHERE attendance_id IS NOT NULL;
+CREATE UNIQUE INDEX student_scores_module_uniq ON student_scores(student_id, criterion_id, module_id) WHERE module_id IS NOT NULL;
+CREATE INDEX student_scores_student_id_idx ON student_scores(student_id);
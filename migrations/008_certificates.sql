@@ -0,0 +1,28 @@
+-- Шаблоны и выданные сертификаты об окончании курса
+-- Шаблон сертификата курса. body — text/template с полями .StudentName, .CourseTitle,
+-- .StartDate, .EndDate, .CuratorName, .Number
+CREATE TABLE certificate_templates (
+    course_id uuid NOT NULL PRIMARY KEY REFERENCES courses(id) ON DELETE CASCADE,
+    title text NOT NULL,
+    body text NOT NULL,
+    signature_name text NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+-- Выданный сертификат. Данные сохраняются на момент выдачи, чтобы проверка по номеру
+-- не зависела от последующих изменений студента или курса.
+CREATE TABLE certificates (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    number text NOT NULL UNIQUE,
+    enrollment_id uuid NULL UNIQUE REFERENCES enrollments(id) ON DELETE SET NULL,
+    student_id uuid NULL REFERENCES students(id) ON DELETE SET NULL,
+    student_name text NOT NULL,
+    course_title text NOT NULL,
+    curator_name text NULL,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    storage_path text NOT NULL,
+    issued_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX certificates_student_id_idx ON certificates(student_id);
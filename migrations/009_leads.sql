@@ -0,0 +1,35 @@
+-- Воронка лидов и пробные уроки
+CREATE TYPE public."lead_status" AS ENUM ('новый', 'связались', 'пробный назначен', 'пробный проведен', 'конвертирован', 'потерян');
+
+-- Лид — потенциальный студент до первой оплаты. После конвертации ссылается на созданного студента
+CREATE TABLE leads (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    full_name text NOT NULL,
+    phone_number text NULL,
+    parent_name text NOT NULL,
+    parent_phone_number text NOT NULL,
+    source text NULL,
+    course_id uuid NULL REFERENCES courses(id) ON DELETE SET NULL,
+    status public."lead_status" DEFAULT 'новый'::lead_status NOT NULL,
+    comment text NULL,
+    student_id uuid NULL REFERENCES students(id) ON DELETE SET NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX leads_branch_status_idx ON leads(branch_id, status);
+
+-- Пробный урок лида с куратором
+CREATE TABLE trial_lessons (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    date timestamptz NOT NULL,
+    status public."lessons_status" DEFAULT 'запланирован'::lessons_status NOT NULL,
+    feedback text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX trial_lessons_lead_id_idx ON trial_lessons(lead_id);
+CREATE INDEX trial_lessons_curator_date_idx ON trial_lessons(curator_id, date);
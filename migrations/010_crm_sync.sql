@@ -0,0 +1,15 @@
+-- Синхронизация студентов и оплат с внешней CRM
+-- Идентификатор студента во внешней CRM; по нему входящие вебхуки находят студента
+ALTER TABLE students ADD COLUMN crm_external_id text NULL UNIQUE;
+
+-- События синхронизации с CRM, которые не удалось доставить после всех попыток
+CREATE TABLE crm_dead_letters (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    event_id uuid NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL,
+    last_error text NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    last_attempt_at timestamptz DEFAULT now() NOT NULL
+);
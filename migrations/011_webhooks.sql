@@ -0,0 +1,33 @@
+-- Исходящие вебхуки доменных событий
+CREATE TYPE public."webhook_delivery_status" AS ENUM ('pending', 'delivered', 'failed');
+
+-- Подписка внешней системы (бот, скрипты учета) на доменные события
+CREATE TABLE webhook_subscriptions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    is_active boolean DEFAULT true NOT NULL,
+    description text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+-- Доставка события подписчику. pending доставляется фоновым процессом с повторами,
+-- после исчерпания попыток переходит в failed
+CREATE TABLE webhook_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    subscription_id uuid NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id uuid NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status public."webhook_delivery_status" DEFAULT 'pending'::webhook_delivery_status NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    response_status integer NULL,
+    last_error text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL,
+    delivered_at timestamptz NULL
+);
+
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries(subscription_id, created_at);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,21 @@
+-- Transactional outbox: доменные события записываются в одной транзакции с изменением
+-- данных и передаются подписчикам фоновым диспетчером (доставка at-least-once)
+CREATE TABLE outbox (
+    id uuid NOT NULL PRIMARY KEY,
+    event text NOT NULL,
+    data jsonb NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    available_at timestamptz DEFAULT now() NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text NULL,
+    processed_at timestamptz NULL
+);
+
+CREATE INDEX outbox_pending_idx ON outbox(available_at) WHERE processed_at IS NULL;
+
+-- Событие из outbox может быть обработано повторно; доставка подписчику создается один раз
+DELETE FROM webhook_deliveries d
+USING webhook_deliveries dup
+WHERE d.subscription_id = dup.subscription_id AND d.event_id = dup.event_id AND d.id > dup.id;
+
+CREATE UNIQUE INDEX webhook_deliveries_event_uniq ON webhook_deliveries(subscription_id, event_id);
@@ -0,0 +1,2 @@
+-- Время отправки напоминания куратору о пробном уроке; сбрасывается при переносе урока
+ALTER TABLE trial_lessons ADD COLUMN reminded_at timestamptz NULL;
@@ -0,0 +1,2 @@
+-- Периодическое удаление истекших сессий
+CREATE INDEX sessions_expires_at_idx ON sessions(expires_at);
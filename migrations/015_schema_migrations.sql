@@ -0,0 +1,23 @@
+-- Учет примененных миграций: /readyz сравнивает его с миграциями, встроенными в бинарник.
+-- Каждая следующая миграция добавляет в таблицу свою версию (имя файла без .sql)
+CREATE TABLE schema_migrations (
+    version text NOT NULL PRIMARY KEY,
+    applied_at timestamptz DEFAULT now() NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001_branches'),
+    ('002_groups'),
+    ('003_enrollments'),
+    ('004_curator_assignments'),
+    ('005_course_catalog'),
+    ('006_homework'),
+    ('007_grading'),
+    ('008_certificates'),
+    ('009_leads'),
+    ('010_crm_sync'),
+    ('011_webhooks'),
+    ('012_outbox'),
+    ('013_trial_reminders'),
+    ('014_sessions_expiry'),
+    ('015_schema_migrations');
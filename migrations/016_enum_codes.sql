@@ -0,0 +1,39 @@
+-- Значения перечислений хранятся нейтральными кодами вместо русских слов. RENAME VALUE
+-- меняет только подпись значения, поэтому данные, значения по умолчанию и частичные индексы
+-- переходят на коды сами. Прежние русские значения API по-прежнему принимает
+ALTER TYPE attendance_type RENAME VALUE 'урок' TO 'lesson';
+ALTER TYPE attendance_type RENAME VALUE 'заморозка' TO 'freeze';
+ALTER TYPE attendance_type RENAME VALUE 'пролонгация' TO 'prolongation';
+
+ALTER TYPE is_active RENAME VALUE 'активен' TO 'active';
+ALTER TYPE is_active RENAME VALUE 'неактивен' TO 'inactive';
+
+ALTER TYPE lessons_status RENAME VALUE 'пропущен' TO 'missed';
+ALTER TYPE lessons_status RENAME VALUE 'проведен' TO 'conducted';
+ALTER TYPE lessons_status RENAME VALUE 'запланирован' TO 'scheduled';
+ALTER TYPE lessons_status RENAME VALUE 'отменен' TO 'canceled';
+
+ALTER TYPE payment_type RENAME VALUE 'оплата' TO 'payment';
+ALTER TYPE payment_type RENAME VALUE 'предоплата' TO 'prepayment';
+ALTER TYPE payment_type RENAME VALUE 'доплата' TO 'additional_payment';
+
+ALTER TYPE enrollment_status RENAME VALUE 'активен' TO 'active';
+ALTER TYPE enrollment_status RENAME VALUE 'приостановлен' TO 'paused';
+ALTER TYPE enrollment_status RENAME VALUE 'завершен' TO 'completed';
+ALTER TYPE enrollment_status RENAME VALUE 'отменен' TO 'canceled';
+
+ALTER TYPE course_status RENAME VALUE 'активен' TO 'active';
+ALTER TYPE course_status RENAME VALUE 'архив' TO 'archived';
+
+ALTER TYPE homework_status RENAME VALUE 'не сдано' TO 'not_submitted';
+ALTER TYPE homework_status RENAME VALUE 'сдано' TO 'submitted';
+ALTER TYPE homework_status RENAME VALUE 'проверено' TO 'checked';
+
+ALTER TYPE lead_status RENAME VALUE 'новый' TO 'new';
+ALTER TYPE lead_status RENAME VALUE 'связались' TO 'contacted';
+ALTER TYPE lead_status RENAME VALUE 'пробный назначен' TO 'trial_scheduled';
+ALTER TYPE lead_status RENAME VALUE 'пробный проведен' TO 'trial_conducted';
+ALTER TYPE lead_status RENAME VALUE 'конвертирован' TO 'converted';
+ALTER TYPE lead_status RENAME VALUE 'потерян' TO 'lost';
+
+INSERT INTO schema_migrations (version) VALUES ('016_enum_codes');
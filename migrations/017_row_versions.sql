@@ -0,0 +1,99 @@
+-- Оптимистичная блокировка и аудит изменений. version растет на каждом UPDATE (триггер),
+-- клиент получает ее в ETag и возвращает в If-Match; запрос со старой версией получает 412.
+-- updated_by заполняет приложение: пользователь запроса или NULL для фоновых задач и CRM;
+-- служебные обновления (токены сброса пароля, отметки о напоминаниях) его не меняют.
+-- Внешнего ключа на users у updated_by нет, чтобы автор изменения сохранялся после удаления пользователя.
+-- Строки attendance_lessons/freezes/prolongations меняются только вместе с attendance
+-- и версионируются через нее
+CREATE FUNCTION touch_row_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE branches
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER branches_version BEFORE UPDATE ON branches
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE courses
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER courses_version BEFORE UPDATE ON courses
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE users
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER users_version BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE students
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER students_version BEFORE UPDATE ON students
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE enrollments
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER enrollments_version BEFORE UPDATE ON enrollments
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE attendance
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER attendance_version BEFORE UPDATE ON attendance
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE groups
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER groups_version BEFORE UPDATE ON groups
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE homework_submissions
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER homework_submissions_version BEFORE UPDATE ON homework_submissions
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+-- У leads и certificate_templates updated_at уже есть
+ALTER TABLE leads
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER leads_version BEFORE UPDATE ON leads
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE trial_lessons
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER trial_lessons_version BEFORE UPDATE ON trial_lessons
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE certificate_templates
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER certificate_templates_version BEFORE UPDATE ON certificate_templates
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN version integer DEFAULT 1 NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_by uuid NULL;
+CREATE TRIGGER webhook_subscriptions_version BEFORE UPDATE ON webhook_subscriptions
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+INSERT INTO schema_migrations (version) VALUES ('017_row_versions');
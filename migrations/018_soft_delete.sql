@@ -0,0 +1,31 @@
+-- Мягкое удаление студентов, пользователей, курсов и записей посещаемости. Удаленная запись
+-- получает deleted_at и deleted_by, скрывается из выборок и попадает в корзину (/settings/trash),
+-- откуда ее можно восстановить. Через TRASH_RETENTION задание trash-purge удаляет ее окончательно.
+-- Email пользователя и crm_external_id студента остаются занятыми, пока запись в корзине
+ALTER TABLE students
+    ADD COLUMN deleted_at timestamptz NULL,
+    ADD COLUMN deleted_by uuid NULL;
+CREATE INDEX students_deleted_at_idx ON students(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE users
+    ADD COLUMN deleted_at timestamptz NULL,
+    ADD COLUMN deleted_by uuid NULL;
+CREATE INDEX users_deleted_at_idx ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE courses
+    ADD COLUMN deleted_at timestamptz NULL,
+    ADD COLUMN deleted_by uuid NULL;
+CREATE INDEX courses_deleted_at_idx ON courses(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE attendance
+    ADD COLUMN deleted_at timestamptz NULL,
+    ADD COLUMN deleted_by uuid NULL;
+CREATE INDEX attendance_deleted_at_idx ON attendance(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Окончательное удаление курса больше не уносит посещаемость и оплаты студентов:
+-- курс, на который ссылаются записи посещаемости, остается в корзине
+ALTER TABLE attendance
+    DROP CONSTRAINT attendance_course_id_fkey,
+    ADD CONSTRAINT attendance_course_id_fkey FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE RESTRICT;
+
+INSERT INTO schema_migrations (version) VALUES ('018_soft_delete');
@@ -0,0 +1,43 @@
+-- Жизненный цикл студента вместо бинарного is_active: lead → trial → active ⇄ frozen → graduated,
+-- а также churned и archived. Допустимые переходы проверяет приложение (models.CanChangeLifecycle),
+-- каждый переход пишется в student_lifecycle_transitions со временем и автором.
+-- is_active остается для совместимости и вычисляется из состояния: active и frozen — 'active'.
+-- Задание lifecycle-freezes переводит active в frozen на время заморозки (attendance_freezes) и обратно
+CREATE TYPE public."student_lifecycle_state" AS ENUM ('lead', 'trial', 'active', 'frozen', 'graduated', 'churned', 'archived');
+
+ALTER TABLE students
+    ADD COLUMN lifecycle_state public."student_lifecycle_state" DEFAULT 'active' NOT NULL,
+    ADD COLUMN lifecycle_changed_at timestamptz DEFAULT now() NOT NULL;
+
+-- Перенос не считается изменением карточки: версии и updated_at остаются прежними
+ALTER TABLE students DISABLE TRIGGER students_version;
+UPDATE students SET lifecycle_state = 'churned' WHERE is_active = 'inactive';
+ALTER TABLE students ENABLE TRIGGER students_version;
+
+ALTER TABLE students DROP COLUMN is_active;
+ALTER TABLE students ADD COLUMN is_active public."is_active" GENERATED ALWAYS AS (
+    CASE WHEN lifecycle_state IN ('active', 'frozen') THEN 'active'::is_active ELSE 'inactive'::is_active END
+) STORED;
+
+CREATE INDEX students_lifecycle_state_idx ON students(lifecycle_state);
+
+-- source: manual — сотрудник, created — создание карточки, crm — входящий вебхук CRM,
+-- attendance_freeze — автоматическая заморозка и разморозка
+CREATE TABLE student_lifecycle_transitions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    from_state public."student_lifecycle_state" NULL,
+    to_state public."student_lifecycle_state" NOT NULL,
+    source text NOT NULL,
+    comment text NULL,
+    changed_at timestamptz DEFAULT now() NOT NULL,
+    changed_by uuid NULL
+);
+
+CREATE INDEX student_lifecycle_transitions_student_id_idx ON student_lifecycle_transitions(student_id, changed_at);
+
+-- Начальная запись истории для существующих студентов
+INSERT INTO student_lifecycle_transitions (student_id, from_state, to_state, source, changed_at)
+SELECT id, NULL, lifecycle_state, 'created', COALESCE(created_at, now()) FROM students;
+
+INSERT INTO schema_migrations (version) VALUES ('019_student_lifecycle');
@@ -0,0 +1,20 @@
+-- Доставка событий в CRM через outbox: события записываются в транзакции доменного изменения,
+-- crm.Client переносит их в crm_deliveries и отправляет в фоне. Повторы планируются по next_attempt_at,
+-- после исчерпания попыток доставка переносится в crm_dead_letters
+CREATE TABLE crm_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    event_id uuid NOT NULL UNIQUE,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX crm_deliveries_due_idx ON crm_deliveries(next_attempt_at);
+
+-- Обработанные события outbox удаляются заданием обслуживания после OUTBOX_RETENTION
+CREATE INDEX outbox_processed_idx ON outbox(processed_at) WHERE processed_at IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES ('020_crm_deliveries');
@@ -0,0 +1,24 @@
+// Package migrations встраивает SQL-миграции в бинарник, чтобы проверка готовности
+// могла сравнить их со списком примененных в schema_migrations
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Versions возвращает версии миграций (имена файлов без .sql) в порядке применения
+func Versions() []string {
+	names, _ := fs.Glob(files, "*.sql")
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, strings.TrimSuffix(name, ".sql"))
+	}
+	sort.Strings(versions)
+	return versions
+}
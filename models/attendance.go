@@ -12,6 +12,10 @@ type Attendance struct {
 	CourseId  uuid.UUID `json:"course_id"`
 	Type      string    `json:"type"` // lesson, freeze, prolongation
 	CreatedAt time.Time `json:"created_at"`
+	// Version растет при каждом изменении записи (включая урок, заморозку или пролонгацию) и отдается в ETag
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy *uuid.UUID `json:"updated_by"`
 }
 
 type AttendanceLesson struct {
@@ -19,10 +23,12 @@ type AttendanceLesson struct {
 	CuratorId     uuid.UUID  `json:"curator_id"`
 	Date          time.Time  `json:"date"`
 	Format        *string    `json:"format"`
-	Feedback      *string    `json:"feedback"`	
+	Feedback      *string    `json:"feedback"`
 	LessonStatus  string     `json:"lessons_status"`
-	CreatedAt 	  time.Time `json:"created_at"`	
+	CreatedAt     time.Time  `json:"created_at"`
 	FeedbackDate  *time.Time `json:"feedback_date"`
+	GroupLessonId *uuid.UUID `json:"group_lesson_id,omitempty"`
+	TopicId       *uuid.UUID `json:"topic_id"`
 }
 
 type AttendanceFreeze struct {
@@ -41,8 +47,8 @@ type AttendanceProlongation struct {
 }
 
 type AttendanceFullResponse struct {
-    Attendance  *Attendance           		`json:"attendance"`
-    Lesson      *AttendanceLesson     		`json:"lesson,omitempty"`
-    Freeze      *AttendanceFreeze     		`json:"freeze,omitempty"`
-    Prolongation *AttendanceProlongation 	`json:"prolongation,omitempty"`
-}
\ No newline at end of file
+	Attendance   *Attendance             `json:"attendance"`
+	Lesson       *AttendanceLesson       `json:"lesson,omitempty"`
+	Freeze       *AttendanceFreeze       `json:"freeze,omitempty"`
+	Prolongation *AttendanceProlongation `json:"prolongation,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Branch struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Address   *string   `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BranchReport — сводные показатели филиала для отчета владельца
+type BranchReport struct {
+	BranchId         uuid.UUID `json:"branch_id"`
+	BranchName       string    `json:"branch_name"`
+	ActiveStudents   int       `json:"active_students"`
+	InactiveStudents int       `json:"inactive_students"`
+	Courses          int       `json:"courses"`
+	Users            int       `json:"users"`
+	LessonsConducted int       `json:"lessons_conducted"`
+	PaymentsTotal    float64   `json:"payments_total"`
+}
+
+type branchContextKey struct{}
+
+// WithBranch кладет текущий филиал в контекст запроса
+func WithBranch(c context.Context, branchID uuid.UUID) context.Context {
+	return context.WithValue(c, branchContextKey{}, branchID)
+}
+
+// BranchFromContext возвращает текущий филиал или nil, если запрос не ограничен филиалом
+// (например, владелец смотрит данные по всем филиалам)
+func BranchFromContext(c context.Context) *uuid.UUID {
+	branchID, ok := c.Value(branchContextKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &branchID
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CertificateTemplate — оформление сертификата курса. Body — text/template с полями CertificateData
+type CertificateTemplate struct {
+	CourseId      uuid.UUID `json:"course_id"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	SignatureName *string   `json:"signature_name"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DefaultCertificateTemplate используется, если для курса шаблон не задан
+var DefaultCertificateTemplate = CertificateTemplate{
+	Title: "Сертификат",
+	Body:  "Настоящим подтверждается, что {{.StudentName}} успешно завершил(а) курс «{{.CourseTitle}}» ({{.StartDate}} — {{.EndDate}}).",
+}
+
+// Certificate — выданный сертификат
+type Certificate struct {
+	Id           uuid.UUID  `json:"id"`
+	Number       string     `json:"number"`
+	EnrollmentId *uuid.UUID `json:"enrollment_id"`
+	StudentId    *uuid.UUID `json:"student_id"`
+	StudentName  string     `json:"student_name"`
+	CourseTitle  string     `json:"course_title"`
+	CuratorName  *string    `json:"curator_name"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	StoragePath  string     `json:"-"`
+	IssuedAt     time.Time  `json:"issued_at"`
+}
+
+// CertificateVerification — публичный ответ на проверку сертификата по номеру
+type CertificateVerification struct {
+	Number      string    `json:"number"`
+	StudentName string    `json:"student_name"`
+	CourseTitle string    `json:"course_title"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
@@ -1,8 +1,62 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Course struct {
-	Id    uuid.UUID `json:"id"`
-	Title string    `json:"title"`
+	Id            uuid.UUID `json:"id"`
+	Title         string    `json:"title"`
+	BranchId      uuid.UUID `json:"branch_id"`
+	Description   *string   `json:"description"`
+	AgeMin        *int      `json:"age_min"`
+	AgeMax        *int      `json:"age_max"`
+	DurationWeeks *int      `json:"duration_weeks"`
+	LessonsCount  *int      `json:"lessons_count"`
+	Price         *float64  `json:"price"`
+	Status        string    `json:"status"` // active, archived
+	CreatedAt     time.Time `json:"created_at"`
+	// Version растет при каждом изменении и отдается в ETag
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy *uuid.UUID `json:"updated_by"`
+}
+
+// CourseModule — раздел программы курса
+type CourseModule struct {
+	Id          uuid.UUID     `json:"id"`
+	CourseId    uuid.UUID     `json:"course_id"`
+	Position    int           `json:"position"`
+	Title       string        `json:"title"`
+	Description *string       `json:"description"`
+	Topics      []CourseTopic `json:"topics"`
+}
+
+// CourseTopic — тема модуля с планом урока
+type CourseTopic struct {
+	Id          uuid.UUID `json:"id"`
+	ModuleId    uuid.UUID `json:"module_id"`
+	Position    int       `json:"position"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	LessonPlan  *string   `json:"lesson_plan"`
+}
+
+// CourseProgress — прохождение программы курса студентом
+type CourseProgress struct {
+	StudentId     uuid.UUID       `json:"student_id"`
+	CourseId      uuid.UUID       `json:"course_id"`
+	TotalTopics   int             `json:"total_topics"`
+	CoveredTopics int             `json:"covered_topics"`
+	Topics        []TopicProgress `json:"topics"`
+}
+
+// TopicProgress — тема программы и дата первого проведенного урока по ней
+type TopicProgress struct {
+	TopicId     uuid.UUID  `json:"topic_id"`
+	ModuleTitle string     `json:"module_title"`
+	Title       string     `json:"title"`
+	CoveredAt   *time.Time `json:"covered_at"`
 }
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CrmEvent — событие, отправляемое во внешнюю CRM и принимаемое от нее
+type CrmEvent struct {
+	Id         uuid.UUID       `json:"id"`
+	Event      string          `json:"event"` // student.created, student.updated, student.deleted, payment.created, payment.updated, student.upsert
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// CrmStudent — данные студента в событиях CRM
+type CrmStudent struct {
+	Id                uuid.UUID `json:"id"`
+	ExternalId        *string   `json:"external_id"`
+	BranchId          uuid.UUID `json:"branch_id"`
+	FullName          string    `json:"full_name"`
+	PhoneNumber       *string   `json:"phone_number"`
+	ParentName        string    `json:"parent_name"`
+	ParentPhoneNumber *string   `json:"parent_phone_number"`
+	PlatformLink      string    `json:"platform_link"`
+	CrmLink           string    `json:"crm_link"`
+	IsActive          *string   `json:"is_active"`
+	LifecycleState    string    `json:"lifecycle_state,omitempty"`
+}
+
+func NewCrmStudent(student Student) CrmStudent {
+	crmStudent := CrmStudent{
+		Id:                student.Id,
+		ExternalId:        student.CrmExternalId,
+		BranchId:          student.BranchId,
+		FullName:          student.FullName,
+		PhoneNumber:       student.PhoneNumber,
+		ParentName:        student.ParentName,
+		ParentPhoneNumber: student.ParentPhoneNumber,
+		PlatformLink:      student.PlatformLink,
+		CrmLink:           student.CrmLink,
+		IsActive:          student.IsActive,
+		LifecycleState:    student.LifecycleState,
+	}
+	// is_active вычисляется из состояния, если оно известно
+	if student.LifecycleState != "" {
+		isActive := LifecycleIsActive(student.LifecycleState)
+		crmStudent.IsActive = &isActive
+	}
+	return crmStudent
+}
+
+// CrmPayment — оплата (пролонгация) в событиях CRM
+type CrmPayment struct {
+	AttendanceId uuid.UUID `json:"attendance_id"`
+	StudentId    uuid.UUID `json:"student_id"`
+	CourseId     uuid.UUID `json:"course_id"`
+	PaymentType  string    `json:"payment_type"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Comment      *string   `json:"comment"`
+}
+
+// CrmDeadLetter — событие, которое не удалось доставить в CRM
+type CrmDeadLetter struct {
+	Id            uuid.UUID       `json:"id"`
+	EventId       uuid.UUID       `json:"event_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastAttemptAt time.Time       `json:"last_attempt_at"`
+}
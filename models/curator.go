@@ -1,9 +1,24 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Curator struct {
 	UserID     uuid.UUID   `json:"user_id"`
 	StudentIDs []uuid.UUID `json:"student_ids"`
 	CourseIDs  []uuid.UUID `json:"course_ids"`
 }
+
+// CuratorAssignment — период, в течение которого куратор вел студента
+type CuratorAssignment struct {
+	Id           uuid.UUID  `json:"id"`
+	CuratorId    uuid.UUID  `json:"curator_id"`
+	CuratorName  string     `json:"curator_name"`
+	StudentId    uuid.UUID  `json:"student_id"`
+	AssignedAt   time.Time  `json:"assigned_at"`
+	UnassignedAt *time.Time `json:"unassigned_at"`
+	AssignedBy   *uuid.UUID `json:"assigned_by"`
+}
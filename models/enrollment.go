@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Enrollment — зачисление студента на курс со своим куратором, сроками и статусом
+type Enrollment struct {
+	Id        uuid.UUID  `json:"id"`
+	StudentId uuid.UUID  `json:"student_id"`
+	CourseId  uuid.UUID  `json:"course_id"`
+	CuratorId *uuid.UUID `json:"curator_id"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Status    string     `json:"status"` // active, paused, completed, canceled
+	CreatedAt time.Time  `json:"created_at"`
+	Version   int        `json:"version"` // растет при каждом изменении, передается в If-Match при обновлении
+}
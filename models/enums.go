@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Коды значений перечислений. В БД и API хранятся коды, подписи на языке пользователя — в пакете i18n
+const (
+	AttendanceTypeLesson       = "lesson"
+	AttendanceTypeFreeze       = "freeze"
+	AttendanceTypeProlongation = "prolongation"
+
+	StudentActive   = "active"
+	StudentInactive = "inactive"
+
+	LifecycleLead      = "lead"
+	LifecycleTrial     = "trial"
+	LifecycleActive    = "active"
+	LifecycleFrozen    = "frozen"
+	LifecycleGraduated = "graduated"
+	LifecycleChurned   = "churned"
+	LifecycleArchived  = "archived"
+
+	LessonMissed    = "missed"
+	LessonConducted = "conducted"
+	LessonScheduled = "scheduled"
+	LessonCanceled  = "canceled"
+
+	PaymentFull       = "payment"
+	PaymentPrepayment = "prepayment"
+	PaymentAdditional = "additional_payment"
+
+	EnrollmentActive    = "active"
+	EnrollmentPaused    = "paused"
+	EnrollmentCompleted = "completed"
+	EnrollmentCanceled  = "canceled"
+
+	CourseActive   = "active"
+	CourseArchived = "archived"
+
+	HomeworkNotSubmitted = "not_submitted"
+	HomeworkSubmitted    = "submitted"
+	HomeworkChecked      = "checked"
+
+	LeadNew            = "new"
+	LeadContacted      = "contacted"
+	LeadTrialScheduled = "trial_scheduled"
+	LeadTrialConducted = "trial_conducted"
+	LeadConverted      = "converted"
+	LeadLost           = "lost"
+)
+
+// Имена перечислений (совпадают с типами в БД)
+const (
+	EnumAttendanceType   = "attendance_type"
+	EnumIsActive         = "is_active"
+	EnumLessonsStatus    = "lessons_status"
+	EnumPaymentType      = "payment_type"
+	EnumEnrollmentStatus = "enrollment_status"
+	EnumCourseStatus     = "course_status"
+	EnumHomeworkStatus   = "homework_status"
+	EnumLeadStatus       = "lead_status"
+	EnumLifecycleState   = "student_lifecycle_state"
+)
+
+// Enums — значения каждого перечисления в порядке отображения
+var Enums = map[string][]string{
+	EnumAttendanceType:   {AttendanceTypeLesson, AttendanceTypeFreeze, AttendanceTypeProlongation},
+	EnumIsActive:         {StudentActive, StudentInactive},
+	EnumLessonsStatus:    {LessonScheduled, LessonConducted, LessonMissed, LessonCanceled},
+	EnumPaymentType:      {PaymentFull, PaymentPrepayment, PaymentAdditional},
+	EnumEnrollmentStatus: {EnrollmentActive, EnrollmentPaused, EnrollmentCompleted, EnrollmentCanceled},
+	EnumCourseStatus:     {CourseActive, CourseArchived},
+	EnumHomeworkStatus:   {HomeworkNotSubmitted, HomeworkSubmitted, HomeworkChecked},
+	EnumLeadStatus:       {LeadNew, LeadContacted, LeadTrialScheduled, LeadTrialConducted, LeadConverted, LeadLost},
+	EnumLifecycleState:   {LifecycleLead, LifecycleTrial, LifecycleActive, LifecycleFrozen, LifecycleGraduated, LifecycleChurned, LifecycleArchived},
+}
+
+// legacyEnumValues — русские значения, которые API принимал до перехода на коды.
+// Одно русское слово во всех перечислениях означает одно и то же, поэтому таблица общая
+var legacyEnumValues = map[string]string{
+	"урок":             AttendanceTypeLesson,
+	"заморозка":        AttendanceTypeFreeze,
+	"пролонгация":      AttendanceTypeProlongation,
+	"активен":          StudentActive,
+	"неактивен":        StudentInactive,
+	"пропущен":         LessonMissed,
+	"проведен":         LessonConducted,
+	"запланирован":     LessonScheduled,
+	"отменен":          LessonCanceled,
+	"оплата":           PaymentFull,
+	"предоплата":       PaymentPrepayment,
+	"доплата":          PaymentAdditional,
+	"приостановлен":    EnrollmentPaused,
+	"завершен":         EnrollmentCompleted,
+	"архив":            CourseArchived,
+	"не сдано":         HomeworkNotSubmitted,
+	"сдано":            HomeworkSubmitted,
+	"проверено":        HomeworkChecked,
+	"новый":            LeadNew,
+	"связались":        LeadContacted,
+	"пробный назначен": LeadTrialScheduled,
+	"пробный проведен": LeadTrialConducted,
+	"конвертирован":    LeadConverted,
+	"потерян":          LeadLost,
+}
+
+// NormalizeEnum переводит прежнее русское значение в код; коды и неизвестные значения возвращаются как есть
+func NormalizeEnum(value string) string {
+	if code, ok := legacyEnumValues[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return code
+	}
+	return value
+}
+
+// EnumCode — значение перечисления во входящем запросе. При разборе JSON и параметров
+// прежние русские значения заменяются кодами, поэтому проверка oneof идет только по кодам
+type EnumCode string
+
+func (e *EnumCode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*e = EnumCode(NormalizeEnum(value))
+	return nil
+}
+
+func (e *EnumCode) UnmarshalText(text []byte) error {
+	*e = EnumCode(NormalizeEnum(string(text)))
+	return nil
+}
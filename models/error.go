@@ -1,17 +1,199 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
-type ApiError struct {
-	Error string
+// ErrorKind — класс ошибки, по которому выбирается HTTP-статус ответа
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindUnavailable
+	KindBadGateway
+	KindPreconditionFailed
+	KindPreconditionRequired
+)
+
+// Status возвращает HTTP-статус для класса ошибки
+func (k ErrorKind) Status() int {
+	switch k {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindBadGateway:
+		return http.StatusBadGateway
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Коды, общие для всех ресурсов
+const (
+	CodeInternal       = "internal_error"
+	CodeInvalidRequest = "invalid_request"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+)
+
+// FieldError — ошибка конкретного поля запроса
+type FieldError struct {
+	Field   string `json:"field" example:"phone_number"`
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"phone_number is required"`
+}
+
+// AppError — доменная ошибка со стабильным кодом. Репозитории и обработчики возвращают ее,
+// а ErrorMiddleware превращает в ответ application/problem+json
+type AppError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибки по классу и коду, поэтому errors.Is(err, ErrLeadConverted)
+// срабатывает и для копии, полученной через Wrap
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap возвращает копию ошибки с причиной err. Причина пишется в лог, но не уходит клиенту
+func (e *AppError) Wrap(err error) *AppError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func newAppError(kind ErrorKind, code, msg string) *AppError {
+	return &AppError{Kind: kind, Code: code, Message: msg}
+}
+
+func NewValidationError(code, msg string, fields ...FieldError) *AppError {
+	err := newAppError(KindValidation, code, msg)
+	err.Fields = fields
+	return err
+}
+
+// NewBindingError — тело или параметры запроса не разобрались. Ошибки валидатора
+// раскладываются по полям в ErrorMiddleware
+func NewBindingError(msg string, err error) *AppError {
+	return newAppError(KindValidation, CodeInvalidRequest, msg).Wrap(err)
+}
+
+func NewUnauthorizedError(code, msg string) *AppError {
+	return newAppError(KindUnauthorized, code, msg)
+}
+
+func NewForbiddenError(code, msg string) *AppError {
+	return newAppError(KindForbidden, code, msg)
+}
+
+func NewNotFoundError(code, msg string) *AppError {
+	return newAppError(KindNotFound, code, msg)
+}
+
+func NewConflictError(code, msg string) *AppError {
+	return newAppError(KindConflict, code, msg)
+}
+
+func NewTooLargeError(code, msg string) *AppError {
+	return newAppError(KindTooLarge, code, msg)
+}
+
+func NewUnavailableError(code, msg string) *AppError {
+	return newAppError(KindUnavailable, code, msg)
+}
+
+func NewBadGatewayError(code, msg string) *AppError {
+	return newAppError(KindBadGateway, code, msg)
+}
+
+func NewPreconditionFailedError(code, msg string) *AppError {
+	return newAppError(KindPreconditionFailed, code, msg)
+}
+
+func NewPreconditionRequiredError(code, msg string) *AppError {
+	return newAppError(KindPreconditionRequired, code, msg)
+}
+
+// NewInternalError — ошибка сервера. Код у всех внутренних ошибок один, подробности только в логе
+func NewInternalError(msg string) *AppError {
+	return newAppError(KindInternal, CodeInternal, msg)
+}
+
+// AsAppError достает AppError из цепочки ошибок
+func AsAppError(err error) (*AppError, bool) {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
 }
 
-func NewApiError(msg string) ApiError {
-	return ApiError{msg}
+// Problem — тело ответа об ошибке по RFC 7807 (application/problem+json).
+// Клиенты ориентируются на code, а не на текст detail
+type Problem struct {
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"Student not found"`
+	Instance  string       `json:"instance,omitempty" example:"/students/7d5c0a4e-3f0b-4a8e-9d6c-2b1f5e8a9c10"`
+	Code      string       `json:"code" example:"student_not_found"`
+	RequestId string       `json:"request_id,omitempty" example:"0b8f3c2e-6a1d-4f7e-9c5b-8e2d1a4f6b3c"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
 var (
-	ErrMissingLessonData       = errors.New("lesson data is required for type 'lesson'")
-	ErrMissingFreezeData       = errors.New("freeze data is required for type 'freeze'")
-	ErrMissingProlongationData = errors.New("prolongation data is required for type 'prolongation'")
-)
\ No newline at end of file
+	ErrMissingLessonData       = NewValidationError("lesson_data_required", "Lesson data is required for type 'lesson'")
+	ErrMissingFreezeData       = NewValidationError("freeze_data_required", "Freeze data is required for type 'freeze'")
+	ErrMissingProlongationData = NewValidationError("prolongation_data_required", "Prolongation data is required for type 'prolongation'")
+	ErrTopicNotInCourse        = NewValidationError("topic_not_in_course", "Topic does not belong to the course")
+	ErrInvalidScore            = NewValidationError("invalid_score", "Criterion does not belong to the course or score is out of range")
+	ErrEnrollmentNotCompleted  = NewConflictError("enrollment_not_completed", "Enrollment is not completed")
+	ErrLeadConverted           = NewConflictError("lead_converted", "Lead is already converted")
+	ErrLeadCourseRequired      = NewValidationError("lead_course_required", "Course is required to convert lead")
+	ErrCrmBranchRequired       = NewValidationError("crm_branch_required", "Branch is required to create student from CRM")
+	ErrCrmStudentInTrash       = NewConflictError("crm_student_in_trash", "Student is in the trash. Restore it before syncing from CRM")
+	ErrIfMatchRequired         = NewPreconditionRequiredError("if_match_required", "If-Match header is required")
+	ErrVersionConflict         = NewPreconditionFailedError("version_conflict", "Resource was modified by another request")
+	ErrCourseInUse             = NewConflictError("course_in_use", "Course has enrolled students")
+	ErrEmailAlreadyExists      = NewConflictError("email_already_exists", "Email already exists")
+	ErrEmailInTrash            = NewConflictError("email_in_trash", "User with this email is in the trash. Restore it instead")
+	ErrLifecycleTransition     = NewConflictError("invalid_lifecycle_transition", "Student lifecycle transition is not allowed")
+)
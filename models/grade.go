@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RubricCriterion — критерий оценивания курса со шкалой баллов
+type RubricCriterion struct {
+	Id          uuid.UUID `json:"id"`
+	CourseId    uuid.UUID `json:"course_id"`
+	Position    int       `json:"position"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	MinScore    int       `json:"min_score"`
+	MaxScore    int       `json:"max_score"`
+}
+
+// StudentScore — балл студента по критерию за урок или модуль
+type StudentScore struct {
+	Id           uuid.UUID  `json:"id"`
+	StudentId    uuid.UUID  `json:"student_id"`
+	CriterionId  uuid.UUID  `json:"criterion_id"`
+	AttendanceId *uuid.UUID `json:"attendance_id"`
+	ModuleId     *uuid.UUID `json:"module_id"`
+	Score        int        `json:"score"`
+	Comment      *string    `json:"comment"`
+	ScoredBy     *uuid.UUID `json:"scored_by"`
+	ScoredAt     time.Time  `json:"scored_at"`
+}
+
+// CriterionProgress — динамика баллов по одному критерию для графика
+type CriterionProgress struct {
+	CriterionId uuid.UUID    `json:"criterion_id"`
+	Title       string       `json:"title"`
+	MinScore    int          `json:"min_score"`
+	MaxScore    int          `json:"max_score"`
+	Average     *float64     `json:"average"`
+	Points      []ScorePoint `json:"points"`
+}
+
+// ScorePoint — балл на дату урока (или дату оценки модуля)
+type ScorePoint struct {
+	Date     time.Time  `json:"date"`
+	Score    int        `json:"score"`
+	ModuleId *uuid.UUID `json:"module_id,omitempty"`
+}
+
+// ReportCard — данные табеля студента по курсу за период
+type ReportCard struct {
+	StudentName   string              `json:"student_name"`
+	ParentName    string              `json:"parent_name"`
+	CourseTitle   string              `json:"course_title"`
+	CuratorName   *string             `json:"curator_name"`
+	From          time.Time           `json:"from"`
+	To            time.Time           `json:"to"`
+	Criteria      []CriterionProgress `json:"criteria"`
+	LessonsHeld   int                 `json:"lessons_held"`
+	LessonsMissed int                 `json:"lessons_missed"`
+	TopicsCovered int                 `json:"topics_covered"`
+	TopicsTotal   int                 `json:"topics_total"`
+	HomeworkTotal int                 `json:"homework_total"`
+	HomeworkDone  int                 `json:"homework_done"`
+	HomeworkGrade *float64            `json:"homework_grade"`
+	Comments      []string            `json:"comments"`
+	GeneratedAt   time.Time           `json:"generated_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group — учебная группа (класс) из нескольких студентов одного курса
+type Group struct {
+	Id         uuid.UUID           `json:"id"`
+	BranchId   uuid.UUID           `json:"branch_id"`
+	CourseId   uuid.UUID           `json:"course_id"`
+	CuratorId  *uuid.UUID          `json:"curator_id"`
+	Title      string              `json:"title"`
+	Schedule   []GroupScheduleSlot `json:"schedule"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StudentIds []uuid.UUID         `json:"student_ids"`
+	Version    int                 `json:"version"` // растет при каждом изменении и отдается в ETag
+}
+
+// GroupScheduleSlot — регулярное занятие группы
+type GroupScheduleSlot struct {
+	Weekday         int    `json:"weekday"`    // 1 — понедельник, 7 — воскресенье
+	StartTime       string `json:"start_time"` // HH:MM
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// GroupLesson — групповой урок, который раскладывается на записи посещаемости каждого студента
+type GroupLesson struct {
+	Id          uuid.UUID               `json:"id"`
+	GroupId     uuid.UUID               `json:"group_id"`
+	CuratorId   uuid.UUID               `json:"curator_id"`
+	Date        time.Time               `json:"date"`
+	Format      *string                 `json:"format"`
+	TopicId     *uuid.UUID              `json:"topic_id"`
+	CreatedAt   time.Time               `json:"created_at"`
+	Attendances []GroupLessonAttendance `json:"attendances"`
+}
+
+// GroupLessonAttendance — индивидуальный статус студента на групповом уроке
+type GroupLessonAttendance struct {
+	AttendanceId uuid.UUID `json:"attendance_id"`
+	StudentId    uuid.UUID `json:"student_id"`
+	FullName     string    `json:"full_name"`
+	LessonStatus string    `json:"lessons_status"`
+	Feedback     *string   `json:"feedback"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Homework — домашнее задание, выданное на индивидуальном или групповом уроке
+type Homework struct {
+	Id            uuid.UUID            `json:"id"`
+	AttendanceId  *uuid.UUID           `json:"attendance_id"`
+	GroupLessonId *uuid.UUID           `json:"group_lesson_id"`
+	Description   string               `json:"description"`
+	DueDate       time.Time            `json:"due_date"`
+	CreatedBy     *uuid.UUID           `json:"created_by"`
+	CreatedAt     time.Time            `json:"created_at"`
+	Submissions   []HomeworkSubmission `json:"submissions"`
+}
+
+// HomeworkSubmission — сдача задания конкретным студентом
+type HomeworkSubmission struct {
+	Id          uuid.UUID            `json:"id"`
+	HomeworkId  uuid.UUID            `json:"homework_id"`
+	StudentId   uuid.UUID            `json:"student_id"`
+	FullName    string               `json:"full_name"`
+	Status      string               `json:"status"` // not_submitted, submitted, checked
+	SubmittedAt *time.Time           `json:"submitted_at"`
+	CheckedAt   *time.Time           `json:"checked_at"`
+	CheckedBy   *uuid.UUID           `json:"checked_by"`
+	Grade       *int                 `json:"grade"`
+	Comment     *string              `json:"comment"`
+	Attachments []HomeworkAttachment `json:"attachments"`
+}
+
+type HomeworkAttachment struct {
+	Id           uuid.UUID  `json:"id"`
+	SubmissionId uuid.UUID  `json:"submission_id"`
+	FileName     string     `json:"file_name"`
+	ContentType  string     `json:"content_type"`
+	Size         int64      `json:"size"`
+	StoragePath  string     `json:"-"`
+	UploadedBy   *uuid.UUID `json:"uploaded_by"`
+	UploadedAt   time.Time  `json:"uploaded_at"`
+}
+
+// OverdueHomework — несданное задание с истекшим сроком
+type OverdueHomework struct {
+	HomeworkId   uuid.UUID `json:"homework_id"`
+	SubmissionId uuid.UUID `json:"submission_id"`
+	StudentId    uuid.UUID `json:"student_id"`
+	FullName     string    `json:"full_name"`
+	CuratorId    uuid.UUID `json:"curator_id"`
+	Description  string    `json:"description"`
+	DueDate      time.Time `json:"due_date"`
+	DaysOverdue  int       `json:"days_overdue"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Lead — потенциальный студент до первой оплаты
+type Lead struct {
+	Id                uuid.UUID     `json:"id"`
+	BranchId          uuid.UUID     `json:"branch_id"`
+	FullName          string        `json:"full_name"`
+	PhoneNumber       *string       `json:"phone_number"`
+	ParentName        string        `json:"parent_name"`
+	ParentPhoneNumber string        `json:"parent_phone_number"`
+	Source            *string       `json:"source"`
+	CourseId          *uuid.UUID    `json:"course_id"`
+	Status            string        `json:"status"` // new, contacted, trial_scheduled, trial_conducted, converted, lost
+	Comment           *string       `json:"comment"`
+	StudentId         *uuid.UUID    `json:"student_id"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	Version           int           `json:"version"` // растет при каждом изменении и отдается в ETag
+	Trials            []TrialLesson `json:"trials"`
+}
+
+type LeadFilters struct {
+	Status   string
+	Source   string
+	CourseId *uuid.UUID
+	Search   string
+}
+
+// TrialLesson — пробный урок лида с куратором
+type TrialLesson struct {
+	Id        uuid.UUID  `json:"id"`
+	LeadId    uuid.UUID  `json:"lead_id"`
+	CuratorId *uuid.UUID `json:"curator_id"`
+	Date      time.Time  `json:"date"`
+	Status    string     `json:"status"` // scheduled, conducted, missed, canceled
+	Feedback  *string    `json:"feedback"`
+	CreatedAt time.Time  `json:"created_at"`
+	Version   int        `json:"version"` // растет при каждом изменении, передается в If-Match при обновлении
+	LeadName  string     `json:"lead_name,omitempty"`
+}
+
+// LeadConversion — данные для перевода лида в студенты: первая оплата и зачисление
+type LeadConversion struct {
+	CourseId     *uuid.UUID
+	CuratorId    *uuid.UUID
+	PhoneNumber  string
+	PlatformLink string
+	PaymentType  string
+	PaymentDate  time.Time
+	Amount       float64
+	Comment      *string
+}
+
+// LeadConversionResult — идентификаторы записей, созданных при конвертации лида
+type LeadConversionResult struct {
+	StudentId    uuid.UUID `json:"student_id"`
+	EnrollmentId uuid.UUID `json:"enrollment_id"`
+	AttendanceId uuid.UUID `json:"attendance_id"`
+}
+
+// TrialReminder — пробный урок, о котором нужно напомнить куратору
+type TrialReminder struct {
+	TrialId      uuid.UUID
+	Date         time.Time
+	LeadName     string
+	CuratorEmail string
+	CuratorName  *string
+}
@@ -1,15 +1,22 @@
 package models
 
 import (
+	"it_school/redact"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
 )
 
 type Session struct {
-	ID           uuid.UUID       `json:"id" db:"id"`
-	UserID       uuid.UUID       `json:"user_id" db:"user_id"`
-	RefreshToken string    `json:"refresh_token" db:"refresh_token"`
+	ID           uuid.UUID `json:"id" db:"id"`
+	UserID       uuid.UUID `json:"user_id" db:"user_id"`
+	RefreshToken string    `json:"refresh_token" db:"refresh_token" log:"secret"`
 	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-}
\ No newline at end of file
+}
+
+// MarshalLogObject скрывает refresh-токен при логировании сессии
+func (s Session) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return redact.Object(enc, s)
+}
@@ -1,28 +1,51 @@
 package models
 
 import (
+	"it_school/redact"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
 )
 
 type Student struct {
 	Id                uuid.UUID  `json:"id"`
-	CourseId          uuid.UUID  `json:"course_id"`
-	FullName          string     `json:"full_name"`
-	PhoneNumber       *string    `json:"phone_number"`
-	ParentName        string     `json:"parent_name"`
-	ParentPhoneNumber *string    `json:"parent_phone_number"`
+	BranchId          uuid.UUID  `json:"branch_id"`
+	FullName          string     `json:"full_name" log:"name"`
+	PhoneNumber       *string    `json:"phone_number" log:"phone"`
+	ParentName        string     `json:"parent_name" log:"name"`
+	ParentPhoneNumber *string    `json:"parent_phone_number" log:"phone"`
 	CuratorId         *uuid.UUID `json:"curator_id"`
 	PlatformLink      string     `json:"platform_link"`
 	CrmLink           string     `json:"crm_link"`
+	CrmExternalId     *string    `json:"crm_external_id"`
 	CreatedAt         *time.Time `json:"created_at"`
-	IsActive          *string    `json:"is_active"`
+	// IsActive вычисляется из LifecycleState (active и frozen — active) и оставлен для совместимости
+	IsActive           *string      `json:"is_active"`
+	LifecycleState     string       `json:"lifecycle_state" enums:"lead,trial,active,frozen,graduated,churned,archived"`
+	LifecycleChangedAt time.Time    `json:"lifecycle_changed_at"`
+	Enrollments        []Enrollment `json:"enrollments"`
+	// Version растет при каждом изменении и отдается в ETag
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy *uuid.UUID `json:"updated_by"`
+}
+
+// MarshalLogObject маскирует имена и телефоны при логировании студента
+func (s Student) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return redact.Object(enc, s)
 }
 
 type StudentFilters struct {
-	Search    string
-	Course    string
-	IsActive  string
-	CuratorId string
+	Search           string `log:"name"`
+	Course           string
+	IsActive         string
+	CuratorId        string
+	EnrollmentStatus string
+	// LifecycleStates — студенты в любом из перечисленных состояний
+	LifecycleStates []string
+}
+
+func (f StudentFilters) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return redact.Object(enc, f)
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Источники переходов жизненного цикла студента
+const (
+	LifecycleSourceManual  = "manual"
+	LifecycleSourceCreated = "created"
+	LifecycleSourceCrm     = "crm"
+	LifecycleSourceFreeze  = "attendance_freeze"
+)
+
+// lifecycleTransitions — состояния, в которые студента можно перевести из текущего.
+// frozen ⇄ active также меняет задание lifecycle-freezes по окнам заморозки
+var lifecycleTransitions = map[string][]string{
+	LifecycleLead:      {LifecycleTrial, LifecycleActive, LifecycleChurned, LifecycleArchived},
+	LifecycleTrial:     {LifecycleActive, LifecycleChurned, LifecycleArchived},
+	LifecycleActive:    {LifecycleFrozen, LifecycleGraduated, LifecycleChurned, LifecycleArchived},
+	LifecycleFrozen:    {LifecycleActive, LifecycleChurned, LifecycleArchived},
+	LifecycleGraduated: {LifecycleActive, LifecycleArchived},
+	LifecycleChurned:   {LifecycleTrial, LifecycleActive, LifecycleArchived},
+	LifecycleArchived:  {LifecycleLead, LifecycleActive},
+}
+
+// LifecycleInitialStates — состояния, в которых можно создать студента
+var LifecycleInitialStates = []string{LifecycleLead, LifecycleTrial, LifecycleActive}
+
+// IsLifecycleState сообщает, является ли state состоянием жизненного цикла
+func IsLifecycleState(state string) bool {
+	return slices.Contains(Enums[EnumLifecycleState], state)
+}
+
+// CanChangeLifecycle сообщает, разрешен ли переход из from в to
+func CanChangeLifecycle(from, to string) bool {
+	return slices.Contains(lifecycleTransitions[from], to)
+}
+
+// LifecycleForIsActive — состояние, в которое переводит прежний признак is_active
+// (создание студента без lifecycle_state, вебхук CRM)
+func LifecycleForIsActive(isActive string) string {
+	if isActive == StudentInactive {
+		return LifecycleChurned
+	}
+	return LifecycleActive
+}
+
+// LifecycleIsActive — значение is_active для состояния; так же его вычисляет БД
+func LifecycleIsActive(state string) string {
+	if state == LifecycleActive || state == LifecycleFrozen {
+		return StudentActive
+	}
+	return StudentInactive
+}
+
+// StudentLifecycleTransition — запись истории жизненного цикла студента.
+// У начальной записи from_state пустой, changed_by пустой у фоновых задач и CRM
+type StudentLifecycleTransition struct {
+	Id        uuid.UUID  `json:"id"`
+	StudentId uuid.UUID  `json:"student_id"`
+	FromState *string    `json:"from_state" enums:"lead,trial,active,frozen,graduated,churned,archived"`
+	ToState   string     `json:"to_state" enums:"lead,trial,active,frozen,graduated,churned,archived"`
+	Source    string     `json:"source" enums:"manual,created,crm,attendance_freeze"`
+	Comment   *string    `json:"comment"`
+	ChangedAt time.Time  `json:"changed_at"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+}
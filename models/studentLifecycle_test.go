@@ -0,0 +1,125 @@
+package models
+
+import "testing"
+
+func TestCanChangeLifecycle(t *testing.T) {
+	// Разрешенные переходы из описания POST /settings/students/{studentId}/lifecycle
+	allowed := map[[2]string]bool{
+		{LifecycleLead, LifecycleTrial}:         true,
+		{LifecycleLead, LifecycleActive}:        true,
+		{LifecycleLead, LifecycleChurned}:       true,
+		{LifecycleLead, LifecycleArchived}:      true,
+		{LifecycleTrial, LifecycleActive}:       true,
+		{LifecycleTrial, LifecycleChurned}:      true,
+		{LifecycleTrial, LifecycleArchived}:     true,
+		{LifecycleActive, LifecycleFrozen}:      true,
+		{LifecycleActive, LifecycleGraduated}:   true,
+		{LifecycleActive, LifecycleChurned}:     true,
+		{LifecycleActive, LifecycleArchived}:    true,
+		{LifecycleFrozen, LifecycleActive}:      true,
+		{LifecycleFrozen, LifecycleChurned}:     true,
+		{LifecycleFrozen, LifecycleArchived}:    true,
+		{LifecycleGraduated, LifecycleActive}:   true,
+		{LifecycleGraduated, LifecycleArchived}: true,
+		{LifecycleChurned, LifecycleTrial}:      true,
+		{LifecycleChurned, LifecycleActive}:     true,
+		{LifecycleChurned, LifecycleArchived}:   true,
+		{LifecycleArchived, LifecycleLead}:      true,
+		{LifecycleArchived, LifecycleActive}:    true,
+	}
+
+	// Полная матрица: каждая пара состояний, включая переход в то же состояние
+	states := Enums[EnumLifecycleState]
+	for _, from := range states {
+		for _, to := range states {
+			want := allowed[[2]string{from, to}]
+			if got := CanChangeLifecycle(from, to); got != want {
+				t.Errorf("CanChangeLifecycle(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestCanChangeLifecycleUnknownStates(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{name: "unknown source", from: "expelled", to: LifecycleActive},
+		{name: "unknown target", from: LifecycleActive, to: "expelled"},
+		{name: "empty source", from: "", to: LifecycleActive},
+		{name: "empty target", from: LifecycleLead, to: ""},
+		{name: "legacy is_active value", from: LifecycleActive, to: StudentInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if CanChangeLifecycle(tt.from, tt.to) {
+				t.Errorf("CanChangeLifecycle(%q, %q) = true", tt.from, tt.to)
+			}
+		})
+	}
+}
+
+// Из каждого состояния можно выйти, а переходы и начальные состояния — только известные
+func TestLifecycleTransitionsCoverAllStates(t *testing.T) {
+	for _, state := range Enums[EnumLifecycleState] {
+		if len(lifecycleTransitions[state]) == 0 {
+			t.Errorf("no transitions from %s", state)
+		}
+		for _, to := range lifecycleTransitions[state] {
+			if !IsLifecycleState(to) {
+				t.Errorf("transition %s → %s targets an unknown state", state, to)
+			}
+		}
+	}
+	for _, state := range LifecycleInitialStates {
+		if !IsLifecycleState(state) {
+			t.Errorf("initial state %s is unknown", state)
+		}
+	}
+}
+
+func TestLifecycleIsActive(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{state: LifecycleLead, want: StudentInactive},
+		{state: LifecycleTrial, want: StudentInactive},
+		{state: LifecycleActive, want: StudentActive},
+		{state: LifecycleFrozen, want: StudentActive},
+		{state: LifecycleGraduated, want: StudentInactive},
+		{state: LifecycleChurned, want: StudentInactive},
+		{state: LifecycleArchived, want: StudentInactive},
+	}
+
+	for _, tt := range tests {
+		if got := LifecycleIsActive(tt.state); got != tt.want {
+			t.Errorf("LifecycleIsActive(%s) = %s, want %s", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestLifecycleForIsActive(t *testing.T) {
+	tests := []struct {
+		isActive string
+		want     string
+	}{
+		{isActive: StudentActive, want: LifecycleActive},
+		{isActive: StudentInactive, want: LifecycleChurned},
+		{isActive: "", want: LifecycleActive},
+	}
+
+	for _, tt := range tests {
+		got := LifecycleForIsActive(tt.isActive)
+		if got != tt.want {
+			t.Errorf("LifecycleForIsActive(%q) = %s, want %s", tt.isActive, got, tt.want)
+		}
+		// Состояние, выбранное по is_active, дает тот же is_active обратно
+		if tt.isActive != "" && LifecycleIsActive(got) != tt.isActive {
+			t.Errorf("round trip for %s gives %s", tt.isActive, LifecycleIsActive(got))
+		}
+	}
+}
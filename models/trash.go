@@ -0,0 +1,35 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы записей корзины
+const (
+	TrashStudents   = "students"
+	TrashUsers      = "users"
+	TrashCourses    = "courses"
+	TrashAttendance = "attendance"
+)
+
+// TrashTypes — типы в порядке окончательного удаления: сначала зависимые записи
+var TrashTypes = []string{TrashAttendance, TrashStudents, TrashCourses, TrashUsers}
+
+// IsTrashType проверяет, что записи такого типа удаляются в корзину
+func IsTrashType(itemType string) bool {
+	return slices.Contains(TrashTypes, itemType)
+}
+
+// TrashItem — мягко удаленная запись
+type TrashItem struct {
+	Type      string     `json:"type" enums:"students,users,courses,attendance" example:"students"`
+	Id        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"` // ФИО, название курса или «студент: тип записи»
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy *uuid.UUID `json:"deleted_by"`
+	// PurgeAt — когда запись будет удалена окончательно; nil, если очистка корзины отключена
+	PurgeAt *time.Time `json:"purge_at"`
+}
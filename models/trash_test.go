@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestIsTrashType(t *testing.T) {
+	tests := []struct {
+		itemType string
+		want     bool
+	}{
+		{itemType: TrashStudents, want: true},
+		{itemType: TrashUsers, want: true},
+		{itemType: TrashCourses, want: true},
+		{itemType: TrashAttendance, want: true},
+		{itemType: "", want: false},
+		{itemType: "groups", want: false},
+		{itemType: "student", want: false},
+		{itemType: "Students", want: false},
+		{itemType: " students", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTrashType(tt.itemType); got != tt.want {
+			t.Errorf("IsTrashType(%q) = %v, want %v", tt.itemType, got, tt.want)
+		}
+	}
+}
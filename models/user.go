@@ -1,17 +1,42 @@
 package models
 
 import (
+	"context"
+	"it_school/redact"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
 )
 
 type User struct {
-    Id                  uuid.UUID `json:"id"`
-    Full_name           string    `json:"full_name"`
-    Email               string    `json:"email"`
-    PasswordHash        string    `json:"password_hash"`
-    Telephone           string    `json:"telephone"`
-    RoleID              uuid.UUID `json:"role_id"`
-    ResetTokenExpiresAt time.Time `json:"reset_token_expires_at"`
-}
\ No newline at end of file
+	Id                  uuid.UUID `json:"id"`
+	Full_name           string    `json:"full_name" log:"name"`
+	Email               string    `json:"email" log:"email"`
+	PasswordHash        string    `json:"-" log:"secret"` // не отдается в API
+	Telephone           string    `json:"telephone" log:"phone"`
+	RoleID              uuid.UUID `json:"role_id"`
+	ResetTokenExpiresAt time.Time `json:"reset_token_expires_at"`
+	Version             int       `json:"version"`
+}
+
+// MarshalLogObject маскирует email, телефон, имя и хеш пароля при логировании пользователя
+func (u User) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return redact.Object(enc, u)
+}
+
+type userContextKey struct{}
+
+// WithUser кладет аутентифицированного пользователя в контекст запроса
+func WithUser(c context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(c, userContextKey{}, userID)
+}
+
+// UserFromContext возвращает пользователя запроса или nil для фоновых задач и входящих вебхуков
+func UserFromContext(c context.Context) *uuid.UUID {
+	userID, ok := c.Value(userContextKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &userID
+}
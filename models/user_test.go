@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUserJSONOmitsPasswordHash(t *testing.T) {
+	user := User{Email: "curator@school.kz", PasswordHash: "$2a$10$secret-hash"}
+
+	raw, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "password") || strings.Contains(string(raw), user.PasswordHash) {
+		t.Errorf("json.Marshal(User) = %s, want no password hash", raw)
+	}
+}
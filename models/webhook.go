@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription — подписка внешней системы на доменные события
+type WebhookSubscription struct {
+	Id          uuid.UUID `json:"id"`
+	Url         string    `json:"url"`
+	Secret      string    `json:"-"`
+	Events      []string  `json:"events"`
+	IsActive    bool      `json:"is_active"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int       `json:"version"` // растет при каждом изменении и отдается в ETag
+}
+
+// WebhookDelivery — доставка события подписчику
+type WebhookDelivery struct {
+	Id             uuid.UUID       `json:"id"`
+	SubscriptionId uuid.UUID       `json:"subscription_id"`
+	EventId        uuid.UUID       `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"` // pending, delivered, failed
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
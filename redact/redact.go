@@ -0,0 +1,174 @@
+// Package redact маскирует персональные данные в логах. Поля моделей помечаются тегом
+// log:"phone|email|name|secret", а модели реализуют zapcore.ObjectMarshaler через Object,
+// поэтому zap.Any/zap.Object со студентом, пользователем или сессией не выводит данные целиком
+package redact
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Виды данных в теге log
+const (
+	KindPhone  = "phone"
+	KindEmail  = "email"
+	KindName   = "name"
+	KindSecret = "secret"
+)
+
+const secretMask = "[redacted]"
+
+var disabled atomic.Bool
+
+// SetEnabled включает или выключает маскирование телефонов, email и имен (для локальной разработки).
+// Токены и пароли маскируются всегда
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+// Phone оставляет две последние цифры: +7 (701) 123-45-67 → +* (***) ***-**-67
+func Phone(phone string) string {
+	if disabled.Load() {
+		return phone
+	}
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	keep := 2
+	if digits < 5 {
+		keep = 0
+	}
+	var b strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			seen++
+			if seen <= digits-keep {
+				r = '*'
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Email оставляет первую букву и домен: ivan.petrov@school.kz → i***@school.kz
+func Email(email string) string {
+	if disabled.Load() {
+		return email
+	}
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	first, _ := utf8.DecodeRuneInString(email)
+	return string(first) + "***" + email[at:]
+}
+
+// Name оставляет первые буквы слов: Иванова Мария → И*** М***
+func Name(name string) string {
+	if disabled.Load() {
+		return name
+	}
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, _ := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// Secret скрывает токены и пароли целиком, независимо от SetEnabled
+func Secret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return secretMask
+}
+
+func mask(kind, value string) string {
+	switch kind {
+	case KindPhone:
+		return Phone(value)
+	case KindEmail:
+		return Email(value)
+	case KindName:
+		return Name(value)
+	case KindSecret:
+		return Secret(value)
+	default:
+		return value
+	}
+}
+
+// Object записывает структуру в лог, маскируя строковые поля с тегом log.
+// Ключи берутся из тега json; поля с json:"-" или log:"-" пропускаются.
+// Вложенные модели с MarshalLogObject (например, студент в структуре ответа) маскируются так же
+func Object(enc zapcore.ObjectEncoder, v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return enc.AddReflected("value", v)
+	}
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		kind := field.Tag.Get("log")
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "-" || kind == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+
+		fieldValue := value.Field(i)
+		if kind == "" {
+			if marshaler, ok := objectMarshaler(fieldValue); ok {
+				if err := enc.AddObject(key, marshaler); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := enc.AddReflected(key, fieldValue.Interface()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Pointer {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		if fieldValue.Kind() != reflect.String {
+			enc.AddString(key, secretMask)
+			continue
+		}
+		enc.AddString(key, mask(kind, fieldValue.String()))
+	}
+	return nil
+}
+
+// objectMarshaler возвращает значение поля как zapcore.ObjectMarshaler, если оно его реализует.
+// Пустой указатель логируется как null через AddReflected
+func objectMarshaler(v reflect.Value) (zapcore.ObjectMarshaler, bool) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, false
+	}
+	marshaler, ok := v.Interface().(zapcore.ObjectMarshaler)
+	return marshaler, ok
+}
@@ -0,0 +1,303 @@
+package redact_test
+
+import (
+	"fmt"
+	"it_school/models"
+	"it_school/redact"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{phone: "+7 (701) 123-45-67", want: "+* (***) ***-**-67"},
+		{phone: "87011234567", want: "*********67"},
+		{phone: "12345", want: "***45"},
+		{phone: "1234", want: "****"},
+		{phone: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := redact.Phone(tt.phone); got != tt.want {
+			t.Errorf("Phone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{email: "ivan.petrov@school.kz", want: "i***@school.kz"},
+		{email: "Айгуль@mail.kz", want: "А***@mail.kz"},
+		{email: "a.b@c@school.kz", want: "a***@school.kz"},
+		{email: "@school.kz", want: "***"},
+		{email: "not-an-email", want: "***"},
+		{email: "", want: "***"},
+	}
+
+	for _, tt := range tests {
+		if got := redact.Email(tt.email); got != tt.want {
+			t.Errorf("Email(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Иванова Мария", want: "И*** М***"},
+		{name: "  Ivan   Petrov ", want: "I*** P***"},
+		{name: "Бауыржан", want: "Б***"},
+		{name: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := redact.Name(tt.name); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	if got := redact.Secret("refresh-token"); got != "[redacted]" {
+		t.Errorf("Secret(token) = %q, want [redacted]", got)
+	}
+	if got := redact.Secret(""); got != "" {
+		t.Errorf("Secret(\"\") = %q, want empty", got)
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	redact.SetEnabled(false)
+	t.Cleanup(func() { redact.SetEnabled(true) })
+
+	tests := []struct {
+		name  string
+		mask  func(string) string
+		value string
+		want  string
+	}{
+		{name: "phone", mask: redact.Phone, value: "+77011234567", want: "+77011234567"},
+		{name: "email", mask: redact.Email, value: "ivan@school.kz", want: "ivan@school.kz"},
+		{name: "name", mask: redact.Name, value: "Иванова Мария", want: "Иванова Мария"},
+		{name: "secret is always masked", mask: redact.Secret, value: "token", want: "[redacted]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mask(tt.value); got != tt.want {
+				t.Errorf("mask(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	fields := encode(t, models.Session{RefreshToken: "token"})
+	if fields["refresh_token"] != "[redacted]" {
+		t.Errorf("refresh_token = %v, want [redacted] with masking disabled", fields["refresh_token"])
+	}
+}
+
+// tagged покрывает все варианты тегов, которые разбирает Object
+type tagged struct {
+	Phone     string  `json:"phone" log:"phone"`
+	Email     string  `json:"email,omitempty" log:"email"`
+	Name      *string `json:"name" log:"name"`
+	NilName   *string `json:"nil_name" log:"name"`
+	Token     string  `json:"token" log:"secret"`
+	Pin       int     `json:"pin" log:"secret"`
+	Untagged  string
+	Plain     string `json:"plain"`
+	Hidden    string `json:"-" log:"phone"`
+	Skipped   string `json:"skipped" log:"-"`
+	Unknown   string `json:"unknown" log:"other"`
+	unexposed string
+}
+
+// envelope — модели внутри другой структуры (например, в теле ответа)
+type envelope struct {
+	Student models.Student  `json:"student"`
+	Owner   *models.User    `json:"owner"`
+	Missing *models.User    `json:"missing"`
+	Session *models.Session `json:"session"`
+}
+
+func TestObject(t *testing.T) {
+	name := "Иванова Мария"
+	phone := "+77011234567"
+	parentPhone := "+77017654321"
+
+	student := models.Student{
+		FullName:          "Петров Иван",
+		PhoneNumber:       &phone,
+		ParentName:        name,
+		ParentPhoneNumber: &parentPhone,
+		PlatformLink:      "https://platform.kz/1",
+	}
+	user := models.User{
+		Full_name:    name,
+		Email:        "maria@school.kz",
+		PasswordHash: "$2a$10$hash",
+		Telephone:    phone,
+	}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   map[string]interface{}
+		absent []string
+		leaked []string
+	}{
+		{
+			name: "tag kinds",
+			value: tagged{
+				Phone:     phone,
+				Email:     "maria@school.kz",
+				Name:      &name,
+				Token:     "secret-token",
+				Pin:       1234,
+				Untagged:  "visible",
+				Plain:     "visible",
+				Hidden:    phone,
+				Skipped:   phone,
+				Unknown:   "as is",
+				unexposed: phone,
+			},
+			want: map[string]interface{}{
+				"phone":    "+*********67",
+				"email":    "m***@school.kz",
+				"name":     "И*** М***",
+				"token":    "[redacted]",
+				"pin":      "[redacted]",
+				"Untagged": "visible",
+				"plain":    "visible",
+				"unknown":  "as is",
+			},
+			absent: []string{"nil_name", "Hidden", "-", "skipped", "unexposed"},
+			leaked: []string{phone, "maria@", name, "secret-token", "1234"},
+		},
+		{
+			name:  "student",
+			value: student,
+			want: map[string]interface{}{
+				"full_name":           "П*** И***",
+				"phone_number":        "+*********67",
+				"parent_name":         "И*** М***",
+				"parent_phone_number": "+*********21",
+				"platform_link":       "https://platform.kz/1",
+			},
+			leaked: []string{"Петров", name, phone, parentPhone},
+		},
+		{
+			name:   "student pointer",
+			value:  &student,
+			want:   map[string]interface{}{"full_name": "П*** И***", "phone_number": "+*********67"},
+			leaked: []string{"Петров", phone},
+		},
+		{
+			name:   "student without phones",
+			value:  models.Student{FullName: "Петров Иван"},
+			want:   map[string]interface{}{"full_name": "П*** И***"},
+			absent: []string{"phone_number", "parent_phone_number"},
+		},
+		{
+			name:  "user",
+			value: user,
+			want: map[string]interface{}{
+				"full_name": "И*** М***",
+				"email":     "m***@school.kz",
+				"telephone": "+*********67",
+			},
+			absent: []string{"password_hash", "PasswordHash"},
+			leaked: []string{name, "maria@", phone, "$2a$10$hash"},
+		},
+		{
+			name:  "student filters",
+			value: models.StudentFilters{Search: "Иванова", Course: "course-id", LifecycleStates: []string{"active"}},
+			want: map[string]interface{}{
+				"Search":          "И***",
+				"Course":          "course-id",
+				"LifecycleStates": []string{"active"},
+			},
+			leaked: []string{"Иванова"},
+		},
+		{
+			name:  "nested models",
+			value: envelope{Student: student, Owner: &user, Session: &models.Session{RefreshToken: "secret-token"}},
+			want: map[string]interface{}{
+				"missing": (*models.User)(nil),
+			},
+			leaked: []string{"Петров", name, phone, parentPhone, "maria@", "$2a$10$hash", "secret-token"},
+		},
+		{
+			name:  "not a struct",
+			value: "plain value",
+			want:  map[string]interface{}{"value": "plain value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := encode(t, tt.value)
+
+			for key, want := range tt.want {
+				if got, ok := fields[key]; !ok || !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if got, ok := fields[key]; ok {
+					t.Errorf("%s = %#v, want field to be skipped", key, got)
+				}
+			}
+			logged := fmt.Sprint(fields)
+			for _, raw := range tt.leaked {
+				if strings.Contains(logged, raw) {
+					t.Errorf("log %s contains %q", logged, raw)
+				}
+			}
+		})
+	}
+}
+
+func TestObjectNested(t *testing.T) {
+	phone := "+77011234567"
+	fields := encode(t, envelope{
+		Student: models.Student{FullName: "Петров Иван", PhoneNumber: &phone},
+		Owner:   &models.User{Email: "maria@school.kz"},
+	})
+
+	student, ok := fields["student"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("student = %#v, want nested object", fields["student"])
+	}
+	if student["full_name"] != "П*** И***" || student["phone_number"] != "+*********67" {
+		t.Errorf("student = %v, want masked name and phone", student)
+	}
+
+	owner, ok := fields["owner"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("owner = %#v, want nested object", fields["owner"])
+	}
+	if owner["email"] != "m***@school.kz" {
+		t.Errorf("owner.email = %v, want m***@school.kz", owner["email"])
+	}
+}
+
+func encode(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	enc := zapcore.NewMapObjectEncoder()
+	if err := redact.Object(enc, v); err != nil {
+		t.Fatalf("Object(%T) error = %v", v, err)
+	}
+	return enc.Fields
+}
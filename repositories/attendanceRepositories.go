@@ -3,9 +3,12 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"it_school/events"
 	"it_school/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,8 +20,8 @@ func NewAttendanceRepository(conn *pgxpool.Pool) *AttendanceRepository {
 	return &AttendanceRepository{db: conn}
 }
 
-func (r *AttendanceRepository) CreateAttendance(c context.Context,attendance *models.Attendance,lesson *models.AttendanceLesson,
-	freeze *models.AttendanceFreeze, prolongation *models.AttendanceProlongation,) (uuid.UUID, error) {
+func (r *AttendanceRepository) CreateAttendance(c context.Context, attendance *models.Attendance, lesson *models.AttendanceLesson,
+	freeze *models.AttendanceFreeze, prolongation *models.AttendanceProlongation) (uuid.UUID, error) {
 
 	tx, err := r.db.Begin(c)
 	if err != nil {
@@ -28,6 +31,10 @@ func (r *AttendanceRepository) CreateAttendance(c context.Context,attendance *mo
 
 	attendance.ID = uuid.New()
 
+	if err := checkAttendanceRefs(c, tx, attendance.StudentId, attendance.CourseId); err != nil {
+		return uuid.Nil, err
+	}
+
 	_, err = tx.Exec(c, `
 		INSERT INTO attendance (id, student_id, course_id, type)
 		VALUES ($1, $2, $3, $4)
@@ -37,19 +44,22 @@ func (r *AttendanceRepository) CreateAttendance(c context.Context,attendance *mo
 	}
 
 	switch attendance.Type {
-	case "урок":
+	case models.AttendanceTypeLesson:
+		if err := checkTopicInCourse(c, tx, lesson.TopicId, attendance.CourseId); err != nil {
+			return uuid.Nil, err
+		}
 		_, err = tx.Exec(c, `
-			INSERT INTO attendance_lessons (attendance_id, curator_id, date, format, feedback, feedbackdate, lessons_status)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`, attendance.ID, lesson.CuratorId, lesson.Date, lesson.Format, lesson.Feedback, lesson.FeedbackDate, lesson.LessonStatus)
+			INSERT INTO attendance_lessons (attendance_id, curator_id, date, format, feedback, feedbackdate, lessons_status, topic_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, attendance.ID, lesson.CuratorId, lesson.Date, lesson.Format, lesson.Feedback, lesson.FeedbackDate, lesson.LessonStatus, lesson.TopicId)
 
-	case "заморозка":
+	case models.AttendanceTypeFreeze:
 		_, err = tx.Exec(c, `
 			INSERT INTO attendance_freezes (attendance_id, start_date, end_date, comment)
 			VALUES ($1, $2, $3, $4)
 		`, attendance.ID, freeze.StartDate, freeze.EndDate, freeze.Comment)
 
-	case "пролонгация":
+	case models.AttendanceTypeProlongation:
 		_, err = tx.Exec(c, `
 			INSERT INTO attendance_prolongations (attendance_id, payment_type, date, amount, comment)
 			VALUES ($1, $2, $3, $4, $5)
@@ -60,6 +70,38 @@ func (r *AttendanceRepository) CreateAttendance(c context.Context,attendance *mo
 		return uuid.Nil, err
 	}
 
+	switch attendance.Type {
+	case models.AttendanceTypeLesson:
+		if lesson.LessonStatus == models.LessonConducted {
+			err = events.Record(c, tx, events.AttendanceLessonConducted, lessonConductedData(attendance, lesson))
+		}
+	case models.AttendanceTypeFreeze:
+		err = events.Record(c, tx, events.FreezeCreated, events.FreezeCreatedData{
+			AttendanceId: attendance.ID,
+			StudentId:    attendance.StudentId,
+			CourseId:     attendance.CourseId,
+			StartDate:    freeze.StartDate,
+			EndDate:      freeze.EndDate,
+			Comment:      freeze.Comment,
+		})
+	case models.AttendanceTypeProlongation:
+		err = events.Record(c, tx, events.ProlongationCreated, events.ProlongationCreatedData{
+			AttendanceId: attendance.ID,
+			StudentId:    attendance.StudentId,
+			CourseId:     attendance.CourseId,
+			PaymentType:  prolongation.PaymentType,
+			Date:         prolongation.Date,
+			Amount:       prolongation.Amount,
+			Comment:      prolongation.Comment,
+		})
+		if err == nil {
+			err = events.Record(c, tx, events.CrmPaymentCreated, crmPayment(attendance, prolongation))
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	if err = tx.Commit(c); err != nil {
 		return uuid.Nil, err
 	}
@@ -67,14 +109,52 @@ func (r *AttendanceRepository) CreateAttendance(c context.Context,attendance *mo
 	return attendance.ID, nil
 }
 
+// checkAttendanceRefs проверяет, что студент относится к текущему филиалу и не удален в корзину,
+// а курс — к филиалу студента. Иначе — ошибка student_not_found или course_not_found
+func checkAttendanceRefs(c context.Context, tx pgx.Tx, studentID, courseID uuid.UUID) error {
+	var branchID uuid.UUID
+	err := tx.QueryRow(c,
+		`SELECT branch_id FROM students WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL`,
+		studentID, currentBranch(c),
+	).Scan(&branchID)
+	if err != nil {
+		return notFoundIfNoRows(err, "student_not_found", "Student not found")
+	}
+	return checkGroupCourse(c, tx, courseID, branchID)
+}
+
+// crmPayment собирает данные оплаты для CRM из пролонгации
+func crmPayment(attendance *models.Attendance, prolongation *models.AttendanceProlongation) models.CrmPayment {
+	return models.CrmPayment{
+		AttendanceId: attendance.ID,
+		StudentId:    attendance.StudentId,
+		CourseId:     attendance.CourseId,
+		PaymentType:  prolongation.PaymentType,
+		Date:         prolongation.Date,
+		Amount:       prolongation.Amount,
+		Comment:      prolongation.Comment,
+	}
+}
 
-func (r *AttendanceRepository) FindFullByStudent(ctx context.Context, studentID uuid.UUID) ([]models.AttendanceFullResponse, error) {
-    rows, err := r.db.Query(ctx, `
+func lessonConductedData(attendance *models.Attendance, lesson *models.AttendanceLesson) events.LessonConductedData {
+	return events.LessonConductedData{
+		AttendanceId:  attendance.ID,
+		StudentId:     attendance.StudentId,
+		CourseId:      attendance.CourseId,
+		CuratorId:     lesson.CuratorId,
+		Date:          lesson.Date,
+		TopicId:       lesson.TopicId,
+		GroupLessonId: lesson.GroupLessonId,
+	}
+}
+
+// attendanceFullSelect — запись посещаемости вместе с уроком, заморозкой или пролонгацией
+const attendanceFullSelect = `
         SELECT 
-            a.id, a.student_id, a.course_id, a.type, a.created_at,
+            a.id, a.student_id, a.course_id, a.type, a.created_at, a.version, a.updated_at, a.updated_by,
 
             -- lesson
-            l.curator_id, l.date, l.format, l.feedback, l.lessons_status, l.feedbackdate,
+            l.curator_id, l.date, l.format, l.feedback, l.lessons_status, l.feedbackdate, l.group_lesson_id, l.topic_id,
 
             -- freeze
             f.start_date, f.end_date, f.comment,
@@ -83,178 +163,245 @@ func (r *AttendanceRepository) FindFullByStudent(ctx context.Context, studentID
             p.payment_type, p.date, p.amount, p.comment
 
         FROM attendance a
-        LEFT JOIN attendance_lessons l ON a.id = l.attendance_id AND a.type = 'урок'
-        LEFT JOIN attendance_freezes f ON a.id = f.attendance_id AND a.type = 'заморозка'
-        LEFT JOIN attendance_prolongations p ON a.id = p.attendance_id AND a.type = 'пролонгация'
-        WHERE a.student_id = $1
+        LEFT JOIN attendance_lessons l ON a.id = l.attendance_id AND a.type = 'lesson'
+        LEFT JOIN attendance_freezes f ON a.id = f.attendance_id AND a.type = 'freeze'
+        LEFT JOIN attendance_prolongations p ON a.id = p.attendance_id AND a.type = 'prolongation'
+        JOIN students s ON s.id = a.student_id
+`
+
+func (r *AttendanceRepository) FindFullByStudent(ctx context.Context, studentID uuid.UUID) ([]models.AttendanceFullResponse, error) {
+	rows, err := r.db.Query(ctx, attendanceFullSelect+`
+        WHERE a.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+          AND a.deleted_at IS NULL AND s.deleted_at IS NULL
         ORDER BY a.created_at DESC
-    `, studentID)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var responses []models.AttendanceFullResponse
-
-    for rows.Next() {
-        var att models.Attendance
-
-        // lesson (nullable)
-        var lessonDate, feedbackDate sql.NullTime
-        var format, feedback, lessonStatus sql.NullString
-        var curatorID uuid.NullUUID
-
-        // freeze (nullable)
-        var startDate, endDate sql.NullTime
-        var freezeComment sql.NullString
-
-        // prolongation (nullable)
-        var paymentType, prolongComment sql.NullString
-        var prolongDate sql.NullTime
-        var amount sql.NullFloat64
-
-        err := rows.Scan(
-            &att.ID, &att.StudentId, &att.CourseId, &att.Type, &att.CreatedAt,
-            &curatorID, &lessonDate, &format, &feedback, &lessonStatus, &feedbackDate,
-            &startDate, &endDate, &freezeComment,
-            &paymentType, &prolongDate, &amount, &prolongComment,
-        )
-        if err != nil {
-            return nil, err
-        }
-
-        response := models.AttendanceFullResponse{
-            Attendance: &att,
-        }
-
-        switch att.Type {
-        case "урок":
-            lesson := models.AttendanceLesson{
-                AttendanceID: att.ID,
-            }
-
-            hasData := false
-
-            if curatorID.Valid {
-                lesson.CuratorId = curatorID.UUID
-                hasData = true
-            }
-            if lessonDate.Valid {
-                lesson.Date = lessonDate.Time
-                hasData = true
-            }
-            if format.Valid {
-                lesson.Format = &format.String
-                hasData = true
-            }
-            if feedback.Valid {
-                lesson.Feedback = &feedback.String
-                hasData = true
-            }
-            if feedbackDate.Valid {
-                lesson.FeedbackDate = &feedbackDate.Time
-                hasData = true
-            }
-            if lessonStatus.Valid {
-                lesson.LessonStatus = lessonStatus.String
-                hasData = true
-            }
-
-            if hasData {
-                response.Lesson = &lesson
-            }
-
-        case "заморозка":
-            freeze := models.AttendanceFreeze{
-                AttendanceID: att.ID,
-            }
-
-            hasData := false
-
-            if startDate.Valid {
-                freeze.StartDate = startDate.Time
-                hasData = true
-            }
-            if endDate.Valid {
-                freeze.EndDate = endDate.Time
-                hasData = true
-            }
-            if freezeComment.Valid {
-                freeze.Comment = &freezeComment.String
-                hasData = true
-            }
-
-            if hasData {
-                response.Freeze = &freeze
-            }
-
-        case "пролонгация":
-            prolongation := models.AttendanceProlongation{
-                AttendanceID: att.ID,
-            }
-
-            hasData := false
-
-            if paymentType.Valid {
-                prolongation.PaymentType = paymentType.String
-                hasData = true
-            }
-            if prolongDate.Valid {
-                prolongation.Date = prolongDate.Time
-                hasData = true
-            }
-            if amount.Valid {
-                prolongation.Amount = amount.Float64
-                hasData = true
-            }
-            if prolongComment.Valid {
-                prolongation.Comment = &prolongComment.String
-                hasData = true
-            }
-
-            if hasData {
-                response.Prolongation = &prolongation
-            }
-        }
-
-        responses = append(responses, response)
-    }
-
-    return responses, nil
+    `, studentID, currentBranch(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var responses []models.AttendanceFullResponse
+
+	for rows.Next() {
+		response, err := scanAttendanceFull(rows)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// FindFullById возвращает запись посещаемости с данными по ее типу
+func (r *AttendanceRepository) FindFullById(ctx context.Context, attendanceID uuid.UUID) (models.AttendanceFullResponse, error) {
+	row := r.db.QueryRow(ctx, attendanceFullSelect+`
+        WHERE a.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+          AND a.deleted_at IS NULL AND s.deleted_at IS NULL
+    `, attendanceID, currentBranch(ctx))
+	response, err := scanAttendanceFull(row)
+	if err != nil {
+		return models.AttendanceFullResponse{}, notFoundIfNoRows(err, "attendance_record_not_found", "Attendance record not found")
+	}
+	return response, nil
 }
 
+func scanAttendanceFull(row pgx.Row) (models.AttendanceFullResponse, error) {
+	var att models.Attendance
+
+	// lesson (nullable)
+	var lessonDate, feedbackDate sql.NullTime
+	var format, feedback, lessonStatus sql.NullString
+	var curatorID, groupLessonID, topicID uuid.NullUUID
+
+	// freeze (nullable)
+	var startDate, endDate sql.NullTime
+	var freezeComment sql.NullString
+
+	// prolongation (nullable)
+	var paymentType, prolongComment sql.NullString
+	var prolongDate sql.NullTime
+	var amount sql.NullFloat64
+
+	err := row.Scan(
+		&att.ID, &att.StudentId, &att.CourseId, &att.Type, &att.CreatedAt, &att.Version, &att.UpdatedAt, &att.UpdatedBy,
+		&curatorID, &lessonDate, &format, &feedback, &lessonStatus, &feedbackDate, &groupLessonID, &topicID,
+		&startDate, &endDate, &freezeComment,
+		&paymentType, &prolongDate, &amount, &prolongComment,
+	)
+	if err != nil {
+		return models.AttendanceFullResponse{}, err
+	}
+
+	response := models.AttendanceFullResponse{
+		Attendance: &att,
+	}
+
+	switch att.Type {
+	case models.AttendanceTypeLesson:
+		lesson := models.AttendanceLesson{
+			AttendanceID: att.ID,
+		}
+
+		hasData := false
+
+		if curatorID.Valid {
+			lesson.CuratorId = curatorID.UUID
+			hasData = true
+		}
+		if lessonDate.Valid {
+			lesson.Date = lessonDate.Time
+			hasData = true
+		}
+		if format.Valid {
+			lesson.Format = &format.String
+			hasData = true
+		}
+		if feedback.Valid {
+			lesson.Feedback = &feedback.String
+			hasData = true
+		}
+		if feedbackDate.Valid {
+			lesson.FeedbackDate = &feedbackDate.Time
+			hasData = true
+		}
+		if lessonStatus.Valid {
+			lesson.LessonStatus = lessonStatus.String
+			hasData = true
+		}
+		if groupLessonID.Valid {
+			lesson.GroupLessonId = &groupLessonID.UUID
+		}
+		if topicID.Valid {
+			lesson.TopicId = &topicID.UUID
+		}
+
+		if hasData {
+			response.Lesson = &lesson
+		}
+
+	case models.AttendanceTypeFreeze:
+		freeze := models.AttendanceFreeze{
+			AttendanceID: att.ID,
+		}
+
+		hasData := false
+
+		if startDate.Valid {
+			freeze.StartDate = startDate.Time
+			hasData = true
+		}
+		if endDate.Valid {
+			freeze.EndDate = endDate.Time
+			hasData = true
+		}
+		if freezeComment.Valid {
+			freeze.Comment = &freezeComment.String
+			hasData = true
+		}
+
+		if hasData {
+			response.Freeze = &freeze
+		}
+
+	case models.AttendanceTypeProlongation:
+		prolongation := models.AttendanceProlongation{
+			AttendanceID: att.ID,
+		}
+
+		hasData := false
+
+		if paymentType.Valid {
+			prolongation.PaymentType = paymentType.String
+			hasData = true
+		}
+		if prolongDate.Valid {
+			prolongation.Date = prolongDate.Time
+			hasData = true
+		}
+		if amount.Valid {
+			prolongation.Amount = amount.Float64
+			hasData = true
+		}
+		if prolongComment.Valid {
+			prolongation.Comment = &prolongComment.String
+			hasData = true
+		}
+
+		if hasData {
+			response.Prolongation = &prolongation
+		}
+	}
 
+	return response, nil
+}
 
-func (r *AttendanceRepository) Update(c context.Context, attendance *models.Attendance, lesson *models.AttendanceLesson, freeze *models.AttendanceFreeze, prolongation *models.AttendanceProlongation) error {
+// Update перезаписывает запись посещаемости текущего филиала, если ее версия входит в versions (nil — любая версия).
+// Новые студент и курс проверяются так же, как при создании. Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *AttendanceRepository) Update(c context.Context, attendance *models.Attendance, lesson *models.AttendanceLesson, freeze *models.AttendanceFreeze, prolongation *models.AttendanceProlongation, versions []int) (int, error) {
 	tx, err := r.db.Begin(c)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback(c)
 
-	_, err = tx.Exec(c, `
-		UPDATE attendance SET student_id = $1, course_id = $2, type = $3 WHERE id = $4
-	`, attendance.StudentId, attendance.CourseId, attendance.Type, attendance.ID)
+	var version int
+	err = tx.QueryRow(c, `
+		UPDATE attendance a SET student_id = $1, course_id = $2, type = $3, updated_by = $5
+		FROM students s
+		WHERE a.id = $4 AND s.id = a.student_id AND ($7::uuid IS NULL OR s.branch_id = $7)
+		  AND a.deleted_at IS NULL AND s.deleted_at IS NULL AND ($6::int[] IS NULL OR a.version = ANY($6))
+		RETURNING a.version
+	`, attendance.StudentId, attendance.CourseId, attendance.Type, attendance.ID, currentUser(c), versions, currentBranch(c)).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		exists, err := r.Exists(c, attendance.ID)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, models.ErrVersionConflict
+		}
+		return 0, notFound("attendance_record_not_found", "Attendance record not found")
+	}
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if err := checkAttendanceRefs(c, tx, attendance.StudentId, attendance.CourseId); err != nil {
+		return 0, err
 	}
 
+	// Событие о проведении урока отправляется только при смене статуса на conducted
+	conducted := false
+
 	switch attendance.Type {
-	case "урок":
+	case models.AttendanceTypeLesson:
+		if err := checkTopicInCourse(c, tx, lesson.TopicId, attendance.CourseId); err != nil {
+			return 0, err
+		}
+		var prevStatus string
+		err = tx.QueryRow(c,
+			`SELECT lessons_status FROM attendance_lessons WHERE attendance_id = $1 FOR UPDATE`,
+			attendance.ID,
+		).Scan(&prevStatus)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return 0, err
+		}
+		conducted = prevStatus != models.LessonConducted && lesson.LessonStatus == models.LessonConducted
+
 		_, err = tx.Exec(c, `
 			UPDATE attendance_lessons
-			SET curator_id = $1, date = $2, format = $3, feedback = $4, feedbackdate = $5, lessons_status = $6
-			WHERE attendance_id = $7
-		`, lesson.CuratorId, lesson.Date, lesson.Format, lesson.Feedback, lesson.FeedbackDate, lesson.LessonStatus, attendance.ID)
+			SET curator_id = $1, date = $2, format = $3, feedback = $4, feedbackdate = $5, lessons_status = $6, topic_id = $7
+			WHERE attendance_id = $8
+		`, lesson.CuratorId, lesson.Date, lesson.Format, lesson.Feedback, lesson.FeedbackDate, lesson.LessonStatus, lesson.TopicId, attendance.ID)
 
-	case "заморозка":
+	case models.AttendanceTypeFreeze:
 		_, err = tx.Exec(c, `
 			UPDATE attendance_freezes
 			SET start_date = $1, end_date = $2, comment = $3
 			WHERE attendance_id = $4
 		`, freeze.StartDate, freeze.EndDate, freeze.Comment, attendance.ID)
 
-	case "пролонгация":
+	case models.AttendanceTypeProlongation:
 		_, err = tx.Exec(c, `
 			UPDATE attendance_prolongations
 			SET payment_type = $1, date = $2, amount = $3, comment = $4
@@ -263,19 +410,43 @@ func (r *AttendanceRepository) Update(c context.Context, attendance *models.Atte
 	}
 
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if conducted {
+		if err := events.Record(c, tx, events.AttendanceLessonConducted, lessonConductedData(attendance, lesson)); err != nil {
+			return 0, err
+		}
+	}
+	if attendance.Type == models.AttendanceTypeProlongation {
+		if err := events.Record(c, tx, events.CrmPaymentUpdated, crmPayment(attendance, prolongation)); err != nil {
+			return 0, err
+		}
 	}
 
-	return tx.Commit(c)
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+	return version, nil
 }
 
+// Delete переносит запись посещаемости в корзину вместе с уроком, заморозкой или оплатой
 func (r *AttendanceRepository) Delete(c context.Context, attendanceID uuid.UUID) error {
-	_, err := r.db.Exec(c, `DELETE FROM attendance WHERE id = $1`, attendanceID)
+	_, err := r.db.Exec(c, `
+		UPDATE attendance a SET deleted_at = now(), deleted_by = $3
+		FROM students s
+		WHERE a.id = $1 AND s.id = a.student_id AND ($2::uuid IS NULL OR s.branch_id = $2)
+		  AND a.deleted_at IS NULL
+	`, attendanceID, currentBranch(c), currentUser(c))
 	return err
 }
 
 func (r *AttendanceRepository) Exists(c context.Context, id uuid.UUID) (bool, error) {
-    var exists bool
-    err := r.db.QueryRow(c, "SELECT EXISTS(SELECT 1 FROM attendance WHERE id = $1)", id).Scan(&exists)
-    return exists, err
-}
\ No newline at end of file
+	var exists bool
+	err := r.db.QueryRow(c, `SELECT EXISTS(
+        SELECT 1 FROM attendance a
+        JOIN students s ON s.id = a.student_id
+        WHERE a.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+          AND a.deleted_at IS NULL AND s.deleted_at IS NULL)`, id, currentBranch(c)).Scan(&exists)
+	return exists, err
+}
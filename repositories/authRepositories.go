@@ -18,14 +18,14 @@ func NewAuthRepository(conn *pgxpool.Pool) *AuthRepository {
 }
 
 func (r *AuthRepository) SetResetToken(c context.Context, email, resetToken string, expirationTime time.Time) error {
-	query := `UPDATE users SET reset_token = $1, reset_token_expires_at = $2 WHERE email = $3`
+	query := `UPDATE users SET reset_token = $1, reset_token_expires_at = $2 WHERE email = $3 AND deleted_at IS NULL`
 	_, err := r.db.Exec(c, query, resetToken, expirationTime, email)
 	return err
 }
 
 func (r *AuthRepository) GetUserByResetToken(c context.Context, resetToken string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, email FROM users WHERE reset_token = $1 AND reset_token_expires_at > NOW()`
+	query := `SELECT id, email FROM users WHERE reset_token = $1 AND reset_token_expires_at > NOW() AND deleted_at IS NULL`
 	err := r.db.QueryRow(c, query, resetToken).Scan(&user.Id, &user.Email)
 	if err != nil {
 		return nil, err
@@ -40,9 +40,19 @@ func (r *AuthRepository) ClearResetToken(c context.Context, userID uuid.UUID) er
 }
 
 func (r *AuthRepository) UpdatePassword(c context.Context, userID uuid.UUID, hashedPassword string) error {
-	query := `UPDATE users SET password = $1, reset_token = NULL WHERE id = $2`
-	_, err := r.db.Exec(c, query, hashedPassword, userID)
+	// Пароль по ссылке сброса меняет сам пользователь, без аутентификации
+	query := `UPDATE users SET password = $1, reset_token = NULL, updated_by = COALESCE($3::uuid, id) WHERE id = $2`
+	_, err := r.db.Exec(c, query, hashedPassword, userID, currentUser(c))
 	return err
 }
 
-
+// ClearExpiredResetTokens удаляет просроченные токены сброса пароля и возвращает их количество
+func (r *AuthRepository) ClearExpiredResetTokens(c context.Context) (int64, error) {
+	tag, err := r.db.Exec(c, `
+		UPDATE users SET reset_token = NULL, reset_token_expires_at = NULL
+		WHERE reset_token IS NOT NULL AND (reset_token_expires_at IS NULL OR reset_token_expires_at <= NOW())`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
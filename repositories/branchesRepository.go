@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"context"
+	"it_school/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BranchesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBranchesRepository(conn *pgxpool.Pool) *BranchesRepository {
+	return &BranchesRepository{db: conn}
+}
+
+// currentBranch возвращает филиал из контекста запроса для фильтрации в SQL.
+// nil означает «все филиалы» и в запросах проверяется как `$N::uuid IS NULL`.
+func currentBranch(c context.Context) *uuid.UUID {
+	return models.BranchFromContext(c)
+}
+
+// currentUser — автор изменения для updated_by; nil, если запрос не от пользователя
+func currentUser(c context.Context) *uuid.UUID {
+	return models.UserFromContext(c)
+}
+
+func (r *BranchesRepository) Create(c context.Context, branch models.Branch) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(c,
+		`INSERT INTO branches (name, address) VALUES ($1, $2) RETURNING id`,
+		branch.Name, branch.Address,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func (r *BranchesRepository) Update(c context.Context, branch models.Branch) error {
+	_, err := r.db.Exec(c,
+		`UPDATE branches SET name = $1, address = $2, updated_by = $4 WHERE id = $3`,
+		branch.Name, branch.Address, branch.Id, currentUser(c),
+	)
+	return err
+}
+
+func (r *BranchesRepository) FindAll(c context.Context) ([]models.Branch, error) {
+	rows, err := r.db.Query(c, `SELECT id, name, address, created_at FROM branches ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	branches := make([]models.Branch, 0)
+	for rows.Next() {
+		var branch models.Branch
+		if err := rows.Scan(&branch.Id, &branch.Name, &branch.Address, &branch.CreatedAt); err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, rows.Err()
+}
+
+func (r *BranchesRepository) FindById(c context.Context, id uuid.UUID) (models.Branch, error) {
+	var branch models.Branch
+	err := r.db.QueryRow(c,
+		`SELECT id, name, address, created_at FROM branches WHERE id = $1`, id,
+	).Scan(&branch.Id, &branch.Name, &branch.Address, &branch.CreatedAt)
+	if err != nil {
+		return models.Branch{}, notFoundIfNoRows(err, "branch_not_found", "Branch not found")
+	}
+	return branch, nil
+}
+
+func (r *BranchesRepository) FindByName(c context.Context, name string) (models.Branch, error) {
+	var branch models.Branch
+	err := r.db.QueryRow(c,
+		`SELECT id, name, address, created_at FROM branches WHERE name = $1`, name,
+	).Scan(&branch.Id, &branch.Name, &branch.Address, &branch.CreatedAt)
+	if err != nil {
+		return models.Branch{}, err
+	}
+	return branch, nil
+}
+
+func (r *BranchesRepository) Delete(c context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(c, `DELETE FROM branches WHERE id = $1`, id)
+	return err
+}
+
+// FindByUser возвращает филиалы, к которым прикреплен пользователь
+func (r *BranchesRepository) FindByUser(c context.Context, userID uuid.UUID) ([]models.Branch, error) {
+	rows, err := r.db.Query(c, `
+		SELECT b.id, b.name, b.address, b.created_at
+		FROM branches b
+		JOIN user_branches ub ON ub.branch_id = b.id
+		WHERE ub.user_id = $1
+		ORDER BY b.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	branches := make([]models.Branch, 0)
+	for rows.Next() {
+		var branch models.Branch
+		if err := rows.Scan(&branch.Id, &branch.Name, &branch.Address, &branch.CreatedAt); err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, rows.Err()
+}
+
+func (r *BranchesRepository) IsUserAssigned(c context.Context, userID, branchID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(c,
+		`SELECT EXISTS(SELECT 1 FROM user_branches WHERE user_id = $1 AND branch_id = $2)`,
+		userID, branchID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *BranchesRepository) AssignUser(c context.Context, userID, branchID uuid.UUID) error {
+	_, err := r.db.Exec(c,
+		`INSERT INTO user_branches (user_id, branch_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		userID, branchID,
+	)
+	return err
+}
+
+func (r *BranchesRepository) UnassignUser(c context.Context, userID, branchID uuid.UUID) error {
+	_, err := r.db.Exec(c,
+		`DELETE FROM user_branches WHERE user_id = $1 AND branch_id = $2`,
+		userID, branchID,
+	)
+	return err
+}
+
+// Report собирает сводку по всем филиалам. Уроки и оплаты учитываются в интервале [from, to],
+// если границы заданы.
+func (r *BranchesRepository) Report(c context.Context, from, to *time.Time) ([]models.BranchReport, error) {
+	rows, err := r.db.Query(c, `
+		SELECT
+			b.id,
+			b.name,
+			(SELECT COUNT(*) FROM students s WHERE s.branch_id = b.id AND s.deleted_at IS NULL AND s.is_active = 'active'),
+			(SELECT COUNT(*) FROM students s WHERE s.branch_id = b.id AND s.deleted_at IS NULL AND s.is_active IS DISTINCT FROM 'active'),
+			(SELECT COUNT(*) FROM courses co WHERE co.branch_id = b.id AND co.deleted_at IS NULL),
+			(SELECT COUNT(*)
+				FROM user_branches ub
+				JOIN users u ON u.id = ub.user_id
+				WHERE ub.branch_id = b.id AND u.deleted_at IS NULL),
+			(SELECT COUNT(*)
+				FROM attendance a
+				JOIN students s ON s.id = a.student_id
+				JOIN attendance_lessons l ON l.attendance_id = a.id
+				WHERE s.branch_id = b.id AND s.deleted_at IS NULL AND a.deleted_at IS NULL
+				  AND l.lessons_status = 'conducted'
+				  AND ($1::date IS NULL OR l.date >= $1)
+				  AND ($2::date IS NULL OR l.date <= $2)),
+			(SELECT COALESCE(SUM(p.amount), 0)
+				FROM attendance a
+				JOIN students s ON s.id = a.student_id
+				JOIN attendance_prolongations p ON p.attendance_id = a.id
+				WHERE s.branch_id = b.id AND s.deleted_at IS NULL AND a.deleted_at IS NULL
+				  AND ($1::date IS NULL OR p.date >= $1)
+				  AND ($2::date IS NULL OR p.date <= $2))
+		FROM branches b
+		ORDER BY b.name`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]models.BranchReport, 0)
+	for rows.Next() {
+		var report models.BranchReport
+		err := rows.Scan(
+			&report.BranchId,
+			&report.BranchName,
+			&report.ActiveStudents,
+			&report.InactiveStudents,
+			&report.Courses,
+			&report.Users,
+			&report.LessonsConducted,
+			&report.PaymentsTotal,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
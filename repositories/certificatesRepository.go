@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"it_school/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CertificatesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCertificatesRepository(conn *pgxpool.Pool) *CertificatesRepository {
+	return &CertificatesRepository{db: conn}
+}
+
+func (r *CertificatesRepository) FindTemplate(c context.Context, courseID uuid.UUID) (models.CertificateTemplate, error) {
+	var template models.CertificateTemplate
+	err := r.db.QueryRow(c, `
+		SELECT t.course_id, t.title, t.body, t.signature_name, t.updated_at
+		FROM certificate_templates t
+		JOIN courses co ON co.id = t.course_id
+		WHERE t.course_id = $1 AND ($2::uuid IS NULL OR co.branch_id = $2)`,
+		courseID, currentBranch(c),
+	).Scan(&template.CourseId, &template.Title, &template.Body, &template.SignatureName, &template.UpdatedAt)
+	return template, err
+}
+
+func (r *CertificatesRepository) SaveTemplate(c context.Context, template models.CertificateTemplate) error {
+	tag, err := r.db.Exec(c, `
+		INSERT INTO certificate_templates (course_id, title, body, signature_name)
+		SELECT co.id, $2, $3, $4 FROM courses co
+		WHERE co.id = $1 AND ($5::uuid IS NULL OR co.branch_id = $5)
+		ON CONFLICT (course_id) DO UPDATE SET title = excluded.title, body = excluded.body,
+			signature_name = excluded.signature_name, updated_at = now(), updated_by = $6`,
+		template.CourseId, template.Title, template.Body, template.SignatureName, currentBranch(c), currentUser(c),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("course_not_found", "Course not found")
+	}
+	return nil
+}
+
+// PrepareForEnrollment собирает данные сертификата по завершенному зачислению.
+// Куратор берется из зачисления, иначе текущий куратор студента; дата окончания — из зачисления, иначе сегодня.
+func (r *CertificatesRepository) PrepareForEnrollment(c context.Context, enrollmentID uuid.UUID) (models.Certificate, uuid.UUID, error) {
+	var certificate models.Certificate
+	var courseID uuid.UUID
+	var status string
+	err := r.db.QueryRow(c, `
+		SELECT e.id, s.id, s.full_name, co.id, co.title, e.status, e.start_date, COALESCE(e.end_date, current_date),
+		       COALESCE(
+		           (SELECT u.full_name FROM users u WHERE u.id = e.curator_id),
+		           (SELECT u.full_name FROM curator_students cs JOIN users u ON u.id = cs.curator_id
+		            WHERE cs.student_id = s.id AND cs.unassigned_at IS NULL))
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		JOIN courses co ON co.id = e.course_id
+		WHERE e.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)`,
+		enrollmentID, currentBranch(c),
+	).Scan(
+		&certificate.EnrollmentId,
+		&certificate.StudentId,
+		&certificate.StudentName,
+		&courseID,
+		&certificate.CourseTitle,
+		&status,
+		&certificate.StartDate,
+		&certificate.EndDate,
+		&certificate.CuratorName,
+	)
+	if err != nil {
+		return models.Certificate{}, uuid.Nil, err
+	}
+	if status != models.EnrollmentCompleted {
+		return models.Certificate{}, uuid.Nil, models.ErrEnrollmentNotCompleted
+	}
+	return certificate, courseID, nil
+}
+
+func (r *CertificatesRepository) Create(c context.Context, certificate models.Certificate) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(c, `
+		INSERT INTO certificates (number, enrollment_id, student_id, student_name, course_title, curator_name,
+			start_date, end_date, storage_path)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		certificate.Number,
+		certificate.EnrollmentId,
+		certificate.StudentId,
+		certificate.StudentName,
+		certificate.CourseTitle,
+		certificate.CuratorName,
+		certificate.StartDate,
+		certificate.EndDate,
+		certificate.StoragePath,
+	).Scan(&id)
+	return id, err
+}
+
+const certificateColumns = `ce.id, ce.number, ce.enrollment_id, ce.student_id, ce.student_name, ce.course_title,
+	ce.curator_name, ce.start_date, ce.end_date, ce.storage_path, ce.issued_at`
+
+func scanCertificate(row pgx.Row) (models.Certificate, error) {
+	var certificate models.Certificate
+	err := row.Scan(
+		&certificate.Id,
+		&certificate.Number,
+		&certificate.EnrollmentId,
+		&certificate.StudentId,
+		&certificate.StudentName,
+		&certificate.CourseTitle,
+		&certificate.CuratorName,
+		&certificate.StartDate,
+		&certificate.EndDate,
+		&certificate.StoragePath,
+		&certificate.IssuedAt,
+	)
+	return certificate, err
+}
+
+func (r *CertificatesRepository) FindById(c context.Context, id uuid.UUID) (models.Certificate, error) {
+	row := r.db.QueryRow(c, `SELECT `+certificateColumns+`
+		FROM certificates ce
+		LEFT JOIN students s ON s.id = ce.student_id
+		WHERE ce.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)`,
+		id, currentBranch(c),
+	)
+	certificate, err := scanCertificate(row)
+	return certificate, notFoundIfNoRows(err, "certificate_not_found", "Certificate not found")
+}
+
+func (r *CertificatesRepository) FindByEnrollment(c context.Context, enrollmentID uuid.UUID) (models.Certificate, error) {
+	row := r.db.QueryRow(c, `SELECT `+certificateColumns+` FROM certificates ce WHERE ce.enrollment_id = $1`, enrollmentID)
+	return scanCertificate(row)
+}
+
+// FindByNumber используется публичной проверкой, поэтому не ограничивается филиалом
+func (r *CertificatesRepository) FindByNumber(c context.Context, number string) (models.Certificate, error) {
+	row := r.db.QueryRow(c, `SELECT `+certificateColumns+` FROM certificates ce WHERE ce.number = $1`, number)
+	return scanCertificate(row)
+}
+
+func (r *CertificatesRepository) FindByStudent(c context.Context, studentID uuid.UUID) ([]models.Certificate, error) {
+	rows, err := r.db.Query(c, `SELECT `+certificateColumns+`
+		FROM certificates ce
+		JOIN students s ON s.id = ce.student_id
+		WHERE ce.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+		ORDER BY ce.issued_at DESC`,
+		studentID, currentBranch(c),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	certificates := make([]models.Certificate, 0)
+	for rows.Next() {
+		certificate, err := scanCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, rows.Err()
+}
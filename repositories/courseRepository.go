@@ -2,10 +2,12 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"it_school/logger"
 	"it_school/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -18,11 +20,37 @@ func NewCourseRepository(conn *pgxpool.Pool) *CourseRepository {
 	return &CourseRepository{db: conn}
 }
 
+const courseColumns = `c.id, c.title, c.branch_id, c.description, c.age_min, c.age_max, c.duration_weeks,
+	c.lessons_count, c.price, c.status, c.created_at, c.version, c.updated_at, c.updated_by`
 
+func scanCourse(row pgx.Row) (models.Course, error) {
+	var course models.Course
+	err := row.Scan(
+		&course.Id,
+		&course.Title,
+		&course.BranchId,
+		&course.Description,
+		&course.AgeMin,
+		&course.AgeMax,
+		&course.DurationWeeks,
+		&course.LessonsCount,
+		&course.Price,
+		&course.Status,
+		&course.CreatedAt,
+		&course.Version,
+		&course.UpdatedAt,
+		&course.UpdatedBy,
+	)
+	return course, err
+}
 
 func (r *CourseRepository) Create(c context.Context, course models.Course) (uuid.UUID, error) {
 	course.Id = uuid.New()
-	row := r.db.QueryRow(c, `insert into courses (id, title) values ($1, $2) returning id`, course.Id, course.Title)
+	row := r.db.QueryRow(c, `insert into courses (id, title, branch_id, description, age_min, age_max, duration_weeks, lessons_count, price, status)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, coalesce(nullif($10, '')::course_status, 'active'))
+		returning id`,
+		course.Id, course.Title, course.BranchId, course.Description, course.AgeMin, course.AgeMax,
+		course.DurationWeeks, course.LessonsCount, course.Price, course.Status)
 	err := row.Scan(&course.Id)
 	if err != nil {
 		return uuid.UUID{}, err
@@ -30,76 +58,291 @@ func (r *CourseRepository) Create(c context.Context, course models.Course) (uuid
 	return course.Id, nil
 }
 
-func (r *CourseRepository) Update(c context.Context, updateCourse models.Course) error {
-	l := logger.GetLogger()
+// Update перезаписывает курс, если его версия входит в versions (nil — любая версия).
+// Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *CourseRepository) Update(c context.Context, updateCourse models.Course, versions []int) (int, error) {
+	l := logger.FromContext(c)
 
 	tx, err := r.db.Begin(c)
 	if err != nil {
 		l.Error("Ошибка начала транзакции", zap.String("db_msg", err.Error()))
-		return err
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var version int
+	err = tx.QueryRow(c, `update courses set title = $1, description = $2, age_min = $3, age_max = $4, duration_weeks = $5,
+		lessons_count = $6, price = $7, status = coalesce(nullif($8, '')::course_status, status), updated_by = $11
+		where id = $9 and ($10::uuid is null or branch_id = $10) and deleted_at is null and ($12::int[] is null or version = any($12))
+		returning version`,
+		updateCourse.Title, updateCourse.Description, updateCourse.AgeMin, updateCourse.AgeMax, updateCourse.DurationWeeks,
+		updateCourse.LessonsCount, updateCourse.Price, updateCourse.Status, updateCourse.Id, currentBranch(c), currentUser(c),
+		versions).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, r.staleOrNotFound(c, updateCourse.Id)
 	}
-	_, err = tx.Exec(c, `update courses set title = $1 where id = $2`, updateCourse.Title, updateCourse.Id)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	err = tx.Commit(c)
 	if err != nil {
 		l.Error("Ошибка при коммите транзакции", zap.String("commit_msg", err.Error()))
+		return 0, err
+	}
+	return version, nil
+}
+
+// staleOrNotFound объясняет, почему UPDATE с проверкой версии не нашел строку
+func (r *CourseRepository) staleOrNotFound(c context.Context, courseId uuid.UUID) error {
+	var exists bool
+	err := r.db.QueryRow(c, `select exists(select 1 from courses where id = $1 and ($2::uuid is null or branch_id = $2) and deleted_at is null)`,
+		courseId, currentBranch(c)).Scan(&exists)
+	if err != nil {
 		return err
 	}
-	return nil
+	if exists {
+		return models.ErrVersionConflict
+	}
+	return notFound("course_not_found", "Course not found")
 }
 
-func (r *CourseRepository) FindAll(c context.Context) ([]models.Course, error) {
-	sql := `select c.id, c.title from courses c`
+// FindAll возвращает курсы текущего филиала; status (active, archived) необязателен
+func (r *CourseRepository) FindAll(c context.Context, status string) ([]models.Course, error) {
+	sql := `select ` + courseColumns + ` from courses c
+		where c.deleted_at is null and ($1::uuid is null or c.branch_id = $1) and ($2 = '' or c.status::text = $2)
+		order by c.title`
 
-	row, err := r.db.Query(c, sql)
+	row, err := r.db.Query(c, sql, currentBranch(c), status)
 	if err != nil {
 		return nil, err
 	}
+	defer row.Close()
 
 	courses := make([]models.Course, 0)
 	for row.Next() {
-		var course models.Course
-		err := row.Scan(&course.Id, &course.Title)
+		course, err := scanCourse(row)
 		if err != nil {
 			return nil, err
 		}
 		courses = append(courses, course)
 	}
-	return courses, nil
+	return courses, row.Err()
 }
 
 func (r *CourseRepository) FindById(c context.Context, courseId uuid.UUID) (models.Course, error) {
-	var course models.Course
-	row := r.db.QueryRow(c, `select c.id, c.title from courses c where c.id = $1`, courseId)
-	if err := row.Scan(&course.Id, &course.Title); err != nil {
-		return models.Course{}, err
-	}
-	return course, nil
+	row := r.db.QueryRow(c, `select `+courseColumns+` from courses c where c.id = $1 and ($2::uuid is null or c.branch_id = $2) and c.deleted_at is null`, courseId, currentBranch(c))
+	course, err := scanCourse(row)
+	return course, notFoundIfNoRows(err, "course_not_found", "Course not found")
 }
 
+// Delete переносит курс в корзину. Курс, на который зачислены студенты, удалить нельзя —
+// возвращается models.ErrCourseInUse. Программа курса и история посещаемости сохраняются
 func (r *CourseRepository) Delete(c context.Context, courseId uuid.UUID) error {
-	l := logger.GetLogger()
+	tag, err := r.db.Exec(c, `update courses set deleted_at = now(), deleted_by = $3
+		where id = $1 and ($2::uuid is null or branch_id = $2) and deleted_at is null
+		  and not exists (select 1 from enrollments e where e.course_id = courses.id)`,
+		courseId, currentBranch(c), currentUser(c))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCourseInUse
+	}
+	return nil
+}
 
+// FindCurriculum возвращает программу курса: модули и темы в порядке position
+func (r *CourseRepository) FindCurriculum(c context.Context, courseId uuid.UUID) ([]models.CourseModule, error) {
+	rows, err := r.db.Query(c, `
+		select m.id, m.course_id, m.position, m.title, m.description,
+		       t.id, t.position, t.title, t.description, t.lesson_plan
+		from course_modules m
+		join courses c on c.id = m.course_id
+		left join course_topics t on t.module_id = m.id
+		where m.course_id = $1 and ($2::uuid is null or c.branch_id = $2)
+		order by m.position, t.position`,
+		courseId, currentBranch(c),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modules := make([]models.CourseModule, 0)
+	for rows.Next() {
+		var module models.CourseModule
+		var topicID uuid.NullUUID
+		var topicPosition *int
+		var topicTitle, topicDescription, lessonPlan *string
+		err := rows.Scan(
+			&module.Id, &module.CourseId, &module.Position, &module.Title, &module.Description,
+			&topicID, &topicPosition, &topicTitle, &topicDescription, &lessonPlan,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if n := len(modules); n == 0 || modules[n-1].Id != module.Id {
+			module.Topics = make([]models.CourseTopic, 0)
+			modules = append(modules, module)
+		}
+		if topicID.Valid {
+			last := &modules[len(modules)-1]
+			last.Topics = append(last.Topics, models.CourseTopic{
+				Id:          topicID.UUID,
+				ModuleId:    module.Id,
+				Position:    *topicPosition,
+				Title:       *topicTitle,
+				Description: topicDescription,
+				LessonPlan:  lessonPlan,
+			})
+		}
+	}
+	return modules, rows.Err()
+}
+
+// ReplaceCurriculum сохраняет программу курса целиком. Порядок модулей и тем берется из порядка
+// в списке. Модули и темы с id обновляются (ссылки уроков на них сохраняются), без id — создаются,
+// отсутствующие в списке — удаляются.
+func (r *CourseRepository) ReplaceCurriculum(c context.Context, courseId uuid.UUID, modules []models.CourseModule) error {
 	tx, err := r.db.Begin(c)
 	if err != nil {
-		l.Error("Ошибка начала транзакции", zap.String("db_msg", err.Error()))
 		return err
 	}
+	defer tx.Rollback(c)
 
-	_, err = tx.Exec(c, "DELETE FROM courses WHERE id = $1", courseId)
+	var exists bool
+	err = tx.QueryRow(c,
+		`select exists(select 1 from courses where id = $1 and ($2::uuid is null or branch_id = $2) and deleted_at is null)`,
+		courseId, currentBranch(c),
+	).Scan(&exists)
 	if err != nil {
-		l.Error(err.Error())
-		tx.Rollback(c)
 		return err
 	}
+	if !exists {
+		return notFound("course_not_found", "Course not found")
+	}
 
-	err = tx.Commit(c)
+	keepModules := make([]uuid.UUID, 0, len(modules))
+	keepTopics := make([]uuid.UUID, 0)
+	for i, module := range modules {
+		if module.Id == uuid.Nil {
+			module.Id = uuid.New()
+		}
+		// Модуль с чужим id не перехватывается: upsert обновляет только модули этого курса
+		tag, err := tx.Exec(c, `
+			insert into course_modules (id, course_id, position, title, description)
+			values ($1, $2, $3, $4, $5)
+			on conflict (id) do update set position = excluded.position, title = excluded.title, description = excluded.description
+			where course_modules.course_id = excluded.course_id`,
+			module.Id, courseId, i+1, module.Title, module.Description,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return notFound("module_not_found", "Module not found")
+		}
+		keepModules = append(keepModules, module.Id)
+
+		for j, topic := range module.Topics {
+			if topic.Id == uuid.Nil {
+				topic.Id = uuid.New()
+			}
+			tag, err := tx.Exec(c, `
+				insert into course_topics (id, module_id, position, title, description, lesson_plan)
+				values ($1, $2, $3, $4, $5, $6)
+				on conflict (id) do update set module_id = excluded.module_id, position = excluded.position,
+					title = excluded.title, description = excluded.description, lesson_plan = excluded.lesson_plan
+				where course_topics.module_id in (select id from course_modules where course_id = $7)`,
+				topic.Id, module.Id, j+1, topic.Title, topic.Description, topic.LessonPlan, courseId,
+			)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return notFound("topic_not_found", "Topic not found")
+			}
+			keepTopics = append(keepTopics, topic.Id)
+		}
+	}
+
+	_, err = tx.Exec(c, `
+		delete from course_topics t using course_modules m
+		where t.module_id = m.id and m.course_id = $1 and t.id <> all($2)`,
+		courseId, keepTopics,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(c,
+		`delete from course_modules where course_id = $1 and id <> all($2)`,
+		courseId, keepModules,
+	)
 	if err != nil {
-		l.Error("Ошибка при коммите транзакции", zap.String("commit_msg", err.Error()))
 		return err
 	}
+
+	return tx.Commit(c)
+}
+
+// Progress сопоставляет программу курса с проведенными уроками студента
+func (r *CourseRepository) Progress(c context.Context, studentId, courseId uuid.UUID) (models.CourseProgress, error) {
+	progress := models.CourseProgress{
+		StudentId: studentId,
+		CourseId:  courseId,
+		Topics:    make([]models.TopicProgress, 0),
+	}
+
+	rows, err := r.db.Query(c, `
+		select t.id, m.title, t.title,
+		       (select min(l.date) from attendance_lessons l
+		        join attendance a on a.id = l.attendance_id
+		        where l.topic_id = t.id and a.student_id = $1 and l.lessons_status = 'conducted')
+		from course_topics t
+		join course_modules m on m.id = t.module_id
+		join courses c on c.id = m.course_id
+		where m.course_id = $2 and ($3::uuid is null or c.branch_id = $3)
+		order by m.position, t.position`,
+		studentId, courseId, currentBranch(c),
+	)
+	if err != nil {
+		return progress, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var topic models.TopicProgress
+		if err := rows.Scan(&topic.TopicId, &topic.ModuleTitle, &topic.Title, &topic.CoveredAt); err != nil {
+			return progress, err
+		}
+		progress.TotalTopics++
+		if topic.CoveredAt != nil {
+			progress.CoveredTopics++
+		}
+		progress.Topics = append(progress.Topics, topic)
+	}
+	return progress, rows.Err()
+}
+
+// checkTopicInCourse проверяет, что тема входит в программу курса
+func checkTopicInCourse(c context.Context, tx pgx.Tx, topicId *uuid.UUID, courseId uuid.UUID) error {
+	if topicId == nil {
+		return nil
+	}
+	var ok bool
+	err := tx.QueryRow(c, `
+		select exists(select 1 from course_topics t join course_modules m on m.id = t.module_id
+		where t.id = $1 and m.course_id = $2)`,
+		*topicId, courseId,
+	).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.ErrTopicNotInCourse
+	}
 	return nil
 }
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"it_school/events"
+	"it_school/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CrmRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCrmRepository(conn *pgxpool.Pool) *CrmRepository {
+	return &CrmRepository{db: conn}
+}
+
+const deadLetterColumns = `id, event_id, event, payload, attempts, last_error, created_at, last_attempt_at`
+
+func scanDeadLetter(row pgx.Row) (models.CrmDeadLetter, error) {
+	var letter models.CrmDeadLetter
+	err := row.Scan(
+		&letter.Id,
+		&letter.EventId,
+		&letter.Event,
+		&letter.Payload,
+		&letter.Attempts,
+		&letter.LastError,
+		&letter.CreatedAt,
+		&letter.LastAttemptAt,
+	)
+	return letter, err
+}
+
+// CrmDelivery — событие в очереди доставки в CRM
+type CrmDelivery struct {
+	Id       uuid.UUID
+	Event    models.CrmEvent
+	Attempts int
+}
+
+// recordCrmStudent записывает в outbox событие CRM с карточкой студента в том виде, в котором
+// она сохранена транзакцией tx. Студент из корзины тоже читается: так отправляется student.deleted
+func recordCrmStudent(c context.Context, tx pgx.Tx, eventType string, studentId uuid.UUID) error {
+	var student models.Student
+	err := tx.QueryRow(c, `
+		SELECT id, branch_id, full_name, phone_number, parent_name, parent_phone_number,
+		       platform_link, crm_link, is_active, lifecycle_state, crm_external_id
+		FROM students WHERE id = $1`,
+		studentId,
+	).Scan(
+		&student.Id,
+		&student.BranchId,
+		&student.FullName,
+		&student.PhoneNumber,
+		&student.ParentName,
+		&student.ParentPhoneNumber,
+		&student.PlatformLink,
+		&student.CrmLink,
+		&student.IsActive,
+		&student.LifecycleState,
+		&student.CrmExternalId,
+	)
+	if err != nil {
+		return err
+	}
+	return events.Record(c, tx, eventType, models.NewCrmStudent(student))
+}
+
+// EnqueueDelivery ставит событие в очередь доставки. Повторная обработка того же события
+// из outbox не создает вторую доставку. Возвращает false, если событие уже в очереди
+func (r *CrmRepository) EnqueueDelivery(c context.Context, event models.CrmEvent) (bool, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+	tag, err := r.db.Exec(c, `
+		INSERT INTO crm_deliveries (event_id, event, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id) DO NOTHING`,
+		event.Id, event.Event, payload,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DueDeliveries берет в работу доставки, время очередной попытки которых наступило, в порядке создания.
+// Выбранные доставки сдвигаются на lease вперед: другие экземпляры их не возьмут, а доставка,
+// итог которой не удалось записать, повторится только после истечения lease
+func (r *CrmRepository) DueDeliveries(c context.Context, limit int, lease time.Duration) ([]CrmDelivery, error) {
+	rows, err := r.db.Query(c, `
+		UPDATE crm_deliveries d
+		SET next_attempt_at = now() + $2::interval
+		FROM (
+			SELECT id FROM crm_deliveries
+			WHERE next_attempt_at <= now()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE d.id = due.id
+		RETURNING d.id, d.payload, d.attempts`,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []CrmDelivery
+	for rows.Next() {
+		var delivery CrmDelivery
+		var payload []byte
+		if err := rows.Scan(&delivery.Id, &payload, &delivery.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &delivery.Event); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// DeleteDelivery убирает доставленное событие из очереди
+func (r *CrmRepository) DeleteDelivery(c context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(c, `DELETE FROM crm_deliveries WHERE id = $1`, id)
+	return err
+}
+
+// MarkDeliveryFailed фиксирует неудачную попытку и планирует следующую на nextAttemptAt
+func (r *CrmRepository) MarkDeliveryFailed(c context.Context, id uuid.UUID, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.Exec(c, `
+		UPDATE crm_deliveries
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE id = $3`,
+		lastError, nextAttemptAt, id,
+	)
+	return err
+}
+
+// MoveToDeadLetters переносит доставку, исчерпавшую попытки, в crm_dead_letters
+func (r *CrmRepository) MoveToDeadLetters(c context.Context, id uuid.UUID, lastError string) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, `
+		INSERT INTO crm_dead_letters (event_id, event, payload, attempts, last_error)
+		SELECT event_id, event, payload, attempts + 1, $2 FROM crm_deliveries WHERE id = $1`,
+		id, lastError,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(c, `DELETE FROM crm_deliveries WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(c)
+}
+
+func (r *CrmRepository) FindDeadLetters(c context.Context) ([]models.CrmDeadLetter, error) {
+	rows, err := r.db.Query(c, `SELECT `+deadLetterColumns+` FROM crm_dead_letters ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := make([]models.CrmDeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+func (r *CrmRepository) FindDeadLetter(c context.Context, id uuid.UUID) (models.CrmDeadLetter, error) {
+	return scanDeadLetter(r.db.QueryRow(c, `SELECT `+deadLetterColumns+` FROM crm_dead_letters WHERE id = $1`, id))
+}
+
+func (r *CrmRepository) DeleteDeadLetter(c context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(c, `DELETE FROM crm_dead_letters WHERE id = $1`, id)
+	return err
+}
+
+// RecordDeadLetterAttempt фиксирует очередную неудачную ручную попытку доставки
+func (r *CrmRepository) RecordDeadLetterAttempt(c context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.Exec(c, `
+		UPDATE crm_dead_letters
+		SET attempts = attempts + 1, last_error = $1, last_attempt_at = now()
+		WHERE id = $2`,
+		lastError, id,
+	)
+	return err
+}
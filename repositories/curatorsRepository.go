@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"it_school/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,18 +19,24 @@ func NewCuratorsRepository(conn *pgxpool.Pool) *CuratorsRepository {
 }
 
 type CuratorResponse struct {
-    ID         uuid.UUID   `json:"id"`
-    FullName   string      `json:"full_name"`
-    Email      string      `json:"email"`
-    Telephone  string      `json:"telephone"`
-    RoleID     uuid.UUID   `json:"role_id"`
-    StudentIDs []uuid.UUID `json:"student_ids"`
-    CourseIDs  []uuid.UUID `json:"course_ids"`
+	ID         uuid.UUID   `json:"id"`
+	FullName   string      `json:"full_name"`
+	Email      string      `json:"email"`
+	Telephone  string      `json:"telephone"`
+	RoleID     uuid.UUID   `json:"role_id"`
+	StudentIDs []uuid.UUID `json:"student_ids"`
+	CourseIDs  []uuid.UUID `json:"course_ids"`
 }
 
+// GetCuratorByUserID возвращает куратора с его текущими студентами и курсами
 func (r *CuratorsRepository) GetCuratorByUserID(ctx context.Context, userID uuid.UUID) (models.Curator, error) {
 	var curator models.Curator
-	query := `SELECT user_id, student_ids, course_ids FROM curators WHERE user_id = $1`
+	query := `SELECT c.user_id,
+		COALESCE((SELECT array_agg(cs.student_id ORDER BY cs.assigned_at) FROM curator_students cs
+			WHERE cs.curator_id = c.user_id AND cs.unassigned_at IS NULL), '{}'),
+		COALESCE((SELECT array_agg(cc.course_id ORDER BY cc.assigned_at) FROM curator_courses cc
+			WHERE cc.curator_id = c.user_id), '{}')
+		FROM curators c WHERE c.user_id = $1`
 	err := r.db.QueryRow(ctx, query, userID).Scan(
 		&curator.UserID,
 		&curator.StudentIDs,
@@ -38,84 +46,221 @@ func (r *CuratorsRepository) GetCuratorByUserID(ctx context.Context, userID uuid
 }
 
 func (r *CuratorsRepository) Create(c context.Context, curator models.Curator) error {
-	_, err := r.db.Exec(c,
-		`INSERT INTO curators(user_id, student_ids, course_ids) VALUES ($1, $2, $3)`,
-		curator.UserID, curator.StudentIDs, curator.CourseIDs,
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, `INSERT INTO curators(user_id) VALUES ($1)`, curator.UserID); err != nil {
+		return err
+	}
+
+	for _, studentID := range curator.StudentIDs {
+		if err := assignStudent(c, tx, curator.UserID, studentID, nil); err != nil {
+			return err
+		}
+	}
+	for _, courseID := range curator.CourseIDs {
+		if err := assignCourse(c, tx, curator.UserID, courseID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+// assignStudent закрывает текущее назначение студента (если оно у другого куратора)
+// и открывает новое. Повторное назначение тому же куратору ничего не меняет.
+// Студент должен относиться к текущему филиалу, а куратор — к филиалу студента
+func assignStudent(c context.Context, tx pgx.Tx, curatorID, studentID uuid.UUID, assignedBy *uuid.UUID) error {
+	var branchID uuid.UUID
+	err := tx.QueryRow(c,
+		`SELECT branch_id FROM students WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL`,
+		studentID, currentBranch(c),
+	).Scan(&branchID)
+	if err != nil {
+		return notFoundIfNoRows(err, "student_not_found", "Student not found")
+	}
+	if err := checkGroupCurator(c, tx, curatorID, branchID); err != nil {
+		return err
+	}
+
+	var current uuid.UUID
+	err = tx.QueryRow(c,
+		`SELECT curator_id FROM curator_students WHERE student_id = $1 AND unassigned_at IS NULL FOR UPDATE`,
+		studentID,
+	).Scan(&current)
+	switch {
+	case err == nil && current == curatorID:
+		return nil
+	case err == nil:
+		_, err = tx.Exec(c,
+			`UPDATE curator_students SET unassigned_at = now() WHERE student_id = $1 AND unassigned_at IS NULL`,
+			studentID,
+		)
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return err
+	}
+
+	_, err = tx.Exec(c,
+		`INSERT INTO curator_students (curator_id, student_id, assigned_by) VALUES ($1, $2, $3)`,
+		curatorID, studentID, assignedBy,
 	)
 	return err
 }
 
-func (r *CuratorsRepository) AddStudent(c context.Context, curatorID, studentID uuid.UUID) error {
+// AddStudent назначает студента текущего филиала куратору. Если у студента был другой куратор,
+// его назначение закрывается и остается в истории.
+func (r *CuratorsRepository) AddStudent(c context.Context, curatorID, studentID uuid.UUID, assignedBy *uuid.UUID) error {
 	tx, err := r.db.Begin(c)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(c)
 
-	// 1. Добавляем студента в curators
-	_, err = tx.Exec(c,
-		`UPDATE curators SET student_ids = array_append(student_ids, $1) 
-		 WHERE user_id = $2 AND NOT ($1 = ANY(student_ids))`,
-		studentID, curatorID,
-	)
+	if err := assignStudent(c, tx, curatorID, studentID, assignedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// ReassignStudent передает студента от одного куратора другому одной транзакцией.
+// Если fromCuratorID не является текущим куратором студента — ошибка student_not_assigned_to_curator.
+func (r *CuratorsRepository) ReassignStudent(c context.Context, studentID, fromCuratorID, toCuratorID uuid.UUID, assignedBy *uuid.UUID) error {
+	tx, err := r.db.Begin(c)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(c)
 
-	// 2. Обновляем curator_id у студента
-	_, err = tx.Exec(c,
-		`UPDATE students SET curator_id = $1 WHERE id = $2`,
-		curatorID, studentID,
-	)
+	var current uuid.UUID
+	err = tx.QueryRow(c,
+		`SELECT curator_id FROM curator_students WHERE student_id = $1 AND unassigned_at IS NULL FOR UPDATE`,
+		studentID,
+	).Scan(&current)
 	if err != nil {
+		return notFoundIfNoRows(err, "student_not_assigned_to_curator", "Student is not assigned to this curator")
+	}
+	if current != fromCuratorID {
+		return notFound("student_not_assigned_to_curator", "Student is not assigned to this curator")
+	}
+
+	if err := assignStudent(c, tx, toCuratorID, studentID, assignedBy); err != nil {
 		return err
 	}
 
 	return tx.Commit(c)
 }
 
+// assignCourse закрепляет курс за куратором. Курс должен относиться к текущему филиалу
+// и не быть в корзине, а куратор — к филиалу курса
+func assignCourse(c context.Context, tx pgx.Tx, curatorID, courseID uuid.UUID) error {
+	var branchID uuid.UUID
+	err := tx.QueryRow(c,
+		`SELECT branch_id FROM courses WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL`,
+		courseID, currentBranch(c),
+	).Scan(&branchID)
+	if err != nil {
+		return notFoundIfNoRows(err, "course_not_found", "Course not found")
+	}
+	if err := checkGroupCurator(c, tx, curatorID, branchID); err != nil {
+		return err
+	}
 
-func (r *CuratorsRepository) AddCourse(c context.Context, curatorID, courseID uuid.UUID) error {
-	_, err := r.db.Exec(c,
-		`UPDATE curators SET course_ids = array_append(course_ids, $1) WHERE user_id = $2 AND NOT ($1 = ANY(course_ids))`,
-		courseID, curatorID,
+	_, err = tx.Exec(c,
+		`INSERT INTO curator_courses (curator_id, course_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		curatorID, courseID,
 	)
 	return err
 }
 
-func (r *CuratorsRepository) RemoveStudent(c context.Context, curatorID, studentID uuid.UUID) error {
+// AddCourse закрепляет курс текущего филиала за куратором этого филиала
+func (r *CuratorsRepository) AddCourse(c context.Context, curatorID, courseID uuid.UUID) error {
 	tx, err := r.db.Begin(c)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(c)
 
-	// 1. Убираем студента из массива у куратора
-	_, err = tx.Exec(c,
-		`UPDATE curators SET student_ids = array_remove(student_ids, $1) 
-		 WHERE user_id = $2`,
-		studentID, curatorID,
+	if err := assignCourse(c, tx, curatorID, courseID); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// RemoveStudent закрывает текущее назначение студента текущего филиала; запись остается в истории
+func (r *CuratorsRepository) RemoveStudent(c context.Context, curatorID, studentID uuid.UUID) error {
+	tag, err := r.db.Exec(c,
+		`UPDATE curator_students cs SET unassigned_at = now()
+		 FROM students s
+		 WHERE cs.curator_id = $1 AND cs.student_id = $2 AND cs.unassigned_at IS NULL
+		   AND s.id = cs.student_id AND ($3::uuid IS NULL OR s.branch_id = $3)`,
+		curatorID, studentID, currentBranch(c),
 	)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return notFound("student_not_assigned_to_curator", "Student is not assigned to this curator")
+	}
+	return nil
+}
 
-	// 2. Обнуляем curator_id у студента
-	_, err = tx.Exec(c,
-		`UPDATE students SET curator_id = NULL WHERE id = $1 AND curator_id = $2`,
-		studentID, curatorID,
+// RemoveCourse снимает с куратора курс текущего филиала
+func (r *CuratorsRepository) RemoveCourse(c context.Context, curatorID, courseID uuid.UUID) error {
+	tag, err := r.db.Exec(c,
+		`DELETE FROM curator_courses cc USING courses co
+		 WHERE cc.curator_id = $1 AND cc.course_id = $2
+		   AND co.id = cc.course_id AND ($3::uuid IS NULL OR co.branch_id = $3)`,
+		curatorID, courseID, currentBranch(c),
 	)
 	if err != nil {
 		return err
 	}
-
-	return tx.Commit(c)
+	if tag.RowsAffected() == 0 {
+		return notFound("curator_course_not_found", "Course is not assigned to this curator")
+	}
+	return nil
 }
 
-func (r *CuratorsRepository) RemoveCourse(c context.Context, curatorID, courseID uuid.UUID) error {
-	_, err := r.db.Exec(c,
-		`UPDATE curators SET course_ids = array_remove(course_ids, $1) WHERE user_id = $2`,
-		courseID, curatorID,
+// History возвращает всех кураторов студента, начиная с текущего
+func (r *CuratorsRepository) History(c context.Context, studentID uuid.UUID) ([]models.CuratorAssignment, error) {
+	rows, err := r.db.Query(c, `
+		SELECT cs.id, cs.curator_id, COALESCE(u.full_name, ''), cs.student_id, cs.assigned_at, cs.unassigned_at, cs.assigned_by
+		FROM curator_students cs
+		JOIN students s ON s.id = cs.student_id
+		JOIN users u ON u.id = cs.curator_id
+		WHERE cs.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+		ORDER BY cs.assigned_at DESC`,
+		studentID, currentBranch(c),
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]models.CuratorAssignment, 0)
+	for rows.Next() {
+		var assignment models.CuratorAssignment
+		err := rows.Scan(
+			&assignment.Id,
+			&assignment.CuratorId,
+			&assignment.CuratorName,
+			&assignment.StudentId,
+			&assignment.AssignedAt,
+			&assignment.UnassignedAt,
+			&assignment.AssignedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, assignment)
+	}
+	return history, rows.Err()
 }
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"it_school/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EnrollmentsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEnrollmentsRepository(conn *pgxpool.Pool) *EnrollmentsRepository {
+	return &EnrollmentsRepository{db: conn}
+}
+
+const enrollmentColumns = `e.id, e.student_id, e.course_id, e.curator_id, e.start_date, e.end_date, e.status, e.created_at, e.version`
+
+func scanEnrollment(row pgx.Row) (models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := row.Scan(
+		&enrollment.Id,
+		&enrollment.StudentId,
+		&enrollment.CourseId,
+		&enrollment.CuratorId,
+		&enrollment.StartDate,
+		&enrollment.EndDate,
+		&enrollment.Status,
+		&enrollment.CreatedAt,
+		&enrollment.Version,
+	)
+	return enrollment, err
+}
+
+// insertEnrollment используется и при создании студента, чтобы зачисление попало в ту же транзакцию
+func insertEnrollment(c context.Context, tx pgx.Tx, enrollment models.Enrollment) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(c, `
+		INSERT INTO enrollments (student_id, course_id, curator_id, start_date, end_date, status)
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, '')::enrollment_status, 'active'))
+		RETURNING id`,
+		enrollment.StudentId,
+		enrollment.CourseId,
+		enrollment.CuratorId,
+		enrollment.StartDate,
+		enrollment.EndDate,
+		enrollment.Status,
+	).Scan(&id)
+	return id, err
+}
+
+// findEnrollmentsByStudents загружает зачисления сразу для списка студентов
+func findEnrollmentsByStudents(c context.Context, db *pgxpool.Pool, studentIDs []uuid.UUID) (map[uuid.UUID][]models.Enrollment, error) {
+	result := make(map[uuid.UUID][]models.Enrollment, len(studentIDs))
+	if len(studentIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.Query(c, `SELECT `+enrollmentColumns+`
+		FROM enrollments e
+		WHERE e.student_id = ANY($1)
+		ORDER BY e.start_date DESC, e.created_at DESC`, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		result[enrollment.StudentId] = append(result[enrollment.StudentId], enrollment)
+	}
+	return result, rows.Err()
+}
+
+// Create зачисляет студента текущего филиала на курс
+func (r *EnrollmentsRepository) Create(c context.Context, enrollment models.Enrollment) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	var exists bool
+	err = tx.QueryRow(c,
+		`SELECT EXISTS(SELECT 1 FROM students WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL)`,
+		enrollment.StudentId, currentBranch(c),
+	).Scan(&exists)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, notFound("student_not_found", "Student not found")
+	}
+
+	id, err := insertEnrollment(c, tx, enrollment)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func (r *EnrollmentsRepository) FindById(c context.Context, id uuid.UUID) (models.Enrollment, error) {
+	row := r.db.QueryRow(c, `SELECT `+enrollmentColumns+`
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)`,
+		id, currentBranch(c),
+	)
+	enrollment, err := scanEnrollment(row)
+	return enrollment, notFoundIfNoRows(err, "enrollment_not_found", "Enrollment not found")
+}
+
+func (r *EnrollmentsRepository) FindByStudent(c context.Context, studentID uuid.UUID) ([]models.Enrollment, error) {
+	rows, err := r.db.Query(c, `SELECT `+enrollmentColumns+`
+		FROM enrollments e
+		JOIN students s ON s.id = e.student_id
+		WHERE e.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)
+		ORDER BY e.start_date DESC, e.created_at DESC`,
+		studentID, currentBranch(c),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := make([]models.Enrollment, 0)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, rows.Err()
+}
+
+// Update меняет зачисление, если его версия входит в versions (nil — любая версия).
+// Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *EnrollmentsRepository) Update(c context.Context, enrollment models.Enrollment, versions []int) (int, error) {
+	var version int
+	err := r.db.QueryRow(c, `
+		UPDATE enrollments SET curator_id = $1, start_date = $2, end_date = $3, status = $4, updated_by = $6
+		WHERE id = $5 AND ($7::int[] IS NULL OR version = ANY($7))
+		RETURNING version`,
+		enrollment.CuratorId,
+		enrollment.StartDate,
+		enrollment.EndDate,
+		enrollment.Status,
+		enrollment.Id,
+		currentUser(c),
+		versions,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, r.staleOrNotFound(c, enrollment.Id)
+	}
+	return version, err
+}
+
+// staleOrNotFound объясняет, почему UPDATE с проверкой версии не нашел строку
+func (r *EnrollmentsRepository) staleOrNotFound(c context.Context, id uuid.UUID) error {
+	var exists bool
+	err := r.db.QueryRow(c, `SELECT EXISTS(SELECT 1 FROM enrollments WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrVersionConflict
+	}
+	return notFound("enrollment_not_found", "Enrollment not found")
+}
+
+func (r *EnrollmentsRepository) Delete(c context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(c, `DELETE FROM enrollments WHERE id = $1`, id)
+	return err
+}
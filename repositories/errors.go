@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"errors"
+	"it_school/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// notFound — типизированная ошибка «не найдено». Она оборачивает pgx.ErrNoRows,
+// поэтому проверки errors.Is(err, pgx.ErrNoRows) в вызывающем коде продолжают работать
+func notFound(code, msg string) error {
+	return models.NewNotFoundError(code, msg).Wrap(pgx.ErrNoRows)
+}
+
+// notFoundIfNoRows заменяет pgx.ErrNoRows из Scan на типизированную ошибку, остальные ошибки возвращает как есть
+func notFoundIfNoRows(err error, code, msg string) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notFound(code, msg)
+	}
+	return err
+}
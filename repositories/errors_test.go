@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"it_school/models"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestNotFoundIfNoRows(t *testing.T) {
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name         string
+		err          error
+		wantNotFound bool
+		wantErr      error
+	}{
+		{name: "nil", err: nil, wantErr: nil},
+		{name: "no rows", err: pgx.ErrNoRows, wantNotFound: true},
+		{name: "wrapped no rows", err: fmt.Errorf("scan student: %w", pgx.ErrNoRows), wantNotFound: true},
+		{name: "other error", err: other, wantErr: other},
+		{name: "context canceled", err: context.Canceled, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := notFoundIfNoRows(tt.err, "student_not_found", "Student not found")
+
+			if !tt.wantNotFound {
+				if got != tt.wantErr {
+					t.Fatalf("notFoundIfNoRows() = %v, want %v unchanged", got, tt.wantErr)
+				}
+				return
+			}
+
+			var appErr *models.AppError
+			if !errors.As(got, &appErr) {
+				t.Fatalf("notFoundIfNoRows() = %v, want *models.AppError", got)
+			}
+			if appErr.Kind != models.KindNotFound || appErr.Code != "student_not_found" || appErr.Message != "Student not found" {
+				t.Errorf("got kind=%v code=%s message=%q", appErr.Kind, appErr.Code, appErr.Message)
+			}
+			// Вызывающий код проверяет «не найдено» через pgx.ErrNoRows
+			if !errors.Is(got, pgx.ErrNoRows) {
+				t.Error("result does not wrap pgx.ErrNoRows")
+			}
+		})
+	}
+}
+
+// notFound не должен менять общую ошибку-образец: каждый вызов получает свою копию
+func TestNotFoundDoesNotShareState(t *testing.T) {
+	first := notFound("course_not_found", "Course not found")
+	second := notFound("group_not_found", "Group not found")
+
+	if errors.Is(first, second) {
+		t.Error("errors with different codes compare equal")
+	}
+	if !errors.Is(first, models.NewNotFoundError("course_not_found", "")) {
+		t.Error("error does not match its code")
+	}
+}
@@ -0,0 +1,340 @@
+package repositories
+
+import (
+	"context"
+	"it_school/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GradesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGradesRepository(conn *pgxpool.Pool) *GradesRepository {
+	return &GradesRepository{db: conn}
+}
+
+// FindRubric возвращает критерии оценивания курса в порядке position
+func (r *GradesRepository) FindRubric(c context.Context, courseID uuid.UUID) ([]models.RubricCriterion, error) {
+	rows, err := r.db.Query(c, `
+		SELECT rc.id, rc.course_id, rc.position, rc.title, rc.description, rc.min_score, rc.max_score
+		FROM rubric_criteria rc
+		JOIN courses co ON co.id = rc.course_id
+		WHERE rc.course_id = $1 AND ($2::uuid IS NULL OR co.branch_id = $2)
+		ORDER BY rc.position`,
+		courseID, currentBranch(c),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	criteria := make([]models.RubricCriterion, 0)
+	for rows.Next() {
+		var criterion models.RubricCriterion
+		err := rows.Scan(
+			&criterion.Id,
+			&criterion.CourseId,
+			&criterion.Position,
+			&criterion.Title,
+			&criterion.Description,
+			&criterion.MinScore,
+			&criterion.MaxScore,
+		)
+		if err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, criterion)
+	}
+	return criteria, rows.Err()
+}
+
+// ReplaceRubric сохраняет рубрику курса целиком, как и программу курса: критерии с id обновляются,
+// без id — создаются, отсутствующие в списке удаляются вместе с выставленными по ним баллами.
+func (r *GradesRepository) ReplaceRubric(c context.Context, courseID uuid.UUID, criteria []models.RubricCriterion) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var exists bool
+	err = tx.QueryRow(c,
+		`SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL)`,
+		courseID, currentBranch(c),
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return notFound("course_not_found", "Course not found")
+	}
+
+	keep := make([]uuid.UUID, 0, len(criteria))
+	for i, criterion := range criteria {
+		if criterion.Id == uuid.Nil {
+			criterion.Id = uuid.New()
+		}
+		tag, err := tx.Exec(c, `
+			INSERT INTO rubric_criteria (id, course_id, position, title, description, min_score, max_score)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id) DO UPDATE SET position = excluded.position, title = excluded.title,
+				description = excluded.description, min_score = excluded.min_score, max_score = excluded.max_score
+			WHERE rubric_criteria.course_id = excluded.course_id`,
+			criterion.Id, courseID, i+1, criterion.Title, criterion.Description, criterion.MinScore, criterion.MaxScore,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return notFound("criterion_not_found", "Criterion not found")
+		}
+		keep = append(keep, criterion.Id)
+	}
+
+	_, err = tx.Exec(c, `DELETE FROM rubric_criteria WHERE course_id = $1 AND id <> ALL($2)`, courseID, keep)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// ScoreLesson выставляет баллы за урок. Студент и курс берутся из записи посещаемости;
+// повторная оценка по тому же критерию перезаписывает прежнюю.
+func (r *GradesRepository) ScoreLesson(c context.Context, attendanceID uuid.UUID, scores []models.StudentScore, scoredBy uuid.UUID) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var studentID, courseID uuid.UUID
+	err = tx.QueryRow(c, `
+		SELECT a.student_id, a.course_id
+		FROM attendance_lessons l
+		JOIN attendance a ON a.id = l.attendance_id
+		JOIN students s ON s.id = a.student_id
+		WHERE l.attendance_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2)`,
+		attendanceID, currentBranch(c),
+	).Scan(&studentID, &courseID)
+	if err != nil {
+		return err
+	}
+
+	for _, score := range scores {
+		tag, err := tx.Exec(c, `
+			INSERT INTO student_scores (student_id, criterion_id, attendance_id, score, comment, scored_by)
+			SELECT $1, rc.id, $3, $4, $5, $6
+			FROM rubric_criteria rc
+			WHERE rc.id = $2 AND rc.course_id = $7 AND $4 BETWEEN rc.min_score AND rc.max_score
+			ON CONFLICT (criterion_id, attendance_id) WHERE attendance_id IS NOT NULL
+			DO UPDATE SET score = excluded.score, comment = excluded.comment,
+				scored_by = excluded.scored_by, scored_at = now()`,
+			studentID, score.CriterionId, attendanceID, score.Score, score.Comment, scoredBy, courseID,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrInvalidScore
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+// ScoreModule выставляет итоговые баллы студента за модуль программы
+func (r *GradesRepository) ScoreModule(c context.Context, studentID, moduleID uuid.UUID, scores []models.StudentScore, scoredBy uuid.UUID) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var exists bool
+	err = tx.QueryRow(c, `
+		SELECT EXISTS(SELECT 1 FROM students s, course_modules m
+		WHERE s.id = $1 AND m.id = $2 AND ($3::uuid IS NULL OR s.branch_id = $3))`,
+		studentID, moduleID, currentBranch(c),
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return notFound("student_or_module_not_found", "Student or module not found")
+	}
+
+	for _, score := range scores {
+		tag, err := tx.Exec(c, `
+			INSERT INTO student_scores (student_id, criterion_id, module_id, score, comment, scored_by)
+			SELECT $1, rc.id, m.id, $4, $5, $6
+			FROM rubric_criteria rc
+			JOIN course_modules m ON m.course_id = rc.course_id
+			WHERE rc.id = $2 AND m.id = $3 AND $4 BETWEEN rc.min_score AND rc.max_score
+			ON CONFLICT (student_id, criterion_id, module_id) WHERE module_id IS NOT NULL
+			DO UPDATE SET score = excluded.score, comment = excluded.comment,
+				scored_by = excluded.scored_by, scored_at = now()`,
+			studentID, score.CriterionId, moduleID, score.Score, score.Comment, scoredBy,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrInvalidScore
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+// Progress возвращает динамику баллов студента по каждому критерию курса за период.
+// Балл за урок датируется датой урока, балл за модуль — датой выставления.
+func (r *GradesRepository) Progress(c context.Context, studentID, courseID uuid.UUID, from, to *time.Time) ([]models.CriterionProgress, error) {
+	criteria, err := r.FindRubric(c, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make([]models.CriterionProgress, len(criteria))
+	index := make(map[uuid.UUID]int, len(criteria))
+	for i, criterion := range criteria {
+		progress[i] = models.CriterionProgress{
+			CriterionId: criterion.Id,
+			Title:       criterion.Title,
+			MinScore:    criterion.MinScore,
+			MaxScore:    criterion.MaxScore,
+			Points:      make([]models.ScorePoint, 0),
+		}
+		index[criterion.Id] = i
+	}
+	if len(criteria) == 0 {
+		return progress, nil
+	}
+
+	rows, err := r.db.Query(c, `
+		SELECT ss.criterion_id, COALESCE(l.date, ss.scored_at::date) AS scored_on, ss.score, ss.module_id
+		FROM student_scores ss
+		JOIN rubric_criteria rc ON rc.id = ss.criterion_id
+		JOIN students s ON s.id = ss.student_id
+		LEFT JOIN attendance_lessons l ON l.attendance_id = ss.attendance_id
+		WHERE ss.student_id = $1 AND rc.course_id = $2
+		  AND ($3::uuid IS NULL OR s.branch_id = $3)
+		  AND ($4::date IS NULL OR COALESCE(l.date, ss.scored_at::date) >= $4)
+		  AND ($5::date IS NULL OR COALESCE(l.date, ss.scored_at::date) <= $5)
+		ORDER BY scored_on, ss.scored_at`,
+		studentID, courseID, currentBranch(c), from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make([]int, len(criteria))
+	for rows.Next() {
+		var criterionID uuid.UUID
+		var point models.ScorePoint
+		if err := rows.Scan(&criterionID, &point.Date, &point.Score, &point.ModuleId); err != nil {
+			return nil, err
+		}
+		i := index[criterionID]
+		progress[i].Points = append(progress[i].Points, point)
+		sums[i] += point.Score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range progress {
+		if n := len(progress[i].Points); n > 0 {
+			average := float64(sums[i]) / float64(n)
+			progress[i].Average = &average
+		}
+	}
+	return progress, nil
+}
+
+// ReportCard собирает табель студента по курсу за период: баллы по критериям, посещаемость,
+// пройденные темы, домашние задания и последние отзывы кураторов.
+func (r *GradesRepository) ReportCard(c context.Context, studentID, courseID uuid.UUID, from, to time.Time) (models.ReportCard, error) {
+	card := models.ReportCard{From: from, To: to, GeneratedAt: time.Now(), Comments: make([]string, 0)}
+
+	err := r.db.QueryRow(c, `
+		SELECT s.full_name, s.parent_name, co.title,
+		       (SELECT u.full_name FROM curator_students cs JOIN users u ON u.id = cs.curator_id
+		        WHERE cs.student_id = s.id AND cs.unassigned_at IS NULL)
+		FROM students s, courses co
+		WHERE s.id = $1 AND co.id = $2 AND ($3::uuid IS NULL OR s.branch_id = $3)`,
+		studentID, courseID, currentBranch(c),
+	).Scan(&card.StudentName, &card.ParentName, &card.CourseTitle, &card.CuratorName)
+	if err != nil {
+		return card, err
+	}
+
+	card.Criteria, err = r.Progress(c, studentID, courseID, &from, &to)
+	if err != nil {
+		return card, err
+	}
+
+	err = r.db.QueryRow(c, `
+		SELECT COUNT(*) FILTER (WHERE l.lessons_status = 'conducted'),
+		       COUNT(*) FILTER (WHERE l.lessons_status = 'missed')
+		FROM attendance a
+		JOIN attendance_lessons l ON l.attendance_id = a.id
+		WHERE a.student_id = $1 AND a.course_id = $2 AND l.date BETWEEN $3 AND $4`,
+		studentID, courseID, from, to,
+	).Scan(&card.LessonsHeld, &card.LessonsMissed)
+	if err != nil {
+		return card, err
+	}
+
+	err = r.db.QueryRow(c, `
+		SELECT
+			(SELECT COUNT(DISTINCT l.topic_id)
+			 FROM attendance a
+			 JOIN attendance_lessons l ON l.attendance_id = a.id
+			 WHERE a.student_id = $1 AND a.course_id = $2 AND l.lessons_status = 'conducted' AND l.date <= $3),
+			(SELECT COUNT(*) FROM course_topics t JOIN course_modules m ON m.id = t.module_id WHERE m.course_id = $2)`,
+		studentID, courseID, to,
+	).Scan(&card.TopicsCovered, &card.TopicsTotal)
+	if err != nil {
+		return card, err
+	}
+
+	err = r.db.QueryRow(c, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE hs.status IN ('submitted', 'checked')),
+		       AVG(hs.grade)::float8
+		FROM homework_submissions hs
+		JOIN homework h ON h.id = hs.homework_id
+		LEFT JOIN attendance a ON a.id = h.attendance_id
+		LEFT JOIN group_lessons gl ON gl.id = h.group_lesson_id
+		LEFT JOIN groups g ON g.id = gl.group_id
+		WHERE hs.student_id = $1 AND COALESCE(a.course_id, g.course_id) = $2
+		  AND h.due_date BETWEEN $3 AND $4`,
+		studentID, courseID, from, to,
+	).Scan(&card.HomeworkTotal, &card.HomeworkDone, &card.HomeworkGrade)
+	if err != nil {
+		return card, err
+	}
+
+	rows, err := r.db.Query(c, `
+		SELECT l.feedback
+		FROM attendance a
+		JOIN attendance_lessons l ON l.attendance_id = a.id
+		WHERE a.student_id = $1 AND a.course_id = $2 AND l.date BETWEEN $3 AND $4
+		  AND NULLIF(TRIM(l.feedback), '') IS NOT NULL
+		ORDER BY l.date DESC
+		LIMIT 5`,
+		studentID, courseID, from, to,
+	)
+	if err != nil {
+		return card, err
+	}
+	card.Comments, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	return card, err
+}
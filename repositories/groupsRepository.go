@@ -0,0 +1,420 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"it_school/events"
+	"it_school/models"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GroupsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGroupsRepository(conn *pgxpool.Pool) *GroupsRepository {
+	return &GroupsRepository{db: conn}
+}
+
+// GroupLessonMark — статус конкретного студента при проведении группового урока
+type GroupLessonMark struct {
+	StudentId    uuid.UUID
+	LessonStatus string
+	Feedback     *string
+}
+
+// groupColumns — колонки models.Group; студенты из корзины в состав не попадают
+const groupColumns = `g.id, g.branch_id, g.course_id, g.curator_id, g.title, g.schedule, g.created_at, g.version,
+	COALESCE((SELECT array_agg(gm.student_id ORDER BY gm.joined_at)
+		FROM group_members gm
+		JOIN students s ON s.id = gm.student_id AND s.deleted_at IS NULL
+		WHERE gm.group_id = g.id), '{}')`
+
+func scanGroup(row pgx.Row) (models.Group, error) {
+	var group models.Group
+	var schedule []byte
+	err := row.Scan(
+		&group.Id,
+		&group.BranchId,
+		&group.CourseId,
+		&group.CuratorId,
+		&group.Title,
+		&schedule,
+		&group.CreatedAt,
+		&group.Version,
+		&group.StudentIds,
+	)
+	if err != nil {
+		return models.Group{}, err
+	}
+	if err := json.Unmarshal(schedule, &group.Schedule); err != nil {
+		return models.Group{}, err
+	}
+	return group, nil
+}
+
+// checkGroupCourse проверяет, что курс принадлежит филиалу branchID и не удален в корзину
+func checkGroupCourse(c context.Context, tx pgx.Tx, courseID, branchID uuid.UUID) error {
+	var ok bool
+	err := tx.QueryRow(c,
+		`SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1 AND branch_id = $2 AND deleted_at IS NULL)`,
+		courseID, branchID,
+	).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return notFound("course_not_found", "Course not found")
+	}
+	return nil
+}
+
+// checkGroupRefs проверяет курс и куратора группы (если указан) в филиале группы
+func checkGroupRefs(c context.Context, tx pgx.Tx, group models.Group, branchID uuid.UUID) error {
+	if err := checkGroupCourse(c, tx, group.CourseId, branchID); err != nil {
+		return err
+	}
+	if group.CuratorId != nil {
+		return checkGroupCurator(c, tx, *group.CuratorId, branchID)
+	}
+	return nil
+}
+
+// Create создает группу. Курс и куратор должны относиться к филиалу группы,
+// иначе — ошибка course_not_found или curator_not_found
+func (r *GroupsRepository) Create(c context.Context, group models.Group) (uuid.UUID, error) {
+	schedule, err := json.Marshal(group.Schedule)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	if err := checkGroupRefs(c, tx, group, group.BranchId); err != nil {
+		return uuid.Nil, err
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRow(c, `
+		INSERT INTO groups (branch_id, course_id, curator_id, title, schedule)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		group.BranchId, group.CourseId, group.CuratorId, group.Title, schedule,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// Update обновляет группу, если ее версия входит в versions (nil — любая версия). Курс и куратор
+// проверяются в филиале группы, как при создании. Возвращает новую версию; при устаревшей версии —
+// models.ErrVersionConflict
+func (r *GroupsRepository) Update(c context.Context, group models.Group, versions []int) (int, error) {
+	schedule, err := json.Marshal(group.Schedule)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var branchID uuid.UUID
+	var version int
+	err = tx.QueryRow(c,
+		`SELECT branch_id, version FROM groups WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) FOR UPDATE`,
+		group.Id, currentBranch(c),
+	).Scan(&branchID, &version)
+	if err != nil {
+		return 0, notFoundIfNoRows(err, "group_not_found", "Group not found")
+	}
+	if versions != nil && !slices.Contains(versions, version) {
+		return 0, models.ErrVersionConflict
+	}
+	if err := checkGroupRefs(c, tx, group, branchID); err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(c, `
+		UPDATE groups SET course_id = $1, curator_id = $2, title = $3, schedule = $4, updated_by = $6
+		WHERE id = $5
+		RETURNING version`,
+		group.CourseId, group.CuratorId, group.Title, schedule, group.Id, currentUser(c),
+	).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Delete удаляет группу вместе с ее уроками и записями посещаемости по ним. Записи attendance
+// удаляются явно: каскад group_lessons → attendance_lessons оставил бы их без урока
+func (r *GroupsRepository) Delete(c context.Context, groupID uuid.UUID) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var id uuid.UUID
+	err = tx.QueryRow(c,
+		`SELECT id FROM groups WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) FOR UPDATE`,
+		groupID, currentBranch(c),
+	).Scan(&id)
+	if err != nil {
+		return notFoundIfNoRows(err, "group_not_found", "Group not found")
+	}
+
+	_, err = tx.Exec(c, `
+		DELETE FROM attendance a
+		USING attendance_lessons l, group_lessons gl
+		WHERE l.attendance_id = a.id AND gl.id = l.group_lesson_id AND gl.group_id = $1`,
+		groupID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(c, `DELETE FROM groups WHERE id = $1`, groupID); err != nil {
+		return err
+	}
+	return tx.Commit(c)
+}
+
+func (r *GroupsRepository) FindById(c context.Context, groupID uuid.UUID) (models.Group, error) {
+	row := r.db.QueryRow(c, `SELECT `+groupColumns+`
+		FROM groups g
+		WHERE g.id = $1 AND ($2::uuid IS NULL OR g.branch_id = $2)`,
+		groupID, currentBranch(c),
+	)
+	group, err := scanGroup(row)
+	return group, notFoundIfNoRows(err, "group_not_found", "Group not found")
+}
+
+// FindAll возвращает группы текущего филиала; curatorID ограничивает выборку группами куратора
+func (r *GroupsRepository) FindAll(c context.Context, curatorID *uuid.UUID) ([]models.Group, error) {
+	rows, err := r.db.Query(c, `SELECT `+groupColumns+`
+		FROM groups g
+		WHERE ($1::uuid IS NULL OR g.branch_id = $1)
+		  AND ($2::uuid IS NULL OR g.curator_id = $2)
+		ORDER BY g.title`,
+		currentBranch(c), curatorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]models.Group, 0)
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// AddMember добавляет студента в группу. Студент должен быть активно зачислен на курс группы
+// и учиться в том же филиале.
+func (r *GroupsRepository) AddMember(c context.Context, groupID, studentID uuid.UUID) error {
+	tag, err := r.db.Exec(c, `
+		INSERT INTO group_members (group_id, student_id)
+		SELECT g.id, s.id
+		FROM groups g
+		JOIN students s ON s.id = $2 AND s.branch_id = g.branch_id AND s.deleted_at IS NULL
+		WHERE g.id = $1
+		  AND EXISTS (
+			SELECT 1 FROM enrollments e
+			WHERE e.student_id = s.id AND e.course_id = g.course_id AND e.status = 'active'
+		  )
+		ON CONFLICT DO NOTHING`,
+		groupID, studentID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		err := r.db.QueryRow(c,
+			`SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = $1 AND student_id = $2)`,
+			groupID, studentID,
+		).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return notFound("group_or_student_not_found", "Group or student not found")
+		}
+	}
+	return nil
+}
+
+func (r *GroupsRepository) RemoveMember(c context.Context, groupID, studentID uuid.UUID) error {
+	_, err := r.db.Exec(c,
+		`DELETE FROM group_members WHERE group_id = $1 AND student_id = $2`,
+		groupID, studentID,
+	)
+	return err
+}
+
+// checkGroupCurator проверяет, что curatorID — куратор филиала branchID и не удален в корзину
+func checkGroupCurator(c context.Context, tx pgx.Tx, curatorID, branchID uuid.UUID) error {
+	var ok bool
+	err := tx.QueryRow(c, `
+		SELECT EXISTS(
+			SELECT 1 FROM curators cu
+			JOIN users u ON u.id = cu.user_id AND u.deleted_at IS NULL
+			JOIN user_branches ub ON ub.user_id = u.id AND ub.branch_id = $2
+			WHERE cu.user_id = $1)`,
+		curatorID, branchID,
+	).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return notFound("curator_not_found", "Curator not found")
+	}
+	return nil
+}
+
+// CreateLesson проводит групповой урок: создает урок группы и для каждого участника
+// отдельную запись посещаемости типа «урок» со своим статусом. Участники без явной отметки
+// получают defaultStatus. Урок ведет куратор филиала группы, иначе — ошибка curator_not_found
+func (r *GroupsRepository) CreateLesson(c context.Context, group models.Group, lesson models.GroupLesson, defaultStatus string, marks []GroupLessonMark) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	if err := checkTopicInCourse(c, tx, lesson.TopicId, group.CourseId); err != nil {
+		return uuid.Nil, err
+	}
+	if err := checkGroupCurator(c, tx, lesson.CuratorId, group.BranchId); err != nil {
+		return uuid.Nil, err
+	}
+
+	lesson.Id = uuid.New()
+	_, err = tx.Exec(c, `
+		INSERT INTO group_lessons (id, group_id, curator_id, date, format, topic_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		lesson.Id, group.Id, lesson.CuratorId, lesson.Date, lesson.Format, lesson.TopicId,
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	byStudent := make(map[uuid.UUID]GroupLessonMark, len(marks))
+	for _, mark := range marks {
+		byStudent[mark.StudentId] = mark
+	}
+
+	for _, studentID := range group.StudentIds {
+		status := defaultStatus
+		var feedback *string
+		if mark, ok := byStudent[studentID]; ok {
+			if mark.LessonStatus != "" {
+				status = mark.LessonStatus
+			}
+			feedback = mark.Feedback
+		}
+
+		attendanceID := uuid.New()
+		_, err = tx.Exec(c, `
+			INSERT INTO attendance (id, student_id, course_id, type)
+			VALUES ($1, $2, $3, 'lesson')`,
+			attendanceID, studentID, group.CourseId,
+		)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		_, err = tx.Exec(c, `
+			INSERT INTO attendance_lessons (attendance_id, curator_id, date, format, feedback, lessons_status, group_lesson_id, topic_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			attendanceID, lesson.CuratorId, lesson.Date, lesson.Format, feedback, status, lesson.Id, lesson.TopicId,
+		)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		if status == models.LessonConducted {
+			err = events.Record(c, tx, events.AttendanceLessonConducted, events.LessonConductedData{
+				AttendanceId:  attendanceID,
+				StudentId:     studentID,
+				CourseId:      group.CourseId,
+				CuratorId:     lesson.CuratorId,
+				Date:          lesson.Date,
+				TopicId:       lesson.TopicId,
+				GroupLessonId: &lesson.Id,
+			})
+			if err != nil {
+				return uuid.Nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return lesson.Id, nil
+}
+
+// FindLessons возвращает групповые уроки с индивидуальными статусами студентов, новые первыми
+func (r *GroupsRepository) FindLessons(c context.Context, groupID uuid.UUID) ([]models.GroupLesson, error) {
+	rows, err := r.db.Query(c, `
+		SELECT gl.id, gl.group_id, gl.curator_id, gl.date, gl.format, gl.topic_id, gl.created_at,
+		       l.attendance_id, a.student_id, s.full_name, l.lessons_status, l.feedback
+		FROM group_lessons gl
+		JOIN attendance_lessons l ON l.group_lesson_id = gl.id
+		JOIN attendance a ON a.id = l.attendance_id
+		JOIN students s ON s.id = a.student_id
+		WHERE gl.group_id = $1 AND a.deleted_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY gl.date DESC, gl.created_at DESC, s.full_name`,
+		groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lessons := make([]models.GroupLesson, 0)
+	for rows.Next() {
+		var lesson models.GroupLesson
+		var mark models.GroupLessonAttendance
+		err := rows.Scan(
+			&lesson.Id, &lesson.GroupId, &lesson.CuratorId, &lesson.Date, &lesson.Format, &lesson.TopicId, &lesson.CreatedAt,
+			&mark.AttendanceId, &mark.StudentId, &mark.FullName, &mark.LessonStatus, &mark.Feedback,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if n := len(lessons); n > 0 && lessons[n-1].Id == lesson.Id {
+			lessons[n-1].Attendances = append(lessons[n-1].Attendances, mark)
+			continue
+		}
+		lesson.Attendances = []models.GroupLessonAttendance{mark}
+		lessons = append(lessons, lesson)
+	}
+	return lessons, rows.Err()
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HealthRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHealthRepository(conn *pgxpool.Pool) *HealthRepository {
+	return &HealthRepository{db: conn}
+}
+
+func (r *HealthRepository) Ping(c context.Context) error {
+	return r.db.Ping(c)
+}
+
+// AppliedMigrations возвращает версии миграций из schema_migrations
+func (r *HealthRepository) AppliedMigrations(c context.Context) (map[string]bool, error) {
+	rows, err := r.db.Query(c, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
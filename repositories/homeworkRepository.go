@@ -0,0 +1,408 @@
+package repositories
+
+import (
+	"context"
+	"it_school/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HomeworkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHomeworkRepository(conn *pgxpool.Pool) *HomeworkRepository {
+	return &HomeworkRepository{db: conn}
+}
+
+// Create выдает задание на уроке и создает по сдаче на каждого студента урока:
+// одну для индивидуального урока, по одной на участника для группового.
+func (r *HomeworkRepository) Create(c context.Context, homework models.Homework) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT a.student_id
+		FROM attendance_lessons l
+		JOIN attendance a ON a.id = l.attendance_id
+		JOIN students s ON s.id = a.student_id
+		WHERE (l.attendance_id = $1 OR l.group_lesson_id = $2)
+		  AND ($3::uuid IS NULL OR s.branch_id = $3)
+		  AND a.deleted_at IS NULL AND s.deleted_at IS NULL`,
+		homework.AttendanceId, homework.GroupLessonId, currentBranch(c),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	studentIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(studentIDs) == 0 {
+		return uuid.Nil, notFound("lesson_not_found", "Lesson not found")
+	}
+
+	homework.Id = uuid.New()
+	_, err = tx.Exec(c, `
+		INSERT INTO homework (id, attendance_id, group_lesson_id, description, due_date, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		homework.Id, homework.AttendanceId, homework.GroupLessonId, homework.Description, homework.DueDate, homework.CreatedBy,
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO homework_submissions (homework_id, student_id)
+		SELECT $1, unnest($2::uuid[])`,
+		homework.Id, studentIDs,
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return homework.Id, nil
+}
+
+const homeworkColumns = `h.id, h.attendance_id, h.group_lesson_id, h.description, h.due_date, h.created_by, h.created_at`
+
+const submissionColumns = `hs.id, hs.homework_id, hs.student_id, s.full_name, hs.status, hs.submitted_at,
+	hs.checked_at, hs.checked_by, hs.grade, hs.comment`
+
+func scanSubmission(row pgx.Row) (models.HomeworkSubmission, error) {
+	var submission models.HomeworkSubmission
+	err := row.Scan(
+		&submission.Id,
+		&submission.HomeworkId,
+		&submission.StudentId,
+		&submission.FullName,
+		&submission.Status,
+		&submission.SubmittedAt,
+		&submission.CheckedAt,
+		&submission.CheckedBy,
+		&submission.Grade,
+		&submission.Comment,
+	)
+	submission.Attachments = make([]models.HomeworkAttachment, 0)
+	return submission, err
+}
+
+// FindById возвращает задание со сдачами всех студентов и их вложениями
+func (r *HomeworkRepository) FindById(c context.Context, homeworkID uuid.UUID) (models.Homework, error) {
+	var homework models.Homework
+	err := r.db.QueryRow(c, `SELECT `+homeworkColumns+` FROM homework h WHERE h.id = $1`, homeworkID).Scan(
+		&homework.Id,
+		&homework.AttendanceId,
+		&homework.GroupLessonId,
+		&homework.Description,
+		&homework.DueDate,
+		&homework.CreatedBy,
+		&homework.CreatedAt,
+	)
+	if err != nil {
+		return models.Homework{}, notFoundIfNoRows(err, "homework_not_found", "Homework not found")
+	}
+
+	rows, err := r.db.Query(c, `SELECT `+submissionColumns+`
+		FROM homework_submissions hs
+		JOIN students s ON s.id = hs.student_id
+		WHERE hs.homework_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL
+		ORDER BY s.full_name`,
+		homeworkID, currentBranch(c),
+	)
+	if err != nil {
+		return models.Homework{}, err
+	}
+	defer rows.Close()
+
+	homework.Submissions = make([]models.HomeworkSubmission, 0)
+	for rows.Next() {
+		submission, err := scanSubmission(rows)
+		if err != nil {
+			return models.Homework{}, err
+		}
+		homework.Submissions = append(homework.Submissions, submission)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Homework{}, err
+	}
+	// Задание другого филиала выглядит как несуществующее
+	if len(homework.Submissions) == 0 {
+		return models.Homework{}, notFound("homework_not_found", "Homework not found")
+	}
+
+	if err := r.attachAttachments(c, homework.Submissions); err != nil {
+		return models.Homework{}, err
+	}
+	return homework, nil
+}
+
+// FindByStudent возвращает задания студента, у каждого — только сдача этого студента
+func (r *HomeworkRepository) FindByStudent(c context.Context, studentID uuid.UUID) ([]models.Homework, error) {
+	rows, err := r.db.Query(c, `SELECT `+homeworkColumns+`, `+submissionColumns+`
+		FROM homework_submissions hs
+		JOIN homework h ON h.id = hs.homework_id
+		JOIN students s ON s.id = hs.student_id
+		WHERE hs.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL
+		ORDER BY h.due_date DESC, h.created_at DESC`,
+		studentID, currentBranch(c),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	homeworks := make([]models.Homework, 0)
+	submissions := make([]models.HomeworkSubmission, 0)
+	for rows.Next() {
+		var homework models.Homework
+		var submission models.HomeworkSubmission
+		err := rows.Scan(
+			&homework.Id, &homework.AttendanceId, &homework.GroupLessonId, &homework.Description,
+			&homework.DueDate, &homework.CreatedBy, &homework.CreatedAt,
+			&submission.Id, &submission.HomeworkId, &submission.StudentId, &submission.FullName, &submission.Status,
+			&submission.SubmittedAt, &submission.CheckedAt, &submission.CheckedBy, &submission.Grade, &submission.Comment,
+		)
+		if err != nil {
+			return nil, err
+		}
+		submission.Attachments = make([]models.HomeworkAttachment, 0)
+		homeworks = append(homeworks, homework)
+		submissions = append(submissions, submission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachAttachments(c, submissions); err != nil {
+		return nil, err
+	}
+	for i := range homeworks {
+		homeworks[i].Submissions = []models.HomeworkSubmission{submissions[i]}
+	}
+	return homeworks, nil
+}
+
+func (r *HomeworkRepository) attachAttachments(c context.Context, submissions []models.HomeworkSubmission) error {
+	if len(submissions) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(submissions))
+	index := make(map[uuid.UUID]int, len(submissions))
+	for i, submission := range submissions {
+		ids[i] = submission.Id
+		index[submission.Id] = i
+	}
+
+	rows, err := r.db.Query(c, `
+		SELECT id, submission_id, file_name, content_type, size, storage_path, uploaded_by, uploaded_at
+		FROM homework_attachments
+		WHERE submission_id = ANY($1)
+		ORDER BY uploaded_at`,
+		ids,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return err
+		}
+		i := index[attachment.SubmissionId]
+		submissions[i].Attachments = append(submissions[i].Attachments, attachment)
+	}
+	return rows.Err()
+}
+
+func scanAttachment(row pgx.Row) (models.HomeworkAttachment, error) {
+	var attachment models.HomeworkAttachment
+	err := row.Scan(
+		&attachment.Id,
+		&attachment.SubmissionId,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StoragePath,
+		&attachment.UploadedBy,
+		&attachment.UploadedAt,
+	)
+	return attachment, err
+}
+
+// FindSubmission возвращает сдачу студента текущего филиала; сдачи студентов в корзине не находятся
+func (r *HomeworkRepository) FindSubmission(c context.Context, submissionID uuid.UUID) (models.HomeworkSubmission, error) {
+	row := r.db.QueryRow(c, `SELECT `+submissionColumns+`
+		FROM homework_submissions hs
+		JOIN students s ON s.id = hs.student_id
+		WHERE hs.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL`,
+		submissionID, currentBranch(c),
+	)
+	submission, err := scanSubmission(row)
+	if err != nil {
+		return models.HomeworkSubmission{}, notFoundIfNoRows(err, "submission_not_found", "Submission not found")
+	}
+	return submission, nil
+}
+
+// UpdateSubmission меняет статус, оценку и комментарий сдачи студента текущего филиала. Переход в «сдано»
+// фиксирует время сдачи, в «проверено» — время и автора проверки.
+func (r *HomeworkRepository) UpdateSubmission(c context.Context, submission models.HomeworkSubmission, userID uuid.UUID) error {
+	tag, err := r.db.Exec(c, `
+		UPDATE homework_submissions hs SET
+			status = $1,
+			grade = $2,
+			comment = $3,
+			submitted_at = CASE WHEN $1 = 'not_submitted' THEN NULL ELSE COALESCE(hs.submitted_at, now()) END,
+			checked_at = CASE WHEN $1 = 'checked' THEN COALESCE(hs.checked_at, now()) ELSE NULL END,
+			checked_by = CASE WHEN $1 = 'checked' THEN COALESCE(hs.checked_by, $4) ELSE NULL END,
+			updated_by = $4
+		FROM students s
+		WHERE hs.id = $5 AND s.id = hs.student_id AND ($6::uuid IS NULL OR s.branch_id = $6) AND s.deleted_at IS NULL`,
+		submission.Status, submission.Grade, submission.Comment, userID, submission.Id, currentBranch(c),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("submission_not_found", "Submission not found")
+	}
+	return nil
+}
+
+// AddAttachment сохраняет метаданные файла и отмечает задание сданным, если оно еще не было сдано
+func (r *HomeworkRepository) AddAttachment(c context.Context, attachment models.HomeworkAttachment) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	attachment.Id = uuid.New()
+	_, err = tx.Exec(c, `
+		INSERT INTO homework_attachments (id, submission_id, file_name, content_type, size, storage_path, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		attachment.Id, attachment.SubmissionId, attachment.FileName, attachment.ContentType,
+		attachment.Size, attachment.StoragePath, attachment.UploadedBy,
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE homework_submissions SET status = 'submitted', submitted_at = now(), updated_by = $2
+		WHERE id = $1 AND status = 'not_submitted'`,
+		attachment.SubmissionId, currentUser(c),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return attachment.Id, nil
+}
+
+func (r *HomeworkRepository) FindAttachment(c context.Context, attachmentID uuid.UUID) (models.HomeworkAttachment, error) {
+	row := r.db.QueryRow(c, `
+		SELECT ha.id, ha.submission_id, ha.file_name, ha.content_type, ha.size, ha.storage_path, ha.uploaded_by, ha.uploaded_at
+		FROM homework_attachments ha
+		JOIN homework_submissions hs ON hs.id = ha.submission_id
+		JOIN students s ON s.id = hs.student_id
+		WHERE ha.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL`,
+		attachmentID, currentBranch(c),
+	)
+	attachment, err := scanAttachment(row)
+	if err != nil {
+		return models.HomeworkAttachment{}, notFoundIfNoRows(err, "attachment_not_found", "Attachment not found")
+	}
+	return attachment, nil
+}
+
+// Delete удаляет задание и возвращает пути вложений, которые нужно убрать из хранилища
+func (r *HomeworkRepository) Delete(c context.Context, homeworkID uuid.UUID) ([]string, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT ha.storage_path
+		FROM homework_attachments ha
+		JOIN homework_submissions hs ON hs.id = ha.submission_id
+		WHERE hs.homework_id = $1`,
+		homeworkID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(c, `DELETE FROM homework WHERE id = $1`, homeworkID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Overdue возвращает несданные задания с истекшим сроком. curatorID ограничивает выборку
+// уроками, которые провел куратор.
+func (r *HomeworkRepository) Overdue(c context.Context, curatorID *uuid.UUID) ([]models.OverdueHomework, error) {
+	rows, err := r.db.Query(c, `
+		SELECT h.id, hs.id, s.id, s.full_name, COALESCE(l.curator_id, gl.curator_id), h.description, h.due_date,
+		       current_date - h.due_date
+		FROM homework_submissions hs
+		JOIN homework h ON h.id = hs.homework_id
+		JOIN students s ON s.id = hs.student_id
+		LEFT JOIN attendance_lessons l ON l.attendance_id = h.attendance_id
+		LEFT JOIN group_lessons gl ON gl.id = h.group_lesson_id
+		WHERE hs.status = 'not_submitted' AND h.due_date < current_date
+		  AND ($1::uuid IS NULL OR s.branch_id = $1) AND s.deleted_at IS NULL
+		  AND ($2::uuid IS NULL OR COALESCE(l.curator_id, gl.curator_id) = $2)
+		ORDER BY h.due_date, s.full_name`,
+		currentBranch(c), curatorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overdue := make([]models.OverdueHomework, 0)
+	for rows.Next() {
+		var item models.OverdueHomework
+		err := rows.Scan(
+			&item.HomeworkId,
+			&item.SubmissionId,
+			&item.StudentId,
+			&item.FullName,
+			&item.CuratorId,
+			&item.Description,
+			&item.DueDate,
+			&item.DaysOverdue,
+		)
+		if err != nil {
+			return nil, err
+		}
+		overdue = append(overdue, item)
+	}
+	return overdue, rows.Err()
+}
@@ -0,0 +1,501 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"it_school/events"
+	"it_school/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LeadsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLeadsRepository(conn *pgxpool.Pool) *LeadsRepository {
+	return &LeadsRepository{db: conn}
+}
+
+const leadColumns = `l.id, l.branch_id, l.full_name, l.phone_number, l.parent_name, l.parent_phone_number,
+	l.source, l.course_id, l.status, l.comment, l.student_id, l.created_at, l.updated_at, l.version`
+
+func scanLead(row pgx.Row) (models.Lead, error) {
+	var lead models.Lead
+	err := row.Scan(
+		&lead.Id,
+		&lead.BranchId,
+		&lead.FullName,
+		&lead.PhoneNumber,
+		&lead.ParentName,
+		&lead.ParentPhoneNumber,
+		&lead.Source,
+		&lead.CourseId,
+		&lead.Status,
+		&lead.Comment,
+		&lead.StudentId,
+		&lead.CreatedAt,
+		&lead.UpdatedAt,
+		&lead.Version,
+	)
+	return lead, err
+}
+
+const trialColumns = `t.id, t.lead_id, t.curator_id, t.date, t.status, t.feedback, t.created_at, t.version, l.full_name`
+
+func scanTrial(row pgx.Row) (models.TrialLesson, error) {
+	var trial models.TrialLesson
+	err := row.Scan(
+		&trial.Id,
+		&trial.LeadId,
+		&trial.CuratorId,
+		&trial.Date,
+		&trial.Status,
+		&trial.Feedback,
+		&trial.CreatedAt,
+		&trial.Version,
+		&trial.LeadName,
+	)
+	return trial, err
+}
+
+func (r *LeadsRepository) Create(c context.Context, lead models.Lead) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(c, `
+		INSERT INTO leads (branch_id, full_name, phone_number, parent_name, parent_phone_number, source, course_id, comment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		lead.BranchId, lead.FullName, lead.PhoneNumber, lead.ParentName, lead.ParentPhoneNumber,
+		lead.Source, lead.CourseId, lead.Comment,
+	).Scan(&id)
+	return id, err
+}
+
+// Update меняет данные и статус лида. Конвертированный лид не редактируется
+// Update меняет лида, если его версия входит в versions (nil — любая версия).
+// Возвращает новую версию; конвертированный лид — models.ErrLeadConverted,
+// устаревшая версия — models.ErrVersionConflict
+func (r *LeadsRepository) Update(c context.Context, lead models.Lead, versions []int) (int, error) {
+	var version int
+	err := r.db.QueryRow(c, `
+		WITH prev AS (
+			SELECT id, status FROM leads
+			WHERE id = $9 AND ($10::uuid IS NULL OR branch_id = $10)
+		)
+		UPDATE leads l
+		SET full_name = $1, phone_number = $2, parent_name = $3, parent_phone_number = $4,
+		    source = $5, course_id = $6, status = $7, comment = $8, updated_at = now(), updated_by = $11
+		FROM prev
+		WHERE l.id = prev.id AND prev.status <> 'converted' AND ($12::int[] IS NULL OR l.version = ANY($12))
+		RETURNING l.version`,
+		lead.FullName, lead.PhoneNumber, lead.ParentName, lead.ParentPhoneNumber,
+		lead.Source, lead.CourseId, lead.Status, lead.Comment, lead.Id, currentBranch(c), currentUser(c), versions,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Различаем отсутствующий, уже конвертированный и измененный другим запросом лид
+		current, findErr := r.FindById(c, lead.Id)
+		if findErr != nil {
+			return 0, findErr
+		}
+		if current.Status == models.LeadConverted {
+			return 0, models.ErrLeadConverted
+		}
+		return 0, models.ErrVersionConflict
+	}
+	return version, err
+}
+
+func (r *LeadsRepository) Delete(c context.Context, leadID uuid.UUID) error {
+	tag, err := r.db.Exec(c,
+		`DELETE FROM leads WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2)`,
+		leadID, currentBranch(c),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("lead_not_found", "Lead not found")
+	}
+	return nil
+}
+
+// FindById возвращает лида вместе с историей пробных уроков
+func (r *LeadsRepository) FindById(c context.Context, leadID uuid.UUID) (models.Lead, error) {
+	lead, err := scanLead(r.db.QueryRow(c, `SELECT `+leadColumns+`
+		FROM leads l
+		WHERE l.id = $1 AND ($2::uuid IS NULL OR l.branch_id = $2)`,
+		leadID, currentBranch(c),
+	))
+	if err != nil {
+		return models.Lead{}, notFoundIfNoRows(err, "lead_not_found", "Lead not found")
+	}
+
+	rows, err := r.db.Query(c, `SELECT `+trialColumns+`
+		FROM trial_lessons t
+		JOIN leads l ON l.id = t.lead_id
+		WHERE t.lead_id = $1
+		ORDER BY t.date`,
+		leadID,
+	)
+	if err != nil {
+		return models.Lead{}, err
+	}
+	defer rows.Close()
+
+	lead.Trials = make([]models.TrialLesson, 0)
+	for rows.Next() {
+		trial, err := scanTrial(rows)
+		if err != nil {
+			return models.Lead{}, err
+		}
+		lead.Trials = append(lead.Trials, trial)
+	}
+	return lead, rows.Err()
+}
+
+func (r *LeadsRepository) FindAll(c context.Context, filters models.LeadFilters) ([]models.Lead, error) {
+	query := `SELECT ` + leadColumns + ` FROM leads l WHERE ($1::uuid IS NULL OR l.branch_id = $1)`
+	args := []interface{}{currentBranch(c)}
+
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		query += fmt.Sprintf(" AND l.status = $%d", len(args))
+	}
+	if filters.Source != "" {
+		args = append(args, filters.Source)
+		query += fmt.Sprintf(" AND l.source = $%d", len(args))
+	}
+	if filters.CourseId != nil {
+		args = append(args, *filters.CourseId)
+		query += fmt.Sprintf(" AND l.course_id = $%d", len(args))
+	}
+	if filters.Search != "" {
+		args = append(args, "%"+strings.ToLower(filters.Search)+"%")
+		query += fmt.Sprintf(" AND (LOWER(l.full_name) LIKE $%[1]d OR LOWER(l.parent_name) LIKE $%[1]d)", len(args))
+	}
+	query += " ORDER BY l.created_at DESC"
+
+	rows, err := r.db.Query(c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leads := make([]models.Lead, 0)
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, lead)
+	}
+	return leads, rows.Err()
+}
+
+// ScheduleTrial назначает пробный урок и переводит лида в статус trial_scheduled
+func (r *LeadsRepository) ScheduleTrial(c context.Context, trial models.TrialLesson) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
+
+	var status string
+	err = tx.QueryRow(c, `
+		SELECT status FROM leads
+		WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2)
+		FOR UPDATE`,
+		trial.LeadId, currentBranch(c),
+	).Scan(&status)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if status == models.LeadConverted {
+		return uuid.Nil, models.ErrLeadConverted
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRow(c, `
+		INSERT INTO trial_lessons (lead_id, curator_id, date)
+		VALUES ($1, $2, $3)
+		RETURNING id`,
+		trial.LeadId, trial.CuratorId, trial.Date,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(c,
+		`UPDATE leads SET status = 'trial_scheduled', updated_at = now(), updated_by = $2 WHERE id = $1`,
+		trial.LeadId, currentUser(c),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// UpdateTrial фиксирует итог пробного урока. Проведенный урок переводит лида в статус trial_conducted.
+// curatorID ограничивает изменение уроками этого куратора
+func (r *LeadsRepository) UpdateTrial(c context.Context, trial models.TrialLesson, curatorID *uuid.UUID, versions []int) (int, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var leadID uuid.UUID
+	var version int
+	err = tx.QueryRow(c, `
+		UPDATE trial_lessons t
+		SET date = $1, status = $2, feedback = $3, updated_by = $7,
+		    reminded_at = CASE WHEN t.date = $1 THEN t.reminded_at END
+		FROM leads l
+		WHERE t.id = $4 AND l.id = t.lead_id
+		  AND ($5::uuid IS NULL OR l.branch_id = $5)
+		  AND ($6::uuid IS NULL OR t.curator_id = $6)
+		  AND ($8::int[] IS NULL OR t.version = ANY($8))
+		RETURNING t.lead_id, t.version`,
+		trial.Date, trial.Status, trial.Feedback, trial.Id, currentBranch(c), curatorID, currentUser(c), versions,
+	).Scan(&leadID, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, r.staleOrMissingTrial(c, trial.Id, curatorID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if trial.Status == models.LessonConducted {
+		_, err = tx.Exec(c, `
+			UPDATE leads SET status = 'trial_conducted', updated_at = now(), updated_by = $2
+			WHERE id = $1 AND status IN ('new', 'contacted', 'trial_scheduled')`,
+			leadID, currentUser(c),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// staleOrMissingTrial объясняет, почему UPDATE с проверкой версии не нашел пробный урок
+func (r *LeadsRepository) staleOrMissingTrial(c context.Context, trialID uuid.UUID, curatorID *uuid.UUID) error {
+	var exists bool
+	err := r.db.QueryRow(c, `
+		SELECT EXISTS(
+			SELECT 1 FROM trial_lessons t
+			JOIN leads l ON l.id = t.lead_id
+			WHERE t.id = $1 AND ($2::uuid IS NULL OR l.branch_id = $2) AND ($3::uuid IS NULL OR t.curator_id = $3))`,
+		trialID, currentBranch(c), curatorID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrVersionConflict
+	}
+	return pgx.ErrNoRows
+}
+
+// FindTrials возвращает пробные уроки за период; curatorID ограничивает выборку уроками куратора
+func (r *LeadsRepository) FindTrials(c context.Context, curatorID *uuid.UUID, from, to *time.Time) ([]models.TrialLesson, error) {
+	rows, err := r.db.Query(c, `SELECT `+trialColumns+`
+		FROM trial_lessons t
+		JOIN leads l ON l.id = t.lead_id
+		WHERE ($1::uuid IS NULL OR l.branch_id = $1)
+		  AND ($2::uuid IS NULL OR t.curator_id = $2)
+		  AND ($3::timestamptz IS NULL OR t.date >= $3)
+		  AND ($4::timestamptz IS NULL OR t.date < $4)
+		ORDER BY t.date`,
+		currentBranch(c), curatorID, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trials := make([]models.TrialLesson, 0)
+	for rows.Next() {
+		trial, err := scanTrial(rows)
+		if err != nil {
+			return nil, err
+		}
+		trials = append(trials, trial)
+	}
+	return trials, rows.Err()
+}
+
+// DueTrialReminders возвращает запланированные пробные уроки в ближайшие within,
+// о которых куратору еще не напомнили
+func (r *LeadsRepository) DueTrialReminders(c context.Context, within time.Duration) ([]models.TrialReminder, error) {
+	rows, err := r.db.Query(c, `
+		SELECT t.id, t.date, l.full_name, u.email, u.full_name
+		FROM trial_lessons t
+		JOIN leads l ON l.id = t.lead_id
+		JOIN users u ON u.id = t.curator_id
+		WHERE t.status = 'scheduled' AND t.reminded_at IS NULL
+		  AND t.date > now() AND t.date <= now() + $1::interval
+		ORDER BY t.date`,
+		within,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []models.TrialReminder
+	for rows.Next() {
+		var reminder models.TrialReminder
+		err := rows.Scan(&reminder.TrialId, &reminder.Date, &reminder.LeadName, &reminder.CuratorEmail, &reminder.CuratorName)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+// MarkTrialReminded отмечает, что напоминание о пробном уроке отправлено
+func (r *LeadsRepository) MarkTrialReminded(c context.Context, trialID uuid.UUID) error {
+	_, err := r.db.Exec(c, `UPDATE trial_lessons SET reminded_at = now() WHERE id = $1`, trialID)
+	return err
+}
+
+// Convert переводит лида в студенты: создает студента, зачисление на курс и первую пролонгацию
+// в одной транзакции, после чего отмечает лида как конвертированного
+func (r *LeadsRepository) Convert(c context.Context, leadID uuid.UUID, conversion models.LeadConversion) (models.LeadConversionResult, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	defer tx.Rollback(c)
+
+	lead, err := scanLead(tx.QueryRow(c, `SELECT `+leadColumns+`
+		FROM leads l
+		WHERE l.id = $1 AND ($2::uuid IS NULL OR l.branch_id = $2)
+		FOR UPDATE`,
+		leadID, currentBranch(c),
+	))
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	if lead.Status == models.LeadConverted {
+		return models.LeadConversionResult{}, models.ErrLeadConverted
+	}
+
+	courseID := lead.CourseId
+	if conversion.CourseId != nil {
+		courseID = conversion.CourseId
+	}
+	if courseID == nil {
+		return models.LeadConversionResult{}, models.ErrLeadCourseRequired
+	}
+
+	createdAt := conversion.PaymentDate
+	student := models.Student{
+		BranchId:          lead.BranchId,
+		FullName:          lead.FullName,
+		PhoneNumber:       &conversion.PhoneNumber,
+		ParentName:        lead.ParentName,
+		ParentPhoneNumber: &lead.ParentPhoneNumber,
+		CuratorId:         conversion.CuratorId,
+		PlatformLink:      conversion.PlatformLink,
+		CreatedAt:         &createdAt,
+		LifecycleState:    models.LifecycleActive,
+	}
+
+	var result models.LeadConversionResult
+	result.StudentId, err = insertStudent(c, tx, student)
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	result.EnrollmentId, err = insertEnrollment(c, tx, models.Enrollment{
+		StudentId: result.StudentId,
+		CourseId:  *courseID,
+		CuratorId: conversion.CuratorId,
+		StartDate: conversion.PaymentDate,
+	})
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	// Первая оплата оформляется пролонгацией, как и последующие
+	err = tx.QueryRow(c, `
+		INSERT INTO attendance (student_id, course_id, type)
+		VALUES ($1, $2, 'prolongation')
+		RETURNING id`,
+		result.StudentId, *courseID,
+	).Scan(&result.AttendanceId)
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO attendance_prolongations (attendance_id, payment_type, date, amount, comment)
+		VALUES ($1, $2, $3, $4, $5)`,
+		result.AttendanceId, conversion.PaymentType, conversion.PaymentDate, conversion.Amount, conversion.Comment,
+	)
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE leads SET status = 'converted', student_id = $1, course_id = $2, updated_at = now(), updated_by = $4
+		WHERE id = $3`,
+		result.StudentId, courseID, leadID, currentUser(c),
+	)
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	student.Enrollments = []models.Enrollment{{CourseId: *courseID}}
+	if err := events.Record(c, tx, events.StudentCreated, studentCreatedData(result.StudentId, student)); err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	err = events.Record(c, tx, events.ProlongationCreated, events.ProlongationCreatedData{
+		AttendanceId: result.AttendanceId,
+		StudentId:    result.StudentId,
+		CourseId:     *courseID,
+		PaymentType:  conversion.PaymentType,
+		Date:         conversion.PaymentDate,
+		Amount:       conversion.Amount,
+		Comment:      conversion.Comment,
+	})
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	if err := recordCrmStudent(c, tx, events.CrmStudentCreated, result.StudentId); err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	err = events.Record(c, tx, events.CrmPaymentCreated, models.CrmPayment{
+		AttendanceId: result.AttendanceId,
+		StudentId:    result.StudentId,
+		CourseId:     *courseID,
+		PaymentType:  conversion.PaymentType,
+		Date:         conversion.PaymentDate,
+		Amount:       conversion.Amount,
+		Comment:      conversion.Comment,
+	})
+	if err != nil {
+		return models.LeadConversionResult{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return models.LeadConversionResult{}, err
+	}
+	return result, nil
+}
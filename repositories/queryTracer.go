@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"it_school/logger"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// QueryTracer логирует ошибки SQL-запросов логгером запроса (request_id, user_id), если он есть
+// в контексте. Аргументы запроса не пишутся: в них бывают пароли и персональные данные
+type QueryTracer struct{}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	sql     string
+	started time.Time
+}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{sql: data.SQL, started: time.Now()})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if data.Err == nil || errors.Is(data.Err, pgx.ErrNoRows) {
+		return
+	}
+	trace, _ := ctx.Value(queryTraceKey{}).(queryTrace)
+
+	fields := []zap.Field{
+		zap.String("sql", strings.Join(strings.Fields(trace.sql), " ")),
+		zap.Duration("took", time.Since(trace.started)),
+		zap.Error(data.Err),
+	}
+
+	logger := logger.FromContext(ctx)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(data.Err, context.Canceled):
+		logger.Debug("Query canceled", fields...)
+	case errors.As(data.Err, &pgErr) && strings.HasPrefix(pgErr.Code, "23"):
+		// Нарушения ограничений (уникальность, внешние ключи) обрабатываются хендлерами как 4xx
+		logger.Info("Query violated constraint", append(fields, zap.String("constraint", pgErr.ConstraintName))...)
+	default:
+		logger.Error("Query failed", fields...)
+	}
+}
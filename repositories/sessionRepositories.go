@@ -25,21 +25,20 @@ func (r *SessionsRepository) CreateSession(c context.Context, session models.Ses
 }
 
 func (r *SessionsRepository) GetSession(c context.Context, refreshToken string) (models.Session, uuid.UUID, error) {
-    var session models.Session
-    var roleID uuid.UUID
+	var session models.Session
+	var roleID uuid.UUID
 
-    err := r.db.QueryRow(c,
-        `SELECT s.id, s.user_id, s.refresh_token, s.expires_at, u.role_id 
+	err := r.db.QueryRow(c,
+		`SELECT s.id, s.user_id, s.refresh_token, s.expires_at, u.role_id 
          FROM sessions s
          JOIN users u ON s.user_id = u.id
-         WHERE s.refresh_token = $1 AND s.expires_at > NOW()`,
-        refreshToken).
-        Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.ExpiresAt, &roleID)
+         WHERE s.refresh_token = $1 AND s.expires_at > NOW() AND u.deleted_at IS NULL`,
+		refreshToken).
+		Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.ExpiresAt, &roleID)
 
-    return session, roleID, err
+	return session, roleID, err
 }
 
-
 func (r *SessionsRepository) UpdateSession(c context.Context, session models.Session) error {
 	_, err := r.db.Exec(c,
 		`UPDATE sessions 
@@ -55,4 +54,13 @@ func (r *SessionsRepository) DeleteSession(c context.Context, refreshToken strin
 		 WHERE refresh_token = $1`,
 		refreshToken)
 	return err
-}
\ No newline at end of file
+}
+
+// DeleteExpired удаляет истекшие сессии и возвращает их количество
+func (r *SessionsRepository) DeleteExpired(c context.Context) (int64, error) {
+	tag, err := r.db.Exec(c, `DELETE FROM sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"it_school/events"
 	"it_school/models"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -18,132 +21,260 @@ func NewStudentsRepository(conn *pgxpool.Pool) *StudentsRepository {
 }
 
 func (r *StudentsRepository) Create(c context.Context, student models.Student) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(c)
+
+	id, err := insertStudent(c, tx, student)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := events.Record(c, tx, events.StudentCreated, studentCreatedData(id, student)); err != nil {
+		return uuid.UUID{}, err
+	}
+	if err := recordCrmStudent(c, tx, events.CrmStudentCreated, id); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return id, nil
+}
+
+func studentCreatedData(id uuid.UUID, student models.Student) events.StudentCreatedData {
+	data := events.StudentCreatedData{
+		StudentId: id,
+		BranchId:  student.BranchId,
+		FullName:  student.FullName,
+		CuratorId: student.CuratorId,
+		CourseIds: make([]uuid.UUID, 0, len(student.Enrollments)),
+	}
+	for _, enrollment := range student.Enrollments {
+		data.CourseIds = append(data.CourseIds, enrollment.CourseId)
+	}
+	return data
+}
+
+// insertStudent создает студента вместе с куратором и первичными зачислениями в рамках транзакции.
+// Используется также при конвертации лида. Без LifecycleState начальное состояние берется из IsActive
+func insertStudent(c context.Context, tx pgx.Tx, student models.Student) (uuid.UUID, error) {
 	student.Id = uuid.New()
 
-	row := r.db.QueryRow(c, `INSERT INTO students(id, course_id, full_name, phone_number, parent_name, parent_phone_number, curator_id, platform_link, crm_link, created_at, is_active) 
+	if student.LifecycleState == "" {
+		student.LifecycleState = models.LifecycleActive
+		if student.IsActive != nil {
+			student.LifecycleState = models.LifecycleForIsActive(*student.IsActive)
+		}
+	}
+
+	row := tx.QueryRow(c, `INSERT INTO students(id, branch_id, full_name, phone_number, parent_name, parent_phone_number, platform_link, crm_link, created_at, lifecycle_state, crm_external_id) 
     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
     RETURNING id`,
 		student.Id,
-		student.CourseId,
+		student.BranchId,
 		student.FullName,
 		student.PhoneNumber,
 		student.ParentName,
 		student.ParentPhoneNumber,
-		student.CuratorId,
 		student.PlatformLink,
 		student.CrmLink,
 		student.CreatedAt,
-		student.IsActive,
+		student.LifecycleState,
+		student.CrmExternalId,
 	)
 
-	err := row.Scan(&student.Id)
+	if err := row.Scan(&student.Id); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	_, err := tx.Exec(c, `
+		INSERT INTO student_lifecycle_transitions (student_id, to_state, source, changed_by)
+		VALUES ($1, $2, $3, $4)`,
+		student.Id, student.LifecycleState, models.LifecycleSourceCreated, currentUser(c))
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
+	if student.CuratorId != nil {
+		_, err := tx.Exec(c,
+			`INSERT INTO curator_students (curator_id, student_id) VALUES ($1, $2)`,
+			*student.CuratorId, student.Id,
+		)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+	}
+
+	// Первичные зачисления создаются вместе со студентом
+	for _, enrollment := range student.Enrollments {
+		enrollment.StudentId = student.Id
+		if _, err := insertEnrollment(c, tx, enrollment); err != nil {
+			return uuid.UUID{}, err
+		}
+	}
+
 	return student.Id, nil
 }
 
 func (r *StudentsRepository) FindAll(c context.Context, filters models.StudentFilters) ([]models.Student, error) {
-    sql := `SELECT 
+	sql := `SELECT 
         s.id,
-        s.course_id, 
+        s.branch_id,
         s.full_name, 
         s.phone_number, 
         s.parent_name, 
         s.parent_phone_number, 
-        s.curator_id, 
+        cs.curator_id, 
         s.platform_link, 
         s.crm_link, 
         s.created_at,
-        s.is_active
+        s.is_active,
+        s.lifecycle_state,
+        s.lifecycle_changed_at,
+        s.crm_external_id,
+        s.version,
+        s.updated_at,
+        s.updated_by
     FROM students s
-    WHERE 1=1`
-    
-    params := pgx.NamedArgs{}
-
-    if filters.Search != "" {
-        sql += " AND s.full_name ILIKE @search"
-        params["search"] = "%" + filters.Search + "%"
-    }
-
-    if filters.Course != "" {
-        courseUUID, err := uuid.Parse(filters.Course)
-        if err != nil {
-            return nil, err
-        }
-        sql += " AND s.course_id = @course"
-        params["course"] = courseUUID
-    }
-
-    if filters.IsActive != "" {
-        sql += " AND s.is_active = @is_active"
-        params["is_active"] = filters.IsActive
-    }
-
-    if filters.CuratorId != "" {
-        curatorUUID, err := uuid.Parse(filters.CuratorId)
-        if err != nil {
-            return nil, err
-        }
-        sql += " AND s.curator_id = @curator_id"
-        params["curator_id"] = curatorUUID
-    }
-
-    rows, err := r.db.Query(c, sql, params)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    students := make([]models.Student, 0)
-
-    for rows.Next() {
-        var student models.Student
-        err := rows.Scan(
-            &student.Id,
-            &student.CourseId,
-            &student.FullName,
-            &student.PhoneNumber,
-            &student.ParentName,
-            &student.ParentPhoneNumber,
-            &student.CuratorId,
-            &student.PlatformLink,
-            &student.CrmLink,
-            &student.CreatedAt,
-            &student.IsActive,
-        )
-        if err != nil {
-            return nil, err
-        }
-        students = append(students, student)
-    }
-
-    return students, nil
-}
+    LEFT JOIN curator_students cs ON cs.student_id = s.id AND cs.unassigned_at IS NULL
+    WHERE s.deleted_at IS NULL`
+
+	params := pgx.NamedArgs{}
+
+	if branchID := currentBranch(c); branchID != nil {
+		sql += " AND s.branch_id = @branch_id"
+		params["branch_id"] = *branchID
+	}
+
+	if filters.Search != "" {
+		sql += " AND s.full_name ILIKE @search"
+		params["search"] = "%" + filters.Search + "%"
+	}
+
+	// Фильтры по курсу и статусу зачисления применяются к одному и тому же зачислению
+	if filters.Course != "" || filters.EnrollmentStatus != "" {
+		sql += " AND EXISTS (SELECT 1 FROM enrollments e WHERE e.student_id = s.id"
+		if filters.Course != "" {
+			courseUUID, err := uuid.Parse(filters.Course)
+			if err != nil {
+				return nil, err
+			}
+			sql += " AND e.course_id = @course"
+			params["course"] = courseUUID
+		}
+		if filters.EnrollmentStatus != "" {
+			sql += " AND e.status = @enrollment_status"
+			params["enrollment_status"] = filters.EnrollmentStatus
+		}
+		sql += ")"
+	}
+
+	if filters.IsActive != "" {
+		sql += " AND s.is_active = @is_active"
+		params["is_active"] = filters.IsActive
+	}
+
+	if len(filters.LifecycleStates) > 0 {
+		sql += " AND s.lifecycle_state = ANY((@lifecycle_states::text[])::student_lifecycle_state[])"
+		params["lifecycle_states"] = filters.LifecycleStates
+	}
+
+	if filters.CuratorId != "" {
+		curatorUUID, err := uuid.Parse(filters.CuratorId)
+		if err != nil {
+			return nil, err
+		}
+		sql += ` AND (cs.curator_id = @curator_id OR EXISTS (
+            SELECT 1 FROM enrollments e WHERE e.student_id = s.id AND e.curator_id = @curator_id))`
+		params["curator_id"] = curatorUUID
+	}
+
+	rows, err := r.db.Query(c, sql, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := make([]models.Student, 0)
+
+	for rows.Next() {
+		var student models.Student
+		err := rows.Scan(
+			&student.Id,
+			&student.BranchId,
+			&student.FullName,
+			&student.PhoneNumber,
+			&student.ParentName,
+			&student.ParentPhoneNumber,
+			&student.CuratorId,
+			&student.PlatformLink,
+			&student.CrmLink,
+			&student.CreatedAt,
+			&student.IsActive,
+			&student.LifecycleState,
+			&student.LifecycleChangedAt,
+			&student.CrmExternalId,
+			&student.Version,
+			&student.UpdatedAt,
+			&student.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(students))
+	for i := range students {
+		ids[i] = students[i].Id
+	}
+	enrollments, err := findEnrollmentsByStudents(c, r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range students {
+		students[i].Enrollments = enrollments[students[i].Id]
+	}
 
+	return students, nil
+}
 
 func (r *StudentsRepository) FindById(c context.Context, studentId uuid.UUID) (models.Student, error) {
 	sql := `SELECT 
 			s.id, 
-			s.course_id,
+			s.branch_id,
 			s.full_name, 
 			s.phone_number, 
 			s.parent_name, 
 			s.parent_phone_number, 
-       		s.curator_id, 
+       		cs.curator_id, 
 			s.platform_link, 
 			s.crm_link, 
 			s.created_at,
-			s.is_active 
+			s.is_active,
+			s.lifecycle_state,
+			s.lifecycle_changed_at,
+			s.crm_external_id,
+			s.version,
+			s.updated_at,
+			s.updated_by
 			FROM students s
-			WHERE s.id = $1`
+			LEFT JOIN curator_students cs ON cs.student_id = s.id AND cs.unassigned_at IS NULL
+			WHERE s.id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL`
 
 	var student models.Student
-	row := r.db.QueryRow(c, sql, studentId)
+	row := r.db.QueryRow(c, sql, studentId, currentBranch(c))
 	err := row.Scan(
-		&studentId,
-		&student.CourseId,
+		&student.Id,
+		&student.BranchId,
 		&student.FullName,
 		&student.PhoneNumber,
 		&student.ParentName,
@@ -153,71 +284,390 @@ func (r *StudentsRepository) FindById(c context.Context, studentId uuid.UUID) (m
 		&student.CrmLink,
 		&student.CreatedAt,
 		&student.IsActive,
+		&student.LifecycleState,
+		&student.LifecycleChangedAt,
+		&student.CrmExternalId,
+		&student.Version,
+		&student.UpdatedAt,
+		&student.UpdatedBy,
 	)
+	if err != nil {
+		return models.Student{}, notFoundIfNoRows(err, "student_not_found", "Student not found")
+	}
+
+	enrollments, err := findEnrollmentsByStudents(c, r.db, []uuid.UUID{student.Id})
 	if err != nil {
 		return models.Student{}, err
 	}
+	student.Enrollments = enrollments[student.Id]
+
 	return student, nil
 }
 
-func (r *StudentsRepository) Update(c context.Context, student models.Student) error {
-    tx, err := r.db.Begin(c)
-    if err != nil {
-        return err
-    }
-    defer func() {
-        if err != nil {
-            tx.Rollback(c)
-        }
-    }()
-
-    _, err = tx.Exec(c, `
+// Update перезаписывает карточку студента, если ее версия входит в versions (nil — любая версия).
+// Состояние жизненного цикла меняется только через ChangeLifecycle.
+// Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *StudentsRepository) Update(c context.Context, student models.Student, versions []int) (int, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var version int
+	err = tx.QueryRow(c, `
     UPDATE students SET
-        course_id = $1,
-        full_name = $2,
-        phone_number = $3,
-        parent_name = $4,
-        parent_phone_number = $5,
-        curator_id = $6,
-        platform_link = $7,
-        crm_link = $8,
-        created_at = $9,
-        is_active = $10
-    WHERE id = $11`,
-        student.CourseId,
-        student.FullName,
-        student.PhoneNumber,
-        student.ParentName,
-        student.ParentPhoneNumber,
-        student.CuratorId,
-        student.PlatformLink,
-        student.CrmLink,
-        student.CreatedAt,
-        student.IsActive,
-        student.Id)
-
-    if err != nil {
-        return err
-    }
-
-    return tx.Commit(c)
+        full_name = $1,
+        phone_number = $2,
+        parent_name = $3,
+        parent_phone_number = $4,
+        platform_link = $5,
+        crm_link = $6,
+        created_at = $7,
+        updated_by = $10
+    WHERE id = $8 AND ($9::uuid IS NULL OR branch_id = $9) AND deleted_at IS NULL
+        AND ($11::int[] IS NULL OR version = ANY($11))
+    RETURNING version`,
+		student.FullName,
+		student.PhoneNumber,
+		student.ParentName,
+		student.ParentPhoneNumber,
+		student.PlatformLink,
+		student.CrmLink,
+		student.CreatedAt,
+		student.Id,
+		currentBranch(c),
+		currentUser(c),
+		versions).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, r.staleOrNotFound(c, student.Id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// В CRM уходит полная карточка, включая поля, которые не меняются этим запросом
+	if err := recordCrmStudent(c, tx, events.CrmStudentUpdated, student.Id); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// staleOrNotFound объясняет, почему UPDATE с проверкой версии не нашел строку
+func (r *StudentsRepository) staleOrNotFound(c context.Context, studentId uuid.UUID) error {
+	var exists bool
+	err := r.db.QueryRow(c,
+		`SELECT EXISTS(SELECT 1 FROM students WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL)`,
+		studentId, currentBranch(c)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrVersionConflict
+	}
+	return notFound("student_not_found", "Student not found")
+}
+
+// UpsertByExternalId обновляет студента по идентификатору во внешней CRM или создает нового.
+// is_active из CRM переводит существующего студента в active или churned, если такой переход разрешен.
+// Студента из корзины CRM не меняет: models.ErrCrmStudentInTrash, пока его не восстановят.
+// Возвращает ID студента и признак создания. Для создания нужен филиал
+func (r *StudentsRepository) UpsertByExternalId(c context.Context, student models.Student) (uuid.UUID, bool, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	defer tx.Rollback(c)
+
+	var id uuid.UUID
+	var state string
+	err = tx.QueryRow(c, `
+		UPDATE students SET
+			full_name = $1,
+			phone_number = $2,
+			parent_name = $3,
+			parent_phone_number = $4,
+			platform_link = COALESCE(NULLIF($5, ''), platform_link),
+			crm_link = COALESCE(NULLIF($6, ''), crm_link),
+			updated_by = $8
+		WHERE crm_external_id = $7 AND deleted_at IS NULL
+		RETURNING id, lifecycle_state`,
+		student.FullName,
+		student.PhoneNumber,
+		student.ParentName,
+		student.ParentPhoneNumber,
+		student.PlatformLink,
+		student.CrmLink,
+		student.CrmExternalId,
+		currentUser(c),
+	).Scan(&id, &state)
+	created := false
+	switch {
+	case err == nil:
+		if student.IsActive != nil && models.LifecycleIsActive(state) != *student.IsActive {
+			to := models.LifecycleForIsActive(*student.IsActive)
+			if models.CanChangeLifecycle(state, to) {
+				if _, _, err := changeLifecycle(c, tx, id, state, to, models.LifecycleSourceCrm, nil); err != nil {
+					return uuid.Nil, false, err
+				}
+			}
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		var trashed bool
+		err := tx.QueryRow(c,
+			`SELECT EXISTS(SELECT 1 FROM students WHERE crm_external_id = $1 AND deleted_at IS NOT NULL)`,
+			student.CrmExternalId).Scan(&trashed)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if trashed {
+			return uuid.Nil, false, models.ErrCrmStudentInTrash
+		}
+		if student.BranchId == uuid.Nil {
+			return uuid.Nil, false, models.ErrCrmBranchRequired
+		}
+		if id, err = insertStudent(c, tx, student); err != nil {
+			return uuid.Nil, false, err
+		}
+		if err := events.Record(c, tx, events.StudentCreated, studentCreatedData(id, student)); err != nil {
+			return uuid.Nil, false, err
+		}
+		created = true
+	default:
+		return uuid.Nil, false, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, false, err
+	}
+	return id, created, nil
 }
 
+// Delete переносит студента в корзину. Посещаемость, оплаты и прочая история остаются в базе
+// и скрываются вместе с ним до восстановления или окончательного удаления
 func (r *StudentsRepository) Delete(c context.Context, studentId uuid.UUID) error {
 	tx, err := r.db.Begin(c)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(c)
 
-	_, err = tx.Exec(c, "DELETE FROM students WHERE id = $1", studentId)
+	tag, err := tx.Exec(c, `
+		UPDATE students SET deleted_at = now(), deleted_by = $3
+		WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL`,
+		studentId, currentBranch(c), currentUser(c))
 	if err != nil {
-		tx.Rollback(c)
 		return err
 	}
+	if tag.RowsAffected() > 0 {
+		if err := recordCrmStudent(c, tx, events.CrmStudentDeleted, studentId); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(c)
+}
 
-	err = tx.Commit(c)
+// ChangeLifecycle переводит студента в состояние to, если переход разрешен (models.CanChangeLifecycle)
+// и версия карточки входит в versions (nil — любая версия). Возвращает запись истории и новую версию карточки
+func (r *StudentsRepository) ChangeLifecycle(c context.Context, studentId uuid.UUID, to string, comment *string, versions []int) (models.StudentLifecycleTransition, int, error) {
+	tx, err := r.db.Begin(c)
 	if err != nil {
-		return err
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+	defer tx.Rollback(c)
+
+	var from string
+	var version int
+	err = tx.QueryRow(c, `
+		SELECT lifecycle_state, version FROM students
+		WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) AND deleted_at IS NULL
+		FOR UPDATE`,
+		studentId, currentBranch(c)).Scan(&from, &version)
+	if err != nil {
+		return models.StudentLifecycleTransition{}, 0, notFoundIfNoRows(err, "student_not_found", "Student not found")
+	}
+	if versions != nil && !slices.Contains(versions, version) {
+		return models.StudentLifecycleTransition{}, 0, models.ErrVersionConflict
+	}
+	if !models.CanChangeLifecycle(from, to) {
+		return models.StudentLifecycleTransition{}, 0, models.ErrLifecycleTransition
+	}
+
+	transition, version, err := changeLifecycle(c, tx, studentId, from, to, models.LifecycleSourceManual, comment)
+	if err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+	// is_active в CRM вычисляется из состояния
+	if err := recordCrmStudent(c, tx, events.CrmStudentUpdated, studentId); err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+	if err := tx.Commit(c); err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+	return transition, version, nil
+}
+
+// changeLifecycle меняет состояние студента в рамках транзакции, записывает переход в историю
+// и событие student.lifecycle_changed. Допустимость перехода проверяет вызывающий
+func changeLifecycle(c context.Context, tx pgx.Tx, studentId uuid.UUID, from, to, source string, comment *string) (models.StudentLifecycleTransition, int, error) {
+	transition := models.StudentLifecycleTransition{
+		StudentId: studentId,
+		FromState: &from,
+		ToState:   to,
+		Source:    source,
+		Comment:   comment,
+		ChangedBy: currentUser(c),
+	}
+
+	var version int
+	err := tx.QueryRow(c, `
+		UPDATE students SET lifecycle_state = $2, lifecycle_changed_at = now(), updated_by = $3
+		WHERE id = $1
+		RETURNING version, lifecycle_changed_at`,
+		studentId, to, transition.ChangedBy).Scan(&version, &transition.ChangedAt)
+	if err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+
+	err = tx.QueryRow(c, `
+		INSERT INTO student_lifecycle_transitions (student_id, from_state, to_state, source, comment, changed_at, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		studentId, from, to, source, comment, transition.ChangedAt, transition.ChangedBy).Scan(&transition.Id)
+	if err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+
+	err = events.Record(c, tx, events.StudentLifecycleChanged, events.StudentLifecycleChangedData{
+		StudentId: studentId,
+		FromState: from,
+		ToState:   to,
+		Source:    source,
+		ChangedAt: transition.ChangedAt,
+		ChangedBy: transition.ChangedBy,
+	})
+	if err != nil {
+		return models.StudentLifecycleTransition{}, 0, err
+	}
+	return transition, version, nil
+}
+
+// LifecycleHistory возвращает переходы жизненного цикла студента, последние первыми
+func (r *StudentsRepository) LifecycleHistory(c context.Context, studentId uuid.UUID) ([]models.StudentLifecycleTransition, error) {
+	rows, err := r.db.Query(c, `
+		SELECT t.id, t.student_id, t.from_state, t.to_state, t.source, t.comment, t.changed_at, t.changed_by
+		FROM student_lifecycle_transitions t
+		JOIN students s ON s.id = t.student_id
+		WHERE t.student_id = $1 AND ($2::uuid IS NULL OR s.branch_id = $2) AND s.deleted_at IS NULL
+		ORDER BY t.changed_at DESC`,
+		studentId, currentBranch(c))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]models.StudentLifecycleTransition, 0)
+	for rows.Next() {
+		var transition models.StudentLifecycleTransition
+		err := rows.Scan(
+			&transition.Id,
+			&transition.StudentId,
+			&transition.FromState,
+			&transition.ToState,
+			&transition.Source,
+			&transition.Comment,
+			&transition.ChangedAt,
+			&transition.ChangedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, transition)
+	}
+	return history, rows.Err()
+}
+
+// SyncFreezes приводит состояние студентов к окнам заморозки (attendance_freezes) на сегодня:
+// active с действующей заморозкой становится frozen, а замороженный так автоматически —
+// снова active, когда заморозка закончилась или ее удалили. Ручной переход после начала
+// заморозки имеет приоритет: такого студента задание не замораживает повторно.
+// Возвращает число замороженных и размороженных студентов
+func (r *StudentsRepository) SyncFreezes(c context.Context) (int, int, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT s.id, s.lifecycle_state
+		FROM students s
+		WHERE s.deleted_at IS NULL AND (
+			(s.lifecycle_state = 'active' AND EXISTS (
+				SELECT 1
+				FROM attendance a
+				JOIN attendance_freezes f ON f.attendance_id = a.id
+				WHERE a.student_id = s.id AND a.deleted_at IS NULL
+				  AND current_date BETWEEN f.start_date AND f.end_date
+				  AND NOT EXISTS (
+					SELECT 1 FROM student_lifecycle_transitions t
+					WHERE t.student_id = s.id AND t.source = $1 AND t.changed_at >= f.start_date)))
+			OR (s.lifecycle_state = 'frozen' AND NOT EXISTS (
+				SELECT 1
+				FROM attendance a
+				JOIN attendance_freezes f ON f.attendance_id = a.id
+				WHERE a.student_id = s.id AND a.deleted_at IS NULL
+				  AND current_date BETWEEN f.start_date AND f.end_date)
+			AND (
+				SELECT t.source FROM student_lifecycle_transitions t
+				WHERE t.student_id = s.id
+				ORDER BY t.changed_at DESC
+				LIMIT 1) = $2))
+		FOR UPDATE OF s SKIP LOCKED`,
+		models.LifecycleSourceManual, models.LifecycleSourceFreeze)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	type candidate struct {
+		id    uuid.UUID
+		state string
+	}
+	candidates := make([]candidate, 0)
+	for rows.Next() {
+		var student candidate
+		if err := rows.Scan(&student.id, &student.state); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		candidates = append(candidates, student)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	frozen, unfrozen := 0, 0
+	for _, student := range candidates {
+		to := models.LifecycleFrozen
+		if student.state == models.LifecycleFrozen {
+			to = models.LifecycleActive
+		}
+		if _, _, err := changeLifecycle(c, tx, student.id, student.state, to, models.LifecycleSourceFreeze, nil); err != nil {
+			return 0, 0, err
+		}
+		if to == models.LifecycleFrozen {
+			frozen++
+		} else {
+			unfrozen++
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return 0, 0, err
 	}
-	return nil
+	return frozen, unfrozen, nil
 }
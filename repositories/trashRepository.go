@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"it_school/events"
+	"it_school/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TrashRepository — корзина: мягко удаленные студенты, пользователи, курсы и записи посещаемости
+type TrashRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTrashRepository(conn *pgxpool.Pool) *TrashRepository {
+	return &TrashRepository{db: conn}
+}
+
+// trashSelects выбирают удаленные записи текущего филиала ($1), колонки — поля models.TrashItem
+var trashSelects = map[string]string{
+	models.TrashStudents: `
+		SELECT 'students', s.id, s.full_name, s.deleted_at, s.deleted_by
+		FROM students s
+		WHERE s.deleted_at IS NOT NULL AND ($1::uuid IS NULL OR s.branch_id = $1)`,
+	models.TrashUsers: `
+		SELECT 'users', u.id, COALESCE(u.full_name, u.email), u.deleted_at, u.deleted_by
+		FROM users u
+		WHERE u.deleted_at IS NOT NULL AND ($1::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM user_branches ub WHERE ub.user_id = u.id AND ub.branch_id = $1))`,
+	models.TrashCourses: `
+		SELECT 'courses', co.id, co.title, co.deleted_at, co.deleted_by
+		FROM courses co
+		WHERE co.deleted_at IS NOT NULL AND ($1::uuid IS NULL OR co.branch_id = $1)`,
+	models.TrashAttendance: `
+		SELECT 'attendance', a.id, s.full_name || ': ' || a.type, a.deleted_at, a.deleted_by
+		FROM attendance a
+		JOIN students s ON s.id = a.student_id
+		WHERE a.deleted_at IS NOT NULL AND ($1::uuid IS NULL OR s.branch_id = $1)`,
+}
+
+// trashRestores снимают отметку об удалении с записи $1 текущего филиала ($2), $3 — автор изменения
+var trashRestores = map[string]string{
+	models.TrashStudents: `
+		UPDATE students SET deleted_at = NULL, deleted_by = NULL, updated_by = $3
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2::uuid IS NULL OR branch_id = $2)`,
+	models.TrashUsers: `
+		UPDATE users u SET deleted_at = NULL, deleted_by = NULL, updated_by = $3
+		WHERE u.id = $1 AND u.deleted_at IS NOT NULL AND ($2::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM user_branches ub WHERE ub.user_id = u.id AND ub.branch_id = $2))`,
+	models.TrashCourses: `
+		UPDATE courses SET deleted_at = NULL, deleted_by = NULL, updated_by = $3
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2::uuid IS NULL OR branch_id = $2)`,
+	models.TrashAttendance: `
+		UPDATE attendance a SET deleted_at = NULL, deleted_by = NULL, updated_by = $3
+		FROM students s
+		WHERE a.id = $1 AND s.id = a.student_id AND a.deleted_at IS NOT NULL
+		  AND ($2::uuid IS NULL OR s.branch_id = $2)`,
+}
+
+// trashPurges окончательно удаляют записи, попавшие в корзину раньше $1.
+// Студент уходит вместе с посещаемостью и оплатами (ON DELETE CASCADE); курс, на который
+// еще ссылаются посещаемость или зачисления, остается в корзине до их удаления.
+// Так же в корзине остается пользователь, который проводил уроки или курировал студентов: его удаление
+// через curators каскадом унесло бы уроки (attendance_lessons, group_lessons) и историю кураторства
+var trashPurges = map[string]string{
+	models.TrashAttendance: `DELETE FROM attendance WHERE deleted_at < $1`,
+	models.TrashStudents:   `DELETE FROM students WHERE deleted_at < $1`,
+	models.TrashCourses: `
+		DELETE FROM courses co
+		WHERE co.deleted_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM attendance a WHERE a.course_id = co.id)
+		  AND NOT EXISTS (SELECT 1 FROM enrollments e WHERE e.course_id = co.id)`,
+	models.TrashUsers: `
+		DELETE FROM users u
+		WHERE u.deleted_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM attendance_lessons l WHERE l.curator_id = u.id)
+		  AND NOT EXISTS (SELECT 1 FROM group_lessons gl WHERE gl.curator_id = u.id)
+		  AND NOT EXISTS (SELECT 1 FROM curator_students cs WHERE cs.curator_id = u.id)`,
+}
+
+// FindAll возвращает содержимое корзины, недавно удаленные первыми. Пустой itemType — все типы
+func (r *TrashRepository) FindAll(c context.Context, itemType string) ([]models.TrashItem, error) {
+	var selects []string
+	if itemType != "" {
+		selects = []string{trashSelects[itemType]}
+	} else {
+		for _, t := range models.TrashTypes {
+			selects = append(selects, trashSelects[t])
+		}
+	}
+
+	rows, err := r.db.Query(c, strings.Join(selects, "\nUNION ALL")+"\nORDER BY 4 DESC", currentBranch(c))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.TrashItem, 0)
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.Id, &item.Title, &item.DeletedAt, &item.DeletedBy); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Restore возвращает запись из корзины. Повторное использование email или crm_external_id
+// за время нахождения в корзине невозможно, поэтому конфликтов уникальности при восстановлении нет
+func (r *TrashRepository) Restore(c context.Context, itemType string, id uuid.UUID) error {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, trashRestores[itemType], id, currentBranch(c), currentUser(c))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("trash_item_not_found", "Item not found in trash")
+	}
+	// Для CRM восстановленный студент — обновление карточки
+	if itemType == models.TrashStudents {
+		if err := recordCrmStudent(c, tx, events.CrmStudentUpdated, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(c)
+}
+
+// Purge окончательно удаляет записи типа itemType, удаленные раньше before, и возвращает их количество
+func (r *TrashRepository) Purge(c context.Context, itemType string, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(c, trashPurges[itemType], before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
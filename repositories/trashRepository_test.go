@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"it_school/models"
+	"strings"
+	"testing"
+)
+
+// Для каждого типа корзины должен быть запрос выборки, восстановления и очистки:
+// без ключа в карте запрос окажется пустой строкой и упадет только во время работы
+func TestTrashQueriesCoverTypes(t *testing.T) {
+	queries := []struct {
+		name   string
+		byType map[string]string
+		params []string
+	}{
+		{name: "trashSelects", byType: trashSelects, params: []string{"$1"}},
+		{name: "trashRestores", byType: trashRestores, params: []string{"$1", "$2", "$3"}},
+		{name: "trashPurges", byType: trashPurges, params: []string{"$1"}},
+	}
+
+	for _, q := range queries {
+		t.Run(q.name, func(t *testing.T) {
+			if len(q.byType) != len(models.TrashTypes) {
+				t.Errorf("%d queries, want one for each of %v", len(q.byType), models.TrashTypes)
+			}
+			for _, itemType := range models.TrashTypes {
+				query := strings.TrimSpace(q.byType[itemType])
+				if query == "" {
+					t.Errorf("no query for %q", itemType)
+					continue
+				}
+				for _, param := range q.params {
+					if !strings.Contains(query, param) {
+						t.Errorf("%s query does not use %s", itemType, param)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Выборка помечает строки своим типом: по нему клиент восстанавливает запись
+func TestTrashSelectsReturnOwnType(t *testing.T) {
+	for _, itemType := range models.TrashTypes {
+		if !strings.Contains(trashSelects[itemType], "SELECT '"+itemType+"'") {
+			t.Errorf("trashSelects[%q] does not select type %q", itemType, itemType)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"it_school/events"
 	"it_school/models"
 
 	"github.com/google/uuid"
@@ -17,29 +19,18 @@ func NewRUsersRepository(conn *pgxpool.Pool) *UsersRepository {
 	return &UsersRepository{db: conn}
 }
 
-
-
 func (r *UsersRepository) FindAll(c context.Context, roleID *uuid.UUID) ([]models.User, error) {
-	var (
-		rows pgx.Rows
-		err  error
-	)
-
-	if roleID != nil {
-		query := `
-			SELECT id, full_name, email, phone_number, role_id
-			FROM users
-			WHERE role_id = $1;
-		`
-		rows, err = r.db.Query(c, query, *roleID)
-	} else {
-		query := `
-			SELECT id, full_name, email, phone_number, role_id
-			FROM users;
-		`
-		rows, err = r.db.Query(c, query)
-	}
-
+	// Пользователь виден в филиале, если он к нему прикреплен
+	query := `
+		SELECT u.id, u.full_name, u.email, u.phone_number, u.role_id
+		FROM users u
+		WHERE u.deleted_at IS NULL
+		  AND ($1::uuid IS NULL OR u.role_id = $1)
+		  AND ($2::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM user_branches ub WHERE ub.user_id = u.id AND ub.branch_id = $2
+		  ));
+	`
+	rows, err := r.db.Query(c, query, roleID, currentBranch(c))
 	if err != nil {
 		return nil, err
 	}
@@ -57,20 +48,21 @@ func (r *UsersRepository) FindAll(c context.Context, roleID *uuid.UUID) ([]model
 	return users, nil
 }
 
-
 func (r *UsersRepository) FindById(c context.Context, id uuid.UUID) (models.User, error) {
 	var user models.User
-	row := r.db.QueryRow(c, "select id, email, full_name, phone_number, role_id from users where id=$1", id)
-	err := row.Scan(&user.Id, &user.Email, &user.Full_name, &user.Telephone, &user.RoleID)
+	row := r.db.QueryRow(c, `select id, email, full_name, phone_number, role_id, version from users u where id=$1 and deleted_at is null
+		and ($2::uuid is null or exists (select 1 from user_branches ub where ub.user_id = u.id and ub.branch_id = $2))`,
+		id, currentBranch(c))
+	err := row.Scan(&user.Id, &user.Email, &user.Full_name, &user.Telephone, &user.RoleID, &user.Version)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, notFoundIfNoRows(err, "user_not_found", "User not found")
 	}
 	return user, nil
 }
 
 func (r *UsersRepository) FindByEmail(c context.Context, email string) (models.User, error) {
 	var user models.User
-	row := r.db.QueryRow(c, "select id, email, password, role_id from users where email = $1", email)
+	row := r.db.QueryRow(c, "select id, email, password, role_id from users where email = $1 and deleted_at is null", email)
 	if err := row.Scan(&user.Id, &user.Email, &user.PasswordHash, &user.RoleID); err != nil {
 		return models.User{}, err
 	}
@@ -78,53 +70,147 @@ func (r *UsersRepository) FindByEmail(c context.Context, email string) (models.U
 	return user, nil
 }
 
+// FindTrashedIdByEmail возвращает пользователя в корзине с этим email: email остается занятым,
+// пока пользователя не удалят окончательно
+func (r *UsersRepository) FindTrashedIdByEmail(c context.Context, email string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(c, "select id from users where email = $1 and deleted_at is not null", email).Scan(&id)
+	return id, err
+}
 
 func (r *UsersRepository) Create(c context.Context, user models.User) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(c, "insert into users(email, password, full_name, phone_number, role_id) values($1, $2, $3, $4, $5) returning id",
-							user.Email, user.PasswordHash, user.Full_name, user.Telephone, user.RoleID).Scan(&id)
+		user.Email, user.PasswordHash, user.Full_name, user.Telephone, user.RoleID).Scan(&id)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	return id, nil
 }
 
-func (r *UsersRepository) Update(c context.Context, id uuid.UUID, user models.User) error {
-	_, err := r.db.Exec(c, `
-	UPDATE users SET email=$1, full_name=$2, phone_number=$3 WHERE id=$4`,
-	 user.Email, user.Full_name, user.Telephone, id)
+// Provision создает пользователя, прикрепляет его к филиалам branchIDs и, если curator, делает куратором —
+// все в одной транзакции. Используется при создании пользователя в настройках и при автоматической регистрации через OIDC
+func (r *UsersRepository) Provision(c context.Context, user models.User, branchIDs []uuid.UUID, curator bool) (uuid.UUID, error) {
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(c)
 
+	var id uuid.UUID
+	err = tx.QueryRow(c, "insert into users(email, password, full_name, phone_number, role_id) values($1, $2, $3, $4, $5) returning id",
+		user.Email, user.PasswordHash, user.Full_name, user.Telephone, user.RoleID).Scan(&id)
 	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, branchID := range branchIDs {
+		_, err := tx.Exec(c, `INSERT INTO user_branches (user_id, branch_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, branchID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if curator {
+		if _, err := tx.Exec(c, `INSERT INTO curators(user_id) VALUES ($1)`, id); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// Update меняет профиль пользователя, если его версия входит в versions (nil — любая версия).
+// Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *UsersRepository) Update(c context.Context, id uuid.UUID, user models.User, versions []int) (int, error) {
+	var version int
+	err := r.db.QueryRow(c, `
+	UPDATE users SET email=$1, full_name=$2, phone_number=$3, updated_by=$5
+	WHERE id=$4 AND deleted_at IS NULL AND ($6::int[] IS NULL OR version = ANY($6))
+	RETURNING version`,
+		user.Email, user.Full_name, user.Telephone, id, currentUser(c), versions).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, r.staleOrNotFound(c, id)
+	}
+	return version, err
+}
+
+// staleOrNotFound объясняет, почему UPDATE с проверкой версии не нашел строку
+func (r *UsersRepository) staleOrNotFound(c context.Context, id uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(c, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists); err != nil {
 		return err
 	}
-	return nil
+	if exists {
+		return models.ErrVersionConflict
+	}
+	return notFound("user_not_found", "User not found")
 }
 
+// UpdateUserRole меняет роль пользователя. Для неизвестного пользователя или пользователя в корзине — ошибка user_not_found,
+// для неизвестной роли — нарушение внешнего ключа
 func (r *UsersRepository) UpdateUserRole(ctx context.Context, userID, roleID uuid.UUID) error {
-    query := `
-        UPDATE users
-        SET role_id = $1
-        WHERE id = $2
+	query := `
+        UPDATE users u
+        SET role_id = $1, updated_by = $3
+        FROM (SELECT id, role_id FROM users WHERE id = $2 AND deleted_at IS NULL) old
+        WHERE u.id = old.id
+        RETURNING old.role_id
     `
-    _, err := r.db.Exec(ctx, query, roleID, userID)
-    if err != nil {
-        return err
-    }
-    return nil
-}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldRoleID *uuid.UUID
+	err = tx.QueryRow(ctx, query, roleID, userID, currentUser(ctx)).Scan(&oldRoleID)
+	if err != nil {
+		return notFoundIfNoRows(err, "user_not_found", "User not found")
+	}
 
+	if oldRoleID == nil || *oldRoleID != roleID {
+		err = events.Record(ctx, tx, events.UserRoleChanged, events.UserRoleChangedData{
+			UserId:    userID,
+			OldRoleId: oldRoleID,
+			NewRoleId: roleID,
+		})
+		if err != nil {
+			return err
+		}
+	}
 
+	return tx.Commit(ctx)
+}
 
+// Delete переносит пользователя в корзину и завершает его сессии; неизвестный пользователь
+// или пользователь, уже лежащий в корзине, — ошибка user_not_found.
+// Авторство в истории (updated_by, checked_by и т.п.) сохраняется до окончательного удаления
 func (r *UsersRepository) Delete(c context.Context, id uuid.UUID) error {
-	_, err := r.db.Exec(c, "delete from users where id=$1", id)
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, "update users set deleted_at = now(), deleted_by = $2 where id = $1 and deleted_at is null", id, currentUser(c))
 	if err != nil {
 		return err
 	}
-	return nil
+	if tag.RowsAffected() == 0 {
+		return notFound("user_not_found", "User not found")
+	}
+	if _, err := tx.Exec(c, "delete from sessions where user_id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit(c)
 }
 
 func (r *UsersRepository) CountByRoleID(ctx context.Context, roleID uuid.UUID) (int, error) {
-    var cnt int
-    err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role_id = $1`, roleID).Scan(&cnt)
-    return cnt, err
-}
\ No newline at end of file
+	var cnt int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role_id = $1 AND deleted_at IS NULL`, roleID).Scan(&cnt)
+	return cnt, err
+}
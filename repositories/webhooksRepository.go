@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"it_school/events"
+	"it_school/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhooksRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhooksRepository(conn *pgxpool.Pool) *WebhooksRepository {
+	return &WebhooksRepository{db: conn}
+}
+
+// PendingDelivery — доставка, готовая к отправке, вместе с адресом и секретом подписки
+type PendingDelivery struct {
+	models.WebhookDelivery
+	Url    string
+	Secret string
+}
+
+const subscriptionColumns = `id, url, secret, events, is_active, description, created_at, version`
+
+func scanSubscription(row pgx.Row) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := row.Scan(
+		&subscription.Id,
+		&subscription.Url,
+		&subscription.Secret,
+		&subscription.Events,
+		&subscription.IsActive,
+		&subscription.Description,
+		&subscription.CreatedAt,
+		&subscription.Version,
+	)
+	return subscription, err
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event, d.payload, d.status, d.attempts,
+	d.response_status, d.last_error, d.created_at, d.next_attempt_at, d.delivered_at`
+
+func scanDelivery(row pgx.Row, extra ...interface{}) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	dest := []interface{}{
+		&delivery.Id,
+		&delivery.SubscriptionId,
+		&delivery.EventId,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.NextAttemptAt,
+		&delivery.DeliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return delivery, err
+}
+
+func (r *WebhooksRepository) CreateSubscription(c context.Context, subscription models.WebhookSubscription) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(c, `
+		INSERT INTO webhook_subscriptions (url, secret, events, is_active, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		subscription.Url, subscription.Secret, subscription.Events, subscription.IsActive, subscription.Description,
+	).Scan(&id)
+	return id, err
+}
+
+// UpdateSubscription меняет подписку, если ее версия входит в versions (nil — любая версия); пустой Secret оставляет прежний секрет.
+// Возвращает новую версию; при устаревшей версии — models.ErrVersionConflict
+func (r *WebhooksRepository) UpdateSubscription(c context.Context, subscription models.WebhookSubscription, versions []int) (int, error) {
+	var version int
+	err := r.db.QueryRow(c, `
+		UPDATE webhook_subscriptions
+		SET url = $1, secret = COALESCE(NULLIF($2, ''), secret), events = $3, is_active = $4, description = $5, updated_by = $7
+		WHERE id = $6 AND ($8::int[] IS NULL OR version = ANY($8))
+		RETURNING version`,
+		subscription.Url, subscription.Secret, subscription.Events, subscription.IsActive, subscription.Description, subscription.Id,
+		currentUser(c), versions,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := r.db.QueryRow(c, `SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, subscription.Id).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, models.ErrVersionConflict
+		}
+		return 0, notFound("webhook_not_found", "Webhook not found")
+	}
+	return version, err
+}
+
+func (r *WebhooksRepository) DeleteSubscription(c context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(c, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("webhook_not_found", "Webhook not found")
+	}
+	return nil
+}
+
+func (r *WebhooksRepository) FindSubscription(c context.Context, id uuid.UUID) (models.WebhookSubscription, error) {
+	return scanSubscription(r.db.QueryRow(c, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+}
+
+func (r *WebhooksRepository) FindSubscriptions(c context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.Query(c, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]models.WebhookSubscription, 0)
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// EnqueueDeliveries создает доставки события для всех активных подписок на его тип.
+// Повторная обработка того же события не создает дублей. Возвращает количество созданных доставок
+func (r *WebhooksRepository) EnqueueDeliveries(c context.Context, event events.Event, payload []byte) (int64, error) {
+	tag, err := r.db.Exec(c, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event, payload)
+		SELECT id, $1, $2, $3 FROM webhook_subscriptions
+		WHERE is_active AND $2 = ANY(events)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		event.Id, event.Type, payload,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DueDeliveries берет в работу доставки активных подписок, время очередной попытки которых наступило.
+// Выбранные доставки сдвигаются на lease вперед: другие экземпляры их не возьмут, а доставка,
+// итог которой не удалось записать, повторится только после истечения lease
+func (r *WebhooksRepository) DueDeliveries(c context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	rows, err := r.db.Query(c, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + $2::interval
+		FROM (
+			SELECT dd.id FROM webhook_deliveries dd
+			JOIN webhook_subscriptions ss ON ss.id = dd.subscription_id AND ss.is_active
+			WHERE dd.status = 'pending' AND dd.next_attempt_at <= now()
+			ORDER BY dd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		) due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING `+deliveryColumns+`, s.url, s.secret`,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]PendingDelivery, 0)
+	for rows.Next() {
+		var pending PendingDelivery
+		pending.WebhookDelivery, err = scanDelivery(rows, &pending.Url, &pending.Secret)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, pending)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered фиксирует успешную доставку
+func (r *WebhooksRepository) MarkDelivered(c context.Context, id uuid.UUID, responseStatus int) error {
+	_, err := r.db.Exec(c, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, response_status = $1, last_error = NULL, delivered_at = now()
+		WHERE id = $2`,
+		responseStatus, id,
+	)
+	return err
+}
+
+// MarkAttemptFailed фиксирует неудачную попытку. nextAttemptAt == nil — попытки исчерпаны, доставка переходит в failed
+func (r *WebhooksRepository) MarkAttemptFailed(c context.Context, id uuid.UUID, responseStatus *int, lastError string, nextAttemptAt *time.Time) error {
+	_, err := r.db.Exec(c, `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, response_status = $1, last_error = $2,
+		    status = CASE WHEN $3::timestamptz IS NULL THEN 'failed'::webhook_delivery_status ELSE status END,
+		    next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $4`,
+		responseStatus, lastError, nextAttemptAt, id,
+	)
+	return err
+}
+
+// FindDeliveries возвращает последние доставки подписки, status фильтрует по статусу
+func (r *WebhooksRepository) FindDeliveries(c context.Context, subscriptionID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(c, `SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status::text = $2)
+		ORDER BY d.created_at DESC
+		LIMIT $3`,
+		subscriptionID, status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver ставит доставку в очередь заново с новым счетчиком попыток
+func (r *WebhooksRepository) Redeliver(c context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(c, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("delivery_not_found", "Delivery not found")
+	}
+	return nil
+}
@@ -1,15 +1,82 @@
 -- Создание пользовательских типов
-CREATE TYPE public."attendance_type" AS ENUM ('урок', 'заморозка', 'пролонгация');
-CREATE TYPE public."is_active" AS ENUM ('активен', 'неактивен');
-CREATE TYPE public."lessons_status" AS ENUM ('пропущен', 'проведен', 'запланирован', 'отменен');
-CREATE TYPE public."payment_type" AS ENUM ('оплата', 'предоплата', 'доплата');
+CREATE TYPE public."attendance_type" AS ENUM ('lesson', 'freeze', 'prolongation');
+CREATE TYPE public."is_active" AS ENUM ('active', 'inactive');
+CREATE TYPE public."lessons_status" AS ENUM ('missed', 'conducted', 'scheduled', 'canceled');
+CREATE TYPE public."payment_type" AS ENUM ('payment', 'prepayment', 'additional_payment');
+CREATE TYPE public."enrollment_status" AS ENUM ('active', 'paused', 'completed', 'canceled');
+CREATE TYPE public."course_status" AS ENUM ('active', 'archived');
+CREATE TYPE public."homework_status" AS ENUM ('not_submitted', 'submitted', 'checked');
+CREATE TYPE public."lead_status" AS ENUM ('new', 'contacted', 'trial_scheduled', 'trial_conducted', 'converted', 'lost');
+CREATE TYPE public."webhook_delivery_status" AS ENUM ('pending', 'delivered', 'failed');
+CREATE TYPE public."student_lifecycle_state" AS ENUM ('lead', 'trial', 'active', 'frozen', 'graduated', 'churned', 'archived');
+
+-- version растет на каждом UPDATE; клиент передает ее в If-Match (оптимистичная блокировка)
+CREATE FUNCTION touch_row_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
 
 -- Создание таблиц
+CREATE TABLE branches (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    address text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER branches_version BEFORE UPDATE ON branches
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
 CREATE TABLE courses (
     id uuid NOT NULL PRIMARY KEY,
-    title text NOT NULL
+    title text NOT NULL,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    description text NULL,
+    age_min int NULL,
+    age_max int NULL,
+    duration_weeks int NULL,
+    lessons_count int NULL,
+    price numeric NULL,
+    status public."course_status" DEFAULT 'active'::course_status NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL,
+    deleted_at timestamptz NULL,
+    deleted_by uuid NULL,
+    CHECK (age_min IS NULL OR age_max IS NULL OR age_min <= age_max)
+);
+CREATE TRIGGER courses_version BEFORE UPDATE ON courses
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+CREATE INDEX courses_deleted_at_idx ON courses(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Программа курса: упорядоченные модули и темы внутри модулей
+CREATE TABLE course_modules (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL
 );
 
+CREATE INDEX course_modules_course_id_idx ON course_modules(course_id, position);
+
+CREATE TABLE course_topics (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    module_id uuid NOT NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL,
+    lesson_plan text NULL
+);
+
+CREATE INDEX course_topics_module_id_idx ON course_topics(module_id, position);
+
 CREATE TABLE roles (
     id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
     name text NOT NULL,
@@ -25,13 +92,25 @@ CREATE TABLE users (
     reset_token text NULL,
     reset_token_expires_at timestamp NULL,
     full_name text NULL,
-    phone_number text NULL
+    phone_number text NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL,
+    deleted_at timestamptz NULL,
+    deleted_by uuid NULL
+);
+CREATE TRIGGER users_version BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+CREATE INDEX users_deleted_at_idx ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE user_branches (
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, branch_id)
 );
 
 CREATE TABLE curators (
-    user_id uuid NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
-    student_ids _uuid DEFAULT '{}'::uuid[] NULL,
-    course_ids _uuid DEFAULT '{}'::uuid[] NULL
+    user_id uuid NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE
 );
 
 CREATE TABLE sessions (
@@ -42,28 +121,111 @@ CREATE TABLE sessions (
     created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
+CREATE INDEX sessions_expires_at_idx ON sessions(expires_at);
+
 CREATE TABLE students (
     id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
     full_name text NOT NULL,
     phone_number text NOT NULL,
     parent_name text NOT NULL,
     parent_phone_number text NOT NULL,
-    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
-    courses _text NULL,
     platform_link text NULL,
     crm_link text NULL,
     created_at timestamp DEFAULT now() NULL,
-    is_active public."is_active" NULL,
-    course_id uuid NULL REFERENCES courses(id) ON DELETE CASCADE
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    crm_external_id text NULL UNIQUE,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL,
+    deleted_at timestamptz NULL,
+    deleted_by uuid NULL,
+    -- Жизненный цикл студента; is_active вычисляется из него для совместимости
+    lifecycle_state public."student_lifecycle_state" DEFAULT 'active' NOT NULL,
+    lifecycle_changed_at timestamptz DEFAULT now() NOT NULL,
+    is_active public."is_active" GENERATED ALWAYS AS (
+        CASE WHEN lifecycle_state IN ('active', 'frozen') THEN 'active'::is_active ELSE 'inactive'::is_active END
+    ) STORED
 );
+CREATE TRIGGER students_version BEFORE UPDATE ON students
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+CREATE INDEX students_deleted_at_idx ON students(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX students_lifecycle_state_idx ON students(lifecycle_state);
 
-CREATE TABLE attendance (
+-- История переходов жизненного цикла студента. source: manual, created, crm, attendance_freeze
+CREATE TABLE student_lifecycle_transitions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    from_state public."student_lifecycle_state" NULL,
+    to_state public."student_lifecycle_state" NOT NULL,
+    source text NOT NULL,
+    comment text NULL,
+    changed_at timestamptz DEFAULT now() NOT NULL,
+    changed_by uuid NULL
+);
+
+CREATE INDEX student_lifecycle_transitions_student_id_idx ON student_lifecycle_transitions(student_id, changed_at);
+
+-- Зачисление студента на курс. Студент может учиться на нескольких курсах одновременно.
+-- Курс с зачислениями удалить нельзя, чтобы не потерять студентов и их историю.
+CREATE TABLE enrollments (
     id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
     student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE RESTRICT,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    start_date date NOT NULL,
+    end_date date NULL,
+    status public."enrollment_status" DEFAULT 'active'::enrollment_status NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER enrollments_version BEFORE UPDATE ON enrollments
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+CREATE INDEX enrollments_student_id_idx ON enrollments(student_id);
+CREATE INDEX enrollments_course_id_idx ON enrollments(course_id);
+CREATE UNIQUE INDEX enrollments_active_uniq ON enrollments(student_id, course_id) WHERE status = 'active';
+
+-- История кураторства: кто курировал студента и в какой период.
+-- Текущий куратор — запись с unassigned_at IS NULL (не больше одной на студента).
+CREATE TABLE curator_students (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    assigned_at timestamptz DEFAULT now() NOT NULL,
+    unassigned_at timestamptz NULL,
+    assigned_by uuid NULL REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX curator_students_active_uniq ON curator_students(student_id) WHERE unassigned_at IS NULL;
+CREATE INDEX curator_students_curator_id_idx ON curator_students(curator_id);
+
+CREATE TABLE curator_courses (
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
     course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    assigned_at timestamptz DEFAULT now() NOT NULL,
+    PRIMARY KEY (curator_id, course_id)
+);
+
+CREATE INDEX students_branch_id_idx ON students(branch_id);
+CREATE INDEX courses_branch_id_idx ON courses(branch_id);
+
+CREATE TABLE attendance (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE RESTRICT,
     type public."attendance_type" NOT NULL,
-    created_at timestamptz DEFAULT now() NOT NULL
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL,
+    deleted_at timestamptz NULL,
+    deleted_by uuid NULL
 );
+CREATE TRIGGER attendance_version BEFORE UPDATE ON attendance
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+CREATE INDEX attendance_deleted_at_idx ON attendance(deleted_at) WHERE deleted_at IS NOT NULL;
 
 CREATE TABLE attendance_freezes (
     attendance_id uuid NOT NULL PRIMARY KEY REFERENCES attendance(id) ON DELETE CASCADE,
@@ -72,6 +234,38 @@ CREATE TABLE attendance_freezes (
     comment text NULL
 );
 
+CREATE TABLE groups (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    title text NOT NULL,
+    schedule jsonb DEFAULT '[]'::jsonb NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER groups_version BEFORE UPDATE ON groups
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+CREATE TABLE group_members (
+    group_id uuid NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    joined_at timestamptz DEFAULT now() NOT NULL,
+    PRIMARY KEY (group_id, student_id)
+);
+
+CREATE TABLE group_lessons (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    group_id uuid NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
+    date date NOT NULL,
+    format text NULL,
+    topic_id uuid NULL REFERENCES course_topics(id) ON DELETE SET NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
 CREATE TABLE attendance_lessons (
     attendance_id uuid NOT NULL PRIMARY KEY REFERENCES attendance(id) ON DELETE CASCADE,
     curator_id uuid NOT NULL REFERENCES curators(user_id) ON DELETE CASCADE,
@@ -79,8 +273,61 @@ CREATE TABLE attendance_lessons (
     format text NULL,
     feedback text NULL,
     feedbackdate timestamptz DEFAULT now() NULL,
-    lessons_status public."lessons_status" DEFAULT 'запланирован'::lessons_status NOT NULL,
-    created_at timestamptz DEFAULT now() NOT NULL
+    lessons_status public."lessons_status" DEFAULT 'scheduled'::lessons_status NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    group_lesson_id uuid NULL REFERENCES group_lessons(id) ON DELETE CASCADE,
+    topic_id uuid NULL REFERENCES course_topics(id) ON DELETE SET NULL
+);
+
+CREATE INDEX attendance_lessons_group_lesson_id_idx ON attendance_lessons(group_lesson_id);
+CREATE INDEX attendance_lessons_topic_id_idx ON attendance_lessons(topic_id);
+
+-- Домашнее задание выдается на индивидуальном (attendance_id) или групповом (group_lesson_id) уроке.
+-- Каждый студент урока получает свою сдачу со статусом и оценкой.
+CREATE TABLE homework (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    attendance_id uuid NULL REFERENCES attendance_lessons(attendance_id) ON DELETE CASCADE,
+    group_lesson_id uuid NULL REFERENCES group_lessons(id) ON DELETE CASCADE,
+    description text NOT NULL,
+    due_date date NOT NULL,
+    created_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    CHECK ((attendance_id IS NULL) <> (group_lesson_id IS NULL))
+);
+
+CREATE INDEX homework_attendance_id_idx ON homework(attendance_id);
+CREATE INDEX homework_group_lesson_id_idx ON homework(group_lesson_id);
+
+CREATE TABLE homework_submissions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    homework_id uuid NOT NULL REFERENCES homework(id) ON DELETE CASCADE,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    status public."homework_status" DEFAULT 'not_submitted'::homework_status NOT NULL,
+    submitted_at timestamptz NULL,
+    checked_at timestamptz NULL,
+    checked_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    grade int NULL,
+    comment text NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL,
+    UNIQUE (homework_id, student_id)
+);
+CREATE TRIGGER homework_submissions_version BEFORE UPDATE ON homework_submissions
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+CREATE INDEX homework_submissions_student_id_idx ON homework_submissions(student_id);
+
+-- Файлы хранятся на диске (STORAGE_DIR), в базе — только метаданные и относительный путь
+CREATE TABLE homework_attachments (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    submission_id uuid NOT NULL REFERENCES homework_submissions(id) ON DELETE CASCADE,
+    file_name text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    storage_path text NOT NULL,
+    uploaded_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    uploaded_at timestamptz DEFAULT now() NOT NULL
 );
 
 CREATE TABLE attendance_prolongations (
@@ -89,4 +336,220 @@ CREATE TABLE attendance_prolongations (
     date date NOT NULL,
     amount numeric NOT NULL,
     comment text NULL
-);
\ No newline at end of file
+);
+
+-- Критерии оценивания курса (логика, синтаксис, проектная работа...) со шкалой баллов
+CREATE TABLE rubric_criteria (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    course_id uuid NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    title text NOT NULL,
+    description text NULL,
+    min_score int DEFAULT 1 NOT NULL,
+    max_score int DEFAULT 5 NOT NULL,
+    CHECK (min_score < max_score)
+);
+
+CREATE INDEX rubric_criteria_course_id_idx ON rubric_criteria(course_id, position);
+
+-- Оценка студента по критерию: за урок (attendance_id) или за модуль программы (module_id)
+CREATE TABLE student_scores (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    criterion_id uuid NOT NULL REFERENCES rubric_criteria(id) ON DELETE CASCADE,
+    attendance_id uuid NULL REFERENCES attendance_lessons(attendance_id) ON DELETE CASCADE,
+    module_id uuid NULL REFERENCES course_modules(id) ON DELETE CASCADE,
+    score int NOT NULL,
+    comment text NULL,
+    scored_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+    scored_at timestamptz DEFAULT now() NOT NULL,
+    CHECK ((attendance_id IS NULL) <> (module_id IS NULL))
+);
+
+CREATE UNIQUE INDEX student_scores_lesson_uniq ON student_scores(criterion_id, attendance_id) WHERE attendance_id IS NOT NULL;
+CREATE UNIQUE INDEX student_scores_module_uniq ON student_scores(student_id, criterion_id, module_id) WHERE module_id IS NOT NULL;
+CREATE INDEX student_scores_student_id_idx ON student_scores(student_id);
+
+-- Шаблон сертификата курса. body — text/template с полями .StudentName, .CourseTitle,
+-- .StartDate, .EndDate, .CuratorName, .Number
+CREATE TABLE certificate_templates (
+    course_id uuid NOT NULL PRIMARY KEY REFERENCES courses(id) ON DELETE CASCADE,
+    title text NOT NULL,
+    body text NOT NULL,
+    signature_name text NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER certificate_templates_version BEFORE UPDATE ON certificate_templates
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+-- Выданный сертификат. Данные сохраняются на момент выдачи, чтобы проверка по номеру
+-- не зависела от последующих изменений студента или курса.
+CREATE TABLE certificates (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    number text NOT NULL UNIQUE,
+    enrollment_id uuid NULL UNIQUE REFERENCES enrollments(id) ON DELETE SET NULL,
+    student_id uuid NULL REFERENCES students(id) ON DELETE SET NULL,
+    student_name text NOT NULL,
+    course_title text NOT NULL,
+    curator_name text NULL,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    storage_path text NOT NULL,
+    issued_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX certificates_student_id_idx ON certificates(student_id);
+
+-- Лид — потенциальный студент до первой оплаты. После конвертации ссылается на созданного студента
+CREATE TABLE leads (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+    full_name text NOT NULL,
+    phone_number text NULL,
+    parent_name text NOT NULL,
+    parent_phone_number text NOT NULL,
+    source text NULL,
+    course_id uuid NULL REFERENCES courses(id) ON DELETE SET NULL,
+    status public."lead_status" DEFAULT 'new'::lead_status NOT NULL,
+    comment text NULL,
+    student_id uuid NULL REFERENCES students(id) ON DELETE SET NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER leads_version BEFORE UPDATE ON leads
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+CREATE INDEX leads_branch_status_idx ON leads(branch_id, status);
+
+-- Пробный урок лида с куратором
+CREATE TABLE trial_lessons (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    curator_id uuid NULL REFERENCES curators(user_id) ON DELETE SET NULL,
+    date timestamptz NOT NULL,
+    status public."lessons_status" DEFAULT 'scheduled'::lessons_status NOT NULL,
+    feedback text NULL,
+    reminded_at timestamptz NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER trial_lessons_version BEFORE UPDATE ON trial_lessons
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+CREATE INDEX trial_lessons_lead_id_idx ON trial_lessons(lead_id);
+CREATE INDEX trial_lessons_curator_date_idx ON trial_lessons(curator_id, date);
+
+-- События синхронизации с CRM, которые не удалось доставить после всех попыток
+CREATE TABLE crm_dead_letters (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    event_id uuid NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL,
+    last_error text NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    last_attempt_at timestamptz DEFAULT now() NOT NULL
+);
+
+-- Очередь доставки событий в CRM: повторы планируются по next_attempt_at,
+-- после исчерпания попыток доставка переносится в crm_dead_letters
+CREATE TABLE crm_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    event_id uuid NOT NULL UNIQUE,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX crm_deliveries_due_idx ON crm_deliveries(next_attempt_at);
+
+-- Подписка внешней системы (бот, скрипты учета) на доменные события
+CREATE TABLE webhook_subscriptions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    is_active boolean DEFAULT true NOT NULL,
+    description text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    updated_by uuid NULL
+);
+CREATE TRIGGER webhook_subscriptions_version BEFORE UPDATE ON webhook_subscriptions
+    FOR EACH ROW EXECUTE FUNCTION touch_row_version();
+
+-- Доставка события подписчику. pending доставляется фоновым процессом с повторами,
+-- после исчерпания попыток переходит в failed
+CREATE TABLE webhook_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL PRIMARY KEY,
+    subscription_id uuid NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id uuid NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status public."webhook_delivery_status" DEFAULT 'pending'::webhook_delivery_status NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    response_status integer NULL,
+    last_error text NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL,
+    delivered_at timestamptz NULL
+);
+
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries(subscription_id, created_at);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX webhook_deliveries_event_uniq ON webhook_deliveries(subscription_id, event_id);
+
+-- Transactional outbox: доменные события записываются в одной транзакции с изменением
+-- данных и передаются подписчикам фоновым диспетчером (доставка at-least-once)
+CREATE TABLE outbox (
+    id uuid NOT NULL PRIMARY KEY,
+    event text NOT NULL,
+    data jsonb NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    available_at timestamptz DEFAULT now() NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text NULL,
+    processed_at timestamptz NULL
+);
+
+CREATE INDEX outbox_pending_idx ON outbox(available_at) WHERE processed_at IS NULL;
+CREATE INDEX outbox_processed_idx ON outbox(processed_at) WHERE processed_at IS NOT NULL;
+
+-- Учет примененных миграций: /readyz сравнивает его с migrations/*.sql, встроенными в бинарник.
+-- Схема выше соответствует всем перечисленным миграциям; новая миграция добавляет сюда свою версию
+CREATE TABLE schema_migrations (
+    version text NOT NULL PRIMARY KEY,
+    applied_at timestamptz DEFAULT now() NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001_branches'),
+    ('002_groups'),
+    ('003_enrollments'),
+    ('004_curator_assignments'),
+    ('005_course_catalog'),
+    ('006_homework'),
+    ('007_grading'),
+    ('008_certificates'),
+    ('009_leads'),
+    ('010_crm_sync'),
+    ('011_webhooks'),
+    ('012_outbox'),
+    ('013_trial_reminders'),
+    ('014_sessions_expiry'),
+    ('015_schema_migrations'),
+    ('016_enum_codes'),
+    ('017_row_versions'),
+    ('018_soft_delete'),
+    ('019_student_lifecycle'),
+    ('020_crm_deliveries');
@@ -0,0 +1,91 @@
+// Package tracing — трассировка OpenTelemetry: span на каждый HTTP-маршрут (otelgin)
+// и на каждый SQL-запрос (PgxTracer). Экспорт в OTLP/HTTP или stdout
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры, допустимые в TRACING_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "it_school"
+
+// Tracer — трассировщик приложения для собственных span
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Options — настройки трассировки из конфигурации
+type Options struct {
+	Exporter     string
+	ServiceName  string
+	SampleRatio  float64
+	OTLPEndpoint string
+}
+
+// Setup настраивает глобальный TracerProvider и возвращает функцию, которая при остановке
+// приложения отправляет накопленные span. С экспортером none трассировка выключена
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch strings.ToLower(opts.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var exporterOpts []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(opts.OTLPEndpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, exporterOpts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Решение о сэмплировании берется у вызывающего сервиса, если он передал traceparent
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// RequestIDAttribute — атрибут span с идентификатором запроса (X-Request-ID)
+func RequestIDAttribute(requestID string) attribute.KeyValue {
+	return attribute.String("http.request_id", requestID)
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"it_school/models"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// CertificateData — поля, доступные в шаблоне сертификата
+type CertificateData struct {
+	StudentName string
+	CourseTitle string
+	StartDate   string
+	EndDate     string
+	CuratorName string
+	Number      string
+}
+
+func NewCertificateData(certificate models.Certificate) CertificateData {
+	data := CertificateData{
+		StudentName: certificate.StudentName,
+		CourseTitle: certificate.CourseTitle,
+		StartDate:   certificate.StartDate.Format(dateLayout),
+		EndDate:     certificate.EndDate.Format(dateLayout),
+		Number:      certificate.Number,
+	}
+	if certificate.CuratorName != nil {
+		data.CuratorName = *certificate.CuratorName
+	}
+	return data
+}
+
+// ExecuteCertificateBody подставляет данные в текст шаблона. Используется и для проверки шаблона при сохранении
+func ExecuteCertificateBody(body string, data CertificateData) (string, error) {
+	tmpl, err := template.New("certificate").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// RenderCertificate формирует PDF сертификата в альбомной ориентации
+func RenderCertificate(w io.Writer, tmpl models.CertificateTemplate, certificate models.Certificate, fontPath string) error {
+	data := NewCertificateData(certificate)
+	body, err := ExecuteCertificateBody(tmpl.Body, data)
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("L", "mm", "A4", filepath.Dir(fontPath))
+	pdf.AddUTF8Font(reportFont, "", filepath.Base(fontPath))
+	pdf.SetMargins(30, 30, 30)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("не удалось загрузить шрифт %s: %w", fontPath, err)
+	}
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	pdf.SetDrawColor(76, 141, 230)
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(10, 10, pageWidth-20, pageHeight-20, "D")
+
+	pdf.SetY(40)
+	pdf.SetFont(reportFont, "", 34)
+	pdf.CellFormat(width, 16, tmpl.Title, "", 1, "C", false, 0, "")
+	pdf.Ln(10)
+
+	pdf.SetFont(reportFont, "", 26)
+	pdf.CellFormat(width, 14, certificate.StudentName, "", 1, "C", false, 0, "")
+	pdf.Ln(8)
+
+	pdf.SetFont(reportFont, "", 14)
+	pdf.MultiCell(width, 8, body, "", "C", false)
+
+	pdf.SetY(pageHeight - 50)
+	pdf.SetFont(reportFont, "", 11)
+	half := width / 2
+	if data.CuratorName != "" {
+		pdf.CellFormat(half, 6, "Куратор: "+data.CuratorName, "", 0, "L", false, 0, "")
+	} else {
+		pdf.CellFormat(half, 6, "", "", 0, "L", false, 0, "")
+	}
+	if tmpl.SignatureName != nil {
+		pdf.CellFormat(half, 6, *tmpl.SignatureName, "", 0, "R", false, 0, "")
+	}
+	pdf.Ln(10)
+
+	pdf.SetTextColor(120, 120, 120)
+	pdf.SetFont(reportFont, "", 9)
+	pdf.CellFormat(width, 5, fmt.Sprintf("№ %s · выдан %s", certificate.Number, certificate.IssuedAt.Format(dateLayout)), "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
@@ -1,11 +1,13 @@
 package utils
 
-
 import (
 	"time"
 )
 
-const dateLayout = "02.01.2006"
+const (
+	dateLayout     = "02.01.2006"
+	dateTimeLayout = "02.01.2006 15:04"
+)
 
 // parseDate парсит строку с датой в формате DD.MM.YYYY и возвращает time.Time
 // Если строка пустая или nil, возвращает nil
@@ -25,4 +27,19 @@ func ParseDate(dateStr *string) (*time.Time, error) {
 // parseRequiredDate парсит обязательную строку с датой в формате DD.MM.YYYY
 func ParseRequiredDate(dateStr string) (time.Time, error) {
 	return time.Parse(dateLayout, dateStr)
-}
\ No newline at end of file
+}
+
+// ParseRequiredDateTime парсит дату и время в формате DD.MM.YYYY HH:MM в локальной зоне сервера
+func ParseRequiredDateTime(dateStr string) (time.Time, error) {
+	return time.ParseInLocation(dateTimeLayout, dateStr, time.Local)
+}
+
+// FormatDateTime форматирует время в DD.MM.YYYY HH:MM в локальной зоне сервера
+func FormatDateTime(t time.Time) string {
+	return t.In(time.Local).Format(dateTimeLayout)
+}
+
+// FormatDate форматирует дату в DD.MM.YYYY — обратное преобразование к ParseRequiredDate
+func FormatDate(t time.Time) string {
+	return t.Format(dateLayout)
+}
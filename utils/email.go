@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"it_school/config"
+	"mime"
 	"net/smtp"
 )
 
@@ -11,12 +12,12 @@ func SendEmail(to, subject, body string) error {
 	from := config.Config.SMTPEmail
 	password := config.Config.SMTPPassword
 
-
 	smtpHost := config.Config.SMTPHost
 	smtpPort := config.Config.SMTPPort
 
-	// Формируем заголовки и тело письма
-	message := fmt.Sprintf("Subject: %s\r\n\r\n%s", subject, body)
+	// Формируем заголовки и тело письма (UTF-8, чтобы кириллица не искажалась)
+	message := fmt.Sprintf("Subject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		mime.QEncoding.Encode("utf-8", subject), body)
 
 	// Аутентификация на SMTP-сервере
 	auth := smtp.PlainAuth("", from, password, smtpHost)
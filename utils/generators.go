@@ -9,13 +9,14 @@ import (
 	"fmt"
 	"it_school/config"
 	"it_school/logger"
+	"it_school/redact"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error){
+func HashPassword(password string) (string, error) {
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -23,48 +24,70 @@ func HashPassword(password string) (string, error){
 	return string(passwordHash), nil
 }
 
-
 func GenerateRefreshToken(userID uuid.UUID) (string, error) {
-    logger := logger.GetLogger()
-    // Генерация случайных данных для подписи
-    b := make([]byte, 32)
-    if _, err := rand.Read(b); err != nil {
-        logger.Error("Failed to generate random bytes for refresh token", zap.Error(err))
-        return "", err
-    }
+	logger := logger.GetLogger()
+	// Генерация случайных данных для подписи
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		logger.Error("Failed to generate random bytes for refresh token", zap.Error(err))
+		return "", err
+	}
+
+	// Кодируем user_id в base64
+	userIDBase64 := base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%d", userID)))
 
-    // Кодируем user_id в base64
-    userIDBase64 := base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%d", userID)))
+	// HMAC для подписи
+	mac := hmac.New(sha256.New, []byte(config.Config.JwtSecretKey))
+	mac.Write(b)
+	signature := mac.Sum(nil)
 
-    // HMAC для подписи
-    mac := hmac.New(sha256.New, []byte(config.Config.JwtSecretKey))
-    mac.Write(b)
-    signature := mac.Sum(nil)
+	// Генерация refresh token в формате userID.signature
+	refreshToken := fmt.Sprintf("%s.%s", userIDBase64, base64.URLEncoding.EncodeToString(signature))
 
-    // Генерация refresh token в формате userID.signature
-    refreshToken := fmt.Sprintf("%s.%s", userIDBase64, base64.URLEncoding.EncodeToString(signature))
-    
-    logger.Debug("Refresh token generated", zap.Any("user_id", userID))
+	logger.Debug("Refresh token generated", zap.Any("user_id", userID))
 
-    return refreshToken, nil
+	return refreshToken, nil
 }
 
 // generateResetToken — генерирует случайный токен для сброса пароля
 func GenerateResetToken() (string, error) {
-    logger := logger.GetLogger()
-    b := make([]byte, 16) // Создаем 16 байт случайных данных
-    _, err := rand.Read(b)
-    if err != nil {
-        logger.Error("Failed to generate random bytes for reset token", zap.Error(err))
-        return "", err // Ошибка при генерации токена
-    }
-    token := hex.EncodeToString(b)
-    logger.Debug("Reset token generated", zap.String("token", token))
-    return token, nil // Возвращаем токен в виде строки
+	logger := logger.GetLogger()
+	b := make([]byte, 16) // Создаем 16 байт случайных данных
+	_, err := rand.Read(b)
+	if err != nil {
+		logger.Error("Failed to generate random bytes for reset token", zap.Error(err))
+		return "", err // Ошибка при генерации токена
+	}
+	token := hex.EncodeToString(b)
+	logger.Debug("Reset token generated", zap.String("token", redact.Secret(token)))
+	return token, nil // Возвращаем токен в виде строки
 }
 
 // checkPasswordHash — проверяет правильность пароля, сравнивая его с хешом
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// GenerateCertificateNumber — публичный номер сертификата вида ITS-2025-7KQ4M2XA.
+// Случайная часть не позволяет перебором найти чужие сертификаты через страницу проверки.
+func GenerateCertificateNumber(year int) (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return fmt.Sprintf("ITS-%d-%s", year, b), nil
+}
+
+// GenerateWebhookSecret — секрет подписи вебхуков, 32 случайных байта в hex
+func GenerateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
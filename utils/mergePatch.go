@@ -0,0 +1,35 @@
+package utils
+
+import "encoding/json"
+
+// MergePatch применяет JSON Merge Patch (RFC 7396) к документу original: поля из patch
+// заменяют поля документа, null удаляет поле, вложенные объекты сливаются рекурсивно
+func MergePatch(original, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(original, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Примеры из приложения A RFC 7396 и случаи, важные для PATCH карточек
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+	}{
+		{name: "replace field", original: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add field", original: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove field", original: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "remove one of two", original: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "replace array", original: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "array is not merged", original: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "nested merge", original: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "object over scalar", original: `{"a":"c"}`, patch: `{"a":{"b":"c"}}`, want: `{"a":{"b":"c"}}`},
+		{name: "null inside new object", original: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "patch replaces non-object", original: `["a","b"]`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "non-object patch replaces document", original: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null patch", original: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{name: "empty patch keeps document", original: `{"full_name":"Иван","phone_number":"+77011234567"}`, patch: `{}`, want: `{"full_name":"Иван","phone_number":"+77011234567"}`},
+		{name: "numbers survive", original: `{"amount":15000.5,"count":3}`, patch: `{"count":4}`, want: `{"amount":15000.5,"count":4}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.original), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatchInvalidJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+	}{
+		{name: "invalid patch", original: `{}`, patch: `{"a":`},
+		{name: "empty patch", original: `{}`, patch: ``},
+		{name: "invalid original", original: `{`, patch: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MergePatch([]byte(tt.original), []byte(tt.patch)); err == nil {
+				t.Error("MergePatch() error = nil, want error")
+			}
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("result is not JSON: %s", got)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad expectation %s", want)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"it_school/models"
+	"path/filepath"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const reportFont = "report"
+
+// RenderReportCard формирует PDF-табель для родителей. fontPath — TTF-шрифт с кириллицей
+func RenderReportCard(w io.Writer, card models.ReportCard, fontPath string) error {
+	pdf := fpdf.New("P", "mm", "A4", filepath.Dir(fontPath))
+	pdf.AddUTF8Font(reportFont, "", filepath.Base(fontPath))
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("не удалось загрузить шрифт %s: %w", fontPath, err)
+	}
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	pdf.SetFont(reportFont, "", 18)
+	pdf.CellFormat(width, 10, "Табель успеваемости", "", 1, "C", false, 0, "")
+	pdf.SetFont(reportFont, "", 11)
+	pdf.CellFormat(width, 6, fmt.Sprintf("%s — %s", card.From.Format(dateLayout), card.To.Format(dateLayout)), "", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	line := func(label, value string) {
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(45, 7, label, "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(width-45, 7, value, "", 1, "L", false, 0, "")
+	}
+	line("Студент", card.StudentName)
+	line("Родитель", card.ParentName)
+	line("Курс", card.CourseTitle)
+	if card.CuratorName != nil {
+		line("Куратор", *card.CuratorName)
+	}
+	pdf.Ln(4)
+
+	section := func(title string) {
+		pdf.SetFont(reportFont, "", 13)
+		pdf.CellFormat(width, 9, title, "B", 1, "L", false, 0, "")
+		pdf.SetFont(reportFont, "", 11)
+		pdf.Ln(2)
+	}
+
+	section("Оценки по критериям")
+	if len(card.Criteria) == 0 {
+		pdf.CellFormat(width, 7, "Критерии оценивания для курса не заданы", "", 1, "L", false, 0, "")
+	}
+	barWidth := width - 80
+	for _, criterion := range card.Criteria {
+		pdf.CellFormat(60, 7, criterion.Title, "", 0, "L", false, 0, "")
+		x, y := pdf.GetXY()
+		pdf.SetFillColor(230, 230, 230)
+		pdf.Rect(x, y+1.5, barWidth, 4, "F")
+		value := "—"
+		if criterion.Average != nil {
+			share := (*criterion.Average - float64(criterion.MinScore)) / float64(criterion.MaxScore-criterion.MinScore)
+			pdf.SetFillColor(76, 141, 230)
+			pdf.Rect(x, y+1.5, barWidth*share, 4, "F")
+			value = fmt.Sprintf("%.1f / %d", *criterion.Average, criterion.MaxScore)
+		}
+		pdf.SetX(x + barWidth)
+		pdf.CellFormat(20, 7, value, "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	section("Посещаемость и программа")
+	line("Проведено уроков", fmt.Sprint(card.LessonsHeld))
+	line("Пропущено уроков", fmt.Sprint(card.LessonsMissed))
+	line("Пройдено тем", fmt.Sprintf("%d из %d", card.TopicsCovered, card.TopicsTotal))
+	pdf.Ln(4)
+
+	section("Домашние задания")
+	line("Сдано", fmt.Sprintf("%d из %d", card.HomeworkDone, card.HomeworkTotal))
+	if card.HomeworkGrade != nil {
+		line("Средняя оценка", fmt.Sprintf("%.0f", *card.HomeworkGrade))
+	}
+
+	if len(card.Comments) > 0 {
+		pdf.Ln(4)
+		section("Отзывы куратора")
+		for _, comment := range card.Comments {
+			pdf.MultiCell(width, 6, "• "+comment, "", "L", false)
+			pdf.Ln(1)
+		}
+	}
+
+	pdf.SetY(-25)
+	pdf.SetFont(reportFont, "", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(width, 6, "Сформировано "+card.GeneratedAt.Format(dateLayout), "", 1, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignWebhook подписывает тело вебхука: sha256=HMAC-SHA256(secret, "<timestamp>.<body>") в hex.
+// Временная метка в подписи не дает повторно отправить перехваченный запрос позже
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,62 @@
+package utils
+
+import "testing"
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "json body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"id":1}`,
+			want:      "sha256=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11",
+		},
+		{
+			name:      "empty body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      "",
+			want:      "sha256=4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("SignWebhook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Подпись зависит от каждого из входов, иначе ее можно переиспользовать
+func TestSignWebhookInputsMatter(t *testing.T) {
+	base := SignWebhook("secret", "1700000000", []byte("body"))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+	}{
+		{name: "secret", secret: "other", timestamp: "1700000000", body: "body"},
+		{name: "timestamp", secret: "secret", timestamp: "1700000001", body: "body"},
+		{name: "body", secret: "secret", timestamp: "1700000000", body: "body2"},
+		// Разделитель не дает перенести цифры метки в тело
+		{name: "boundary", secret: "secret", timestamp: "170000000", body: "0.body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)) == base {
+				t.Errorf("changing %s did not change the signature", tt.name)
+			}
+		})
+	}
+}
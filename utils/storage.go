@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// LocalStorage хранит загруженные файлы в каталоге на диске
+type LocalStorage struct {
+	dir string
+}
+
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// Save записывает файл под сгенерированным именем и возвращает путь относительно каталога хранилища.
+// Исходное имя не используется в пути, чтобы исключить обход каталогов.
+func (s *LocalStorage) Save(prefix, fileName string, src io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(filepath.Join(s.dir, prefix), 0o750); err != nil {
+		return "", 0, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	relPath := filepath.Join(prefix, uuid.NewString()+ext)
+
+	dst, err := os.OpenFile(filepath.Join(s.dir, relPath), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", 0, err
+	}
+
+	size, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filepath.Join(s.dir, relPath))
+		return "", 0, err
+	}
+	return relPath, size, nil
+}
+
+// Path возвращает абсолютный путь к сохраненному файлу
+func (s *LocalStorage) Path(relPath string) string {
+	return filepath.Join(s.dir, filepath.Clean("/"+relPath))
+}
+
+func (s *LocalStorage) Remove(relPath string) error {
+	return os.Remove(s.Path(relPath))
+}
@@ -2,13 +2,13 @@ package utils
 
 import "it_school/models"
 
-func 	HasAccessToType(role *models.Role, typ string) bool {
+func HasAccessToType(role *models.Role, typ string) bool {
 	switch typ {
-	case "урок":
+	case models.AttendanceTypeLesson:
 		return role.Permissions["access_curator"] || role.Permissions["access_settings"]
-	case "пролонгация", "заморозка":
+	case models.AttendanceTypeProlongation, models.AttendanceTypeFreeze:
 		return role.Permissions["access_manager"] || role.Permissions["access_settings"]
 	default:
 		return false
 	}
-}
\ No newline at end of file
+}
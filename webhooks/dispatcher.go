@@ -0,0 +1,195 @@
+// Package webhooks доставляет доменные события подписчикам из webhook_subscriptions
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"it_school/events"
+	"it_school/logger"
+	"it_school/repositories"
+	"it_school/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Заголовки исходящих вебхуков
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	pollInterval   = 5 * time.Second
+	batchSize      = 50
+	baseBackoff    = 10 * time.Second
+	maxBackoff     = time.Hour
+	// deliveryLease — на это время выбранные доставки скрыты от других экземпляров.
+	// Покрывает пачку целиком: batchSize попыток по requestTimeout
+	deliveryLease = batchSize * requestTimeout
+)
+
+// Dispatcher сохраняет доставки событий в webhook_deliveries и отправляет их в фоне.
+// Состояние доставок хранится в БД, поэтому повторы переживают перезапуск приложения
+type Dispatcher struct {
+	webhooksRepo *repositories.WebhooksRepository
+	maxAttempts  int
+	http         *http.Client
+	wake         chan struct{}
+}
+
+func NewDispatcher(webhooksRepo *repositories.WebhooksRepository, maxAttempts int) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		webhooksRepo: webhooksRepo,
+		maxAttempts:  maxAttempts,
+		http:         &http.Client{Timeout: requestTimeout},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Handle — обработчик доменных событий из outbox: создает доставки для подписчиков.
+// Ошибка возвращается диспетчеру outbox, и событие будет обработано повторно
+func (d *Dispatcher) Handle(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	count, err := d.webhooksRepo.EnqueueDeliveries(ctx, event, payload)
+	if err != nil {
+		return fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+	if count > 0 {
+		d.Wake()
+	}
+	return nil
+}
+
+// Wake запускает отправку, не дожидаясь очередного опроса
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет доставки до отмены контекста
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	logger := logger.GetLogger()
+
+	for ctx.Err() == nil {
+		deliveries, err := d.webhooksRepo.DueDeliveries(ctx, batchSize, deliveryLease)
+		if err != nil {
+			logger.Error("Failed to fetch webhook deliveries", zap.Error(err))
+			return
+		}
+
+		for _, delivery := range deliveries {
+			d.deliver(ctx, delivery)
+		}
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery repositories.PendingDelivery) {
+	logger := logger.GetLogger()
+
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.webhooksRepo.MarkDelivered(ctx, delivery.Id, status); err != nil {
+			logger.Error("Failed to mark webhook delivered", zap.String("delivery_id", delivery.Id.String()), zap.Error(err))
+		}
+		return
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	attempts := delivery.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < d.maxAttempts {
+		next := time.Now().Add(backoff(attempts))
+		nextAttemptAt = &next
+	}
+
+	logger.Warn("Webhook delivery failed",
+		zap.String("delivery_id", delivery.Id.String()),
+		zap.String("event", delivery.Event),
+		zap.Int("attempt", attempts),
+		zap.Bool("final", nextAttemptAt == nil),
+		zap.Error(err))
+
+	if err := d.webhooksRepo.MarkAttemptFailed(ctx, delivery.Id, responseStatus, err.Error(), nextAttemptAt); err != nil {
+		logger.Error("Failed to record webhook attempt", zap.String("delivery_id", delivery.Id.String()), zap.Error(err))
+	}
+}
+
+// backoff — экспоненциальная задержка перед попыткой attempts+1
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// send выполняет одну попытку доставки и возвращает код ответа (0, если ответа не было)
+func (d *Dispatcher) send(ctx context.Context, delivery repositories.PendingDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.Id.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, utils.SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"it_school/logger"
+	"it_school/repositories"
+	"it_school/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TrialReminders отправляет кураторам письма о пробных уроках, которые начнутся в течение within.
+// Напоминание отправляется один раз; при переносе урока на другое время — повторно
+func TrialReminders(leadsRepo *repositories.LeadsRepository, within time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		reminders, err := leadsRepo.DueTrialReminders(ctx, within)
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			body := fmt.Sprintf("Пробный урок с %s запланирован на %s.", reminder.LeadName, utils.FormatDateTime(reminder.Date))
+			if reminder.CuratorName != nil {
+				body = fmt.Sprintf("Здравствуйте, %s!\r\n\r\n%s", *reminder.CuratorName, body)
+			}
+			if err := utils.SendEmail(reminder.CuratorEmail, "Напоминание о пробном уроке", body); err != nil {
+				logger.Warn("Failed to send trial reminder",
+					zap.String("trial_id", reminder.TrialId.String()),
+					zap.Error(err))
+				continue
+			}
+			if err := leadsRepo.MarkTrialReminded(ctx, reminder.TrialId); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"context"
+	"it_school/logger"
+	"it_school/repositories"
+
+	"go.uber.org/zap"
+)
+
+// LifecycleFreezes переводит студентов в frozen на время заморозки из посещаемости
+// и возвращает в active, когда она закончилась
+func LifecycleFreezes(studentsRepo *repositories.StudentsRepository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		frozen, unfrozen, err := studentsRepo.SyncFreezes(ctx)
+		if err != nil {
+			return err
+		}
+		if frozen > 0 || unfrozen > 0 {
+			logger.GetLogger().Info("Student freezes synced", zap.Int("frozen", frozen), zap.Int("unfrozen", unfrozen))
+		}
+		return nil
+	}
+}
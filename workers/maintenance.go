@@ -0,0 +1,56 @@
+package workers
+
+import (
+	"context"
+	"it_school/events"
+	"it_school/logger"
+	"it_school/metrics"
+	"it_school/repositories"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaintenanceResult — количество записей, удаленных при обслуживании БД
+type MaintenanceResult struct {
+	ExpiredSessions    int64 `json:"expired_sessions"`
+	ExpiredResetTokens int64 `json:"expired_reset_tokens"`
+	ProcessedOutbox    int64 `json:"processed_outbox"`
+}
+
+// RunMaintenance удаляет истекшие refresh-сессии, просроченные токены сброса пароля
+// и события outbox, обработанные раньше чем outboxRetention назад (0 — не удалять).
+// Используется периодическим заданием и командой `cleanup`
+func RunMaintenance(ctx context.Context, sessionsRepo *repositories.SessionsRepository, authRepo *repositories.AuthRepository, outbox *events.Dispatcher, outboxRetention time.Duration) (MaintenanceResult, error) {
+	var result MaintenanceResult
+	var err error
+
+	if result.ExpiredSessions, err = sessionsRepo.DeleteExpired(ctx); err != nil {
+		return result, err
+	}
+	if result.ExpiredResetTokens, err = authRepo.ClearExpiredResetTokens(ctx); err != nil {
+		return result, err
+	}
+	if outboxRetention > 0 {
+		if result.ProcessedOutbox, err = outbox.PurgeProcessed(ctx, time.Now().Add(-outboxRetention)); err != nil {
+			return result, err
+		}
+	}
+
+	metrics.MaintenanceDeleted.WithLabelValues("sessions").Add(float64(result.ExpiredSessions))
+	metrics.MaintenanceDeleted.WithLabelValues("reset_tokens").Add(float64(result.ExpiredResetTokens))
+	metrics.MaintenanceDeleted.WithLabelValues("outbox").Add(float64(result.ProcessedOutbox))
+	logger.GetLogger().Info("Maintenance finished",
+		zap.Int64("expired_sessions", result.ExpiredSessions),
+		zap.Int64("expired_reset_tokens", result.ExpiredResetTokens),
+		zap.Int64("processed_outbox", result.ProcessedOutbox))
+	return result, nil
+}
+
+// Maintenance — периодическое задание обслуживания БД
+func Maintenance(sessionsRepo *repositories.SessionsRepository, authRepo *repositories.AuthRepository, outbox *events.Dispatcher, outboxRetention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := RunMaintenance(ctx, sessionsRepo, authRepo, outbox, outboxRetention)
+		return err
+	}
+}
@@ -0,0 +1,133 @@
+// Package workers управляет фоновыми задачами приложения: постоянными воркерами
+// (доставка событий, синхронизация с CRM) и периодическими заданиями (очистка сессий, напоминания)
+package workers
+
+import (
+	"context"
+	"it_school/logger"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// restartDelay — пауза перед перезапуском воркера, завершившегося паникой
+const restartDelay = 5 * time.Second
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Registry запускает зарегистрированные воркеры и останавливает их при завершении приложения.
+// Паника в воркере не роняет процесс: она логируется, а воркер перезапускается
+type Registry struct {
+	workers []worker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Go регистрирует постоянный воркер. run должен вернуться после отмены контекста
+func (r *Registry) Go(name string, run func(ctx context.Context)) {
+	r.workers = append(r.workers, worker{name: name, run: run})
+}
+
+// Every регистрирует периодическое задание: первый запуск сразу после старта, затем раз в interval.
+// Начатый запуск доводится до конца даже при остановке приложения. interval <= 0 отключает задание
+func (r *Registry) Every(name string, interval time.Duration, job func(ctx context.Context) error) {
+	if interval <= 0 {
+		logger.GetLogger().Info("Periodic job disabled", zap.String("job", name))
+		return
+	}
+	r.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			started := time.Now()
+			if err := job(context.WithoutCancel(ctx)); err != nil {
+				logger.GetLogger().Error("Periodic job failed", zap.String("job", name), zap.Error(err))
+			} else {
+				logger.GetLogger().Debug("Periodic job finished", zap.String("job", name), zap.Duration("took", time.Since(started)))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Start запускает все зарегистрированные воркеры
+func (r *Registry) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	for _, w := range r.workers {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.supervise(ctx, w)
+		}()
+	}
+	logger.GetLogger().Info("Background workers started", zap.Int("count", len(r.workers)))
+}
+
+// Stop отменяет контекст воркеров и ждет их завершения, но не дольше дедлайна ctx
+func (r *Registry) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Registry) supervise(ctx context.Context, w worker) {
+	for {
+		if !r.runSafe(ctx, w) || ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restartDelay):
+		}
+		logger.GetLogger().Warn("Restarting background worker", zap.String("worker", w.name))
+	}
+}
+
+// runSafe выполняет воркер и возвращает true, если он завершился паникой
+func (r *Registry) runSafe(ctx context.Context, w worker) (panicked bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.GetLogger().Error("Background worker panicked",
+				zap.String("worker", w.name),
+				zap.Any("error", rec),
+				zap.ByteString("stack", debug.Stack()))
+			panicked = true
+		}
+	}()
+
+	w.run(ctx)
+	return false
+}
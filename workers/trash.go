@@ -0,0 +1,33 @@
+package workers
+
+import (
+	"context"
+	"it_school/logger"
+	"it_school/metrics"
+	"it_school/models"
+	"it_school/repositories"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PurgeTrash окончательно удаляет записи, пролежавшие в корзине дольше retention.
+// Типы обрабатываются по порядку models.TrashTypes, чтобы зависимые записи уходили раньше
+func PurgeTrash(trashRepo *repositories.TrashRepository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		before := time.Now().Add(-retention)
+
+		fields := make([]zap.Field, 0, len(models.TrashTypes))
+		for _, itemType := range models.TrashTypes {
+			purged, err := trashRepo.Purge(ctx, itemType, before)
+			if err != nil {
+				return err
+			}
+			metrics.MaintenanceDeleted.WithLabelValues("trash_" + itemType).Add(float64(purged))
+			fields = append(fields, zap.Int64(itemType, purged))
+		}
+
+		logger.GetLogger().Info("Trash purged", fields...)
+		return nil
+	}
+}